@@ -0,0 +1,149 @@
+// Command import sends a CSV catalog to the track import API of a running
+// server and prints its report. It is a dry run unless -commit is set.
+//
+//	go run ./cmd/import -file catalog.csv -map title="Track Title" -map isrc=ISRC
+//	go run ./cmd/import -file catalog.csv -map title="Track Title" -map isrc=ISRC -commit
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/rolexkdev/emvn-music-library-server/dto"
+)
+
+// mappingFlag collects the repeated -map field=column flags
+type mappingFlag map[string]string
+
+func (m mappingFlag) String() string {
+	return fmt.Sprint(map[string]string(m))
+}
+
+func (m mappingFlag) Set(value string) error {
+	field, column, ok := strings.Cut(value, "=")
+	if !ok || field == "" || column == "" {
+		return fmt.Errorf("expected field=column, got %q", value)
+	}
+	m[field] = column
+	return nil
+}
+
+func main() {
+	mapping := mappingFlag{}
+	server := flag.String("server", "http://localhost:8088/api/v1", "base URL of the API")
+	apiKey := flag.String("key", os.Getenv("API_KEY"), "API key sent in the X-API-Key header, $API_KEY by default")
+	path := flag.String("file", "", "CSV file to import")
+	delimiter := flag.String("delimiter", "", "column delimiter, a comma by default")
+	commit := flag.Bool("commit", false, "write the tracks instead of a dry run")
+	flag.Var(mapping, "map", "field=column header, repeat for several fields")
+	flag.Parse()
+
+	if *path == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	report, err := send(*server, *apiKey, *path, *delimiter, mapping, *commit)
+	if err != nil {
+		log.Fatal(err)
+	}
+	printReport(report)
+	if report.Errors > 0 {
+		os.Exit(1)
+	}
+}
+
+// send posts the CSV file to the import API and returns its report
+func send(server, apiKey, path, delimiter string, mapping mappingFlag, commit bool) (*dto.ImportReport, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	part, err := form.CreateFormFile("file", filepath.Base(path))
+	if err != nil {
+		return nil, err
+	}
+	if _, err := io.Copy(part, file); err != nil {
+		return nil, err
+	}
+	if len(mapping) > 0 {
+		data, _ := json.Marshal(mapping)
+		form.WriteField("mapping", string(data))
+	}
+	if delimiter != "" {
+		form.WriteField("delimiter", delimiter)
+	}
+	form.WriteField("commit", strconv.FormatBool(commit))
+	form.Close()
+
+	request, err := http.NewRequest(http.MethodPost, strings.TrimSuffix(server, "/")+"/tracks/import", &body)
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Set("Content-Type", form.FormDataContentType())
+	if apiKey != "" {
+		request.Header.Set("X-API-Key", apiKey)
+	}
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	// A rejected commit still carries the report
+	var result struct {
+		Data    json.RawMessage `json:"data"`
+		Message string          `json:"message"`
+	}
+	if err := json.NewDecoder(response.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("import answered %s: %w", response.Status, err)
+	}
+	var report dto.ImportReport
+	if err := json.Unmarshal(result.Data, &report); err != nil || report.Results == nil {
+		return nil, fmt.Errorf("import answered %s: %s", response.Status, result.Data)
+	}
+	return &report, nil
+}
+
+func printReport(report *dto.ImportReport) {
+	for _, row := range report.Results {
+		line := fmt.Sprintf("line %d: %s", row.Line, row.Action)
+		if row.TrackID != "" {
+			line += " " + row.TrackID
+		}
+		if row.MatchedBy != "" {
+			line += " (matched by " + row.MatchedBy + ")"
+		}
+		if row.Status != "" {
+			line += " -> " + row.Status
+		}
+		fmt.Println(line)
+		for _, change := range row.Changes {
+			fmt.Printf("    %s: %v -> %v\n", change.Field, change.Old, change.New)
+		}
+		for _, err := range row.Errors {
+			fmt.Printf("    error: %s\n", err)
+		}
+	}
+
+	mode := "dry run"
+	if report.Committed {
+		mode = "committed"
+	}
+	fmt.Printf("%s: %d rows, %d to create, %d to update, %d unchanged, %d with errors\n",
+		mode, report.Rows, report.Create, report.Update, report.Unchanged, report.Errors)
+}
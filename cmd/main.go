@@ -1,12 +1,18 @@
 package main
 
 import (
+	"context"
 	"log"
 
-	"github.com/go-playground/validator"
 	"github.com/rolexkdev/emvn-music-library-server/common/utils"
 	"github.com/rolexkdev/emvn-music-library-server/config"
+	"github.com/rolexkdev/emvn-music-library-server/internal/catalog"
+	"github.com/rolexkdev/emvn-music-library-server/internal/jobs"
+	"github.com/rolexkdev/emvn-music-library-server/internal/licensing"
+	"github.com/rolexkdev/emvn-music-library-server/internal/media"
+	"github.com/rolexkdev/emvn-music-library-server/internal/migrations"
 	"github.com/rolexkdev/emvn-music-library-server/internal/models"
+	"github.com/rolexkdev/emvn-music-library-server/internal/search"
 	"github.com/rolexkdev/emvn-music-library-server/server"
 )
 
@@ -18,6 +24,18 @@ func main() {
 	}
 
 	models.Setup(cfg)
-	utils.Validator = validator.New()
+	if err := migrations.Run(context.Background(), models.DB); err != nil {
+		log.Fatalf("Failed to migrate database: %v", err)
+	}
+	media.Setup(cfg)
+	jobs.Setup(cfg)
+	search.Setup(cfg)
+	catalog.Setup(cfg)
+	licensing.Setup(cfg)
+	utils.Validator = utils.NewValidator()
+	licensing.RegisterValidation(utils.Validator)
+
+	// Media processing runs in the background next to the http server
+	jobs.Start(context.Background())
 	server.InitServer(cfg)
 }
@@ -48,6 +48,11 @@ func (g *Gin) Response201(data interface{}) {
 	return
 }
 
+func (g *Gin) Response202(data interface{}) {
+	g.Response(http.StatusAccepted, e.ACCEPTED, data)
+	return
+}
+
 func (g *Gin) Response400(errCode int, data interface{}) {
 	g.Response(http.StatusBadRequest, errCode, data)
 	return
@@ -73,6 +78,26 @@ func (g *Gin) Response409(errCode int, data interface{}) {
 	return
 }
 
+func (g *Gin) Response412(errCode int, data interface{}) {
+	g.Response(http.StatusPreconditionFailed, errCode, data)
+	return
+}
+
+func (g *Gin) Response415(errCode int, data interface{}) {
+	g.Response(http.StatusUnsupportedMediaType, errCode, data)
+	return
+}
+
+func (g *Gin) Response428(errCode int, data interface{}) {
+	g.Response(http.StatusPreconditionRequired, errCode, data)
+	return
+}
+
+func (g *Gin) Response451(errCode int, data interface{}) {
+	g.Response(http.StatusUnavailableForLegalReasons, errCode, data)
+	return
+}
+
 func (g *Gin) Response500(errCode int, data interface{}) {
 	g.Response(http.StatusInternalServerError, errCode, data)
 	return
@@ -1,12 +1,17 @@
 package e
 
 const (
-	SUCCESS        = 200
-	CREATED        = 201
-	ERROR          = 500
-	INVALID_PARAMS = 400
-	UNAUTHORIZED   = 401
-	FORBIDDEN      = 403
-	NOTFOUND       = 404
-	CONFLICT       = 409
+	SUCCESS                = 200
+	CREATED                = 201
+	ACCEPTED               = 202
+	ERROR                  = 500
+	INVALID_PARAMS         = 400
+	UNAUTHORIZED           = 401
+	FORBIDDEN              = 403
+	NOTFOUND               = 404
+	CONFLICT               = 409
+	PRECONDITION_FAILED    = 412
+	UNSUPPORTED_MEDIA_TYPE = 415
+	PRECONDITION_REQUIRED  = 428
+	UNAVAILABLE            = 451
 )
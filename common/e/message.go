@@ -1,14 +1,19 @@
 package e
 
 var MsgFlags = map[int]string{
-	SUCCESS:        "Ok",
-	CREATED:        "Ok",
-	ERROR:          "Fail",
-	INVALID_PARAMS: "Invalid parameters",
-	UNAUTHORIZED:   "Unauthorized",
-	FORBIDDEN:      "FORBIDDEN",
-	NOTFOUND:       "Not found",
-	CONFLICT:       "Conflict",
+	SUCCESS:                "Ok",
+	CREATED:                "Ok",
+	ACCEPTED:               "Accepted",
+	ERROR:                  "Fail",
+	INVALID_PARAMS:         "Invalid parameters",
+	UNAUTHORIZED:           "Unauthorized",
+	FORBIDDEN:              "FORBIDDEN",
+	NOTFOUND:               "Not found",
+	CONFLICT:               "Conflict",
+	PRECONDITION_FAILED:    "Precondition failed",
+	UNSUPPORTED_MEDIA_TYPE: "Unsupported media type",
+	PRECONDITION_REQUIRED:  "Precondition required",
+	UNAVAILABLE:            "Unavailable for legal reasons",
 }
 
 // GetMsg get error information based on Code
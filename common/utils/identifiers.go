@@ -0,0 +1,112 @@
+package utils
+
+import (
+	"math"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"github.com/go-playground/validator"
+)
+
+// ISRC without separators: country code, registrant code, year of reference
+// and designation code
+var isrcPattern = regexp.MustCompile(`^[A-Z]{2}[A-Z0-9]{3}[0-9]{7}$`)
+
+// NormalizeISRC returns isrc in its stored form, uppercase without hyphens or
+// spaces, e.g. US-RC1-76-07839 becomes USRC17607839
+func NormalizeISRC(isrc string) string {
+	isrc = strings.ToUpper(strings.TrimSpace(isrc))
+	return strings.NewReplacer("-", "", " ", "").Replace(isrc)
+}
+
+// IsISRC reports whether isrc is a well formed ISRC, separators allowed
+func IsISRC(isrc string) bool {
+	return isrcPattern.MatchString(NormalizeISRC(isrc))
+}
+
+// ISWC without separators: T, nine digit work identifier and check digit
+var iswcPattern = regexp.MustCompile(`^T[0-9]{10}$`)
+
+// NormalizeISWC returns iswc in its stored form, uppercase without hyphens,
+// dots or spaces, e.g. T-034.524.680-1 becomes T0345246801
+func NormalizeISWC(iswc string) string {
+	iswc = strings.ToUpper(strings.TrimSpace(iswc))
+	return strings.NewReplacer("-", "", ".", "", " ", "").Replace(iswc)
+}
+
+// IsISWC reports whether iswc is a well formed ISWC with a valid check digit,
+// separators allowed
+func IsISWC(iswc string) bool {
+	iswc = NormalizeISWC(iswc)
+	if !iswcPattern.MatchString(iswc) {
+		return false
+	}
+	sum := 1
+	for i := 1; i <= 9; i++ {
+		sum += i * int(iswc[i]-'0')
+	}
+	return int(iswc[10]-'0') == (10-sum%10)%10
+}
+
+// ISO 3166-1 alpha-2 country code
+var territoryPattern = regexp.MustCompile(`^[A-Z]{2}$`)
+
+// NormalizeTerritory returns territory in its stored form, uppercase
+func NormalizeTerritory(territory string) string {
+	return strings.ToUpper(strings.TrimSpace(territory))
+}
+
+// IsTerritory reports whether territory is shaped like an ISO 3166-1 alpha-2
+// country code, e.g. VN, without regard to case
+func IsTerritory(territory string) bool {
+	return territoryPattern.MatchString(NormalizeTerritory(territory))
+}
+
+// Total the shares of a split add up to, with a tolerance for shares such as
+// 33.33
+const (
+	sharesTotal     = 100
+	sharesTolerance = 0.01
+)
+
+// validShares reports whether the Share fields of the elements of the slice
+// field sum to sharesTotal, an empty slice has no shares to check
+func validShares(field reflect.Value) bool {
+	if field.Kind() != reflect.Slice || field.Len() == 0 {
+		return true
+	}
+	total := 0.0
+	for i := 0; i < field.Len(); i++ {
+		element := reflect.Indirect(field.Index(i))
+		if element.Kind() != reflect.Struct {
+			return false
+		}
+		share := element.FieldByName("Share")
+		if share.Kind() != reflect.Float64 {
+			return false
+		}
+		total += share.Float()
+	}
+	return math.Abs(total-sharesTotal) <= sharesTolerance
+}
+
+// NewValidator returns a validator knowing the identifier tags of the catalog:
+// isrc, iswc, territory and shares, which checks the shares of a list of
+// splits
+func NewValidator() *validator.Validate {
+	v := validator.New()
+	v.RegisterValidation("isrc", func(fl validator.FieldLevel) bool {
+		return IsISRC(fl.Field().String())
+	})
+	v.RegisterValidation("iswc", func(fl validator.FieldLevel) bool {
+		return IsISWC(fl.Field().String())
+	})
+	v.RegisterValidation("territory", func(fl validator.FieldLevel) bool {
+		return IsTerritory(fl.Field().String())
+	})
+	v.RegisterValidation("shares", func(fl validator.FieldLevel) bool {
+		return validShares(fl.Field())
+	})
+	return v
+}
@@ -0,0 +1,352 @@
+package utils
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// assertJSON fails unless got and want hold the same JSON value
+func assertJSON(t *testing.T, got []byte, want string) {
+	t.Helper()
+	var gotValue, wantValue interface{}
+	if err := json.Unmarshal(got, &gotValue); err != nil {
+		t.Fatalf("result %s is not JSON: %v", got, err)
+	}
+	if err := json.Unmarshal([]byte(want), &wantValue); err != nil {
+		t.Fatalf("expected %s is not JSON: %v", want, err)
+	}
+	if !reflect.DeepEqual(gotValue, wantValue) {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}
+
+func TestMergePatch(t *testing.T) {
+	tests := []struct {
+		name   string
+		doc    string
+		patch  string
+		want   string
+		errWas error
+	}{
+		// RFC 7396 appendix A
+		{name: "replace member", doc: `{"a":"b"}`, patch: `{"a":"c"}`, want: `{"a":"c"}`},
+		{name: "add member", doc: `{"a":"b"}`, patch: `{"b":"c"}`, want: `{"a":"b","b":"c"}`},
+		{name: "remove member", doc: `{"a":"b"}`, patch: `{"a":null}`, want: `{}`},
+		{name: "remove one of two", doc: `{"a":"b","b":"c"}`, patch: `{"a":null}`, want: `{"b":"c"}`},
+		{name: "array by string", doc: `{"a":["b"]}`, patch: `{"a":"c"}`, want: `{"a":"c"}`},
+		{name: "string by array", doc: `{"a":"c"}`, patch: `{"a":["b"]}`, want: `{"a":["b"]}`},
+		{name: "nested null", doc: `{"a":{"b":"c"}}`, patch: `{"a":{"b":"d","c":null}}`, want: `{"a":{"b":"d"}}`},
+		{name: "arrays are replaced", doc: `{"a":[{"b":"c"}]}`, patch: `{"a":[1]}`, want: `{"a":[1]}`},
+		{name: "array document", doc: `["a","b"]`, patch: `["c","d"]`, want: `["c","d"]`},
+		{name: "object by array", doc: `{"a":"b"}`, patch: `["c"]`, want: `["c"]`},
+		{name: "null patch", doc: `{"a":"foo"}`, patch: `null`, want: `null`},
+		{name: "string patch", doc: `{"a":"foo"}`, patch: `"bar"`, want: `"bar"`},
+		{name: "null in document kept", doc: `{"e":null}`, patch: `{"a":1}`, want: `{"e":null,"a":1}`},
+		{name: "object on array", doc: `[1,2]`, patch: `{"a":"b","c":null}`, want: `{"a":"b"}`},
+		{name: "nested null on missing member", doc: `{}`, patch: `{"a":{"bb":{"ccc":null}}}`, want: `{"a":{"bb":{}}}`},
+
+		{name: "deep nested null", doc: `{"a":{"b":{"c":1,"d":2}}}`, patch: `{"a":{"b":{"c":null}}}`, want: `{"a":{"b":{"d":2}}}`},
+		{name: "null of a missing member", doc: `{"a":1}`, patch: `{"b":null}`, want: `{"a":1}`},
+		{name: "large number kept", doc: `{"a":1}`, patch: `{"a":9007199254740993}`, want: `{"a":9007199254740993}`},
+		{name: "malformed patch", doc: `{}`, patch: `{"a":`, errWas: ErrInvalidPatch},
+		{name: "trailing data", doc: `{}`, patch: `{} {}`, errWas: ErrInvalidPatch},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := MergePatch([]byte(tt.doc), []byte(tt.patch))
+			if tt.errWas != nil {
+				if !errors.Is(err, tt.errWas) {
+					t.Fatalf("MergePatch error = %v, want %v", err, tt.errWas)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("MergePatch failed: %v", err)
+			}
+			assertJSON(t, got, tt.want)
+		})
+	}
+}
+
+func TestJSONPatch(t *testing.T) {
+	tests := []struct {
+		name   string
+		doc    string
+		patch  string
+		want   string
+		errWas error
+	}{
+		// RFC 6902 appendix A
+		{
+			name:  "A.1 add object member",
+			doc:   `{"foo":"bar"}`,
+			patch: `[{"op":"add","path":"/baz","value":"qux"}]`,
+			want:  `{"baz":"qux","foo":"bar"}`,
+		},
+		{
+			name:  "A.2 add array element",
+			doc:   `{"foo":["bar","baz"]}`,
+			patch: `[{"op":"add","path":"/foo/1","value":"qux"}]`,
+			want:  `{"foo":["bar","qux","baz"]}`,
+		},
+		{
+			name:  "A.3 remove object member",
+			doc:   `{"baz":"qux","foo":"bar"}`,
+			patch: `[{"op":"remove","path":"/baz"}]`,
+			want:  `{"foo":"bar"}`,
+		},
+		{
+			name:  "A.4 remove array element",
+			doc:   `{"foo":["bar","qux","baz"]}`,
+			patch: `[{"op":"remove","path":"/foo/1"}]`,
+			want:  `{"foo":["bar","baz"]}`,
+		},
+		{
+			name:  "A.5 replace value",
+			doc:   `{"baz":"qux","foo":"bar"}`,
+			patch: `[{"op":"replace","path":"/baz","value":"boo"}]`,
+			want:  `{"baz":"boo","foo":"bar"}`,
+		},
+		{
+			name:  "A.6 move value",
+			doc:   `{"foo":{"bar":"baz","waldo":"fred"},"qux":{"corge":"grault"}}`,
+			patch: `[{"op":"move","from":"/foo/waldo","path":"/qux/thud"}]`,
+			want:  `{"foo":{"bar":"baz"},"qux":{"corge":"grault","thud":"fred"}}`,
+		},
+		{
+			name:  "A.7 move array element",
+			doc:   `{"foo":["all","grass","cows","eat"]}`,
+			patch: `[{"op":"move","from":"/foo/1","path":"/foo/3"}]`,
+			want:  `{"foo":["all","cows","eat","grass"]}`,
+		},
+		{
+			name:  "A.8 test success",
+			doc:   `{"baz":"qux","foo":["a",2,"c"]}`,
+			patch: `[{"op":"test","path":"/baz","value":"qux"},{"op":"test","path":"/foo/1","value":2}]`,
+			want:  `{"baz":"qux","foo":["a",2,"c"]}`,
+		},
+		{
+			name:   "A.9 test error",
+			doc:    `{"baz":"qux"}`,
+			patch:  `[{"op":"test","path":"/baz","value":"bar"}]`,
+			errWas: ErrPatchTestFailed,
+		},
+		{
+			name:  "A.10 add nested member object",
+			doc:   `{"foo":"bar"}`,
+			patch: `[{"op":"add","path":"/child","value":{"grandchild":{}}}]`,
+			want:  `{"foo":"bar","child":{"grandchild":{}}}`,
+		},
+		{
+			name:  "A.11 ignore unrecognized elements",
+			doc:   `{"foo":"bar"}`,
+			patch: `[{"op":"add","path":"/baz","value":"qux","xyz":123}]`,
+			want:  `{"foo":"bar","baz":"qux"}`,
+		},
+		{
+			name:   "A.12 add to nonexistent target",
+			doc:    `{"foo":"bar"}`,
+			patch:  `[{"op":"add","path":"/baz/bat","value":"qux"}]`,
+			errWas: ErrInvalidPatch,
+		},
+		{
+			name:   "A.13 invalid patch document",
+			doc:    `{"foo":"bar"}`,
+			patch:  `[{"op":"add","path":"/baz","value":"qux","op":"remove"}]`,
+			errWas: ErrInvalidPatch,
+		},
+		{
+			name:  "A.14 escape ordering",
+			doc:   `{"/":9,"~1":10}`,
+			patch: `[{"op":"test","path":"/~01","value":10}]`,
+			want:  `{"/":9,"~1":10}`,
+		},
+		{
+			name:   "A.15 comparing strings and numbers",
+			doc:    `{"/":9,"~1":10}`,
+			patch:  `[{"op":"test","path":"/~01","value":"10"}]`,
+			errWas: ErrPatchTestFailed,
+		},
+		{
+			name:  "A.16 add array value",
+			doc:   `{"foo":["bar"]}`,
+			patch: `[{"op":"add","path":"/foo/-","value":["abc","def"]}]`,
+			want:  `{"foo":["bar",["abc","def"]]}`,
+		},
+
+		// Moves
+		{
+			name:   "move into its own child",
+			doc:    `{"a":{"b":{}}}`,
+			patch:  `[{"op":"move","from":"/a","path":"/a/b/c"}]`,
+			errWas: ErrInvalidPatch,
+		},
+		{
+			name:  "move onto itself",
+			doc:   `{"a":{"b":1}}`,
+			patch: `[{"op":"move","from":"/a","path":"/a"}]`,
+			want:  `{"a":{"b":1}}`,
+		},
+		{
+			name:  "move to a sibling with a common prefix",
+			doc:   `{"a":1}`,
+			patch: `[{"op":"move","from":"/a","path":"/ab"}]`,
+			want:  `{"ab":1}`,
+		},
+		{
+			name:  "move to the end of an array",
+			doc:   `{"foo":[1,2,3]}`,
+			patch: `[{"op":"move","from":"/foo/0","path":"/foo/-"}]`,
+			want:  `{"foo":[2,3,1]}`,
+		},
+		{
+			name:   "move from a missing member",
+			doc:    `{"a":1}`,
+			patch:  `[{"op":"move","from":"/b","path":"/c"}]`,
+			errWas: ErrInvalidPatch,
+		},
+
+		// The "-" index only appends
+		{
+			name:  "add at - of an empty array",
+			doc:   `{"foo":[]}`,
+			patch: `[{"op":"add","path":"/foo/-","value":1}]`,
+			want:  `{"foo":[1]}`,
+		},
+		{
+			name:  "add at the array length",
+			doc:   `{"foo":[1]}`,
+			patch: `[{"op":"add","path":"/foo/1","value":2}]`,
+			want:  `{"foo":[1,2]}`,
+		},
+		{
+			name:   "remove at -",
+			doc:    `{"foo":[1]}`,
+			patch:  `[{"op":"remove","path":"/foo/-"}]`,
+			errWas: ErrInvalidPatch,
+		},
+		{
+			name:   "replace at -",
+			doc:    `{"foo":[1]}`,
+			patch:  `[{"op":"replace","path":"/foo/-","value":2}]`,
+			errWas: ErrInvalidPatch,
+		},
+		{
+			name:   "test at -",
+			doc:    `{"foo":[1]}`,
+			patch:  `[{"op":"test","path":"/foo/-","value":1}]`,
+			errWas: ErrInvalidPatch,
+		},
+		{
+			name:   "copy from -",
+			doc:    `{"foo":[1]}`,
+			patch:  `[{"op":"copy","from":"/foo/-","path":"/bar"}]`,
+			errWas: ErrInvalidPatch,
+		},
+		{
+			name:   "add past the array length",
+			doc:    `{"foo":[1]}`,
+			patch:  `[{"op":"add","path":"/foo/2","value":2}]`,
+			errWas: ErrInvalidPatch,
+		},
+		{
+			name:   "index with a leading zero",
+			doc:    `{"foo":[1,2]}`,
+			patch:  `[{"op":"remove","path":"/foo/01"}]`,
+			errWas: ErrInvalidPatch,
+		},
+		{
+			name:   "negative index",
+			doc:    `{"foo":[1,2]}`,
+			patch:  `[{"op":"remove","path":"/foo/-1"}]`,
+			errWas: ErrInvalidPatch,
+		},
+
+		// Other operations
+		{
+			name:  "copy is deep",
+			doc:   `{"a":{"b":1}}`,
+			patch: `[{"op":"copy","from":"/a","path":"/c"},{"op":"replace","path":"/c/b","value":2}]`,
+			want:  `{"a":{"b":1},"c":{"b":2}}`,
+		},
+		{
+			name:  "replace the whole document",
+			doc:   `{"a":1}`,
+			patch: `[{"op":"replace","path":"","value":[1]}]`,
+			want:  `[1]`,
+		},
+		{
+			name:  "add a null value",
+			doc:   `{}`,
+			patch: `[{"op":"add","path":"/a","value":null}]`,
+			want:  `{"a":null}`,
+		},
+		{
+			name:   "replace a missing member",
+			doc:    `{}`,
+			patch:  `[{"op":"replace","path":"/a","value":1}]`,
+			errWas: ErrInvalidPatch,
+		},
+		{
+			name:   "remove the whole document",
+			doc:    `{}`,
+			patch:  `[{"op":"remove","path":""}]`,
+			errWas: ErrInvalidPatch,
+		},
+		{
+			name:   "missing value",
+			doc:    `{}`,
+			patch:  `[{"op":"add","path":"/a"}]`,
+			errWas: ErrInvalidPatch,
+		},
+		{
+			name:   "missing path",
+			doc:    `{}`,
+			patch:  `[{"op":"add","value":1}]`,
+			errWas: ErrInvalidPatch,
+		},
+		{
+			name:   "path without a leading slash",
+			doc:    `{}`,
+			patch:  `[{"op":"add","path":"a","value":1}]`,
+			errWas: ErrInvalidPatch,
+		},
+		{
+			name:   "unknown op",
+			doc:    `{}`,
+			patch:  `[{"op":"merge","path":"/a","value":1}]`,
+			errWas: ErrInvalidPatch,
+		},
+		{
+			name:   "patch is not an array",
+			doc:    `{}`,
+			patch:  `{"op":"add","path":"/a","value":1}`,
+			errWas: ErrInvalidPatch,
+		},
+		{
+			name:   "failure after a successful operation",
+			doc:    `{"a":1}`,
+			patch:  `[{"op":"remove","path":"/a"},{"op":"test","path":"/a","value":1}]`,
+			errWas: ErrInvalidPatch,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := JSONPatch([]byte(tt.doc), []byte(tt.patch))
+			if tt.errWas != nil {
+				if !errors.Is(err, tt.errWas) {
+					t.Fatalf("JSONPatch error = %v, want %v", err, tt.errWas)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("JSONPatch failed: %v", err)
+			}
+			assertJSON(t, got, tt.want)
+		})
+	}
+}
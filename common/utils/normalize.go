@@ -0,0 +1,71 @@
+package utils
+
+import (
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// NormalizeSearchText folds s into the form stored in search keys: accents
+// removed (so "Nắng Thơ" becomes "nang tho"), "đ" mapped to "d", lowercased
+// and with runs of white space collapsed
+func NormalizeSearchText(s string) string {
+	var b strings.Builder
+	b.Grow(len(s))
+	for _, r := range norm.NFD.String(s) {
+		switch {
+		case unicode.Is(unicode.Mn, r):
+			// Combining marks left by the decomposition
+			continue
+		case r == 'đ' || r == 'Đ':
+			// Not a combining sequence, NFD leaves it untouched
+			r = 'd'
+		}
+		b.WriteRune(unicode.ToLower(r))
+	}
+	return strings.Join(strings.Fields(b.String()), " ")
+}
+
+// EdgeNGrams returns the leading min to max characters of every phrase
+// starting at a word of the normalised text s, e.g. "nang tho" gives "na",
+// "nan", "nang", "nang t", "nang th", "nang tho", "th" and "tho"
+func EdgeNGrams(s string, min, max int) []string {
+	seen := map[string]bool{}
+	var grams []string
+	words := strings.Fields(s)
+	for i := range words {
+		phrase := []rune(strings.Join(words[i:], " "))
+		for n := min; n <= max && n <= len(phrase); n++ {
+			// Normalised prefixes never end with a space
+			if phrase[n-1] == ' ' {
+				continue
+			}
+			gram := string(phrase[:n])
+			if !seen[gram] {
+				seen[gram] = true
+				grams = append(grams, gram)
+			}
+		}
+	}
+	return grams
+}
+
+// Trigrams returns the distinct three character sequences of the words of the
+// normalised text s, each word padded with two spaces in front and one behind
+// like PostgreSQL pg_trgm, so "tho" gives "  t", " th", "tho" and "ho "
+func Trigrams(s string) []string {
+	seen := map[string]bool{}
+	var trigrams []string
+	for _, word := range strings.Fields(s) {
+		padded := []rune("  " + word + " ")
+		for i := 0; i+3 <= len(padded); i++ {
+			trigram := string(padded[i : i+3])
+			if !seen[trigram] {
+				seen[trigram] = true
+				trigrams = append(trigrams, trigram)
+			}
+		}
+	}
+	return trigrams
+}
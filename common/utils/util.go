@@ -1,6 +1,10 @@
 package utils
 
 import (
+	"errors"
+	"net/url"
+	"os"
+	"path"
 	"path/filepath"
 	"strings"
 
@@ -9,7 +13,59 @@ import (
 
 var Validator *validator.Validate
 
-const UploadDir = "./uploads"
+const (
+	UploadDir    = "./uploads"
+	WaveformDir  = "./uploads/waveforms"
+	ThumbnailDir = "./uploads/thumbnails"
+)
+
+var (
+	ErrNotLocalUpload = errors.New("file url does not point to an uploaded file")
+	ErrUploadName     = errors.New("invalid upload file name")
+)
+
+// UploadPath returns the path of the uploaded file called filename, refusing
+// names that are not a single file of UploadDir
+func UploadPath(filename string) (string, error) {
+	if filename == "" || filename == "." || filename == ".." || filepath.Base(filename) != filename {
+		return "", ErrUploadName
+	}
+	filePath := filepath.Join(UploadDir, filename)
+	rel, err := filepath.Rel(UploadDir, filePath)
+	if err != nil || rel != filename {
+		return "", ErrUploadName
+	}
+	return filePath, nil
+}
+
+// UploadPathFromURL resolves a file_url returned by the upload API to the file on disk
+func UploadPathFromURL(fileURL string) (string, error) {
+	filename, err := UploadNameFromURL(fileURL)
+	if err != nil {
+		return "", err
+	}
+
+	filePath := filepath.Join(UploadDir, filename)
+	if _, err := os.Stat(filePath); err != nil {
+		return "", err
+	}
+	return filePath, nil
+}
+
+// UploadNameFromURL returns the name of the uploaded file a file_url returned
+// by the upload API points to
+func UploadNameFromURL(fileURL string) (string, error) {
+	u, err := url.Parse(fileURL)
+	if err != nil {
+		return "", err
+	}
+
+	dir, filename := path.Split(u.Path)
+	if !strings.HasSuffix(dir, "/uploads/") || filename == "" {
+		return "", ErrNotLocalUpload
+	}
+	return filename, nil
+}
 
 // GetFileContentType determines the MIME type of the file based on its extension
 func GetFileContentType(filename string) string {
@@ -25,11 +81,26 @@ func GetFileContentType(filename string) string {
 		return "image/bmp"
 	case ".mp3":
 		return "audio/mpeg"
+	case ".wav":
+		return "audio/wav"
+	case ".flac":
+		return "audio/flac"
 	default:
 		return "application/octet-stream"
 	}
 }
 
+// SiblingUploadURL returns the URL of filename in the same upload folder as fileURL
+func SiblingUploadURL(fileURL, filename string) (string, error) {
+	u, err := url.Parse(fileURL)
+	if err != nil {
+		return "", err
+	}
+	u.Path = path.Join(path.Dir(u.Path), filename)
+	u.RawPath = ""
+	return u.String(), nil
+}
+
 // remove 1 element in array
 func RemoveElementFromArray(arr *[]string, element string) {
 	index := -1
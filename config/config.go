@@ -1,9 +1,14 @@
 package config
 
 import (
+	"log"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
+	"github.com/rolexkdev/emvn-music-library-server/common/utils"
 )
 
 type ServerEnvironment string
@@ -17,6 +22,13 @@ const (
 type Config struct {
 	Server   ServerConfig
 	Database DatabaseConfig
+	Media    MediaConfig
+	Jobs     JobsConfig
+	Search   SearchConfig
+	Auth     AuthConfig
+	Trash    TrashConfig
+	Catalog  CatalogConfig
+	License  LicenseConfig
 }
 
 // Server config struct
@@ -35,6 +47,80 @@ type DatabaseConfig struct {
 	Name     string
 }
 
+// Media processing config struct
+type MediaConfig struct {
+	// Edge lengths in pixels of the square thumbnails generated for uploaded images
+	ThumbnailSizes []int
+	// Number of audio frames summarised by one waveform min/max pair
+	WaveformSamplesPerPixel int
+	// Waveform sample resolution, 8 or 16
+	WaveformBits int
+}
+
+// Background job queue config struct
+type JobsConfig struct {
+	Workers int
+	// How often idle workers look for new jobs
+	PollInterval time.Duration
+	// How long a leased job stays invisible to other workers without a heartbeat
+	VisibilityTimeout time.Duration
+	MaxAttempts       int
+	// Delay before the first retry, doubled for every further attempt
+	RetryBackoff time.Duration
+}
+
+// Search config struct
+type SearchConfig struct {
+	// Most results returned per collection by one search
+	MaxResults int
+	// Latency budget of a search-as-you-type suggestion lookup
+	SuggestTimeout time.Duration
+}
+
+// Soft-deleted records config struct
+type TrashConfig struct {
+	// How long deleted tracks and playlists can be restored before they are purged
+	Retention time.Duration
+	// How often the purge job is scheduled
+	PurgeInterval time.Duration
+}
+
+// Catalog import and export config struct
+type CatalogConfig struct {
+	// Most rows read from one import file
+	ImportMaxRows int
+	// Folder holding the DDEX deliveries received from partners
+	DDEXDeliveryDir string
+	// DDEX party id (DPID) and name of the sender of exported ERN messages
+	DDEXSenderID   string
+	DDEXSenderName string
+}
+
+// Sync licensing config struct
+type LicenseConfig struct {
+	// JSON file holding the pricing rules of license quotes
+	PricingFile string
+	// How long a quote can be approved after it is sent
+	QuoteValidity time.Duration
+	// Name of the licensor printed on license summaries
+	Licensor string
+}
+
+// API key authentication config struct
+type AuthConfig struct {
+	APIKeys []APIKey
+}
+
+// APIKey identifies a client calling the API with the X-API-Key header
+type APIKey struct {
+	Name string
+	Key  string
+	Role string
+	// ISO 3166-1 alpha-2 territory the client serves, tracks unavailable
+	// there are hidden from it. Empty lets the client send its territory.
+	Territory string
+}
+
 func LoadConfig() (*Config, error) {
 	err := godotenv.Load(".env")
 	if err != nil {
@@ -52,6 +138,40 @@ func LoadConfig() (*Config, error) {
 			URI:  getEnv("DB_URI", "mongodb://localhost:27017"),
 			Name: getEnv("DB_NAME", "test"),
 		},
+		Media: MediaConfig{
+			ThumbnailSizes:          getEnvInts("MEDIA_THUMBNAIL_SIZES", []int{64, 300, 1000}),
+			WaveformSamplesPerPixel: getEnvInt("MEDIA_WAVEFORM_SAMPLES_PER_PIXEL", 256),
+			WaveformBits:            getEnvInt("MEDIA_WAVEFORM_BITS", 8),
+		},
+		Jobs: JobsConfig{
+			Workers:           getEnvInt("JOBS_WORKERS", 2),
+			PollInterval:      getEnvDuration("JOBS_POLL_INTERVAL", time.Second),
+			VisibilityTimeout: getEnvDuration("JOBS_VISIBILITY_TIMEOUT", 5*time.Minute),
+			MaxAttempts:       getEnvInt("JOBS_MAX_ATTEMPTS", 5),
+			RetryBackoff:      getEnvDuration("JOBS_RETRY_BACKOFF", 10*time.Second),
+		},
+		Search: SearchConfig{
+			MaxResults:     getEnvInt("SEARCH_MAX_RESULTS", 50),
+			SuggestTimeout: getEnvDuration("SEARCH_SUGGEST_TIMEOUT", 300*time.Millisecond),
+		},
+		Auth: AuthConfig{
+			APIKeys: getEnvAPIKeys("API_KEYS"),
+		},
+		Trash: TrashConfig{
+			Retention:     getEnvDuration("TRASH_RETENTION", 30*24*time.Hour),
+			PurgeInterval: getEnvDuration("TRASH_PURGE_INTERVAL", time.Hour),
+		},
+		Catalog: CatalogConfig{
+			ImportMaxRows:   getEnvInt("CATALOG_IMPORT_MAX_ROWS", 5000),
+			DDEXDeliveryDir: getEnv("CATALOG_DDEX_DELIVERY_DIR", "./deliveries"),
+			DDEXSenderID:    getEnv("CATALOG_DDEX_SENDER_ID", ""),
+			DDEXSenderName:  getEnv("CATALOG_DDEX_SENDER_NAME", ""),
+		},
+		License: LicenseConfig{
+			PricingFile:   getEnv("LICENSE_PRICING_FILE", "./pricing.json"),
+			QuoteValidity: getEnvDuration("LICENSE_QUOTE_VALIDITY", 30*24*time.Hour),
+			Licensor:      getEnv("LICENSE_LICENSOR", "EMVN"),
+		},
 	}
 	return config, nil
 }
@@ -62,3 +182,70 @@ func getEnv(key, defaultValue string) string {
 	}
 	return defaultValue
 }
+
+func getEnvInt(key string, defaultValue int) int {
+	value, exists := os.LookupEnv(key)
+	if !exists {
+		return defaultValue
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil {
+		return defaultValue
+	}
+	return n
+}
+
+// getEnvDuration reads a duration such as "30s" or "5m"
+func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	value, exists := os.LookupEnv(key)
+	if !exists {
+		return defaultValue
+	}
+	d, err := time.ParseDuration(strings.TrimSpace(value))
+	if err != nil {
+		return defaultValue
+	}
+	return d
+}
+
+// getEnvInts reads a comma separated list of positive integers, falling back to
+// defaultValue when the variable is missing or malformed
+func getEnvInts(key string, defaultValue []int) []int {
+	value, exists := os.LookupEnv(key)
+	if !exists || strings.TrimSpace(value) == "" {
+		return defaultValue
+	}
+
+	var result []int
+	for _, part := range strings.Split(value, ",") {
+		n, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil || n <= 0 {
+			return defaultValue
+		}
+		result = append(result, n)
+	}
+	return result
+}
+
+// getEnvAPIKeys reads a comma separated list of name:key:role entries,
+// optionally followed by :territory, malformed entries and entries whose
+// territory is not an ISO 3166-1 alpha-2 code are skipped
+func getEnvAPIKeys(key string) []APIKey {
+	var keys []APIKey
+	for _, entry := range strings.Split(os.Getenv(key), ",") {
+		parts := strings.Split(strings.TrimSpace(entry), ":")
+		if (len(parts) != 3 && len(parts) != 4) || parts[0] == "" || parts[1] == "" || parts[2] == "" {
+			continue
+		}
+		apiKey := APIKey{Name: parts[0], Key: parts[1], Role: parts[2]}
+		if len(parts) == 4 {
+			if !utils.IsTerritory(parts[3]) {
+				log.Printf("Skip API key %s of %s: territory %q is not an ISO 3166-1 alpha-2 code", parts[0], key, parts[3])
+				continue
+			}
+			apiKey.Territory = utils.NormalizeTerritory(parts[3])
+		}
+		keys = append(keys, apiKey)
+	}
+	return keys
+}
@@ -15,9 +15,9 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
-        "/playlists": {
-            "post": {
-                "description": "create a playlist",
+        "/admin/audit": {
+            "get": {
+                "description": "List the latest mutating requests, newest first. Requires an admin API key.",
                 "consumes": [
                     "application/json"
                 ],
@@ -25,23 +25,62 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "playlist"
+                    "admin"
                 ],
-                "summary": "Create a playlist",
+                "summary": "Get the audit log",
                 "parameters": [
                     {
-                        "description": "Create Playlist Request input",
-                        "name": "input",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/dto.CreatePlaylistRequest"
-                        }
+                        "type": "string",
+                        "description": "name of the API key, anonymous without one",
+                        "name": "actor",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "POST, PUT, PATCH or DELETE",
+                        "name": "method",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "route pattern, e.g. /api/v1/tracks/:id",
+                        "name": "route",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "id of the record changed",
+                        "name": "target_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "response status",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "RFC 3339 time of the oldest entry",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "RFC 3339 time the entries are before",
+                        "name": "to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "entries returned, 100 by default and 1000 at most",
+                        "name": "limit",
+                        "in": "query"
                     }
                 ],
                 "responses": {
-                    "201": {
-                        "description": "Created",
+                    "200": {
+                        "description": "OK",
                         "schema": {
                             "$ref": "#/definitions/app.Response"
                         }
@@ -67,28 +106,78 @@ const docTemplate = `{
                 }
             }
         },
-        "/playlists/": {
+        "/admin/audit/export": {
             "get": {
-                "description": "Get list playlists",
+                "description": "Download every entry matching the filters as JSON Lines, oldest first. Requires an admin API key.",
                 "consumes": [
                     "application/json"
                 ],
                 "produces": [
-                    "application/json"
+                    "application/x-ndjson"
                 ],
                 "tags": [
-                    "playlist"
+                    "admin"
+                ],
+                "summary": "Export the audit log",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "name of the API key, anonymous without one",
+                        "name": "actor",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "POST, PUT, PATCH or DELETE",
+                        "name": "method",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "route pattern, e.g. /api/v1/tracks/:id",
+                        "name": "route",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "id of the record changed",
+                        "name": "target_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "response status",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "RFC 3339 time of the oldest entry",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "RFC 3339 time the entries are before",
+                        "name": "to",
+                        "in": "query"
+                    }
                 ],
-                "summary": "Get list playlists",
                 "responses": {
                     "200": {
                         "description": "OK",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
                         "schema": {
                             "$ref": "#/definitions/app.Response"
                         }
                     },
-                    "500": {
-                        "description": "Internal Server Error",
+                    "403": {
+                        "description": "Forbidden",
                         "schema": {
                             "$ref": "#/definitions/app.Response"
                         }
@@ -96,9 +185,9 @@ const docTemplate = `{
                 }
             }
         },
-        "/playlists/{id}": {
-            "get": {
-                "description": "Get a playlist",
+        "/admin/ddex/import": {
+            "post": {
+                "description": "Read an ERN 4.x NewReleaseMessage from the delivery folder (CATALOG_DDEX_DELIVERY_DIR) and\ncreate or update the artists, album and tracks of its main release. Artists are matched by\nISNI or name, the album by ICPN (UPC/EAN) or title and artist, tracks like the CSV import by\nISRC or title and artist. Audio files and the front cover are read from the folder of the\nmessage, checked against their hash sum and copied to the uploads. Without commit the import\nis a dry run. A commit writes nothing when a record has errors. Requires an admin API key.",
                 "consumes": [
                     "application/json"
                 ],
@@ -106,16 +195,18 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "playlist"
+                    "ddex"
                 ],
-                "summary": "Get a playlist",
+                "summary": "Import a DDEX ERN delivery",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "playlist id",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
+                        "description": "DDEX Import Request input",
+                        "name": "input",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dto.DDEXImportRequest"
+                        }
                     }
                 ],
                 "responses": {
@@ -125,6 +216,18 @@ const docTemplate = `{
                             "$ref": "#/definitions/app.Response"
                         }
                     },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/app.Response"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/app.Response"
+                        }
+                    },
                     "404": {
                         "description": "Not Found",
                         "schema": {
@@ -138,9 +241,11 @@ const docTemplate = `{
                         }
                     }
                 }
-            },
-            "put": {
-                "description": "Update a playlist by id",
+            }
+        },
+        "/albums": {
+            "get": {
+                "description": "Get list albums, the latest release first",
                 "consumes": [
                     "application/json"
                 ],
@@ -148,25 +253,45 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "playlist"
+                    "album"
+                ],
+                "summary": "Get list albums",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/app.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/app.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/albums/{id}": {
+            "get": {
+                "description": "Get album by id, track_ids lists its tracks in order",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "album"
                 ],
-                "summary": "Update a playlist by id",
+                "summary": "Get album by id",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "playlist id",
+                        "description": "Album ID",
                         "name": "id",
                         "in": "path",
                         "required": true
-                    },
-                    {
-                        "description": "Update Playlist Request input",
-                        "name": "input",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/dto.UpdatePlaylistRequest"
-                        }
                     }
                 ],
                 "responses": {
@@ -189,33 +314,48 @@ const docTemplate = `{
                         }
                     }
                 }
-            },
-            "delete": {
-                "description": "Delete a playlist by id",
+            }
+        },
+        "/albums/{id}/ddex": {
+            "get": {
+                "description": "Download the album and its live tracks as an ERN 4.3 NewReleaseMessage for the recipient,\nsent by the party configured in CATALOG_DDEX_SENDER_ID and CATALOG_DDEX_SENDER_NAME. The\nrelease is offered for streaming worldwide from its release date.",
                 "consumes": [
                     "application/json"
                 ],
                 "produces": [
-                    "application/json"
+                    "text/xml"
                 ],
                 "tags": [
-                    "playlist"
+                    "album"
                 ],
-                "summary": "Delete a playlist by id",
+                "summary": "Export an album as DDEX ERN",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "playlist id",
+                        "description": "Album ID",
                         "name": "id",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "DDEX party id (DPID) of the recipient",
+                        "name": "recipient_id",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "name of the recipient",
+                        "name": "recipient_name",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/app.Response"
+                            "type": "file"
                         }
                     },
                     "400": {
@@ -224,6 +364,12 @@ const docTemplate = `{
                             "$ref": "#/definitions/app.Response"
                         }
                     },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/app.Response"
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
@@ -233,34 +379,22 @@ const docTemplate = `{
                 }
             }
         },
-        "/playlists/{id}/m3u": {
+        "/artists": {
             "get": {
-                "description": "Generate M3U playlist",
+                "description": "Get list artists by name",
+                "consumes": [
+                    "application/json"
+                ],
                 "produces": [
-                    "audio/x-mpegurl"
+                    "application/json"
                 ],
                 "tags": [
-                    "playlist"
-                ],
-                "summary": "Generate M3U playlist",
-                "parameters": [
-                    {
-                        "type": "string",
-                        "description": "Playlist ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
-                    }
+                    "artist"
                 ],
+                "summary": "Get list artists",
                 "responses": {
                     "200": {
-                        "description": "M3U playlist",
-                        "schema": {
-                            "type": "string"
-                        }
-                    },
-                    "400": {
-                        "description": "Bad Request",
+                        "description": "OK",
                         "schema": {
                             "$ref": "#/definitions/app.Response"
                         }
@@ -274,9 +408,9 @@ const docTemplate = `{
                 }
             }
         },
-        "/playlists/{id}/tracks": {
-            "post": {
-                "description": "Add or remove a track for playlist",
+        "/artists/{id}": {
+            "get": {
+                "description": "Get artist by id, its id is the artist_id of its tracks and albums",
                 "consumes": [
                     "application/json"
                 ],
@@ -284,25 +418,16 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "playlist"
+                    "artist"
                 ],
-                "summary": "Add or remove a track for playlist",
+                "summary": "Get artist by id",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "playlist id",
+                        "description": "Artist ID",
                         "name": "id",
                         "in": "path",
                         "required": true
-                    },
-                    {
-                        "description": "Update Playlist Track Request input",
-                        "name": "input",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/dto.UpdatePlaylistTrackRequest"
-                        }
                     }
                 ],
                 "responses": {
@@ -327,9 +452,9 @@ const docTemplate = `{
                 }
             }
         },
-        "/search": {
+        "/jobs": {
             "get": {
-                "description": "Search tracks and playlist",
+                "description": "Get the latest 100 jobs, e.g. every job of a track with target={track id} or the dead letters with status=dead",
                 "consumes": [
                     "application/json"
                 ],
@@ -337,16 +462,27 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "search"
+                    "job"
                 ],
-                "summary": "Search tracks and playlist",
+                "summary": "Get list jobs",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "search string",
-                        "name": "query",
-                        "in": "query",
-                        "required": true
+                        "description": "job type",
+                        "name": "type",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "track id or file name the job works on",
+                        "name": "target",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "pending, running, succeeded or dead",
+                        "name": "status",
+                        "in": "query"
                     }
                 ],
                 "responses": {
@@ -371,9 +507,9 @@ const docTemplate = `{
                 }
             }
         },
-        "/tracks": {
-            "post": {
-                "description": "create a track",
+        "/jobs/{id}": {
+            "get": {
+                "description": "Get status and progress of a background processing job",
                 "consumes": [
                     "application/json"
                 ],
@@ -381,35 +517,27 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "track"
+                    "job"
                 ],
-                "summary": "Create a track",
+                "summary": "Get a job",
                 "parameters": [
                     {
-                        "description": "Create Track Request input",
-                        "name": "input",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/dto.CreateTrackRequest"
-                        }
+                        "type": "string",
+                        "description": "job id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
                     }
                 ],
                 "responses": {
-                    "201": {
-                        "description": "Created",
-                        "schema": {
-                            "$ref": "#/definitions/app.Response"
-                        }
-                    },
-                    "400": {
-                        "description": "Bad Request",
+                    "200": {
+                        "description": "OK",
                         "schema": {
                             "$ref": "#/definitions/app.Response"
                         }
                     },
-                    "403": {
-                        "description": "Forbidden",
+                    "404": {
+                        "description": "Not Found",
                         "schema": {
                             "$ref": "#/definitions/app.Response"
                         }
@@ -423,9 +551,9 @@ const docTemplate = `{
                 }
             }
         },
-        "/tracks/": {
-            "get": {
-                "description": "Get list tracks",
+        "/jobs/{id}/retry": {
+            "post": {
+                "description": "Put a job that exhausted its attempts back in the queue",
                 "consumes": [
                     "application/json"
                 ],
@@ -433,9 +561,2172 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "track"
+                    "job"
+                ],
+                "summary": "Retry a dead job",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "job id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/app.Response"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/app.Response"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/app.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/app.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/licenses/requests": {
+            "get": {
+                "description": "Get list license requests, newest first. Admins get every request, other callers the requests they made.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "license"
+                ],
+                "summary": "Get list license requests",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "API key",
+                        "name": "X-API-Key",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "enum": [
+                            "requested",
+                            "quoted",
+                            "approved",
+                            "rejected",
+                            "signed"
+                        ],
+                        "type": "string",
+                        "description": "Only the requests in this status",
+                        "name": "status",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/app.Response"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/app.Response"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/app.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/app.Response"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Request a sync license of tracks or of the tracks of a playlist, for a media type, usage, territories and term. The tracks must be available in every territory, WW alone asks for a worldwide license. The response holds an estimate computed from the pricing rules, the final price comes with the quote.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "license"
+                ],
+                "summary": "Request a sync license",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "API key",
+                        "name": "X-API-Key",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "description": "Create License Request input",
+                        "name": "input",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dto.CreateLicenseRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/app.Response"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/app.Response"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/app.Response"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/app.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/app.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/licenses/requests/{id}": {
+            "get": {
+                "description": "Get license request by id with its estimate, quote and history",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "license"
+                ],
+                "summary": "Get license request by id",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "API key",
+                        "name": "X-API-Key",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "License request ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/app.Response"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/app.Response"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/app.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/app.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/licenses/requests/{id}/quote": {
+            "post": {
+                "description": "Price a requested or quoted license request with the pricing rules and send the quote, total replaces the computed total. The quote can be approved until it expires.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "license"
+                ],
+                "summary": "Quote a license request",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Admin API key",
+                        "name": "X-API-Key",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "License request ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Quote License Request input",
+                        "name": "input",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dto.QuoteLicenseRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/app.Response"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/app.Response"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/app.Response"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/app.Response"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/app.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/app.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/licenses/requests/{id}/status": {
+            "post": {
+                "description": "Move a license request along its workflow: requested, quoted, approved, signed, or rejected before it is approved. The requester or an admin approves an unexpired quote or rejects the request, only an admin marks an approved license signed.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "license"
+                ],
+                "summary": "Approve, reject or sign a license request",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "API key",
+                        "name": "X-API-Key",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "License request ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "License Status Request input",
+                        "name": "input",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dto.LicenseStatusRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/app.Response"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/app.Response"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/app.Response"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/app.Response"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/app.Response"
+                        }
+                    },
+                    "412": {
+                        "description": "Precondition Failed",
+                        "schema": {
+                            "$ref": "#/definitions/app.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/app.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/licenses/requests/{id}/summary": {
+            "get": {
+                "description": "Download the license summary document of a quoted license request as Markdown: grant, tracks with their rights holders, fee and history. It is marked as a draft until the license is signed.",
+                "produces": [
+                    "text/markdown"
+                ],
+                "tags": [
+                    "license"
+                ],
+                "summary": "Get license summary",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "API key",
+                        "name": "X-API-Key",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "License request ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "License summary",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/app.Response"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/app.Response"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/app.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/app.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/playlists": {
+            "post": {
+                "description": "create a playlist",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "playlist"
+                ],
+                "summary": "Create a playlist",
+                "parameters": [
+                    {
+                        "description": "Create Playlist Request input",
+                        "name": "input",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dto.CreatePlaylistRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/app.Response"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/app.Response"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/app.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/app.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/playlists/": {
+            "get": {
+                "description": "Get list playlists",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "playlist"
+                ],
+                "summary": "Get list playlists",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ETag of the cached representation",
+                        "name": "If-None-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/app.Response"
+                        }
+                    },
+                    "304": {
+                        "description": "not modified"
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/app.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/playlists/export": {
+            "get": {
+                "description": "Download every playlist with its live tracks in order, oldest playlist first. JSON Lines\nholds one playlist per line with its tracks, CSV and XLSX one row per track of a playlist\n(a single row without track for an empty playlist). Tracks not available to the caller\nare left out.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "text/csv",
+                    "application/x-ndjson",
+                    "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+                ],
+                "tags": [
+                    "playlist"
+                ],
+                "summary": "Export playlists",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "csv (default), jsonl or xlsx",
+                        "name": "format",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "ISO 3166-1 alpha-2 territory of the caller, e.g. VN, unless set on the API key",
+                        "name": "X-Territory",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/app.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/app.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/playlists/{id}": {
+            "get": {
+                "description": "Get a playlist",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "playlist"
+                ],
+                "summary": "Get a playlist",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "playlist id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of the cached representation",
+                        "name": "If-None-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/app.Response"
+                        }
+                    },
+                    "304": {
+                        "description": "not modified"
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/app.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/app.Response"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "description": "Replace the title, album cover and track list of a playlist, omitted optional fields are cleared",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "playlist"
+                ],
+                "summary": "Replace a playlist by id",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "playlist id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Update Playlist Request input",
+                        "name": "input",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dto.UpdatePlaylistRequest"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of the record as last read",
+                        "name": "If-Match",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/app.Response"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/app.Response"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/app.Response"
+                        }
+                    },
+                    "412": {
+                        "description": "Precondition Failed",
+                        "schema": {
+                            "$ref": "#/definitions/app.Response"
+                        }
+                    },
+                    "428": {
+                        "description": "Precondition Required",
+                        "schema": {
+                            "$ref": "#/definitions/app.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/app.Response"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Delete a playlist by id",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "playlist"
+                ],
+                "summary": "Delete a playlist by id",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "playlist id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of the record as last read",
+                        "name": "If-Match",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/app.Response"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/app.Response"
+                        }
+                    },
+                    "412": {
+                        "description": "Precondition Failed",
+                        "schema": {
+                            "$ref": "#/definitions/app.Response"
+                        }
+                    },
+                    "428": {
+                        "description": "Precondition Required",
+                        "schema": {
+                            "$ref": "#/definitions/app.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/app.Response"
+                        }
+                    }
+                }
+            },
+            "patch": {
+                "description": "Update a playlist with a JSON Merge Patch (RFC 7396, Content-Type application/merge-patch+json)\nor a JSON Patch (RFC 6902, Content-Type application/json-patch+json) applied to the\nfields of dto.UpdatePlaylistRequest, e.g. [{\"op\":\"add\",\"path\":\"/track_ids/-\",\"value\":\"{track id}\"}]",
+                "consumes": [
+                    "application/merge-patch+json",
+                    "application/json-patch+json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "playlist"
+                ],
+                "summary": "Partially update a playlist by id",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "playlist id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "merge patch object or JSON Patch operations",
+                        "name": "input",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of the record as last read",
+                        "name": "If-Match",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/app.Response"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/app.Response"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/app.Response"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/app.Response"
+                        }
+                    },
+                    "412": {
+                        "description": "Precondition Failed",
+                        "schema": {
+                            "$ref": "#/definitions/app.Response"
+                        }
+                    },
+                    "415": {
+                        "description": "Unsupported Media Type",
+                        "schema": {
+                            "$ref": "#/definitions/app.Response"
+                        }
+                    },
+                    "428": {
+                        "description": "Precondition Required",
+                        "schema": {
+                            "$ref": "#/definitions/app.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/app.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/playlists/{id}/history": {
+            "get": {
+                "description": "List the revisions of a playlist, newest first: version produced, action, actor, time and changed fields",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "playlist"
+                ],
+                "summary": "Get the history of a playlist",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "playlist id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/app.Response"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/app.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/app.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/playlists/{id}/history/{rev}/restore": {
+            "post": {
+                "description": "Set the title, album cover and track list of a playlist back to their value after revision rev, recorded as a new revision",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "playlist"
+                ],
+                "summary": "Roll a playlist back to a revision",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "playlist id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "version produced by the revision",
+                        "name": "rev",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of the record as last read",
+                        "name": "If-Match",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/app.Response"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/app.Response"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/app.Response"
+                        }
+                    },
+                    "412": {
+                        "description": "Precondition Failed",
+                        "schema": {
+                            "$ref": "#/definitions/app.Response"
+                        }
+                    },
+                    "428": {
+                        "description": "Precondition Required",
+                        "schema": {
+                            "$ref": "#/definitions/app.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/app.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/playlists/{id}/m3u": {
+            "get": {
+                "description": "Generate M3U playlist of the tracks available to the caller",
+                "produces": [
+                    "audio/x-mpegurl"
+                ],
+                "tags": [
+                    "playlist"
+                ],
+                "summary": "Generate M3U playlist",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Playlist ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ISO 3166-1 alpha-2 territory of the caller, e.g. VN, unless set on the API key",
+                        "name": "X-Territory",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "M3U playlist",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/app.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/app.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/playlists/{id}/restore": {
+            "post": {
+                "description": "Take a playlist out of the trash before it is purged",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "trash"
+                ],
+                "summary": "Restore a deleted playlist",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "playlist id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/app.Response"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/app.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/app.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/playlists/{id}/tracks": {
+            "post": {
+                "description": "Add or remove a track for playlist, only tracks available to the caller can be added",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "playlist"
+                ],
+                "summary": "Add or remove a track for playlist",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "playlist id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Update Playlist Track Request input",
+                        "name": "input",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dto.UpdatePlaylistTrackRequest"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of the record as last read",
+                        "name": "If-Match",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ISO 3166-1 alpha-2 territory of the caller, e.g. VN, unless set on the API key",
+                        "name": "X-Territory",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/app.Response"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/app.Response"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/app.Response"
+                        }
+                    },
+                    "412": {
+                        "description": "Precondition Failed",
+                        "schema": {
+                            "$ref": "#/definitions/app.Response"
+                        }
+                    },
+                    "428": {
+                        "description": "Precondition Required",
+                        "schema": {
+                            "$ref": "#/definitions/app.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/app.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/search": {
+            "get": {
+                "description": "Full-text search of tracks and playlists ranked by relevance.\nTrack matches in the title weigh more than in artist name, album and genre.\nThe query is taken literally unless an admin sets mode=regex, the pattern then\nuses the RE2 syntax without nested repetitions.\nmode=advanced parses a query language, e.g. genre:pop artist:\"Hoang Dung\" year:2019..2021 duration:\u003c240 -remix\nWith fuzzy=true close matches tolerating typos follow the exact ones.\nFacets count the matching tracks by genre, release year, duration and artist.\nEvery result type is paged on its own with a total and a next_cursor, pass\nthe cursor back with the same type to get the following page.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "search"
+                ],
+                "summary": "Search tracks and playlist",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "search string",
+                        "name": "query",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "text (default), advanced or regex, regex requires an admin API key",
+                        "name": "mode",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "also return matches with typos",
+                        "name": "fuzzy",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "comma separated result types: tracks, playlists, albums, artists (default all)",
+                        "name": "type",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "results per type, default 20, at most SEARCH_MAX_RESULTS",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "next_cursor of the previous page, requires a single type",
+                        "name": "cursor",
+                        "in": "query"
+                    },
+                    {
+                        "type": "number",
+                        "description": "minimum tempo",
+                        "name": "min_bpm",
+                        "in": "query"
+                    },
+                    {
+                        "type": "number",
+                        "description": "maximum tempo",
+                        "name": "max_bpm",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "musical key, e.g. A minor, Am, Bb",
+                        "name": "key",
+                        "in": "query"
+                    },
+                    {
+                        "type": "number",
+                        "description": "minimum integrated loudness (LUFS)",
+                        "name": "min_loudness",
+                        "in": "query"
+                    },
+                    {
+                        "type": "number",
+                        "description": "maximum integrated loudness (LUFS)",
+                        "name": "max_loudness",
+                        "in": "query"
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        },
+                        "collectionFormat": "multi",
+                        "description": "genres, repeat for several",
+                        "name": "genre",
+                        "in": "query"
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "integer"
+                        },
+                        "collectionFormat": "multi",
+                        "description": "release years, repeat for several",
+                        "name": "year",
+                        "in": "query"
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        },
+                        "collectionFormat": "multi",
+                        "description": "duration buckets: under_2m, 2m_4m, 4m_6m, over_6m",
+                        "name": "duration",
+                        "in": "query"
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        },
+                        "collectionFormat": "multi",
+                        "description": "artist ids, repeat for several",
+                        "name": "artist_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        },
+                        "collectionFormat": "multi",
+                        "description": "labels, repeat for several",
+                        "name": "label",
+                        "in": "query"
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        },
+                        "collectionFormat": "multi",
+                        "description": "master owners, repeat for several",
+                        "name": "master_owner",
+                        "in": "query"
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        },
+                        "collectionFormat": "multi",
+                        "description": "writers in the publishing splits, repeat for several",
+                        "name": "writer",
+                        "in": "query"
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        },
+                        "collectionFormat": "multi",
+                        "description": "publishers in the publishing splits, repeat for several",
+                        "name": "publisher",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "ISRC, separators allowed",
+                        "name": "isrc",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "ISWC, separators allowed, e.g. T-034.524.680-1",
+                        "name": "iswc",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "ISO 3166-1 alpha-2 territory of the caller, e.g. VN, unless set on the API key",
+                        "name": "X-Territory",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/app.Response"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/app.Response"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/app.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/app.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/search/suggest": {
+            "get": {
+                "description": "Complete the typed prefix with track titles, artist names, albums and playlist titles.\nAccents and case are ignored, a word of the suggestion starts with the prefix.\nTrack values only come from the tracks available to the caller.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "search"
+                ],
+                "summary": "Search-as-you-type suggestions",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "prefix typed by the user, at least 2 characters",
+                        "name": "q",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "number of suggestions, 1 to 20, default 8",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "ISO 3166-1 alpha-2 territory of the caller, e.g. VN, unless set on the API key",
+                        "name": "X-Territory",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/app.Response"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/app.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/app.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/tracks": {
+            "post": {
+                "description": "create a track",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "track"
+                ],
+                "summary": "Create a track",
+                "parameters": [
+                    {
+                        "description": "Create Track Request input",
+                        "name": "input",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dto.CreateTrackRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/app.Response"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/app.Response"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/app.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/app.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/tracks/": {
+            "get": {
+                "description": "Get list tracks",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "track"
                 ],
                 "summary": "Get list tracks",
+                "parameters": [
+                    {
+                        "type": "number",
+                        "description": "minimum tempo",
+                        "name": "min_bpm",
+                        "in": "query"
+                    },
+                    {
+                        "type": "number",
+                        "description": "maximum tempo",
+                        "name": "max_bpm",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "musical key, e.g. A minor, Am, Bb",
+                        "name": "key",
+                        "in": "query"
+                    },
+                    {
+                        "type": "number",
+                        "description": "minimum integrated loudness (LUFS)",
+                        "name": "min_loudness",
+                        "in": "query"
+                    },
+                    {
+                        "type": "number",
+                        "description": "maximum integrated loudness (LUFS)",
+                        "name": "max_loudness",
+                        "in": "query"
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        },
+                        "collectionFormat": "multi",
+                        "description": "genres, repeat for several",
+                        "name": "genre",
+                        "in": "query"
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "integer"
+                        },
+                        "collectionFormat": "multi",
+                        "description": "release years, repeat for several",
+                        "name": "year",
+                        "in": "query"
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        },
+                        "collectionFormat": "multi",
+                        "description": "duration buckets: under_2m, 2m_4m, 4m_6m, over_6m",
+                        "name": "duration",
+                        "in": "query"
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        },
+                        "collectionFormat": "multi",
+                        "description": "artist ids, repeat for several",
+                        "name": "artist_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        },
+                        "collectionFormat": "multi",
+                        "description": "labels, repeat for several",
+                        "name": "label",
+                        "in": "query"
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        },
+                        "collectionFormat": "multi",
+                        "description": "master owners, repeat for several",
+                        "name": "master_owner",
+                        "in": "query"
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        },
+                        "collectionFormat": "multi",
+                        "description": "writers in the publishing splits, repeat for several",
+                        "name": "writer",
+                        "in": "query"
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        },
+                        "collectionFormat": "multi",
+                        "description": "publishers in the publishing splits, repeat for several",
+                        "name": "publisher",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "ISRC, separators allowed",
+                        "name": "isrc",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "ISWC, separators allowed, e.g. T-034.524.680-1",
+                        "name": "iswc",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "ISO 3166-1 alpha-2 territory of the caller, e.g. VN, unless set on the API key",
+                        "name": "X-Territory",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of the cached representation",
+                        "name": "If-None-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/app.Response"
+                        }
+                    },
+                    "304": {
+                        "description": "not modified"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/app.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/app.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/tracks/bulk": {
+            "post": {
+                "description": "Run up to 1000 operations in a single write. create takes a track like POST /tracks, update\nan id, the version last read and a track like PUT /tracks/{id}, delete an id and a version.\nEvery operation is validated and gets its own result. ordered stops at the first failed\noperation, atomic writes every operation or none in a transaction.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "track"
+                ],
+                "summary": "Create, update and delete tracks in bulk",
+                "parameters": [
+                    {
+                        "description": "Bulk Track Request input",
+                        "name": "input",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dto.BulkTrackRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/app.Response"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/app.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/app.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/tracks/export": {
+            "get": {
+                "description": "Download every track matching the filters of the track list, oldest first, as CSV, JSON Lines\nor XLSX. The artist name of a track without one is taken from the other tracks of the artist.\nIn CSV and XLSX release_date is a date and duration m:ss[.mmm], as read by the import.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "text/csv",
+                    "application/x-ndjson",
+                    "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+                ],
+                "tags": [
+                    "track"
+                ],
+                "summary": "Export tracks",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "csv (default), jsonl or xlsx",
+                        "name": "format",
+                        "in": "query"
+                    },
+                    {
+                        "type": "number",
+                        "description": "minimum tempo",
+                        "name": "min_bpm",
+                        "in": "query"
+                    },
+                    {
+                        "type": "number",
+                        "description": "maximum tempo",
+                        "name": "max_bpm",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "musical key, e.g. A minor, Am, Bb",
+                        "name": "key",
+                        "in": "query"
+                    },
+                    {
+                        "type": "number",
+                        "description": "minimum integrated loudness (LUFS)",
+                        "name": "min_loudness",
+                        "in": "query"
+                    },
+                    {
+                        "type": "number",
+                        "description": "maximum integrated loudness (LUFS)",
+                        "name": "max_loudness",
+                        "in": "query"
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        },
+                        "collectionFormat": "multi",
+                        "description": "genres, repeat for several",
+                        "name": "genre",
+                        "in": "query"
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "integer"
+                        },
+                        "collectionFormat": "multi",
+                        "description": "release years, repeat for several",
+                        "name": "year",
+                        "in": "query"
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        },
+                        "collectionFormat": "multi",
+                        "description": "duration buckets: under_2m, 2m_4m, 4m_6m, over_6m",
+                        "name": "duration",
+                        "in": "query"
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        },
+                        "collectionFormat": "multi",
+                        "description": "artist ids, repeat for several",
+                        "name": "artist_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        },
+                        "collectionFormat": "multi",
+                        "description": "labels, repeat for several",
+                        "name": "label",
+                        "in": "query"
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        },
+                        "collectionFormat": "multi",
+                        "description": "master owners, repeat for several",
+                        "name": "master_owner",
+                        "in": "query"
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        },
+                        "collectionFormat": "multi",
+                        "description": "writers in the publishing splits, repeat for several",
+                        "name": "writer",
+                        "in": "query"
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        },
+                        "collectionFormat": "multi",
+                        "description": "publishers in the publishing splits, repeat for several",
+                        "name": "publisher",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "ISRC, separators allowed",
+                        "name": "isrc",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "ISWC, separators allowed, e.g. T-034.524.680-1",
+                        "name": "iswc",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "ISO 3166-1 alpha-2 territory of the caller, e.g. VN, unless set on the API key",
+                        "name": "X-Territory",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/app.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/app.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/tracks/import": {
+            "post": {
+                "description": "Read a CSV file whose first line holds the column headers. mapping gives the column of\neach field of dto.CreateTrackRequest, e.g. {\"title\": \"Track Title\", \"isrc\": \"ISRC\"}; fields\nleft out are read from the column named like them. Rows update the track with their ISRC,\nor else with their title and artist, and create the others. Without commit the import is\na dry run reporting the creates, updates and errors of every row. A commit writes nothing\nwhen a row has errors, and writes every row or none in a transaction, failing with 409\nwhen a track changed since it was matched and with 500 when a write failed otherwise.\nWithout transactions (a standalone mongod) the rows are written in order up to the\nfirst failure, the report tells which ones were.",
+                "consumes": [
+                    "multipart/form-data"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "track"
+                ],
+                "summary": "Import tracks from a CSV file",
+                "parameters": [
+                    {
+                        "type": "file",
+                        "description": "CSV file",
+                        "name": "file",
+                        "in": "formData",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "JSON object of field to column header",
+                        "name": "mapping",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "column delimiter, a comma by default",
+                        "name": "delimiter",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "write the tracks, a dry run by default",
+                        "name": "commit",
+                        "in": "formData"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/app.Response"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/app.Response"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/app.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/app.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/tracks/{id}": {
+            "get": {
+                "description": "Get a track available to the caller",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "track"
+                ],
+                "summary": "Get a track",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "track id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ISO 3166-1 alpha-2 territory of the caller, e.g. VN, unless set on the API key",
+                        "name": "X-Territory",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of the cached representation",
+                        "name": "If-None-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/app.Response"
+                        }
+                    },
+                    "304": {
+                        "description": "not modified"
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/app.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/app.Response"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "description": "Replace every editable field of a track, omitted optional fields are cleared",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "track"
+                ],
+                "summary": "Replace a track by id",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "track id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Update Track Request input",
+                        "name": "input",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dto.UpdateTrackRequest"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of the record as last read",
+                        "name": "If-Match",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/app.Response"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/app.Response"
+                        }
+                    },
+                    "412": {
+                        "description": "Precondition Failed",
+                        "schema": {
+                            "$ref": "#/definitions/app.Response"
+                        }
+                    },
+                    "428": {
+                        "description": "Precondition Required",
+                        "schema": {
+                            "$ref": "#/definitions/app.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/app.Response"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Delete a track by id",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "track"
+                ],
+                "summary": "Delete a track by id",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "track id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of the record as last read",
+                        "name": "If-Match",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/app.Response"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/app.Response"
+                        }
+                    },
+                    "412": {
+                        "description": "Precondition Failed",
+                        "schema": {
+                            "$ref": "#/definitions/app.Response"
+                        }
+                    },
+                    "428": {
+                        "description": "Precondition Required",
+                        "schema": {
+                            "$ref": "#/definitions/app.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/app.Response"
+                        }
+                    }
+                }
+            },
+            "patch": {
+                "description": "Update a track with a JSON Merge Patch (RFC 7396, Content-Type application/merge-patch+json)\nor a JSON Patch (RFC 6902, Content-Type application/json-patch+json) applied to the\nfields of dto.UpdateTrackRequest. A merge patch member set to null clears the field.",
+                "consumes": [
+                    "application/merge-patch+json",
+                    "application/json-patch+json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "track"
+                ],
+                "summary": "Partially update a track by id",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "track id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "merge patch object or JSON Patch operations",
+                        "name": "input",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of the record as last read",
+                        "name": "If-Match",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
                 "responses": {
                     "200": {
                         "description": "OK",
@@ -443,6 +2734,42 @@ const docTemplate = `{
                             "$ref": "#/definitions/app.Response"
                         }
                     },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/app.Response"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/app.Response"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/app.Response"
+                        }
+                    },
+                    "412": {
+                        "description": "Precondition Failed",
+                        "schema": {
+                            "$ref": "#/definitions/app.Response"
+                        }
+                    },
+                    "415": {
+                        "description": "Unsupported Media Type",
+                        "schema": {
+                            "$ref": "#/definitions/app.Response"
+                        }
+                    },
+                    "428": {
+                        "description": "Precondition Required",
+                        "schema": {
+                            "$ref": "#/definitions/app.Response"
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
@@ -452,9 +2779,50 @@ const docTemplate = `{
                 }
             }
         },
-        "/tracks/{id}": {
+        "/tracks/{id}/analysis": {
+            "post": {
+                "description": "Queue a job to (re)compute loudness, true peak, BPM and key of the track audio",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "track"
+                ],
+                "summary": "Analyze a track",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "track id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "202": {
+                        "description": "Accepted",
+                        "schema": {
+                            "$ref": "#/definitions/app.Response"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/app.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/app.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/tracks/{id}/history": {
             "get": {
-                "description": "Get a track",
+                "description": "List the revisions of a track, newest first: version produced, action, actor, time and changed fields",
                 "consumes": [
                     "application/json"
                 ],
@@ -464,7 +2832,7 @@ const docTemplate = `{
                 "tags": [
                     "track"
                 ],
-                "summary": "Get a track",
+                "summary": "Get the history of a track",
                 "parameters": [
                     {
                         "type": "string",
@@ -494,19 +2862,139 @@ const docTemplate = `{
                         }
                     }
                 }
-            },
-            "put": {
-                "description": "Update a track by id",
+            }
+        },
+        "/tracks/{id}/history/{rev}/restore": {
+            "post": {
+                "description": "Set the editable fields of a track back to their value after revision rev, recorded as a new revision",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "track"
+                ],
+                "summary": "Roll a track back to a revision",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "track id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "version produced by the revision",
+                        "name": "rev",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of the record as last read",
+                        "name": "If-Match",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/app.Response"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/app.Response"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/app.Response"
+                        }
+                    },
+                    "412": {
+                        "description": "Precondition Failed",
+                        "schema": {
+                            "$ref": "#/definitions/app.Response"
+                        }
+                    },
+                    "428": {
+                        "description": "Precondition Required",
+                        "schema": {
+                            "$ref": "#/definitions/app.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/app.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/tracks/{id}/restore": {
+            "post": {
+                "description": "Take a track out of the trash before it is purged",
                 "consumes": [
                     "application/json"
                 ],
                 "produces": [
                     "application/json"
                 ],
+                "tags": [
+                    "trash"
+                ],
+                "summary": "Restore a deleted track",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "track id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/app.Response"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/app.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/app.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/tracks/{id}/waveform": {
+            "get": {
+                "description": "Get min/max peak data of a track in audiowaveform JSON or binary (.dat) format.\nWhile the waveform is being generated the queued job is returned with status 202.\nA track not available to the caller is refused with 451.",
+                "produces": [
+                    "application/json",
+                    "application/octet-stream"
+                ],
                 "tags": [
                     "track"
                 ],
-                "summary": "Update a track by id",
+                "summary": "Get track waveform",
                 "parameters": [
                     {
                         "type": "string",
@@ -516,18 +3004,51 @@ const docTemplate = `{
                         "required": true
                     },
                     {
-                        "description": "Update Track Request input",
-                        "name": "input",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/dto.UpdateTrackRequest"
-                        }
+                        "type": "string",
+                        "description": "json (default) or dat",
+                        "name": "format",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "resolution, a multiple of the stored resolution",
+                        "name": "samples_per_pixel",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "ISO 3166-1 alpha-2 territory of the caller, e.g. VN, unless set on the API key",
+                        "name": "X-Territory",
+                        "in": "header"
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/media.Waveform"
+                        }
+                    },
+                    "202": {
+                        "description": "Accepted",
+                        "schema": {
+                            "$ref": "#/definitions/app.Response"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/app.Response"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/app.Response"
+                        }
+                    },
+                    "451": {
+                        "description": "Unavailable For Legal Reasons",
                         "schema": {
                             "$ref": "#/definitions/app.Response"
                         }
@@ -539,9 +3060,11 @@ const docTemplate = `{
                         }
                     }
                 }
-            },
-            "delete": {
-                "description": "Delete a track by id",
+            }
+        },
+        "/trash": {
+            "get": {
+                "description": "List the deleted tracks and playlists, most recently deleted first, with the time they are purged",
                 "consumes": [
                     "application/json"
                 ],
@@ -549,16 +3072,15 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "track"
+                    "trash"
                 ],
-                "summary": "Delete a track by id",
+                "summary": "Get the trash",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "track id",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
+                        "description": "tracks or playlists, both by default",
+                        "name": "type",
+                        "in": "query"
                     }
                 ],
                 "responses": {
@@ -568,8 +3090,8 @@ const docTemplate = `{
                             "$ref": "#/definitions/app.Response"
                         }
                     },
-                    "404": {
-                        "description": "Not Found",
+                    "400": {
+                        "description": "Bad Request",
                         "schema": {
                             "$ref": "#/definitions/app.Response"
                         }
@@ -629,7 +3151,7 @@ const docTemplate = `{
         },
         "/uploads/{filename}": {
             "get": {
-                "description": "Get a file by its filename",
+                "description": "Get a file by its filename, images can be requested as a square thumbnail with size.\nThe audio of tracks is refused with 451 when none of its tracks is available to the caller.",
                 "produces": [
                     "text/plain"
                 ],
@@ -644,6 +3166,18 @@ const docTemplate = `{
                         "name": "filename",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Thumbnail edge length in pixels (images only)",
+                        "name": "size",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "ISO 3166-1 alpha-2 territory of the caller, e.g. VN",
+                        "name": "X-Territory",
+                        "in": "header"
                     }
                 ],
                 "responses": {
@@ -653,11 +3187,29 @@ const docTemplate = `{
                             "$ref": "#/definitions/app.Response"
                         }
                     },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/app.Response"
+                        }
+                    },
                     "404": {
                         "description": "Not Found",
                         "schema": {
                             "$ref": "#/definitions/app.Response"
                         }
+                    },
+                    "451": {
+                        "description": "Unavailable For Legal Reasons",
+                        "schema": {
+                            "$ref": "#/definitions/app.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/app.Response"
+                        }
                     }
                 }
             }
@@ -676,6 +3228,117 @@ const docTemplate = `{
                 }
             }
         },
+        "dto.BulkTrackOperation": {
+            "type": "object",
+            "required": [
+                "op"
+            ],
+            "properties": {
+                "id": {
+                    "type": "string"
+                },
+                "op": {
+                    "type": "string",
+                    "enum": [
+                        "create",
+                        "update",
+                        "delete"
+                    ]
+                },
+                "track": {
+                    "type": "object"
+                },
+                "version": {
+                    "type": "integer"
+                }
+            }
+        },
+        "dto.BulkTrackRequest": {
+            "type": "object",
+            "required": [
+                "operations"
+            ],
+            "properties": {
+                "atomic": {
+                    "description": "Write every operation or none, in a transaction",
+                    "type": "boolean"
+                },
+                "operations": {
+                    "type": "array",
+                    "maxItems": 1000,
+                    "minItems": 1,
+                    "items": {
+                        "$ref": "#/definitions/dto.BulkTrackOperation"
+                    }
+                },
+                "ordered": {
+                    "description": "Stop at the first failed operation",
+                    "type": "boolean"
+                }
+            }
+        },
+        "dto.CreateLicenseRequest": {
+            "type": "object",
+            "required": [
+                "media_type",
+                "project",
+                "term_months",
+                "territories",
+                "usage"
+            ],
+            "properties": {
+                "contact": {
+                    "$ref": "#/definitions/dto.LicenseContact"
+                },
+                "description": {
+                    "type": "string",
+                    "maxLength": 2000
+                },
+                "media_type": {
+                    "description": "Media type and usage priced by the pricing rules, e.g. tv and background",
+                    "type": "string",
+                    "maxLength": 50
+                },
+                "playlist_id": {
+                    "type": "string"
+                },
+                "project": {
+                    "type": "string",
+                    "maxLength": 200
+                },
+                "start_date": {
+                    "description": "First day of the license in milliseconds since the epoch, 0 for the day\nit is signed",
+                    "type": "integer",
+                    "minimum": 0
+                },
+                "term_months": {
+                    "description": "Length of the license in months, 0 for a perpetual license",
+                    "type": "integer",
+                    "maximum": 1200,
+                    "minimum": 0
+                },
+                "territories": {
+                    "description": "ISO 3166-1 alpha-2 codes, or WW alone for worldwide",
+                    "type": "array",
+                    "maxItems": 250,
+                    "minItems": 1,
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "track_ids": {
+                    "type": "array",
+                    "maxItems": 500,
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "usage": {
+                    "type": "string",
+                    "maxLength": 50
+                }
+            }
+        },
         "dto.CreatePlaylistRequest": {
             "type": "object",
             "required": [
@@ -716,6 +3379,21 @@ const docTemplate = `{
                 "artist_id": {
                     "type": "string"
                 },
+                "artist_name": {
+                    "type": "string"
+                },
+                "availability": {
+                    "description": "Territories and dates the track may be listed and streamed in, null for\neverywhere and always",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/dto.TrackAvailability"
+                        }
+                    ]
+                },
+                "c_line": {
+                    "type": "string",
+                    "maxLength": 200
+                },
                 "duration": {
                     "type": "integer"
                 },
@@ -725,25 +3403,191 @@ const docTemplate = `{
                 "genre": {
                     "type": "string"
                 },
+                "isrc": {
+                    "type": "string"
+                },
+                "iswc": {
+                    "type": "string"
+                },
+                "label": {
+                    "type": "string",
+                    "maxLength": 200
+                },
+                "master_owner": {
+                    "type": "string",
+                    "maxLength": 200
+                },
                 "name": {
                     "type": "string"
                 },
+                "p_line": {
+                    "type": "string",
+                    "maxLength": 200
+                },
+                "publishing": {
+                    "description": "Writers and publishers of the work, their shares must sum to 100",
+                    "type": "array",
+                    "maxItems": 50,
+                    "items": {
+                        "$ref": "#/definitions/dto.PublishingSplit"
+                    }
+                },
                 "release_date": {
                     "type": "integer"
                 },
+                "tier": {
+                    "description": "Licensing price tier, one of the tiers of the pricing rules or empty for\nthe default tier",
+                    "type": "string",
+                    "maxLength": 50
+                },
                 "title": {
                     "type": "string"
                 }
             }
         },
+        "dto.DDEXImportRequest": {
+            "type": "object",
+            "required": [
+                "path"
+            ],
+            "properties": {
+                "commit": {
+                    "type": "boolean"
+                },
+                "path": {
+                    "description": "ERN message of the delivery, relative to the delivery folder",
+                    "type": "string"
+                }
+            }
+        },
+        "dto.LicenseContact": {
+            "type": "object",
+            "required": [
+                "email",
+                "name"
+            ],
+            "properties": {
+                "company": {
+                    "type": "string",
+                    "maxLength": 200
+                },
+                "email": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string",
+                    "maxLength": 200
+                }
+            }
+        },
+        "dto.LicenseStatusRequest": {
+            "type": "object",
+            "required": [
+                "status"
+            ],
+            "properties": {
+                "note": {
+                    "type": "string",
+                    "maxLength": 2000
+                },
+                "status": {
+                    "type": "string",
+                    "enum": [
+                        "approved",
+                        "rejected",
+                        "signed"
+                    ]
+                }
+            }
+        },
+        "dto.PublishingSplit": {
+            "type": "object",
+            "required": [
+                "name"
+            ],
+            "properties": {
+                "ipi": {
+                    "description": "Interested Party Information number",
+                    "type": "string",
+                    "maxLength": 11,
+                    "minLength": 9
+                },
+                "name": {
+                    "type": "string",
+                    "maxLength": 200
+                },
+                "role": {
+                    "type": "string",
+                    "enum": [
+                        "writer",
+                        "publisher"
+                    ]
+                },
+                "share": {
+                    "type": "number",
+                    "maximum": 100
+                }
+            }
+        },
+        "dto.QuoteLicenseRequest": {
+            "type": "object",
+            "properties": {
+                "note": {
+                    "type": "string",
+                    "maxLength": 2000
+                },
+                "total": {
+                    "type": "number",
+                    "minimum": 0
+                }
+            }
+        },
+        "dto.TrackAvailability": {
+            "type": "object",
+            "properties": {
+                "blocked_territories": {
+                    "description": "Territories the track is never available in",
+                    "type": "array",
+                    "maxItems": 250,
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "end_at": {
+                    "type": "integer"
+                },
+                "start_at": {
+                    "description": "Milliseconds since the epoch, end_at is exclusive",
+                    "type": "integer",
+                    "minimum": 0
+                },
+                "territories": {
+                    "description": "ISO 3166-1 alpha-2 codes of the only territories the track is available in",
+                    "type": "array",
+                    "maxItems": 250,
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
         "dto.UpdatePlaylistRequest": {
             "type": "object",
+            "required": [
+                "title"
+            ],
             "properties": {
                 "album_cover": {
                     "type": "string"
                 },
                 "title": {
                     "type": "string"
+                },
+                "track_ids": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
                 }
             }
         },
@@ -764,6 +3608,12 @@ const docTemplate = `{
         },
         "dto.UpdateTrackRequest": {
             "type": "object",
+            "required": [
+                "artist_id",
+                "file_url",
+                "name",
+                "title"
+            ],
             "properties": {
                 "album": {
                     "type": "string"
@@ -771,19 +3621,102 @@ const docTemplate = `{
                 "artist_id": {
                     "type": "string"
                 },
+                "artist_name": {
+                    "type": "string"
+                },
+                "availability": {
+                    "description": "Territories and dates the track may be listed and streamed in, null for\neverywhere and always",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/dto.TrackAvailability"
+                        }
+                    ]
+                },
+                "c_line": {
+                    "type": "string",
+                    "maxLength": 200
+                },
+                "duration": {
+                    "type": "integer",
+                    "minimum": 0
+                },
                 "file_url": {
                     "type": "string"
                 },
                 "genre": {
                     "type": "string"
                 },
+                "isrc": {
+                    "type": "string"
+                },
+                "iswc": {
+                    "type": "string"
+                },
+                "label": {
+                    "type": "string",
+                    "maxLength": 200
+                },
+                "master_owner": {
+                    "type": "string",
+                    "maxLength": 200
+                },
                 "name": {
                     "type": "string"
                 },
+                "p_line": {
+                    "type": "string",
+                    "maxLength": 200
+                },
+                "publishing": {
+                    "description": "Writers and publishers of the work, their shares must sum to 100",
+                    "type": "array",
+                    "maxItems": 50,
+                    "items": {
+                        "$ref": "#/definitions/dto.PublishingSplit"
+                    }
+                },
+                "release_date": {
+                    "type": "integer",
+                    "minimum": 0
+                },
+                "tier": {
+                    "description": "Licensing price tier, one of the tiers of the pricing rules or empty for\nthe default tier",
+                    "type": "string",
+                    "maxLength": 50
+                },
                 "title": {
                     "type": "string"
                 }
             }
+        },
+        "media.Waveform": {
+            "type": "object",
+            "properties": {
+                "bits": {
+                    "type": "integer"
+                },
+                "channels": {
+                    "type": "integer"
+                },
+                "data": {
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                },
+                "length": {
+                    "type": "integer"
+                },
+                "sample_rate": {
+                    "type": "integer"
+                },
+                "samples_per_pixel": {
+                    "type": "integer"
+                },
+                "version": {
+                    "type": "integer"
+                }
+            }
         }
     }
 }`
@@ -0,0 +1,14 @@
+package dto
+
+import "time"
+
+type AuditFilterRequest struct {
+	Actor    string    `form:"actor"`
+	Method   string    `form:"method" validate:"omitempty,oneof=POST PUT PATCH DELETE"`
+	Route    string    `form:"route"`
+	TargetID string    `form:"target_id"`
+	Status   int       `form:"status" validate:"omitempty,min=100,max=599"`
+	From     time.Time `form:"from" time_format:"2006-01-02T15:04:05Z07:00"`
+	To       time.Time `form:"to" time_format:"2006-01-02T15:04:05Z07:00"`
+	Limit    int       `form:"limit" validate:"omitempty,min=1,max=1000"`
+}
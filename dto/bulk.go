@@ -0,0 +1,49 @@
+package dto
+
+import "encoding/json"
+
+type BulkTrackRequest struct {
+	// Stop at the first failed operation
+	Ordered bool `json:"ordered"`
+	// Write every operation or none, in a transaction
+	Atomic     bool                  `json:"atomic"`
+	Operations []*BulkTrackOperation `json:"operations" validate:"required,min=1,max=1000"`
+}
+
+// BulkTrackOperation creates a track from Track, or replaces the editable
+// fields of track ID at Version with Track, or deletes track ID at Version
+type BulkTrackOperation struct {
+	Op      string          `json:"op" validate:"required,oneof=create update delete"`
+	ID      string          `json:"id"`
+	Version int64           `json:"version"`
+	Track   json.RawMessage `json:"track" swaggertype:"object"`
+}
+
+// Outcomes of a bulk operation
+const (
+	BulkCreated  = "created"
+	BulkUpdated  = "updated"
+	BulkDeleted  = "deleted"
+	BulkInvalid  = "invalid"
+	BulkNotFound = "not_found"
+	BulkConflict = "conflict"
+	BulkFailed   = "failed"
+	BulkSkipped  = "skipped"
+)
+
+type BulkTrackResult struct {
+	Index   int    `json:"index"`
+	Op      string `json:"op"`
+	ID      string `json:"id,omitempty"`
+	Status  string `json:"status"`
+	Version int64  `json:"version,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+type BulkTrackResponse struct {
+	Created int                `json:"created"`
+	Updated int                `json:"updated"`
+	Deleted int                `json:"deleted"`
+	Failed  int                `json:"failed"`
+	Results []*BulkTrackResult `json:"results"`
+}
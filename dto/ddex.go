@@ -0,0 +1,53 @@
+package dto
+
+import "github.com/rolexkdev/emvn-music-library-server/internal/models"
+
+type DDEXImportRequest struct {
+	// ERN message of the delivery, relative to the delivery folder
+	Path   string `json:"path" validate:"required"`
+	Commit bool   `json:"commit"`
+}
+
+type DDEXExportRequest struct {
+	// DDEX party id (DPID) and name of the recipient of the message
+	RecipientID   string `form:"recipient_id" validate:"required"`
+	RecipientName string `form:"recipient_name"`
+}
+
+// DDEXImportRecord is the planned outcome of the album or an artist of a
+// delivery, an ImportCreate, ImportUpdate, ImportUnchanged or ImportError
+type DDEXImportRecord struct {
+	Action  string                `json:"action"`
+	ID      string                `json:"id"`
+	Name    string                `json:"name"`
+	Changes []*models.FieldChange `json:"changes,omitempty"`
+	Errors  []string              `json:"errors,omitempty"`
+}
+
+// DDEXImportTrack is the planned outcome of a sound recording of a delivery
+type DDEXImportTrack struct {
+	// ResourceReference of the recording in the message
+	Reference string                `json:"reference"`
+	ISRC      string                `json:"isrc,omitempty"`
+	Title     string                `json:"title"`
+	Action    string                `json:"action"`
+	TrackID   string                `json:"track_id,omitempty"`
+	MatchedBy string                `json:"matched_by,omitempty"`
+	Changes   []*models.FieldChange `json:"changes,omitempty"`
+	Status    string                `json:"status,omitempty"`
+	Errors    []string              `json:"errors,omitempty"`
+}
+
+// DDEXImportReport tells what importing a delivery does, or did once
+// committed. The counts are of tracks, Errors counts every record with errors.
+type DDEXImportReport struct {
+	Committed bool                `json:"committed"`
+	MessageID string              `json:"message_id"`
+	Album     *DDEXImportRecord   `json:"album"`
+	Artists   []*DDEXImportRecord `json:"artists"`
+	Create    int                 `json:"create"`
+	Update    int                 `json:"update"`
+	Unchanged int                 `json:"unchanged"`
+	Errors    int                 `json:"errors"`
+	Tracks    []*DDEXImportTrack  `json:"tracks"`
+}
@@ -0,0 +1,46 @@
+package dto
+
+import "time"
+
+type TrackExportRequest struct {
+	TrackFilterRequest
+	Format string `form:"format" validate:"omitempty,oneof=csv jsonl xlsx"`
+}
+
+type PlaylistExportRequest struct {
+	Format string `form:"format" validate:"omitempty,oneof=csv jsonl xlsx"`
+}
+
+// TrackExport is a track as exported, with its artist name resolved
+type TrackExport struct {
+	ID          string             `json:"id"`
+	ISRC        string             `json:"isrc"`
+	ISWC        string             `json:"iswc"`
+	Title       string             `json:"title"`
+	Name        string             `json:"name"`
+	ArtistID    string             `json:"artist_id"`
+	ArtistName  string             `json:"artist_name"`
+	Album       string             `json:"album"`
+	Genre       string             `json:"genre"`
+	ReleaseDate int64              `json:"release_date"`
+	Duration    int64              `json:"duration"`
+	FileURL     string             `json:"file_url"`
+	PLine       string             `json:"p_line"`
+	CLine       string             `json:"c_line"`
+	Label       string             `json:"label"`
+	MasterOwner string             `json:"master_owner"`
+	Publishing  []*PublishingSplit `json:"publishing"`
+	Tier        string             `json:"tier"`
+	CreateAt    time.Time          `json:"create_at"`
+	UpdateAt    time.Time          `json:"update_at"`
+}
+
+// PlaylistExport is a playlist as exported, with its live tracks in order
+type PlaylistExport struct {
+	ID         string         `json:"id"`
+	Title      string         `json:"title"`
+	AlbumCover string         `json:"album_cover"`
+	CreateAt   time.Time      `json:"create_at"`
+	UpdateAt   time.Time      `json:"update_at"`
+	Tracks     []*TrackExport `json:"tracks"`
+}
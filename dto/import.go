@@ -0,0 +1,44 @@
+package dto
+
+import "github.com/rolexkdev/emvn-music-library-server/internal/models"
+
+type ImportTracksRequest struct {
+	Mapping   string `form:"mapping"`
+	Delimiter string `form:"delimiter" validate:"omitempty,len=1"`
+	Commit    bool   `form:"commit"`
+}
+
+// Planned outcomes of an imported row
+const (
+	ImportCreate    = "create"
+	ImportUpdate    = "update"
+	ImportUnchanged = "unchanged"
+	ImportError     = "error"
+)
+
+// How an imported row was matched to an existing track
+const (
+	MatchISRC        = "isrc"
+	MatchTitleArtist = "title_artist"
+)
+
+type ImportRow struct {
+	Line      int                   `json:"line"`
+	Action    string                `json:"action"`
+	TrackID   string                `json:"track_id,omitempty"`
+	MatchedBy string                `json:"matched_by,omitempty"`
+	Changes   []*models.FieldChange `json:"changes,omitempty"`
+	Status    string                `json:"status,omitempty"`
+	Errors    []string              `json:"errors,omitempty"`
+}
+
+// ImportReport tells what an import does, or did once committed
+type ImportReport struct {
+	Committed bool         `json:"committed"`
+	Rows      int          `json:"rows"`
+	Create    int          `json:"create"`
+	Update    int          `json:"update"`
+	Unchanged int          `json:"unchanged"`
+	Errors    int          `json:"errors"`
+	Results   []*ImportRow `json:"results"`
+}
@@ -0,0 +1,7 @@
+package dto
+
+type JobFilterRequest struct {
+	Type   string `form:"type"`
+	Target string `form:"target"`
+	Status string `form:"status" validate:"omitempty,oneof=pending running succeeded dead"`
+}
@@ -0,0 +1,43 @@
+package dto
+
+// CreateLicenseRequest asks for a sync license of tracks, or of the tracks of
+// a playlist
+type CreateLicenseRequest struct {
+	TrackIDs   []string `json:"track_ids" validate:"required_without=PlaylistID,max=500"`
+	PlaylistID string   `json:"playlist_id"`
+	// Media type and usage priced by the pricing rules, e.g. tv and background
+	MediaType string `json:"media_type" validate:"required,max=50"`
+	Usage     string `json:"usage" validate:"required,max=50"`
+	// ISO 3166-1 alpha-2 codes, or WW alone for worldwide
+	Territories []string `json:"territories" validate:"required,min=1,max=250,dive,territory"`
+	// Length of the license in months, 0 for a perpetual license
+	TermMonths *int `json:"term_months" validate:"required,gte=0,lte=1200"`
+	// First day of the license in milliseconds since the epoch, 0 for the day
+	// it is signed
+	StartDate   int64          `json:"start_date" validate:"gte=0"`
+	Project     string         `json:"project" validate:"required,max=200"`
+	Description string         `json:"description" validate:"max=2000"`
+	Contact     LicenseContact `json:"contact"`
+}
+
+type LicenseContact struct {
+	Name    string `json:"name" validate:"required,max=200"`
+	Email   string `json:"email" validate:"required,email"`
+	Company string `json:"company" validate:"max=200"`
+}
+
+type LicenseFilterRequest struct {
+	Status string `form:"status" validate:"omitempty,oneof=requested quoted approved rejected signed"`
+}
+
+// QuoteLicenseRequest prices a license request with the pricing rules, Total
+// replaces the computed total when set
+type QuoteLicenseRequest struct {
+	Total *float64 `json:"total" validate:"omitempty,gte=0"`
+	Note  string   `json:"note" validate:"max=2000"`
+}
+
+type LicenseStatusRequest struct {
+	Status string `json:"status" validate:"required,oneof=approved rejected signed"`
+	Note   string `json:"note" validate:"max=2000"`
+}
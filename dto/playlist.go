@@ -6,9 +6,12 @@ type CreatePlaylistRequest struct {
 	TrackIDs   []string `json:"track_ids"`
 }
 
+// UpdatePlaylistRequest holds every editable field of a playlist, a PUT
+// replaces them all so omitted optional fields are cleared
 type UpdatePlaylistRequest struct {
-	Title      *string `json:"title"`
-	AlbumCover *string `json:"album_cover"`
+	Title      string   `json:"title" validate:"required"`
+	AlbumCover string   `json:"album_cover"`
+	TrackIDs   []string `json:"track_ids"`
 }
 
 type UpdatePlaylistTrackRequest struct {
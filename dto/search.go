@@ -1,12 +1,35 @@
 package dto
 
-import "github.com/rolexkdev/emvn-music-library-server/internal/models"
+import "github.com/rolexkdev/emvn-music-library-server/internal/search"
 
 type SearchRequest struct {
 	Query string `form:"query"`
+	Mode  string `form:"mode"`
+	Fuzzy bool   `form:"fuzzy"`
+	// Comma separated result types, all of them when empty
+	Type string `form:"type"`
+	// Results per type
+	Limit int `form:"limit" validate:"omitempty,min=1"`
+	// Next page of a single type, as returned in next_cursor
+	Cursor string `form:"cursor"`
+	TrackFilterRequest
 }
 
+// SearchResponse holds a page of matches by descending relevance score for
+// every requested type
 type SearchResponse struct {
-	Tracks    []*models.Track    `json:"tracks"`
-	Playlists []*models.Playlist `json:"playlists"`
+	Tracks    *search.TrackPage    `json:"tracks,omitempty"`
+	Playlists *search.PlaylistPage `json:"playlists,omitempty"`
+	Albums    *search.AlbumPage    `json:"albums,omitempty"`
+	Artists   *search.ArtistPage   `json:"artists,omitempty"`
+	Facets    *search.Facets       `json:"facets,omitempty"`
+}
+
+type SuggestRequest struct {
+	Query string `form:"q"`
+	Limit int    `form:"limit" validate:"omitempty,min=1,max=20"`
+}
+
+type SuggestResponse struct {
+	Suggestions []*search.Suggestion `json:"suggestions"`
 }
@@ -4,18 +4,91 @@ type CreateTrackRequest struct {
 	Name        string `json:"name" validate:"required"`
 	Title       string `json:"title" validate:"required"`
 	ArtistID    string `json:"artist_id" validate:"required"`
+	ArtistName  string `json:"artist_name"`
 	Album       string `json:"album" validate:"required"`
 	Genre       string `json:"genre" validate:"required"`
 	ReleaseDate int64  `json:"release_date" validate:"required"`
 	Duration    int64  `json:"duration" validate:"required"`
 	FileURL     string `json:"file_url" validate:"required"`
+	ISRC        string `json:"isrc" validate:"omitempty,isrc"`
+	ISWC        string `json:"iswc" validate:"omitempty,iswc"`
+	PLine       string `json:"p_line" validate:"max=200"`
+	CLine       string `json:"c_line" validate:"max=200"`
+	Label       string `json:"label" validate:"max=200"`
+	MasterOwner string `json:"master_owner" validate:"max=200"`
+	// Writers and publishers of the work, their shares must sum to 100
+	Publishing []*PublishingSplit `json:"publishing" validate:"omitempty,max=50,shares,dive"`
+	// Territories and dates the track may be listed and streamed in, null for
+	// everywhere and always
+	Availability *TrackAvailability `json:"availability"`
+	// Licensing price tier, one of the tiers of the pricing rules or empty for
+	// the default tier
+	Tier string `json:"tier" validate:"omitempty,max=50,tier"`
 }
 
+// UpdateTrackRequest holds every editable field of a track, a PUT replaces
+// them all so omitted optional fields are cleared
 type UpdateTrackRequest struct {
-	Name        *string `json:"name"`
-	Title       *string `json:"title"`
-	ArtistID    *string `json:"artist_id"`
-	Album       *string `json:"album"`
-	Genre       *string `json:"genre"`
-	FileURL     *string `json:"file_url"`
+	Name        string `json:"name" validate:"required"`
+	Title       string `json:"title" validate:"required"`
+	ArtistID    string `json:"artist_id" validate:"required"`
+	ArtistName  string `json:"artist_name"`
+	Album       string `json:"album"`
+	Genre       string `json:"genre"`
+	ReleaseDate int64  `json:"release_date" validate:"gte=0"`
+	Duration    int64  `json:"duration" validate:"gte=0"`
+	FileURL     string `json:"file_url" validate:"required"`
+	ISRC        string `json:"isrc" validate:"omitempty,isrc"`
+	ISWC        string `json:"iswc" validate:"omitempty,iswc"`
+	PLine       string `json:"p_line" validate:"max=200"`
+	CLine       string `json:"c_line" validate:"max=200"`
+	Label       string `json:"label" validate:"max=200"`
+	MasterOwner string `json:"master_owner" validate:"max=200"`
+	// Writers and publishers of the work, their shares must sum to 100
+	Publishing []*PublishingSplit `json:"publishing" validate:"omitempty,max=50,shares,dive"`
+	// Territories and dates the track may be listed and streamed in, null for
+	// everywhere and always
+	Availability *TrackAvailability `json:"availability"`
+	// Licensing price tier, one of the tiers of the pricing rules or empty for
+	// the default tier
+	Tier string `json:"tier" validate:"omitempty,max=50,tier"`
+}
+
+// PublishingSplit is the share of the work owned by a writer or publisher
+type PublishingSplit struct {
+	Name string `json:"name" validate:"required,max=200"`
+	Role string `json:"role" validate:"oneof=writer publisher"`
+	// Interested Party Information number
+	IPI   string  `json:"ipi" validate:"omitempty,numeric,min=9,max=11"`
+	Share float64 `json:"share" validate:"gt=0,lte=100"`
+}
+
+// TrackAvailability restricts where and when a track is available, empty
+// fields do not restrict it
+type TrackAvailability struct {
+	// ISO 3166-1 alpha-2 codes of the only territories the track is available in
+	Territories []string `json:"territories" validate:"max=250,dive,territory"`
+	// Territories the track is never available in
+	BlockedTerritories []string `json:"blocked_territories" validate:"max=250,dive,territory"`
+	// Milliseconds since the epoch, end_at is exclusive
+	StartAt int64 `json:"start_at" validate:"gte=0"`
+	EndAt   int64 `json:"end_at" validate:"omitempty,gtfield=StartAt"`
+}
+
+type TrackFilterRequest struct {
+	MinBPM       *float64 `form:"min_bpm"`
+	MaxBPM       *float64 `form:"max_bpm"`
+	Key          string   `form:"key"`
+	MinLoudness  *float64 `form:"min_loudness"`
+	MaxLoudness  *float64 `form:"max_loudness"`
+	Genres       []string `form:"genre"`
+	Years        []int    `form:"year"`
+	Durations    []string `form:"duration"`
+	ArtistIDs    []string `form:"artist_id"`
+	Labels       []string `form:"label"`
+	MasterOwners []string `form:"master_owner"`
+	Writers      []string `form:"writer"`
+	Publishers   []string `form:"publisher"`
+	ISRC         string   `form:"isrc"`
+	ISWC         string   `form:"iswc"`
 }
@@ -0,0 +1,31 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/rolexkdev/emvn-music-library-server/internal/models"
+)
+
+type TrashRequest struct {
+	Type string `form:"type" validate:"omitempty,oneof=tracks playlists"`
+}
+
+// TrashedTrack is a deleted track with the time it is purged
+type TrashedTrack struct {
+	*models.Track
+	DeletedAt time.Time `json:"deleted_at"`
+	PurgeAt   time.Time `json:"purge_at"`
+}
+
+// TrashedPlaylist is a deleted playlist with the time it is purged
+type TrashedPlaylist struct {
+	*models.Playlist
+	DeletedAt time.Time `json:"deleted_at"`
+	PurgeAt   time.Time `json:"purge_at"`
+}
+
+// TrashResponse lists the deleted records, most recently deleted first
+type TrashResponse struct {
+	Tracks    []*TrashedTrack    `json:"tracks,omitempty"`
+	Playlists []*TrashedPlaylist `json:"playlists,omitempty"`
+}
@@ -2,4 +2,6 @@ package dto
 
 type FileUploadResponse struct {
 	FileURLs []string `json:"file_urls"`
+	// Background jobs processing the uploaded files, poll them with GET /jobs/{id}
+	JobIDs []string `json:"job_ids"`
 }
@@ -0,0 +1,20 @@
+// Package catalog reads and writes the track catalog in the file formats of
+// labels and distributors
+package catalog
+
+import "github.com/rolexkdev/emvn-music-library-server/config"
+
+var (
+	// Most rows read from one import file
+	maxRows = 5000
+	// Folder holding the DDEX deliveries
+	deliveryDir = "./deliveries"
+	// Sender of exported ERN messages
+	sender = Party{}
+)
+
+func Setup(cfg *config.Config) {
+	maxRows = cfg.Catalog.ImportMaxRows
+	deliveryDir = cfg.Catalog.DDEXDeliveryDir
+	sender = Party{ID: cfg.Catalog.DDEXSenderID, Name: cfg.Catalog.DDEXSenderName}
+}
@@ -0,0 +1,329 @@
+package catalog
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rolexkdev/emvn-music-library-server/common/utils"
+	"github.com/rolexkdev/emvn-music-library-server/dto"
+)
+
+// Fields of dto.CreateTrackRequest a CSV column can be mapped to, by JSON name
+var Fields = []string{
+	"name", "title", "artist_id", "artist_name", "album", "genre",
+	"release_date", "duration", "file_url", "isrc", "iswc", "p_line", "c_line",
+	"label", "master_owner", "publishing", "tier",
+}
+
+// Mapping gives the header of the CSV column holding each field. Fields left
+// out are read from the column named like the field, if any.
+type Mapping map[string]string
+
+// Row is one line of a CSV file with the values of its mapped fields. Empty
+// cells are left out.
+type Row struct {
+	Line   int
+	Values map[string]string
+}
+
+// Release date layouts accepted besides milliseconds since the epoch
+var dateLayouts = []string{time.RFC3339, "2006-01-02", "2006/01/02", "2006"}
+
+// ReadCSV reads the rows of a CSV file whose first line holds the column
+// headers, matched without regard to case. A delimiter of 0 means a comma.
+func ReadCSV(r io.Reader, delimiter rune, mapping Mapping) ([]*Row, error) {
+	reader := csv.NewReader(r)
+	if delimiter != 0 {
+		reader.Comma = delimiter
+	}
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, errors.New("CSV file is empty")
+	}
+	if err != nil {
+		return nil, err
+	}
+	columns, err := mapColumns(header, mapping)
+	if err != nil {
+		return nil, err
+	}
+
+	rows := []*Row{}
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(rows) >= maxRows {
+			return nil, fmt.Errorf("CSV file has more than %d rows", maxRows)
+		}
+
+		line, _ := reader.FieldPos(0)
+		row := &Row{Line: line, Values: map[string]string{}}
+		for field, column := range columns {
+			if column >= len(record) {
+				continue
+			}
+			// Text cells of an export are quoted against formulas
+			if value := strings.TrimSpace(UnescapeFormula(strings.TrimSpace(record[column]))); value != "" {
+				row.Values[field] = value
+			}
+		}
+		// Spreadsheets often export trailing blank lines
+		if len(row.Values) > 0 {
+			rows = append(rows, row)
+		}
+	}
+	return rows, nil
+}
+
+// mapColumns returns the column index of every mapped field
+func mapColumns(header []string, mapping Mapping) (map[string]int, error) {
+	for field := range mapping {
+		if !isField(field) {
+			return nil, fmt.Errorf("unknown field %q in mapping, expected one of %s", field, strings.Join(Fields, ", "))
+		}
+	}
+
+	index := map[string]int{}
+	for i, name := range header {
+		// Excel prefixes UTF-8 CSV files with a byte order mark
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		if _, ok := index[name]; !ok {
+			index[name] = i
+		}
+	}
+
+	columns := map[string]int{}
+	for _, field := range Fields {
+		name, mapped := mapping[field]
+		if !mapped {
+			name = field
+		}
+		column, ok := index[strings.ToLower(strings.TrimSpace(name))]
+		if !ok {
+			if mapped {
+				return nil, fmt.Errorf("column %q mapped to %s is not in the CSV header", name, field)
+			}
+			continue
+		}
+		columns[field] = column
+	}
+	if len(columns) == 0 {
+		return nil, errors.New("no CSV column maps to a track field")
+	}
+	return columns, nil
+}
+
+func isField(name string) bool {
+	for _, field := range Fields {
+		if field == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Apply sets the fields of request present in the row and returns the errors
+// of the values that cannot be parsed
+func (row *Row) Apply(request *dto.UpdateTrackRequest) []string {
+	var errs []string
+	for _, field := range Fields {
+		value, ok := row.Values[field]
+		if !ok {
+			continue
+		}
+		switch field {
+		case "name":
+			request.Name = value
+		case "title":
+			request.Title = value
+		case "artist_id":
+			request.ArtistID = value
+		case "artist_name":
+			request.ArtistName = value
+		case "album":
+			request.Album = value
+		case "genre":
+			request.Genre = value
+		case "file_url":
+			request.FileURL = value
+		case "isrc":
+			request.ISRC = utils.NormalizeISRC(value)
+		case "iswc":
+			request.ISWC = utils.NormalizeISWC(value)
+		case "p_line":
+			request.PLine = value
+		case "c_line":
+			request.CLine = value
+		case "label":
+			request.Label = value
+		case "master_owner":
+			request.MasterOwner = value
+		case "tier":
+			request.Tier = value
+		case "publishing":
+			splits, err := parsePublishing(value)
+			if err != nil {
+				errs = append(errs, err.Error())
+				continue
+			}
+			request.Publishing = splits
+		case "release_date":
+			date, err := parseReleaseDate(value)
+			if err != nil {
+				errs = append(errs, err.Error())
+				continue
+			}
+			request.ReleaseDate = date
+		case "duration":
+			duration, err := parseDuration(value)
+			if err != nil {
+				errs = append(errs, err.Error())
+				continue
+			}
+			request.Duration = duration
+		}
+	}
+	return errs
+}
+
+// parseReleaseDate reads milliseconds since the epoch or a date such as
+// 2024-06-07
+func parseReleaseDate(value string) (int64, error) {
+	if ms, err := strconv.ParseInt(value, 10, 64); err == nil && len(value) > 4 {
+		return ms, nil
+	}
+	for _, layout := range dateLayouts {
+		if date, err := time.Parse(layout, value); err == nil {
+			return date.UnixMilli(), nil
+		}
+	}
+	return 0, fmt.Errorf("release_date %q is neither milliseconds since the epoch nor a date such as 2024-06-07", value)
+}
+
+// parseDuration reads milliseconds or a duration such as 4:05, 4:05.250 or
+// 1:02:03
+func parseDuration(value string) (int64, error) {
+	if ms, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return ms, nil
+	}
+
+	parts := strings.Split(value, ":")
+	if len(parts) == 2 || len(parts) == 3 {
+		// The seconds may have up to three decimals
+		seconds, fraction, hasFraction := strings.Cut(parts[len(parts)-1], ".")
+		parts[len(parts)-1] = seconds
+		ms := uint64(0)
+		valid := true
+		if hasFraction {
+			var err error
+			valid = len(fraction) >= 1 && len(fraction) <= 3
+			if valid {
+				ms, err = strconv.ParseUint((fraction + "00")[:3], 10, 64)
+				valid = err == nil
+			}
+		}
+		total := int64(0)
+		for i, part := range parts {
+			n, err := strconv.ParseInt(part, 10, 64)
+			if err != nil || n < 0 || (i > 0 && n >= 60) {
+				valid = false
+				break
+			}
+			total = total*60 + n
+		}
+		if valid {
+			return total*1000 + int64(ms), nil
+		}
+	}
+	return 0, fmt.Errorf("duration %q is neither milliseconds nor a duration such as 4:05", value)
+}
+
+// FormatReleaseDate writes milliseconds since the epoch as a date read back by
+// an import, empty for 0
+func FormatReleaseDate(ms int64) string {
+	if ms == 0 {
+		return ""
+	}
+	return time.UnixMilli(ms).UTC().Format("2006-01-02")
+}
+
+// FormatDuration writes milliseconds as a duration read back by an import
+// without loss, e.g. 4:05, 4:05.250 or 1:02:03
+func FormatDuration(ms int64) string {
+	seconds := ms / 1000
+	fraction := ""
+	if ms%1000 != 0 {
+		fraction = fmt.Sprintf(".%03d", ms%1000)
+	}
+	if seconds >= 3600 {
+		return fmt.Sprintf("%d:%02d:%02d%s", seconds/3600, seconds/60%60, seconds%60, fraction)
+	}
+	return fmt.Sprintf("%d:%02d%s", seconds/60, seconds%60, fraction)
+}
+
+// parsePublishing reads publishing splits written by FormatPublishing, e.g.
+// "writer:Jane Doe:50:00123456789; publisher:EMVN Publishing:50". Names may
+// hold colons but not semicolons.
+func parsePublishing(value string) ([]*dto.PublishingSplit, error) {
+	var splits []*dto.PublishingSplit
+	for _, item := range strings.Split(value, ";") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		parts := strings.Split(item, ":")
+		if len(parts) < 3 {
+			return nil, fmt.Errorf("publishing split %q is not written role:name:share or role:name:share:ipi", item)
+		}
+		split := &dto.PublishingSplit{Role: strings.ToLower(strings.TrimSpace(parts[0]))}
+		parts = parts[1:]
+		// A share is at most 100 so a last part of 9 digits or more is an IPI
+		if last := strings.TrimSpace(parts[len(parts)-1]); len(parts) >= 3 && len(last) >= 9 && isDigits(last) {
+			split.IPI = last
+			parts = parts[:len(parts)-1]
+		}
+		share, err := strconv.ParseFloat(strings.TrimSuffix(strings.TrimSpace(parts[len(parts)-1]), "%"), 64)
+		if err != nil {
+			return nil, fmt.Errorf("publishing split %q has no numeric share", item)
+		}
+		split.Share = share
+		split.Name = strings.TrimSpace(strings.Join(parts[:len(parts)-1], ":"))
+		splits = append(splits, split)
+	}
+	return splits, nil
+}
+
+func isDigits(value string) bool {
+	for _, r := range value {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// FormatPublishing writes publishing splits as read back by an import
+func FormatPublishing(splits []*dto.PublishingSplit) string {
+	items := make([]string, 0, len(splits))
+	for _, split := range splits {
+		item := split.Role + ":" + split.Name + ":" + strconv.FormatFloat(split.Share, 'f', -1, 64)
+		if split.IPI != "" {
+			item += ":" + split.IPI
+		}
+		items = append(items, item)
+	}
+	return strings.Join(items, "; ")
+}
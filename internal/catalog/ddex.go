@@ -0,0 +1,773 @@
+package catalog
+
+import (
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Namespace of the ERN messages written, ERN 4.3
+const ernNamespace = "http://ddex.net/xml/ern/43"
+
+// Namespace prefix shared by the ERN 4.x versions read
+const ernNamespacePrefix = "http://ddex.net/xml/ern/4"
+
+// Largest ERN message read
+const maxERNSize = 32 << 20
+
+// Party is an artist, label or company named in a DDEX message. ID is the ISNI
+// of an artist or the DDEX party id (DPID) of a company.
+type Party struct {
+	ID   string
+	Name string
+}
+
+// File is a resource file of a delivery. Hash is checked when set.
+type File struct {
+	URI           string
+	HashAlgorithm string
+	Hash          string
+}
+
+// Recording is a sound recording of a release
+type Recording struct {
+	Reference string
+	ISRC      string
+	// Identifies a recording without ISRC in the namespace of the sender
+	ProprietaryID string
+	Title         string
+	Artist        Party
+	// Milliseconds
+	Duration int64
+	File     *File
+	// Problems found reading the recording
+	Errors []string
+}
+
+// Release is the main release of a DDEX message, an album, EP or single and
+// its sound recordings in order
+type Release struct {
+	Reference string
+	// Album, EP or Single
+	Type string
+	// UPC or EAN of the release
+	ICPN          string
+	ProprietaryID string
+	Title         string
+	Artist        Party
+	Label         Party
+	Genre         string
+	// Milliseconds since the epoch
+	ReleaseDate int64
+	Cover       *File
+	Recordings  []*Recording
+}
+
+// Message is a DDEX ERN NewReleaseMessage
+type Message struct {
+	ID        string
+	Sender    Party
+	Recipient Party
+	CreatedAt time.Time
+	Release   *Release
+}
+
+// Elements of an ERN 4.x NewReleaseMessage, in the order of the 4.3 schema.
+// The Legacy fields read the ERN 4.1 layout and are never written.
+type ernMessage struct {
+	XMLName               xml.Name
+	Namespace             string           `xml:"xmlns:ern,attr,omitempty"`
+	AvsVersionID          string           `xml:"AvsVersionId,attr,omitempty"`
+	LanguageAndScriptCode string           `xml:"LanguageAndScriptCode,attr,omitempty"`
+	Header                ernMessageHeader `xml:"MessageHeader"`
+	Parties               []*ernParty      `xml:"PartyList>Party"`
+	SoundRecordings       []*ernRecording  `xml:"ResourceList>SoundRecording"`
+	Images                []*ernImage      `xml:"ResourceList>Image"`
+	Release               *ernRelease      `xml:"ReleaseList>Release"`
+	Deals                 []*ernDeal       `xml:"DealList>ReleaseDeal"`
+}
+
+type ernMessageHeader struct {
+	MessageID  string             `xml:"MessageId"`
+	Sender     ernMessageParty    `xml:"MessageSender"`
+	Recipients []*ernMessageParty `xml:"MessageRecipient"`
+	CreatedAt  string             `xml:"MessageCreatedDateTime"`
+	Control    string             `xml:"MessageControlType,omitempty"`
+}
+
+// Elements left empty are omitted through pointers, encoding/xml writes the
+// parents of empty a>b fields
+
+type ernMessageParty struct {
+	PartyID string        `xml:"PartyId"`
+	Name    *ernPartyName `xml:"PartyName,omitempty"`
+}
+
+type ernPartyName struct {
+	FullName string `xml:"FullName"`
+}
+
+type ernParty struct {
+	Reference string       `xml:"PartyReference"`
+	ID        *ernPartyID  `xml:"PartyId,omitempty"`
+	Name      ernPartyName `xml:"PartyName"`
+}
+
+type ernPartyID struct {
+	ISNI string `xml:"ISNI,omitempty"`
+	DPID string `xml:"DPID,omitempty"`
+}
+
+// ernID is a ResourceId or ReleaseId
+type ernID struct {
+	ISRC          string            `xml:"ISRC,omitempty"`
+	ICPN          string            `xml:"ICPN,omitempty"`
+	ProprietaryID *ernProprietaryID `xml:"ProprietaryId,omitempty"`
+}
+
+type ernProprietaryID struct {
+	Namespace string `xml:"Namespace,attr"`
+	Value     string `xml:",chardata"`
+}
+
+type ernTitle struct {
+	Text     string `xml:"TitleText"`
+	SubTitle string `xml:"SubTitle,omitempty"`
+}
+
+type ernDisplayArtist struct {
+	Sequence       int    `xml:"SequenceNumber,attr,omitempty"`
+	PartyReference string `xml:"ArtistPartyReference"`
+	Role           string `xml:"DisplayArtistRole"`
+}
+
+type ernFile struct {
+	URI            string      `xml:"URI,omitempty"`
+	LegacyFileName string      `xml:"FileName,omitempty"`
+	LegacyFilePath string      `xml:"FilePath,omitempty"`
+	HashSum        *ernHashSum `xml:"HashSum,omitempty"`
+}
+
+type ernHashSum struct {
+	Algorithm           string `xml:"Algorithm,omitempty"`
+	Value               string `xml:"HashSumValue,omitempty"`
+	LegacyValue         string `xml:"HashSum,omitempty"`
+	LegacyAlgorithmType string `xml:"HashSumAlgorithmType,omitempty"`
+}
+
+type ernDeliveryFile struct {
+	Type      string  `xml:"Type"`
+	CodecType string  `xml:"AudioCodecType,omitempty"`
+	File      ernFile `xml:"File"`
+}
+
+type ernTechnicalDetails struct {
+	Reference     string             `xml:"TechnicalResourceDetailsReference"`
+	DeliveryFiles []*ernDeliveryFile `xml:"DeliveryFile"`
+	File          *ernFile           `xml:"File"`
+}
+
+type ernRecordingEdition struct {
+	ID               ernID                  `xml:"ResourceId"`
+	TechnicalDetails []*ernTechnicalDetails `xml:"TechnicalDetails"`
+}
+
+type ernRecording struct {
+	Reference            string                 `xml:"ResourceReference"`
+	Type                 string                 `xml:"Type"`
+	Editions             []*ernRecordingEdition `xml:"SoundRecordingEdition"`
+	LegacyID             *ernID                 `xml:"ResourceId,omitempty"`
+	DisplayTitleText     string                 `xml:"DisplayTitleText"`
+	DisplayTitle         ernTitle               `xml:"DisplayTitle"`
+	DisplayArtistName    string                 `xml:"DisplayArtistName"`
+	DisplayArtists       []*ernDisplayArtist    `xml:"DisplayArtist"`
+	Duration             string                 `xml:"Duration"`
+	ParentalWarningType  string                 `xml:"ParentalWarningType"`
+	LegacyTechnicalFiles []*ernTechnicalDetails `xml:"TechnicalDetails"`
+}
+
+type ernImage struct {
+	Reference           string                 `xml:"ResourceReference"`
+	Type                string                 `xml:"Type"`
+	ID                  ernID                  `xml:"ResourceId"`
+	ParentalWarningType string                 `xml:"ParentalWarningType,omitempty"`
+	TechnicalDetails    []*ernTechnicalDetails `xml:"TechnicalDetails"`
+}
+
+type ernGenre struct {
+	Text string `xml:"GenreText"`
+}
+
+type ernResourceGroup struct {
+	Sequence       int                     `xml:"SequenceNumber,omitempty"`
+	Groups         []*ernResourceGroup     `xml:"ResourceGroup"`
+	Items          []*ernResourceGroupItem `xml:"ResourceGroupContentItem"`
+	LinkedResource []string                `xml:"LinkedReleaseResourceReference"`
+}
+
+type ernResourceGroupItem struct {
+	Sequence  int    `xml:"SequenceNumber,omitempty"`
+	Reference string `xml:"ReleaseResourceReference"`
+}
+
+type ernRelease struct {
+	Reference           string              `xml:"ReleaseReference"`
+	Type                string              `xml:"ReleaseType"`
+	ID                  ernID               `xml:"ReleaseId"`
+	DisplayTitleText    string              `xml:"DisplayTitleText"`
+	DisplayTitle        ernTitle            `xml:"DisplayTitle"`
+	DisplayArtistName   string              `xml:"DisplayArtistName"`
+	DisplayArtists      []*ernDisplayArtist `xml:"DisplayArtist"`
+	LabelReferences     []string            `xml:"ReleaseLabelReference"`
+	Genres              []*ernGenre         `xml:"Genre"`
+	ReleaseDate         string              `xml:"ReleaseDate,omitempty"`
+	OriginalReleaseDate string              `xml:"OriginalReleaseDate,omitempty"`
+	ParentalWarningType string              `xml:"ParentalWarningType"`
+	ResourceGroup       ernResourceGroup    `xml:"ResourceGroup"`
+}
+
+type ernDeal struct {
+	ReleaseReferences []string        `xml:"DealReleaseReference"`
+	Deals             []*ernDealTerms `xml:"Deal>DealTerms"`
+}
+
+type ernDealTerms struct {
+	Territories      []string   `xml:"TerritoryCode"`
+	ValidityPeriod   *ernPeriod `xml:"ValidityPeriod,omitempty"`
+	CommercialModels []string   `xml:"CommercialModelType"`
+	UseTypes         []string   `xml:"UseType"`
+}
+
+type ernPeriod struct {
+	StartDate string `xml:"StartDate,omitempty"`
+	EndDate   string `xml:"EndDate,omitempty"`
+}
+
+// ISO 8601 durations such as PT3M25S or PT1H2M3.5S
+var isoDurationPattern = regexp.MustCompile(`^P(?:(\d+)D)?(?:T(?:(\d+)H)?(?:(\d+)M)?(?:(\d+(?:\.\d+)?)S)?)?$`)
+
+// parseISODuration reads an ISO 8601 duration in milliseconds
+func parseISODuration(value string) (int64, error) {
+	parts := isoDurationPattern.FindStringSubmatch(value)
+	if parts == nil || value == "P" || value == "PT" {
+		return 0, fmt.Errorf("duration %q is not an ISO 8601 duration such as PT3M25S", value)
+	}
+	total := 0.0
+	for i, unit := range []float64{24 * 3600, 3600, 60, 1} {
+		if parts[i+1] != "" {
+			n, _ := strconv.ParseFloat(parts[i+1], 64)
+			total += n * unit
+		}
+	}
+	return int64(total*1000 + 0.5), nil
+}
+
+// formatISODuration writes milliseconds as an ISO 8601 duration, e.g. PT3M25S
+func formatISODuration(ms int64) string {
+	seconds := ms / 1000
+	duration := "PT"
+	if seconds >= 3600 {
+		duration += strconv.FormatInt(seconds/3600, 10) + "H"
+	}
+	if seconds >= 60 {
+		duration += strconv.FormatInt(seconds/60%60, 10) + "M"
+	}
+	duration += strconv.FormatInt(seconds%60, 10)
+	if ms%1000 != 0 {
+		duration += strings.TrimRight(fmt.Sprintf(".%03d", ms%1000), "0")
+	}
+	return duration + "S"
+}
+
+// parseERNDate reads a date of an ERN message, a date such as 2024-06-07 or a
+// date and time
+func parseERNDate(value string) (int64, error) {
+	value = strings.TrimSpace(value)
+	for _, layout := range []string{"2006-01-02", time.RFC3339, "2006-01-02T15:04:05", "2006"} {
+		if date, err := time.Parse(layout, value); err == nil {
+			return date.UnixMilli(), nil
+		}
+	}
+	return 0, fmt.Errorf("date %q is not an ISO 8601 date such as 2024-06-07", value)
+}
+
+// ReadERN reads a DDEX ERN 4.x NewReleaseMessage and its main release
+func ReadERN(r io.Reader) (*Message, error) {
+	var ern ernMessage
+	decoder := xml.NewDecoder(io.LimitReader(r, maxERNSize))
+	if err := decoder.Decode(&ern); err != nil {
+		return nil, err
+	}
+	if ern.XMLName.Local != "NewReleaseMessage" || !strings.HasPrefix(ern.XMLName.Space, ernNamespacePrefix) {
+		return nil, fmt.Errorf("%s is not an ERN 4.x NewReleaseMessage", strings.TrimSpace(ern.XMLName.Space+" "+ern.XMLName.Local))
+	}
+	if ern.Release == nil {
+		return nil, errors.New("message has no Release in its ReleaseList")
+	}
+
+	message := &Message{
+		ID:     ern.Header.MessageID,
+		Sender: ern.Header.Sender.party(),
+	}
+	if len(ern.Header.Recipients) > 0 {
+		message.Recipient = ern.Header.Recipients[0].party()
+	}
+	if createdAt, err := time.Parse(time.RFC3339, ern.Header.CreatedAt); err == nil {
+		message.CreatedAt = createdAt
+	}
+
+	parties := map[string]*ernParty{}
+	for _, party := range ern.Parties {
+		parties[party.Reference] = party
+	}
+	partyOf := func(name string, artists []*ernDisplayArtist) Party {
+		result := Party{Name: strings.TrimSpace(name)}
+		for _, artist := range artists {
+			if party, ok := parties[artist.PartyReference]; ok {
+				if result.Name == "" {
+					result.Name = party.Name.FullName
+				}
+				if party.ID != nil {
+					result.ID = party.ID.ISNI
+				}
+				break
+			}
+		}
+		return result
+	}
+
+	source := ern.Release
+	release := &Release{
+		Reference: source.Reference,
+		Type:      source.Type,
+		ICPN:      strings.TrimSpace(source.ID.ICPN),
+		Title:     firstNonEmpty(source.DisplayTitleText, source.DisplayTitle.Text),
+		Artist:    partyOf(source.DisplayArtistName, source.DisplayArtists),
+	}
+	if source.ID.ProprietaryID != nil {
+		release.ProprietaryID = source.ID.ProprietaryID.Value
+	}
+	if release.Title == "" {
+		return nil, errors.New("release has no DisplayTitleText")
+	}
+	for _, reference := range source.LabelReferences {
+		if party, ok := parties[reference]; ok {
+			release.Label = Party{Name: party.Name.FullName}
+			if party.ID != nil {
+				release.Label.ID = party.ID.DPID
+			}
+			break
+		}
+	}
+	if len(source.Genres) > 0 {
+		release.Genre = strings.TrimSpace(source.Genres[0].Text)
+	}
+
+	// The street date of the deals stands in for a missing release date
+	dates := []string{source.OriginalReleaseDate, source.ReleaseDate}
+	for _, deal := range ern.Deals {
+		for _, terms := range deal.Deals {
+			if terms.ValidityPeriod != nil {
+				dates = append(dates, terms.ValidityPeriod.StartDate)
+			}
+		}
+	}
+	for _, date := range dates {
+		if ms, err := parseERNDate(date); err == nil {
+			release.ReleaseDate = ms
+			break
+		}
+	}
+
+	recordings := map[string]*ernRecording{}
+	for _, recording := range ern.SoundRecordings {
+		recordings[recording.Reference] = recording
+	}
+	references := source.ResourceGroup.references()
+	if len(references) == 0 {
+		for _, recording := range ern.SoundRecordings {
+			references = append(references, recording.Reference)
+		}
+	}
+	for _, reference := range references {
+		if recording, ok := recordings[reference]; ok {
+			release.Recordings = append(release.Recordings, newRecording(recording, release, partyOf))
+		}
+	}
+	if len(release.Recordings) == 0 {
+		return nil, errors.New("release has no SoundRecording")
+	}
+
+	// The front cover is the image linked to the release, or else any
+	for _, image := range ern.Images {
+		if image.Type != "FrontCoverImage" {
+			continue
+		}
+		file := technicalFile(image.TechnicalDetails, "")
+		if file == nil {
+			continue
+		}
+		if release.Cover == nil || contains(source.ResourceGroup.LinkedResource, image.Reference) {
+			release.Cover = file
+		}
+	}
+
+	message.Release = release
+	return message, nil
+}
+
+func newRecording(source *ernRecording, release *Release, partyOf func(string, []*ernDisplayArtist) Party) *Recording {
+	recording := &Recording{
+		Reference: source.Reference,
+		Title:     firstNonEmpty(source.DisplayTitleText, source.DisplayTitle.Text),
+		Artist:    partyOf(source.DisplayArtistName, source.DisplayArtists),
+	}
+	if recording.Artist.Name == "" {
+		recording.Artist = release.Artist
+	}
+	if recording.Title == "" {
+		recording.Errors = append(recording.Errors, "sound recording has no DisplayTitleText")
+	}
+
+	ids := []ernID{}
+	if source.LegacyID != nil {
+		ids = append(ids, *source.LegacyID)
+	}
+	details := source.LegacyTechnicalFiles
+	for _, edition := range source.Editions {
+		ids = append(ids, edition.ID)
+		details = append(details, edition.TechnicalDetails...)
+	}
+	for _, id := range ids {
+		if recording.ISRC == "" {
+			recording.ISRC = id.ISRC
+		}
+		if recording.ProprietaryID == "" && id.ProprietaryID != nil {
+			recording.ProprietaryID = id.ProprietaryID.Value
+		}
+	}
+	recording.ISRC = strings.TrimSpace(recording.ISRC)
+	recording.File = technicalFile(details, "AudioFile")
+
+	if source.Duration != "" {
+		duration, err := parseISODuration(strings.TrimSpace(source.Duration))
+		if err != nil {
+			recording.Errors = append(recording.Errors, err.Error())
+		}
+		recording.Duration = duration
+	}
+	return recording
+}
+
+// references returns the resources of group and its nested groups in order
+func (group *ernResourceGroup) references() []string {
+	sort.SliceStable(group.Groups, func(i, j int) bool { return group.Groups[i].Sequence < group.Groups[j].Sequence })
+	sort.SliceStable(group.Items, func(i, j int) bool { return group.Items[i].Sequence < group.Items[j].Sequence })
+
+	references := []string{}
+	for _, item := range group.Items {
+		references = append(references, item.Reference)
+	}
+	for _, nested := range group.Groups {
+		references = append(references, nested.references()...)
+	}
+	return references
+}
+
+// technicalFile returns the first file of details, preferring delivery files
+// of fileType
+func technicalFile(details []*ernTechnicalDetails, fileType string) *File {
+	var found *ernFile
+	for _, detail := range details {
+		for _, delivery := range detail.DeliveryFiles {
+			if fileType == "" || delivery.Type == fileType {
+				return delivery.File.file()
+			}
+			if found == nil {
+				found = &delivery.File
+			}
+		}
+		if found == nil && detail.File != nil {
+			found = detail.File
+		}
+	}
+	if found == nil {
+		return nil
+	}
+	return found.file()
+}
+
+func (f *ernFile) file() *File {
+	uri := f.URI
+	if uri == "" && f.LegacyFileName != "" {
+		uri = strings.TrimSuffix(f.LegacyFilePath, "/")
+		if uri != "" {
+			uri += "/"
+		}
+		uri += f.LegacyFileName
+	}
+	if uri == "" {
+		return nil
+	}
+	file := &File{URI: strings.TrimSpace(uri)}
+	if f.HashSum != nil {
+		file.HashAlgorithm = firstNonEmpty(f.HashSum.Algorithm, f.HashSum.LegacyAlgorithmType)
+		file.Hash = firstNonEmpty(f.HashSum.Value, f.HashSum.LegacyValue)
+	}
+	return file
+}
+
+// newERNFile returns the element of file
+func newERNFile(file *File) *ernFile {
+	f := &ernFile{URI: file.URI}
+	if file.Hash != "" {
+		f.HashSum = &ernHashSum{Algorithm: file.HashAlgorithm, Value: file.Hash}
+	}
+	return f
+}
+
+func (p *ernMessageParty) party() Party {
+	party := Party{ID: strings.TrimSpace(p.PartyID)}
+	if p.Name != nil {
+		party.Name = strings.TrimSpace(p.Name.FullName)
+	}
+	return party
+}
+
+func newERNMessageParty(party Party) *ernMessageParty {
+	p := &ernMessageParty{PartyID: party.ID}
+	if party.Name != "" {
+		p.Name = &ernPartyName{FullName: party.Name}
+	}
+	return p
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value = strings.TrimSpace(value); value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// DeliveryPath resolves path, relative to the delivery folder, to a file in it
+func DeliveryPath(path string) string {
+	return filepath.Join(deliveryDir, filepath.Clean("/"+path))
+}
+
+// ResourcePath returns the local file of a resource of the message at
+// messagePath after checking its hash sum. Resources are looked up in the
+// folder of the message and cannot be URLs.
+func ResourcePath(messagePath string, file *File) (string, error) {
+	uri, err := url.Parse(file.URI)
+	if err != nil {
+		return "", fmt.Errorf("resource %q: %w", file.URI, err)
+	}
+	name := file.URI
+	if uri.Scheme == "file" {
+		name = uri.Path
+	} else if uri.Scheme != "" {
+		return "", fmt.Errorf("resource %q is not a file of the delivery", file.URI)
+	}
+	path := filepath.Join(filepath.Dir(messagePath), filepath.Clean("/"+filepath.FromSlash(name)))
+
+	f, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("resource %q: %w", file.URI, err)
+	}
+	defer f.Close()
+	if file.Hash == "" {
+		return path, nil
+	}
+
+	var h hash.Hash
+	switch strings.ToUpper(strings.ReplaceAll(file.HashAlgorithm, "-", "")) {
+	case "MD5":
+		h = md5.New()
+	case "SHA1":
+		h = sha1.New()
+	case "SHA256", "":
+		h = sha256.New()
+	default:
+		return "", fmt.Errorf("resource %q: unsupported hash algorithm %s", file.URI, file.HashAlgorithm)
+	}
+	if _, err := io.Copy(h, f); err != nil {
+		return "", fmt.Errorf("resource %q: %w", file.URI, err)
+	}
+	if sum := hex.EncodeToString(h.Sum(nil)); !strings.EqualFold(sum, file.Hash) {
+		return "", fmt.Errorf("resource %q: hash sum %s does not match %s of the message", file.URI, sum, file.Hash)
+	}
+	return path, nil
+}
+
+// Sender returns the configured sender of exported messages
+func Sender() Party {
+	return sender
+}
+
+// WriteERN writes message as an ERN 4.3 NewReleaseMessage. The release is
+// offered for streaming worldwide from its release date.
+func WriteERN(w io.Writer, message *Message) error {
+	release := message.Release
+	ern := &ernMessage{
+		XMLName:               xml.Name{Local: "ern:NewReleaseMessage"},
+		Namespace:             ernNamespace,
+		AvsVersionID:          "4",
+		LanguageAndScriptCode: "en",
+		Header: ernMessageHeader{
+			MessageID:  message.ID,
+			Sender:     *newERNMessageParty(message.Sender),
+			Recipients: []*ernMessageParty{newERNMessageParty(message.Recipient)},
+			CreatedAt:  message.CreatedAt.UTC().Format(time.RFC3339),
+			Control:    "LiveMessage",
+		},
+	}
+	proprietaryID := func(value string) *ernProprietaryID {
+		return &ernProprietaryID{Namespace: "DPID:" + message.Sender.ID, Value: value}
+	}
+
+	// Parties are numbered in order of appearance, one per artist or label
+	partyRefs := map[Party]string{}
+	partyRef := func(party Party, isni bool) string {
+		if ref, ok := partyRefs[party]; ok {
+			return ref
+		}
+		ref := "P" + strconv.Itoa(len(partyRefs)+1)
+		partyRefs[party] = ref
+		out := &ernParty{Reference: ref, Name: ernPartyName{FullName: party.Name}}
+		if party.ID != "" && isni {
+			out.ID = &ernPartyID{ISNI: party.ID}
+		} else if party.ID != "" {
+			out.ID = &ernPartyID{DPID: party.ID}
+		}
+		ern.Parties = append(ern.Parties, out)
+		return ref
+	}
+	displayArtists := func(party Party) []*ernDisplayArtist {
+		return []*ernDisplayArtist{{Sequence: 1, PartyReference: partyRef(party, true), Role: "MainArtist"}}
+	}
+
+	out := &ernRelease{
+		Reference:           "R0",
+		Type:                release.Type,
+		DisplayTitleText:    release.Title,
+		DisplayTitle:        ernTitle{Text: release.Title},
+		DisplayArtistName:   release.Artist.Name,
+		DisplayArtists:      displayArtists(release.Artist),
+		OriginalReleaseDate: FormatReleaseDate(release.ReleaseDate),
+		ParentalWarningType: "Unknown",
+		ResourceGroup:       ernResourceGroup{},
+	}
+	out.ID.ICPN = release.ICPN
+	if release.ICPN == "" {
+		out.ID.ProprietaryID = proprietaryID(release.ProprietaryID)
+	}
+	label := release.Label
+	if label.Name == "" {
+		label = message.Sender
+	}
+	out.LabelReferences = []string{partyRef(label, false)}
+	if release.Genre != "" {
+		out.Genres = []*ernGenre{{Text: release.Genre}}
+	}
+
+	group := &ernResourceGroup{Sequence: 1}
+	for i, recording := range release.Recordings {
+		ref := "A" + strconv.Itoa(i+1)
+		edition := &ernRecordingEdition{ID: ernID{ISRC: recording.ISRC}}
+		if recording.ISRC == "" {
+			edition.ID.ProprietaryID = proprietaryID(recording.ProprietaryID)
+		}
+		if recording.File != nil {
+			edition.TechnicalDetails = []*ernTechnicalDetails{{
+				Reference: "T" + strconv.Itoa(i+1),
+				DeliveryFiles: []*ernDeliveryFile{{
+					Type: "AudioFile",
+					File: *newERNFile(recording.File),
+				}},
+			}}
+		}
+		artist := recording.Artist
+		if artist.Name == "" {
+			artist = release.Artist
+		}
+		ern.SoundRecordings = append(ern.SoundRecordings, &ernRecording{
+			Reference:           ref,
+			Type:                "MusicalWorkSoundRecording",
+			Editions:            []*ernRecordingEdition{edition},
+			DisplayTitleText:    recording.Title,
+			DisplayTitle:        ernTitle{Text: recording.Title},
+			DisplayArtistName:   artist.Name,
+			DisplayArtists:      displayArtists(artist),
+			Duration:            formatISODuration(recording.Duration),
+			ParentalWarningType: "Unknown",
+		})
+		group.Items = append(group.Items, &ernResourceGroupItem{Sequence: i + 1, Reference: ref})
+	}
+
+	if release.Cover != nil {
+		ref := "A" + strconv.Itoa(len(release.Recordings)+1)
+		ern.Images = []*ernImage{{
+			Reference:           ref,
+			Type:                "FrontCoverImage",
+			ID:                  ernID{ProprietaryID: proprietaryID(firstNonEmpty(release.ICPN, release.ProprietaryID) + "-cover")},
+			ParentalWarningType: "Unknown",
+			TechnicalDetails: []*ernTechnicalDetails{{
+				Reference: "T" + strconv.Itoa(len(release.Recordings)+1),
+				File:      newERNFile(release.Cover),
+			}},
+		}}
+		group.LinkedResource = []string{ref}
+	}
+	out.ResourceGroup.Groups = []*ernResourceGroup{group}
+	ern.Release = out
+
+	startDate := FormatReleaseDate(release.ReleaseDate)
+	if startDate == "" {
+		startDate = message.CreatedAt.UTC().Format("2006-01-02")
+	}
+	ern.Deals = []*ernDeal{{
+		ReleaseReferences: []string{out.Reference},
+		Deals: []*ernDealTerms{{
+			Territories:      []string{"Worldwide"},
+			ValidityPeriod:   &ernPeriod{StartDate: startDate},
+			CommercialModels: []string{"SubscriptionModel", "AdvertisementSupportedModel"},
+			UseTypes:         []string{"OnDemandStream"},
+		}},
+	}}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(ern); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
@@ -0,0 +1,210 @@
+package catalog
+
+import (
+	"archive/zip"
+	"bufio"
+	"encoding/csv"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Export formats
+const (
+	FormatCSV   = "csv"
+	FormatJSONL = "jsonl"
+	FormatXLSX  = "xlsx"
+)
+
+// ContentTypes of the export formats
+var ContentTypes = map[string]string{
+	FormatCSV:   "text/csv; charset=utf-8",
+	FormatJSONL: "application/x-ndjson",
+	FormatXLSX:  "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+}
+
+// Table writes rows of cells, strings or numbers, to a spreadsheet file as
+// they come. Close must be called to complete the file.
+type Table interface {
+	WriteRow(cells []interface{}) error
+	Flush() error
+	Close() error
+}
+
+// NewTable returns a table writing the CSV or XLSX format to w, starting with
+// the header row
+func NewTable(format string, w io.Writer, header []string) (Table, error) {
+	var table Table
+	switch format {
+	case FormatCSV:
+		table = &csvTable{writer: csv.NewWriter(w)}
+	case FormatXLSX:
+		xlsx, err := newXLSXTable(w)
+		if err != nil {
+			return nil, err
+		}
+		table = xlsx
+	default:
+		return nil, fmt.Errorf("unsupported table format %q", format)
+	}
+
+	cells := make([]interface{}, len(header))
+	for i, name := range header {
+		cells[i] = name
+	}
+	if err := table.WriteRow(cells); err != nil {
+		return nil, err
+	}
+	return table, nil
+}
+
+// Characters starting a formula in Excel and Google Sheets
+const formulaPrefixes = "=+-@\t\r"
+
+// escapeFormula prefixes text a spreadsheet would run as a formula with a
+// quote, so user text cannot inject one
+func escapeFormula(text string) string {
+	if text != "" && strings.IndexByte(formulaPrefixes, text[0]) >= 0 {
+		return "'" + text
+	}
+	return text
+}
+
+// UnescapeFormula removes the quote escapeFormula put in front of text
+func UnescapeFormula(text string) string {
+	if len(text) > 1 && text[0] == '\'' && strings.IndexByte(formulaPrefixes, text[1]) >= 0 {
+		return text[1:]
+	}
+	return text
+}
+
+type csvTable struct {
+	writer *csv.Writer
+}
+
+func (t *csvTable) WriteRow(cells []interface{}) error {
+	record := make([]string, len(cells))
+	for i, cell := range cells {
+		switch v := cell.(type) {
+		case int, int64, float64:
+			record[i] = fmt.Sprint(v)
+		default:
+			record[i] = escapeFormula(fmt.Sprint(v))
+		}
+	}
+	return t.writer.Write(record)
+}
+
+func (t *csvTable) Flush() error {
+	t.writer.Flush()
+	return t.writer.Error()
+}
+
+func (t *csvTable) Close() error {
+	return t.Flush()
+}
+
+// Parts of a workbook holding a single sheet, written before the sheet.
+// Cells hold inline strings so no shared string table is needed.
+var xlsxParts = []struct{ name, content string }{
+	{"[Content_Types].xml", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+		`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+		`<Default Extension="xml" ContentType="application/xml"/>` +
+		`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` +
+		`<Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>` +
+		`</Types>`},
+	{"_rels/.rels", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+		`</Relationships>`},
+	{"xl/workbook.xml", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+		`<sheets><sheet name="Sheet1" sheetId="1" r:id="rId1"/></sheets>` +
+		`</workbook>`},
+	{"xl/_rels/workbook.xml.rels", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>` +
+		`</Relationships>`},
+}
+
+type xlsxTable struct {
+	zip   *zip.Writer
+	sheet *bufio.Writer
+	rows  int
+}
+
+func newXLSXTable(w io.Writer) (*xlsxTable, error) {
+	archive := zip.NewWriter(w)
+	for _, part := range xlsxParts {
+		entry, err := archive.Create(part.name)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := io.WriteString(entry, part.content); err != nil {
+			return nil, err
+		}
+	}
+
+	entry, err := archive.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return nil, err
+	}
+	sheet := bufio.NewWriter(entry)
+	sheet.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` + "\n" +
+		`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`)
+	return &xlsxTable{zip: archive, sheet: sheet}, nil
+}
+
+func (t *xlsxTable) WriteRow(cells []interface{}) error {
+	t.rows++
+	row := strconv.Itoa(t.rows)
+	t.sheet.WriteString(`<row r="` + row + `">`)
+	for i, cell := range cells {
+		ref := columnName(i) + row
+		switch v := cell.(type) {
+		case int:
+			t.sheet.WriteString(`<c r="` + ref + `"><v>` + strconv.Itoa(v) + `</v></c>`)
+		case int64:
+			t.sheet.WriteString(`<c r="` + ref + `"><v>` + strconv.FormatInt(v, 10) + `</v></c>`)
+		case float64:
+			t.sheet.WriteString(`<c r="` + ref + `"><v>` + strconv.FormatFloat(v, 'g', -1, 64) + `</v></c>`)
+		default:
+			text := escapeFormula(fmt.Sprint(v))
+			if text == "" {
+				continue
+			}
+			var escaped strings.Builder
+			xml.EscapeText(&escaped, []byte(text))
+			t.sheet.WriteString(`<c r="` + ref + `" t="inlineStr"><is><t xml:space="preserve">` + escaped.String() + `</t></is></c>`)
+		}
+	}
+	_, err := t.sheet.WriteString(`</row>`)
+	return err
+}
+
+func (t *xlsxTable) Flush() error {
+	if err := t.sheet.Flush(); err != nil {
+		return err
+	}
+	return t.zip.Flush()
+}
+
+func (t *xlsxTable) Close() error {
+	t.sheet.WriteString(`</sheetData></worksheet>`)
+	if err := t.sheet.Flush(); err != nil {
+		return err
+	}
+	return t.zip.Close()
+}
+
+// columnName returns the letters of the zero based column i, e.g. A, Z, AA
+func columnName(i int) string {
+	name := ""
+	for i++; i > 0; i = (i - 1) / 26 {
+		name = string(rune('A'+(i-1)%26)) + name
+	}
+	return name
+}
@@ -0,0 +1,131 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rolexkdev/emvn-music-library-server/common/app"
+	"github.com/rolexkdev/emvn-music-library-server/common/e"
+	"github.com/rolexkdev/emvn-music-library-server/internal/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// GetAlbums godoc
+//
+//	@Summary		Get list albums
+//	@Description	Get list albums, the latest release first
+//	@Tags			album
+//	@Accept			json
+//	@Produce		json
+//
+//	@Success		200				{object}	app.Response
+//	@Failure		500				{object}	app.Response
+//	@Router			/albums [get]
+func GetAlbums(c *gin.Context) {
+	appG := app.Gin{C: c}
+
+	albums, err := models.Repository.Album.FindMany(context.Background())
+	if err != nil {
+		appG.Response500(e.ERROR, "Get albums failed with err: "+err.Error())
+		return
+	}
+
+	appG.Response200(albums)
+}
+
+// GetAlbum godoc
+//
+//	@Summary		Get album by id
+//	@Description	Get album by id, track_ids lists its tracks in order
+//	@Tags			album
+//	@Accept			json
+//	@Produce		json
+//
+//	@Param			id		path		string	true	"Album ID"
+//
+//	@Success		200				{object}	app.Response
+//	@Failure		404				{object}	app.Response
+//	@Failure		500				{object}	app.Response
+//	@Router			/albums/{id} [get]
+func GetAlbum(c *gin.Context) {
+	appG := app.Gin{C: c}
+
+	objID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		appG.Response500(e.ERROR, "convert id string to objectID failed"+err.Error())
+		return
+	}
+
+	album, err := models.Repository.Album.FindByID(context.Background(), objID)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			appG.Response404(e.NOTFOUND, "Album not exist")
+			return
+		}
+		appG.Response500(e.ERROR, "Get album by id failed with err: "+err.Error())
+		return
+	}
+
+	appG.Response200(album)
+}
+
+// GetArtists godoc
+//
+//	@Summary		Get list artists
+//	@Description	Get list artists by name
+//	@Tags			artist
+//	@Accept			json
+//	@Produce		json
+//
+//	@Success		200				{object}	app.Response
+//	@Failure		500				{object}	app.Response
+//	@Router			/artists [get]
+func GetArtists(c *gin.Context) {
+	appG := app.Gin{C: c}
+
+	artists, err := models.Repository.Artist.FindMany(context.Background())
+	if err != nil {
+		appG.Response500(e.ERROR, "Get artists failed with err: "+err.Error())
+		return
+	}
+
+	appG.Response200(artists)
+}
+
+// GetArtist godoc
+//
+//	@Summary		Get artist by id
+//	@Description	Get artist by id, its id is the artist_id of its tracks and albums
+//	@Tags			artist
+//	@Accept			json
+//	@Produce		json
+//
+//	@Param			id		path		string	true	"Artist ID"
+//
+//	@Success		200				{object}	app.Response
+//	@Failure		404				{object}	app.Response
+//	@Failure		500				{object}	app.Response
+//	@Router			/artists/{id} [get]
+func GetArtist(c *gin.Context) {
+	appG := app.Gin{C: c}
+
+	objID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		appG.Response500(e.ERROR, "convert id string to objectID failed"+err.Error())
+		return
+	}
+
+	artist, err := models.Repository.Artist.FindByID(context.Background(), objID)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			appG.Response404(e.NOTFOUND, "Artist not exist")
+			return
+		}
+		appG.Response500(e.ERROR, "Get artist by id failed with err: "+err.Error())
+		return
+	}
+
+	appG.Response200(artist)
+}
@@ -0,0 +1,111 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rolexkdev/emvn-music-library-server/common/app"
+	"github.com/rolexkdev/emvn-music-library-server/common/e"
+	"github.com/rolexkdev/emvn-music-library-server/common/utils"
+	"github.com/rolexkdev/emvn-music-library-server/dto"
+	"github.com/rolexkdev/emvn-music-library-server/internal/jobs"
+	"github.com/rolexkdev/emvn-music-library-server/internal/media"
+	"github.com/rolexkdev/emvn-music-library-server/internal/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// AnalyzeTrack godoc
+//
+//	@Summary		Analyze a track
+//	@Description	Queue a job to (re)compute loudness, true peak, BPM and key of the track audio
+//	@Tags			track
+//	@Produce		json
+//
+//	@Param			id		    path		string	true	"track id"
+//
+//	@Success		202				{object}	app.Response
+//	@Failure		404				{object}	app.Response
+//	@Failure		500				{object}	app.Response
+//	@Router			/tracks/{id}/analysis [post]
+func AnalyzeTrack(c *gin.Context) {
+	appG := app.Gin{C: c}
+	trackID := c.Param("id")
+
+	// convert track_id string to objectID
+	objID, err := primitive.ObjectIDFromHex(trackID)
+	if err != nil {
+		appG.Response500(e.ERROR, "convert id string to objectID failed"+err.Error())
+		return
+	}
+
+	track, err := models.Repository.Track.FindByID(context.Background(), objID)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			appG.Response404(e.NOTFOUND, "Track not exist")
+			return
+		}
+		appG.Response500(e.ERROR, "Get track by id failed with err: "+err.Error())
+		return
+	}
+
+	job, err := jobs.EnqueueFrom(context.Background(), jobs.TypeTrackAnalysis, track.ID.Hex(), track.FileURL)
+	if err != nil {
+		appG.Response500(e.ERROR, "Queue track analysis failed with err: "+err.Error())
+		return
+	}
+
+	appG.Response202(job)
+}
+
+// trackFilterFromRequest validates the analysis filters of a list or search request
+func trackFilterFromRequest(req dto.TrackFilterRequest) (*models.TrackFilter, error) {
+	filter := &models.TrackFilter{
+		MinBPM:          req.MinBPM,
+		MaxBPM:          req.MaxBPM,
+		MinLoudness:     req.MinLoudness,
+		MaxLoudness:     req.MaxLoudness,
+		Genres:          req.Genres,
+		Years:           req.Years,
+		DurationBuckets: req.Durations,
+		ArtistIDs:       req.ArtistIDs,
+		Labels:          req.Labels,
+		MasterOwners:    req.MasterOwners,
+		Writers:         req.Writers,
+		Publishers:      req.Publishers,
+	}
+
+	if req.Key != "" {
+		key, ok := media.ParseKey(req.Key)
+		if !ok {
+			return nil, fmt.Errorf("invalid key %q, expected e.g. \"A minor\", \"Am\" or \"Bb\"", req.Key)
+		}
+		filter.Key = key
+	}
+	if req.ISRC != "" {
+		if !utils.IsISRC(req.ISRC) {
+			return nil, fmt.Errorf("invalid isrc %q", req.ISRC)
+		}
+		filter.ISRC = utils.NormalizeISRC(req.ISRC)
+	}
+	if req.ISWC != "" {
+		if !utils.IsISWC(req.ISWC) {
+			return nil, fmt.Errorf("invalid iswc %q", req.ISWC)
+		}
+		filter.ISWC = utils.NormalizeISWC(req.ISWC)
+	}
+	if req.MinBPM != nil && req.MaxBPM != nil && *req.MinBPM > *req.MaxBPM {
+		return nil, errors.New("min_bpm must not be greater than max_bpm")
+	}
+	if req.MinLoudness != nil && req.MaxLoudness != nil && *req.MinLoudness > *req.MaxLoudness {
+		return nil, errors.New("min_loudness must not be greater than max_loudness")
+	}
+	for _, name := range req.Durations {
+		if _, ok := models.FindDurationBucket(name); !ok {
+			return nil, fmt.Errorf("invalid duration %q, expected under_2m, 2m_4m, 4m_6m or over_6m", name)
+		}
+	}
+	return filter, nil
+}
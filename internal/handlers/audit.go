@@ -0,0 +1,141 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rolexkdev/emvn-music-library-server/common/app"
+	"github.com/rolexkdev/emvn-music-library-server/common/e"
+	"github.com/rolexkdev/emvn-music-library-server/common/utils"
+	"github.com/rolexkdev/emvn-music-library-server/dto"
+	"github.com/rolexkdev/emvn-music-library-server/internal/models"
+)
+
+const (
+	defaultAuditLimit = 100
+	// Entries written between two flushes of an export
+	auditFlushEvery = 500
+)
+
+// auditFilter binds and validates the audit filter of the query. On failure
+// the response is written and false returned.
+func auditFilter(appG app.Gin) (*models.AuditFilter, int, bool) {
+	var request dto.AuditFilterRequest
+	if err := appG.C.BindQuery(&request); err != nil {
+		appG.Response400(e.INVALID_PARAMS, "Bind query failed: "+err.Error())
+		return nil, 0, false
+	}
+
+	// Validate request input
+	if err := utils.Validator.Struct(request); err != nil {
+		appG.Response400(e.INVALID_PARAMS, "Validate query failed: "+err.Error())
+		return nil, 0, false
+	}
+
+	limit := request.Limit
+	if limit == 0 {
+		limit = defaultAuditLimit
+	}
+	return &models.AuditFilter{
+		Actor:    request.Actor,
+		Method:   request.Method,
+		Route:    request.Route,
+		TargetID: request.TargetID,
+		Status:   request.Status,
+		From:     request.From,
+		To:       request.To,
+	}, limit, true
+}
+
+// GetAuditLog godoc
+//
+//	@Summary		Get the audit log
+//	@Description	List the latest mutating requests, newest first. Requires an admin API key.
+//	@Tags			admin
+//	@Accept			json
+//	@Produce		json
+//
+//	@Param			actor		query		string	false	"name of the API key, anonymous without one"
+//	@Param			method		query		string	false	"POST, PUT, PATCH or DELETE"
+//	@Param			route		query		string	false	"route pattern, e.g. /api/v1/tracks/:id"
+//	@Param			target_id	query		string	false	"id of the record changed"
+//	@Param			status		query		int		false	"response status"
+//	@Param			from		query		string	false	"RFC 3339 time of the oldest entry"
+//	@Param			to			query		string	false	"RFC 3339 time the entries are before"
+//	@Param			limit		query		int		false	"entries returned, 100 by default and 1000 at most"
+//
+//	@Success		200				{object}	app.Response
+//	@Failure		400				{object}	app.Response
+//	@Failure		403				{object}	app.Response
+//	@Failure		500				{object}	app.Response
+//	@Router			/admin/audit [get]
+func GetAuditLog(c *gin.Context) {
+	appG := app.Gin{C: c}
+
+	filter, limit, ok := auditFilter(appG)
+	if !ok {
+		return
+	}
+
+	entries, err := models.Repository.Audit.FindMany(context.Background(), filter, limit)
+	if err != nil {
+		appG.Response500(e.ERROR, "Get audit log failed with err: "+err.Error())
+		return
+	}
+
+	appG.Response200(entries)
+}
+
+// ExportAuditLog godoc
+//
+//	@Summary		Export the audit log
+//	@Description	Download every entry matching the filters as JSON Lines, oldest first. Requires an admin API key.
+//	@Tags			admin
+//	@Accept			json
+//	@Produce		application/x-ndjson
+//
+//	@Param			actor		query		string	false	"name of the API key, anonymous without one"
+//	@Param			method		query		string	false	"POST, PUT, PATCH or DELETE"
+//	@Param			route		query		string	false	"route pattern, e.g. /api/v1/tracks/:id"
+//	@Param			target_id	query		string	false	"id of the record changed"
+//	@Param			status		query		int		false	"response status"
+//	@Param			from		query		string	false	"RFC 3339 time of the oldest entry"
+//	@Param			to			query		string	false	"RFC 3339 time the entries are before"
+//
+//	@Success		200				{file}		file
+//	@Failure		400				{object}	app.Response
+//	@Failure		403				{object}	app.Response
+//	@Router			/admin/audit/export [get]
+func ExportAuditLog(c *gin.Context) {
+	appG := app.Gin{C: c}
+
+	filter, _, ok := auditFilter(appG)
+	if !ok {
+		return
+	}
+
+	c.Writer.Header().Set("Content-Type", "application/x-ndjson")
+	c.Writer.Header().Set("Content-Disposition", "attachment; filename=\"audit.jsonl\"")
+	c.Status(http.StatusOK)
+
+	// Entries are streamed, an error once the first is written can only end
+	// the download early
+	encoder := json.NewEncoder(c.Writer)
+	written := 0
+	err := models.Repository.Audit.Export(context.Background(), filter, func(entry *models.AuditEntry) error {
+		if err := encoder.Encode(entry); err != nil {
+			return err
+		}
+		written++
+		if written%auditFlushEvery == 0 {
+			c.Writer.Flush()
+		}
+		return nil
+	})
+	if err != nil {
+		log.Printf("Export audit log failed after %d entries with error: %v", written, err)
+	}
+}
@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rolexkdev/emvn-music-library-server/common/utils"
+	"github.com/rolexkdev/emvn-music-library-server/dto"
+	"github.com/rolexkdev/emvn-music-library-server/internal/models"
+	"github.com/rolexkdev/emvn-music-library-server/middleware"
+)
+
+// callerAvailability returns the territory and time the caller gets tracks
+// in. Admins see every track unless they send a territory.
+func callerAvailability(c *gin.Context) *models.Availability {
+	territory := middleware.GetTerritory(c)
+	if territory == "" && middleware.IsAdmin(c) {
+		return nil
+	}
+	return &models.Availability{Territory: territory, At: time.Now()}
+}
+
+// anyAvailable reports whether availability allows one of tracks. A file of
+// no track is not restricted.
+func anyAvailable(availability *models.Availability, tracks []*models.Track) bool {
+	if len(tracks) == 0 {
+		return true
+	}
+	for _, track := range tracks {
+		if availability.Allows(track) {
+			return true
+		}
+	}
+	return false
+}
+
+// availabilityRequest returns the availability rules of a track as
+// requested, nil when it has none
+func availabilityRequest(availability *models.TrackAvailability) *dto.TrackAvailability {
+	if availability == nil {
+		return nil
+	}
+	return &dto.TrackAvailability{
+		Territories:        availability.Territories,
+		BlockedTerritories: availability.BlockedTerritories,
+		StartAt:            availability.StartAt,
+		EndAt:              availability.EndAt,
+	}
+}
+
+// trackAvailability returns the requested availability rules of a track with
+// its territories normalised, nil when they restrict nothing
+func trackAvailability(request *dto.TrackAvailability) *models.TrackAvailability {
+	if request == nil {
+		return nil
+	}
+	availability := &models.TrackAvailability{
+		Territories:        normalizeTerritories(request.Territories),
+		BlockedTerritories: normalizeTerritories(request.BlockedTerritories),
+		StartAt:            request.StartAt,
+		EndAt:              request.EndAt,
+	}
+	if availability.Territories == nil && availability.BlockedTerritories == nil && availability.StartAt == 0 && availability.EndAt == 0 {
+		return nil
+	}
+	return availability
+}
+
+// normalizeTerritories returns territories uppercase without duplicates, nil
+// when there are none
+func normalizeTerritories(territories []string) []string {
+	var normalized []string
+	seen := map[string]bool{}
+	for _, territory := range territories {
+		territory = utils.NormalizeTerritory(territory)
+		if !seen[territory] {
+			seen[territory] = true
+			normalized = append(normalized, territory)
+		}
+	}
+	return normalized
+}
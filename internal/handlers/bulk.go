@@ -0,0 +1,266 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rolexkdev/emvn-music-library-server/common/app"
+	"github.com/rolexkdev/emvn-music-library-server/common/e"
+	"github.com/rolexkdev/emvn-music-library-server/common/utils"
+	"github.com/rolexkdev/emvn-music-library-server/dto"
+	"github.com/rolexkdev/emvn-music-library-server/internal/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// bulkTrackWrite validates a bulk operation and returns its write
+func bulkTrackWrite(operation *dto.BulkTrackOperation) (*models.TrackWrite, error) {
+	write := &models.TrackWrite{Op: operation.Op, Version: operation.Version}
+	if operation.Op != models.BulkCreate {
+		id, err := primitive.ObjectIDFromHex(operation.ID)
+		if err != nil {
+			return nil, errors.New("id must be a track id")
+		}
+		if operation.Version < 1 {
+			return nil, errors.New("version of the track as last read is required")
+		}
+		write.ID = id
+	}
+
+	switch operation.Op {
+	case models.BulkCreate:
+		var request dto.CreateTrackRequest
+		if err := decodeBulkTrack(operation.Track, &request); err != nil {
+			return nil, err
+		}
+		write.Track = &models.Track{}
+		applyTrackRequest(write.Track, dto.UpdateTrackRequest(request))
+	case models.BulkUpdate:
+		var request dto.UpdateTrackRequest
+		if err := decodeBulkTrack(operation.Track, &request); err != nil {
+			return nil, err
+		}
+		write.Track = &models.Track{ID: write.ID}
+		applyTrackRequest(write.Track, request)
+	}
+	return write, nil
+}
+
+// decodeBulkTrack parses and validates the track of a bulk operation
+func decodeBulkTrack(data json.RawMessage, request interface{}) error {
+	if len(data) == 0 || string(data) == "null" {
+		return errors.New("track is required")
+	}
+	if err := json.Unmarshal(data, request); err != nil {
+		return errors.New("Parse track failed: " + err.Error())
+	}
+	if err := utils.Validator.Struct(request); err != nil {
+		return errors.New("Validate track failed: " + err.Error())
+	}
+	return nil
+}
+
+// bulkFailure returns the status of an operation that failed with err
+func bulkFailure(err error) string {
+	switch {
+	case errors.Is(err, models.ErrNotFound):
+		return dto.BulkNotFound
+	case errors.Is(err, models.ErrVersionConflict):
+		return dto.BulkConflict
+	case errors.Is(err, models.ErrNotWritten):
+		return dto.BulkSkipped
+	default:
+		return dto.BulkFailed
+	}
+}
+
+// trackWritten records the revision of a track written by a bulk write and
+// queues the processing of its audio. before is the track an update or delete
+// was based on. It returns the status and version of the track.
+func trackWritten(c *gin.Context, write *models.TrackWrite, before *models.Track) (string, int64) {
+	revision := &models.Revision{Kind: models.RevisionTrack, TargetID: write.ID}
+	switch write.Op {
+	case models.BulkCreate:
+		revision.Version = write.Track.Version
+		revision.Action = models.RevisionCreate
+		recordRevision(c, revision, nil, trackRequest(write.Track))
+		enqueueTrackProcessing(write.Track)
+		return dto.BulkCreated, revision.Version
+	case models.BulkUpdate:
+		revision.Version = write.Track.Version
+		revision.Action = models.RevisionUpdate
+		recordRevision(c, revision, trackRequest(before), trackRequest(write.Track))
+		if write.Track.FileURL != before.FileURL {
+			enqueueTrackProcessing(write.Track)
+		}
+		return dto.BulkUpdated, revision.Version
+	default:
+		revision.Version = write.Version + 1
+		revision.Action = models.RevisionDelete
+		recordRevision(c, revision, trackRequest(before), trackRequest(before))
+		return dto.BulkDeleted, revision.Version
+	}
+}
+
+// BulkTracks godoc
+//
+//	@Summary		Create, update and delete tracks in bulk
+//	@Description	Run up to 1000 operations in a single write. create takes a track like POST /tracks, update
+//	@Description	an id, the version last read and a track like PUT /tracks/{id}, delete an id and a version.
+//	@Description	Every operation is validated and gets its own result. ordered stops at the first failed
+//	@Description	operation, atomic writes every operation or none in a transaction.
+//	@Tags			track
+//	@Accept			json
+//	@Produce		json
+//
+//	@Param			input		    body		dto.BulkTrackRequest	true	"Bulk Track Request input"
+//
+//	@Success		200				{object}	app.Response
+//	@Failure		400				{object}	app.Response
+//	@Failure		500				{object}	app.Response
+//	@Router			/tracks/bulk [post]
+func BulkTracks(c *gin.Context) {
+	appG := app.Gin{C: c}
+
+	var request dto.BulkTrackRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		appG.Response400(e.INVALID_PARAMS, "Parse JSON body failed: "+err.Error())
+		return
+	}
+
+	// Validate request input
+	if err := utils.Validator.Struct(request); err != nil {
+		appG.Response400(e.INVALID_PARAMS, "Validate JSON body failed: "+err.Error())
+		return
+	}
+	ordered := request.Ordered || request.Atomic
+
+	results := make([]*dto.BulkTrackResult, len(request.Operations))
+	writes := make([]*models.TrackWrite, len(request.Operations))
+	seen := map[primitive.ObjectID]int{}
+	ids := []primitive.ObjectID{}
+	for i, operation := range request.Operations {
+		results[i] = &dto.BulkTrackResult{Index: i}
+		if operation == nil {
+			results[i].Status = dto.BulkInvalid
+			results[i].Error = "operation is required"
+			continue
+		}
+		results[i].Op = operation.Op
+		results[i].ID = operation.ID
+		if err := utils.Validator.Struct(operation); err != nil {
+			results[i].Status = dto.BulkInvalid
+			results[i].Error = "Validate operation failed: " + err.Error()
+			continue
+		}
+
+		write, err := bulkTrackWrite(operation)
+		if err != nil {
+			results[i].Status = dto.BulkInvalid
+			results[i].Error = err.Error()
+			continue
+		}
+		if write.Op != models.BulkCreate {
+			// The version of a second operation on a track would be outdated
+			if first, ok := seen[write.ID]; ok {
+				results[i].Status = dto.BulkInvalid
+				results[i].Error = fmt.Sprintf("track is already written by operation %d", first)
+				continue
+			}
+			seen[write.ID] = i
+			ids = append(ids, write.ID)
+		}
+		writes[i] = write
+	}
+
+	current := map[primitive.ObjectID]*models.Track{}
+	tracks, err := models.Repository.Track.FindByIDs(context.Background(), ids)
+	if err != nil {
+		appG.Response500(e.ERROR, "Get tracks by id failed with err: "+err.Error())
+		return
+	}
+	for _, track := range tracks {
+		current[track.ID] = track
+	}
+	for i, write := range writes {
+		if write == nil || write.Op == models.BulkCreate {
+			continue
+		}
+		track, ok := current[write.ID]
+		if !ok {
+			results[i].Status = dto.BulkNotFound
+			results[i].Error = "Track not exist"
+			writes[i] = nil
+		} else if track.Version != write.Version {
+			results[i].Status = dto.BulkConflict
+			results[i].Error = "Track is at version " + strconv.FormatInt(track.Version, 10) + ", get it again and retry"
+			writes[i] = nil
+		}
+	}
+
+	// Leave out what an ordered or atomic write must not run
+	failed := -1
+	for i, result := range results {
+		if result.Status != "" {
+			failed = i
+			break
+		}
+	}
+	if failed >= 0 && ordered {
+		for i, result := range results {
+			if result.Status == "" && (request.Atomic || i > failed) {
+				result.Status = dto.BulkSkipped
+				result.Error = fmt.Sprintf("not written because operation %d failed", failed)
+				writes[i] = nil
+			}
+		}
+	}
+
+	pending := []*models.TrackWrite{}
+	indexes := []int{}
+	for i, write := range writes {
+		if write != nil {
+			pending = append(pending, write)
+			indexes = append(indexes, i)
+		}
+	}
+	errs := []error{}
+	if len(pending) > 0 {
+		errs, err = models.Repository.Track.BulkWrite(context.Background(), pending, ordered, request.Atomic)
+		if err != nil {
+			appG.Response500(e.ERROR, "Bulk write tracks failed with err: "+err.Error())
+			return
+		}
+	}
+
+	for j, write := range pending {
+		result := results[indexes[j]]
+		if errs[j] != nil {
+			result.Status = bulkFailure(errs[j])
+			result.Error = errs[j].Error()
+			continue
+		}
+
+		result.Status, result.Version = trackWritten(c, write, current[write.ID])
+		result.ID = write.ID.Hex()
+	}
+
+	response := dto.BulkTrackResponse{Results: results}
+	for _, result := range results {
+		switch result.Status {
+		case dto.BulkCreated:
+			response.Created++
+		case dto.BulkUpdated:
+			response.Updated++
+		case dto.BulkDeleted:
+			response.Deleted++
+		default:
+			response.Failed++
+		}
+	}
+
+	appG.Response200(response)
+}
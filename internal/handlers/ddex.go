@@ -0,0 +1,765 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rolexkdev/emvn-music-library-server/common/app"
+	"github.com/rolexkdev/emvn-music-library-server/common/e"
+	"github.com/rolexkdev/emvn-music-library-server/common/utils"
+	"github.com/rolexkdev/emvn-music-library-server/dto"
+	"github.com/rolexkdev/emvn-music-library-server/internal/catalog"
+	"github.com/rolexkdev/emvn-music-library-server/internal/jobs"
+	"github.com/rolexkdev/emvn-music-library-server/internal/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// ddexArtist is an artist of a delivery as it will be written
+type ddexArtist struct {
+	artist *models.Artist
+	record *dto.DDEXImportRecord
+}
+
+// ddexTrack is the planned write of a sound recording of a delivery
+type ddexTrack struct {
+	result  *dto.DDEXImportTrack
+	request dto.UpdateTrackRequest
+	// Existing track the recording updates, nil for a new track
+	track *models.Track
+	// Audio file of the delivery and its name in the uploads
+	source   string
+	filename string
+}
+
+// ddexImport is the planned import of a delivery
+type ddexImport struct {
+	report  *dto.DDEXImportReport
+	artists []*ddexArtist
+	album   *models.Album
+	// Cover of the delivery and its name in the uploads
+	coverSource   string
+	coverFilename string
+	tracks        []*ddexTrack
+}
+
+func artistFields(artist *models.Artist) map[string]interface{} {
+	if artist == nil {
+		return nil
+	}
+	return map[string]interface{}{"name": artist.Name, "isni": artist.ISNI}
+}
+
+func albumFields(album *models.Album) map[string]interface{} {
+	if album == nil {
+		return nil
+	}
+	return map[string]interface{}{
+		"title": album.Title, "release_type": album.ReleaseType, "icpn": album.ICPN,
+		"artist_id": album.ArtistID, "artist_name": album.ArtistName, "label": album.Label,
+		"genre": album.Genre, "release_date": album.ReleaseDate, "cover_url": album.CoverURL,
+	}
+}
+
+// updateAction returns the action writing changes to an existing record
+func updateAction(changes []*models.FieldChange) string {
+	if len(changes) > 0 {
+		return dto.ImportUpdate
+	}
+	return dto.ImportUnchanged
+}
+
+// stageUpload copies the file at source to a temporary file of the uploads,
+// publishUpload moves it to its name once the records using it are stored
+func stageUpload(source string) (string, error) {
+	in, err := os.Open(source)
+	if err != nil {
+		return "", err
+	}
+	defer in.Close()
+
+	if err := os.MkdirAll(utils.UploadDir, 0o755); err != nil {
+		return "", err
+	}
+	out, err := os.CreateTemp(utils.UploadDir, ".ddex-*.tmp")
+	if err != nil {
+		return "", err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		os.Remove(out.Name())
+		return "", err
+	}
+	if err := out.Close(); err != nil {
+		os.Remove(out.Name())
+		return "", err
+	}
+	return out.Name(), nil
+}
+
+// publishUpload replaces the uploaded file called filename with the staged file
+func publishUpload(staged, filename string) error {
+	filePath, err := utils.UploadPath(filename)
+	if err != nil {
+		return err
+	}
+	return os.Rename(staged, filePath)
+}
+
+// checkUploadTarget returns an error when writing filename would replace an
+// uploaded file that is not only played by track, nil for a new track
+func checkUploadTarget(ctx context.Context, filename string, track *models.Track) error {
+	filePath, err := utils.UploadPath(filename)
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(filePath); errors.Is(err, os.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	users, err := models.Repository.Track.FindByUploadName(ctx, filename)
+	if err != nil {
+		return err
+	}
+	if track == nil || len(users) == 0 {
+		return fmt.Errorf("file %s already exists in the uploads", filename)
+	}
+	for _, user := range users {
+		if user.ID != track.ID {
+			return fmt.Errorf("file %s is used by track %s", filename, user.ID.Hex())
+		}
+	}
+	return nil
+}
+
+// planDDEXImport works out what importing the delivery whose message is at
+// path does to its artists, album and tracks
+func planDDEXImport(c *gin.Context, path string, message *catalog.Message) (*ddexImport, error) {
+	ctx := context.Background()
+	release := message.Release
+	plan := &ddexImport{report: &dto.DDEXImportReport{
+		MessageID: message.ID,
+		Artists:   []*dto.DDEXImportRecord{},
+		Tracks:    []*dto.DDEXImportTrack{},
+	}}
+
+	// Artists are matched by ISNI, or else by name
+	byKey := map[string]*ddexArtist{}
+	artistOf := func(party catalog.Party) (*ddexArtist, error) {
+		key := "isni:" + party.ID
+		if party.ID == "" {
+			key = "name:" + utils.NormalizeSearchText(party.Name)
+		}
+		if artist, ok := byKey[key]; ok {
+			return artist, nil
+		}
+
+		artist := &ddexArtist{record: &dto.DDEXImportRecord{Name: party.Name}}
+		existing, err := models.Repository.Artist.Match(ctx, party.ID, party.Name)
+		if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, err
+		}
+		if existing != nil {
+			updated := *existing
+			updated.Name = party.Name
+			if party.ID != "" {
+				updated.ISNI = party.ID
+			}
+			artist.artist = &updated
+			artist.record.Changes = diffFields(artistFields(existing), artistFields(&updated))
+			artist.record.Action = updateAction(artist.record.Changes)
+		} else {
+			artist.artist = &models.Artist{ID: primitive.NewObjectID(), Name: party.Name, ISNI: party.ID}
+			artist.record.Action = dto.ImportCreate
+		}
+		if party.Name == "" {
+			artist.record.Action = dto.ImportError
+			artist.record.Errors = []string{"artist has no DisplayArtistName or party name"}
+		}
+		artist.record.ID = artist.artist.ID.Hex()
+
+		byKey[key] = artist
+		plan.artists = append(plan.artists, artist)
+		plan.report.Artists = append(plan.report.Artists, artist.record)
+		return artist, nil
+	}
+
+	albumArtist, err := artistOf(release.Artist)
+	if err != nil {
+		return nil, err
+	}
+	existing, err := models.Repository.Album.Match(ctx, release.ICPN, release.Title, albumArtist.artist.ID.Hex())
+	if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, err
+	}
+	album := &models.Album{ID: primitive.NewObjectID()}
+	if existing != nil {
+		*album = *existing
+	}
+	album.Title = release.Title
+	album.ReleaseType = release.Type
+	album.ArtistID = albumArtist.artist.ID.Hex()
+	album.ArtistName = albumArtist.artist.Name
+	if release.ICPN != "" {
+		album.ICPN = release.ICPN
+	}
+	if release.Label.Name != "" {
+		album.Label = release.Label.Name
+	}
+	if release.Genre != "" {
+		album.Genre = release.Genre
+	}
+	if release.ReleaseDate != 0 {
+		album.ReleaseDate = release.ReleaseDate
+	}
+
+	albumRecord := &dto.DDEXImportRecord{ID: album.ID.Hex(), Name: album.Title}
+	if release.Cover != nil {
+		source, err := catalog.ResourcePath(path, release.Cover)
+		if err != nil {
+			albumRecord.Errors = append(albumRecord.Errors, err.Error())
+		} else {
+			plan.coverSource = source
+			plan.coverFilename = album.ID.Hex() + "_cover" + strings.ToLower(filepath.Ext(source))
+			album.CoverURL = uploadURL(c, plan.coverFilename)
+		}
+	}
+	if existing != nil {
+		albumRecord.Changes = diffFields(albumFields(existing), albumFields(album))
+		albumRecord.Action = updateAction(albumRecord.Changes)
+	} else {
+		albumRecord.Action = dto.ImportCreate
+	}
+	if len(albumRecord.Errors) > 0 {
+		albumRecord.Action = dto.ImportError
+	}
+	plan.album = album
+	plan.report.Album = albumRecord
+
+	isrcs := []string{}
+	titles := []string{}
+	for i, recording := range release.Recordings {
+		artist, err := artistOf(recording.Artist)
+		if err != nil {
+			return nil, err
+		}
+
+		track := &ddexTrack{result: &dto.DDEXImportTrack{
+			Reference: recording.Reference,
+			ISRC:      utils.NormalizeISRC(recording.ISRC),
+			Title:     recording.Title,
+			Errors:    recording.Errors,
+		}}
+		track.request = dto.UpdateTrackRequest{
+			Name:        recording.Title,
+			Title:       recording.Title,
+			ArtistID:    artist.artist.ID.Hex(),
+			ArtistName:  artist.artist.Name,
+			Album:       album.Title,
+			Genre:       album.Genre,
+			ReleaseDate: album.ReleaseDate,
+			Duration:    recording.Duration,
+			ISRC:        track.result.ISRC,
+		}
+		if recording.File != nil {
+			source, err := catalog.ResourcePath(path, recording.File)
+			if err != nil {
+				track.result.Errors = append(track.result.Errors, err.Error())
+			} else {
+				// Files are named by ISRC so a redelivery replaces the audio of its
+				// recording, or else by album and sequence number, never by message text
+				name := track.result.ISRC
+				if !utils.IsISRC(name) {
+					name = fmt.Sprintf("%s_%02d", album.ID.Hex(), i+1)
+				}
+				track.source = source
+				track.filename = name + strings.ToLower(filepath.Ext(source))
+				track.request.FileURL = uploadURL(c, track.filename)
+			}
+		}
+
+		if track.result.ISRC != "" {
+			isrcs = append(isrcs, track.result.ISRC)
+		}
+		titles = append(titles, utils.NormalizeSearchText(recording.Title))
+		plan.tracks = append(plan.tracks, track)
+		plan.report.Tracks = append(plan.report.Tracks, track.result)
+	}
+
+	tracks, err := models.Repository.Track.FindMatching(ctx, isrcs, titles)
+	if err != nil {
+		return nil, err
+	}
+	matcher := newTrackMatcher(tracks)
+
+	// Two recordings writing the same track would overwrite each other
+	seen := map[string]string{}
+	for _, track := range plan.tracks {
+		result := track.result
+		if len(result.Errors) > 0 {
+			result.Action = dto.ImportError
+			continue
+		}
+
+		existing, matchedBy, err := matcher.match(track.request)
+		if err != nil {
+			result.Action = dto.ImportError
+			result.Errors = []string{err.Error()}
+			continue
+		}
+		key := "isrc:" + result.ISRC
+		if existing != nil {
+			key = existing.ID.Hex()
+		} else if result.ISRC == "" {
+			key = "title:" + utils.NormalizeSearchText(result.Title) + "|" + track.request.ArtistID
+		}
+		if reference, ok := seen[key]; ok {
+			result.Action = dto.ImportError
+			result.Errors = []string{"same track as " + reference}
+			continue
+		}
+		seen[key] = result.Reference
+
+		// A delivery never replaces audio that other tracks play
+		if track.filename != "" {
+			if err := checkUploadTarget(ctx, track.filename, existing); err != nil {
+				result.Action = dto.ImportError
+				result.Errors = []string{err.Error()}
+				continue
+			}
+		}
+
+		if existing == nil {
+			if err := utils.Validator.Struct(dto.CreateTrackRequest(track.request)); err != nil {
+				result.Action = dto.ImportError
+				result.Errors = validationErrors(err)
+				continue
+			}
+			result.Action = dto.ImportCreate
+			continue
+		}
+
+		// The delivery leaves the name and the fields it lacks untouched
+		result.TrackID = existing.ID.Hex()
+		result.MatchedBy = matchedBy
+		request := trackRequest(existing)
+		request.Title = track.request.Title
+		request.ArtistID = track.request.ArtistID
+		request.ArtistName = track.request.ArtistName
+		request.Album = track.request.Album
+		if track.request.Genre != "" {
+			request.Genre = track.request.Genre
+		}
+		if track.request.ReleaseDate != 0 {
+			request.ReleaseDate = track.request.ReleaseDate
+		}
+		if track.request.Duration != 0 {
+			request.Duration = track.request.Duration
+		}
+		if track.request.FileURL != "" {
+			request.FileURL = track.request.FileURL
+		}
+		if track.request.ISRC != "" {
+			request.ISRC = track.request.ISRC
+		}
+		track.track = existing
+		track.request = request
+		if err := utils.Validator.Struct(request); err != nil {
+			result.Action = dto.ImportError
+			result.Errors = validationErrors(err)
+			continue
+		}
+		result.Changes = diffFields(fieldMap(trackRequest(existing)), fieldMap(request))
+		result.Action = updateAction(result.Changes)
+	}
+
+	report := plan.report
+	for _, track := range plan.tracks {
+		switch track.result.Action {
+		case dto.ImportCreate:
+			report.Create++
+		case dto.ImportUpdate:
+			report.Update++
+		case dto.ImportUnchanged:
+			report.Unchanged++
+		default:
+			report.Errors++
+		}
+	}
+	for _, record := range append([]*dto.DDEXImportRecord{report.Album}, report.Artists...) {
+		if record.Action == dto.ImportError {
+			report.Errors++
+		}
+	}
+	return plan, nil
+}
+
+// commit writes the planned import, the album lists the tracks written in the
+// order of the release
+func (plan *ddexImport) commit(c *gin.Context) error {
+	ctx := context.Background()
+
+	// Files are staged first and only replace uploads once their records are
+	// stored, whatever is left staged is removed
+	staged := map[string]string{}
+	defer func() {
+		for _, path := range staged {
+			os.Remove(path)
+		}
+	}()
+	for _, track := range plan.tracks {
+		action := track.result.Action
+		if track.source != "" && (action == dto.ImportCreate || action == dto.ImportUpdate) {
+			path, err := stageUpload(track.source)
+			if err != nil {
+				return err
+			}
+			staged[track.filename] = path
+		}
+	}
+	if plan.coverSource != "" {
+		path, err := stageUpload(plan.coverSource)
+		if err != nil {
+			return err
+		}
+		staged[plan.coverFilename] = path
+	}
+	publish := func(filename string) error {
+		path, ok := staged[filename]
+		if !ok {
+			return nil
+		}
+		delete(staged, filename)
+		return publishUpload(path, filename)
+	}
+
+	for _, artist := range plan.artists {
+		switch artist.record.Action {
+		case dto.ImportCreate:
+			if _, err := models.Repository.Artist.Create(ctx, artist.artist); err != nil {
+				return err
+			}
+		case dto.ImportUpdate:
+			if err := models.Repository.Artist.Update(ctx, artist.artist); err != nil {
+				return err
+			}
+		}
+	}
+
+	writes := []*models.TrackWrite{}
+	written := []*ddexTrack{}
+	for _, track := range plan.tracks {
+		switch track.result.Action {
+		case dto.ImportCreate:
+			write := &models.TrackWrite{Op: models.BulkCreate, Track: &models.Track{}}
+			applyTrackRequest(write.Track, track.request)
+			writes = append(writes, write)
+		case dto.ImportUpdate:
+			write := &models.TrackWrite{Op: models.BulkUpdate, ID: track.track.ID, Version: track.track.Version, Track: &models.Track{ID: track.track.ID}}
+			applyTrackRequest(write.Track, track.request)
+			writes = append(writes, write)
+		default:
+			continue
+		}
+		written = append(written, track)
+	}
+
+	errs := []error{}
+	if len(writes) > 0 {
+		var err error
+		errs, err = models.Repository.Track.BulkWrite(ctx, writes, false, false)
+		if err != nil {
+			return err
+		}
+	}
+	for i, write := range writes {
+		result := written[i].result
+		if errs[i] != nil {
+			result.Status = bulkFailure(errs[i])
+			result.Errors = []string{errs[i].Error()}
+			plan.report.Errors++
+			continue
+		}
+		if err := publish(written[i].filename); err != nil {
+			return err
+		}
+		result.Status, _ = trackWritten(c, write, written[i].track)
+		result.TrackID = write.ID.Hex()
+	}
+
+	album := plan.album
+	album.TrackIDs = []string{}
+	for _, track := range plan.tracks {
+		if track.result.TrackID != "" && len(track.result.Errors) == 0 {
+			album.TrackIDs = append(album.TrackIDs, track.result.TrackID)
+		}
+	}
+	if plan.report.Album.Action == dto.ImportCreate {
+		if _, err := models.Repository.Album.Create(ctx, album); err != nil {
+			return err
+		}
+	} else if err := models.Repository.Album.Update(ctx, album); err != nil {
+		return err
+	}
+	if plan.coverSource != "" {
+		if err := publish(plan.coverFilename); err != nil {
+			return err
+		}
+		if _, err := jobs.Enqueue(ctx, jobs.TypeThumbnails, plan.coverFilename); err != nil {
+			log.Printf("Queue thumbnails for %s failed with error: %v", plan.coverFilename, err)
+		}
+	}
+
+	plan.report.Committed = true
+	return nil
+}
+
+// ImportDDEX godoc
+//
+//	@Summary		Import a DDEX ERN delivery
+//	@Description	Read an ERN 4.x NewReleaseMessage from the delivery folder (CATALOG_DDEX_DELIVERY_DIR) and
+//	@Description	create or update the artists, album and tracks of its main release. Artists are matched by
+//	@Description	ISNI or name, the album by ICPN (UPC/EAN) or title and artist, tracks like the CSV import by
+//	@Description	ISRC or title and artist. Audio files and the front cover are read from the folder of the
+//	@Description	message, checked against their hash sum and copied to the uploads. Without commit the import
+//	@Description	is a dry run. A commit writes nothing when a record has errors. Requires an admin API key.
+//	@Tags			ddex
+//	@Accept			json
+//	@Produce		json
+//
+//	@Param			input			body		dto.DDEXImportRequest	true	"DDEX Import Request input"
+//
+//	@Success		200				{object}	app.Response
+//	@Failure		400				{object}	app.Response
+//	@Failure		403				{object}	app.Response
+//	@Failure		404				{object}	app.Response
+//	@Failure		500				{object}	app.Response
+//	@Router			/admin/ddex/import [post]
+func ImportDDEX(c *gin.Context) {
+	appG := app.Gin{C: c}
+
+	var request dto.DDEXImportRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		appG.Response400(e.INVALID_PARAMS, "Parse JSON body failed: "+err.Error())
+		return
+	}
+
+	// Validate request input
+	if err := utils.Validator.Struct(request); err != nil {
+		appG.Response400(e.INVALID_PARAMS, "Validate request body failed: "+err.Error())
+		return
+	}
+
+	path := catalog.DeliveryPath(request.Path)
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		appG.Response404(e.NOTFOUND, "Delivery message not exist")
+		return
+	}
+	if err != nil {
+		appG.Response500(e.ERROR, "Open delivery message failed with err: "+err.Error())
+		return
+	}
+	defer file.Close()
+
+	message, err := catalog.ReadERN(file)
+	if err != nil {
+		appG.Response400(e.INVALID_PARAMS, "Read ERN message failed: "+err.Error())
+		return
+	}
+
+	plan, err := planDDEXImport(c, path, message)
+	if err != nil {
+		appG.Response500(e.ERROR, "Match delivery records failed with err: "+err.Error())
+		return
+	}
+
+	if !request.Commit {
+		appG.Response200(plan.report)
+		return
+	}
+	if plan.report.Errors > 0 {
+		appG.Response400(e.INVALID_PARAMS, plan.report)
+		return
+	}
+	if err := plan.commit(c); err != nil {
+		appG.Response500(e.ERROR, "Import delivery failed with err: "+err.Error())
+		return
+	}
+	appG.Response200(plan.report)
+}
+
+// ExportAlbumDDEX godoc
+//
+//	@Summary		Export an album as DDEX ERN
+//	@Description	Download the album and its live tracks as an ERN 4.3 NewReleaseMessage for the recipient,
+//	@Description	sent by the party configured in CATALOG_DDEX_SENDER_ID and CATALOG_DDEX_SENDER_NAME. The
+//	@Description	release is offered for streaming worldwide from its release date.
+//	@Tags			album
+//	@Accept			json
+//	@Produce		xml
+//
+//	@Param			id				path		string	true	"Album ID"
+//	@Param			recipient_id	query		string	true	"DDEX party id (DPID) of the recipient"
+//	@Param			recipient_name	query		string	false	"name of the recipient"
+//
+//	@Success		200				{file}		file
+//	@Failure		400				{object}	app.Response
+//	@Failure		404				{object}	app.Response
+//	@Failure		500				{object}	app.Response
+//	@Router			/albums/{id}/ddex [get]
+func ExportAlbumDDEX(c *gin.Context) {
+	appG := app.Gin{C: c}
+
+	var request dto.DDEXExportRequest
+	if err := c.BindQuery(&request); err != nil {
+		appG.Response400(e.INVALID_PARAMS, "Bind query failed: "+err.Error())
+		return
+	}
+
+	// Validate request input
+	if err := utils.Validator.Struct(request); err != nil {
+		appG.Response400(e.INVALID_PARAMS, "Validate query failed: "+err.Error())
+		return
+	}
+
+	sender := catalog.Sender()
+	if sender.ID == "" {
+		appG.Response500(e.ERROR, "DDEX sender is not configured, set CATALOG_DDEX_SENDER_ID")
+		return
+	}
+
+	objID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		appG.Response500(e.ERROR, "convert id string to objectID failed"+err.Error())
+		return
+	}
+	album, err := models.Repository.Album.FindByID(context.Background(), objID)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			appG.Response404(e.NOTFOUND, "Album not exist")
+			return
+		}
+		appG.Response500(e.ERROR, "Get album by id failed with err: "+err.Error())
+		return
+	}
+
+	release, err := albumRelease(album)
+	if err != nil {
+		appG.Response500(e.ERROR, "Get album tracks failed with err: "+err.Error())
+		return
+	}
+	if len(release.Recordings) == 0 {
+		appG.Response400(e.INVALID_PARAMS, "Album has no tracks to export")
+		return
+	}
+
+	message := &catalog.Message{
+		ID:        album.ID.Hex() + "-" + time.Now().UTC().Format("20060102150405"),
+		Sender:    sender,
+		Recipient: catalog.Party{ID: request.RecipientID, Name: request.RecipientName},
+		CreatedAt: time.Now(),
+		Release:   release,
+	}
+	name := album.ICPN
+	if name == "" {
+		name = album.ID.Hex()
+	}
+	c.Writer.Header().Set("Content-Type", "application/xml; charset=utf-8")
+	c.Writer.Header().Set("Content-Disposition", "attachment; filename=\""+name+".xml\"")
+	c.Status(http.StatusOK)
+	if err := catalog.WriteERN(c.Writer, message); err != nil {
+		log.Printf("Export album %s as DDEX failed with error: %v", album.ID.Hex(), err)
+	}
+}
+
+// albumRelease returns album and its live tracks in order as a DDEX release
+func albumRelease(album *models.Album) (*catalog.Release, error) {
+	ctx := context.Background()
+	ids := []primitive.ObjectID{}
+	for _, trackID := range album.TrackIDs {
+		if id, err := primitive.ObjectIDFromHex(trackID); err == nil {
+			ids = append(ids, id)
+		}
+	}
+	tracks, err := models.Repository.Track.FindByIDs(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[string]*models.Track, len(tracks))
+	for _, track := range tracks {
+		byID[track.ID.Hex()] = track
+	}
+
+	// Artists are named by the track, with the ISNI of their artist record
+	artists := map[string]catalog.Party{}
+	partyOf := func(artistID, name string) (catalog.Party, error) {
+		party, ok := artists[artistID]
+		if !ok {
+			party = catalog.Party{Name: name}
+			if id, err := primitive.ObjectIDFromHex(artistID); err == nil {
+				artist, err := models.Repository.Artist.FindByID(ctx, id)
+				if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+					return party, err
+				}
+				if artist != nil {
+					party = catalog.Party{ID: artist.ISNI, Name: artist.Name}
+				}
+			}
+			artists[artistID] = party
+		}
+		if name != "" {
+			party.Name = name
+		}
+		return party, nil
+	}
+
+	release := &catalog.Release{
+		Type:          album.ReleaseType,
+		ICPN:          album.ICPN,
+		ProprietaryID: album.ID.Hex(),
+		Title:         album.Title,
+		Label:         catalog.Party{Name: album.Label},
+		Genre:         album.Genre,
+		ReleaseDate:   album.ReleaseDate,
+	}
+	if release.Type == "" {
+		release.Type = "Album"
+	}
+	if release.Artist, err = partyOf(album.ArtistID, album.ArtistName); err != nil {
+		return nil, err
+	}
+	if album.CoverURL != "" {
+		release.Cover = &catalog.File{URI: album.CoverURL}
+	}
+	for _, trackID := range album.TrackIDs {
+		track, ok := byID[trackID]
+		if !ok {
+			continue
+		}
+		artist, err := partyOf(track.ArtistID, track.ArtistName)
+		if err != nil {
+			return nil, err
+		}
+		release.Recordings = append(release.Recordings, &catalog.Recording{
+			ISRC:          track.ISRC,
+			ProprietaryID: track.ID.Hex(),
+			Title:         track.Title,
+			Artist:        artist,
+			Duration:      track.Duration,
+			File:          &catalog.File{URI: track.FileURL},
+		})
+	}
+	return release, nil
+}
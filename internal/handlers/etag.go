@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rolexkdev/emvn-music-library-server/common/app"
+	"github.com/rolexkdev/emvn-music-library-server/common/e"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// versionETag returns the strong ETag of a record at version
+func versionETag(version int64) string {
+	return `"` + strconv.FormatInt(version, 10) + `"`
+}
+
+// listETag returns a weak ETag changing whenever a record is added to, removed
+// from or modified in a list
+func listETag[T any](items []T, version func(T) (primitive.ObjectID, int64)) string {
+	hash := sha1.New()
+	for _, item := range items {
+		id, v := version(item)
+		hash.Write(id[:])
+		hash.Write([]byte(strconv.FormatInt(v, 10) + ","))
+	}
+	return `W/"` + hex.EncodeToString(hash.Sum(nil)) + `"`
+}
+
+// etagListed reports whether the If-Match or If-None-Match header value lists
+// etag. The weak comparison ignores the W/ prefix, the strong one never
+// matches weak ETags.
+func etagListed(header, etag string, weak bool) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" {
+			return true
+		}
+		if weak {
+			if strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(etag, "W/") {
+				return true
+			}
+		} else if candidate == etag && !strings.HasPrefix(etag, "W/") {
+			return true
+		}
+	}
+	return false
+}
+
+// notModified sets the ETag header of a GET response and answers 304 when the
+// client already holds that representation, returning true
+func notModified(c *gin.Context, etag string) bool {
+	c.Header("ETag", etag)
+	match := c.GetHeader("If-None-Match")
+	if match == "" || !etagListed(match, etag, true) {
+		return false
+	}
+	c.Status(http.StatusNotModified)
+	return true
+}
+
+// checkIfMatch enforces the If-Match header of a write to a record at version.
+// A missing header is answered with 428 and an outdated one with 412, and false
+// is returned.
+func checkIfMatch(appG app.Gin, version int64) bool {
+	match := appG.C.GetHeader("If-Match")
+	if match == "" {
+		appG.Response428(e.PRECONDITION_REQUIRED, "If-Match header with the ETag of the record is required")
+		return false
+	}
+	if !etagListed(match, versionETag(version), false) {
+		appG.C.Header("ETag", versionETag(version))
+		appG.Response412(e.PRECONDITION_FAILED, "Record was modified, get it again and retry")
+		return false
+	}
+	return true
+}
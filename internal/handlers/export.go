@@ -0,0 +1,313 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rolexkdev/emvn-music-library-server/common/app"
+	"github.com/rolexkdev/emvn-music-library-server/common/e"
+	"github.com/rolexkdev/emvn-music-library-server/common/utils"
+	"github.com/rolexkdev/emvn-music-library-server/dto"
+	"github.com/rolexkdev/emvn-music-library-server/internal/catalog"
+	"github.com/rolexkdev/emvn-music-library-server/internal/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Records written between two flushes of an export
+const exportFlushEvery = 500
+
+// Columns of an exported track in the CSV and XLSX formats, named like the
+// fields of an import so an export can be imported back
+var trackExportColumns = []string{
+	"id", "isrc", "iswc", "title", "name", "artist_id", "artist_name", "album", "genre",
+	"release_date", "duration", "file_url", "p_line", "c_line", "label", "master_owner",
+	"publishing", "tier", "create_at", "update_at",
+}
+
+// Columns of an exported playlist, one row per track
+var playlistExportColumns = append([]string{"playlist_id", "playlist_title", "album_cover", "position"}, trackExportColumns...)
+
+// newTrackExport returns track as exported, naming its artist from artists
+// when the track does not
+func newTrackExport(track *models.Track, artists map[string]string) *dto.TrackExport {
+	artistName := track.ArtistName
+	if artistName == "" {
+		artistName = artists[track.ArtistID]
+	}
+	return &dto.TrackExport{
+		ID:          track.ID.Hex(),
+		ISRC:        track.ISRC,
+		ISWC:        track.ISWC,
+		Title:       track.Title,
+		Name:        track.Name,
+		ArtistID:    track.ArtistID,
+		ArtistName:  artistName,
+		Album:       track.Album,
+		Genre:       track.Genre,
+		ReleaseDate: track.ReleaseDate,
+		Duration:    track.Duration,
+		FileURL:     track.FileURL,
+		PLine:       track.PLine,
+		CLine:       track.CLine,
+		Label:       track.Label,
+		MasterOwner: track.MasterOwner,
+		Publishing:  publishingRequest(track.Publishing),
+		Tier:        track.Tier,
+		CreateAt:    track.CreateAt,
+		UpdateAt:    track.UpdateAt,
+	}
+}
+
+func trackExportCells(track *dto.TrackExport) []interface{} {
+	return []interface{}{
+		track.ID, track.ISRC, track.ISWC, track.Title, track.Name, track.ArtistID, track.ArtistName, track.Album, track.Genre,
+		catalog.FormatReleaseDate(track.ReleaseDate), catalog.FormatDuration(track.Duration), track.FileURL,
+		track.PLine, track.CLine, track.Label, track.MasterOwner, catalog.FormatPublishing(track.Publishing),
+		track.Tier, track.CreateAt.UTC().Format(time.RFC3339), track.UpdateAt.UTC().Format(time.RFC3339),
+	}
+}
+
+// exportWriter streams the records of an export in its format
+type exportWriter struct {
+	c       *gin.Context
+	encoder *json.Encoder
+	table   catalog.Table
+	written int
+}
+
+// startExport answers with the headers of a download called name in format,
+// CSV by default. header names the columns of the CSV and XLSX formats.
+func startExport(c *gin.Context, format, name string, header []string) (*exportWriter, error) {
+	if format == "" {
+		format = catalog.FormatCSV
+	}
+	c.Writer.Header().Set("Content-Type", catalog.ContentTypes[format])
+	c.Writer.Header().Set("Content-Disposition", "attachment; filename=\""+name+"."+format+"\"")
+	c.Status(http.StatusOK)
+
+	w := &exportWriter{c: c}
+	if format == catalog.FormatJSONL {
+		w.encoder = json.NewEncoder(c.Writer)
+		return w, nil
+	}
+	table, err := catalog.NewTable(format, c.Writer, header)
+	if err != nil {
+		return nil, err
+	}
+	w.table = table
+	return w, nil
+}
+
+// write adds a record, written whole in JSON Lines or as rows in a table
+func (w *exportWriter) write(record interface{}, rows ...[]interface{}) error {
+	if w.encoder != nil {
+		if err := w.encoder.Encode(record); err != nil {
+			return err
+		}
+	} else {
+		for _, row := range rows {
+			if err := w.table.WriteRow(row); err != nil {
+				return err
+			}
+		}
+	}
+
+	w.written++
+	if w.written%exportFlushEvery == 0 {
+		if w.table != nil {
+			if err := w.table.Flush(); err != nil {
+				return err
+			}
+		}
+		w.c.Writer.Flush()
+	}
+	return nil
+}
+
+// finish completes the download. Once records are sent an error can only end
+// it early, so it is logged.
+func (w *exportWriter) finish(name string, err error) {
+	if w.table != nil {
+		if closeErr := w.table.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	if err != nil {
+		log.Printf("Export %s failed after %d records with error: %v", name, w.written, err)
+	}
+}
+
+// ExportTracks godoc
+//
+//	@Summary		Export tracks
+//	@Description	Download every track matching the filters of the track list, oldest first, as CSV, JSON Lines
+//	@Description	or XLSX. The artist name of a track without one is taken from the other tracks of the artist.
+//	@Description	In CSV and XLSX release_date is a date and duration m:ss[.mmm], as read by the import.
+//	@Tags			track
+//	@Accept			json
+//	@Produce		text/csv,application/x-ndjson,application/vnd.openxmlformats-officedocument.spreadsheetml.sheet
+//
+//	@Param			format			query		string	false	"csv (default), jsonl or xlsx"
+//	@Param			min_bpm			query		number	false	"minimum tempo"
+//	@Param			max_bpm			query		number	false	"maximum tempo"
+//	@Param			key				query		string	false	"musical key, e.g. A minor, Am, Bb"
+//	@Param			min_loudness	query		number	false	"minimum integrated loudness (LUFS)"
+//	@Param			max_loudness	query		number	false	"maximum integrated loudness (LUFS)"
+//	@Param			genre			query		[]string	false	"genres, repeat for several"	collectionFormat(multi)
+//	@Param			year			query		[]int		false	"release years, repeat for several"	collectionFormat(multi)
+//	@Param			duration		query		[]string	false	"duration buckets: under_2m, 2m_4m, 4m_6m, over_6m"	collectionFormat(multi)
+//	@Param			artist_id		query		[]string	false	"artist ids, repeat for several"	collectionFormat(multi)
+//	@Param			label			query		[]string	false	"labels, repeat for several"	collectionFormat(multi)
+//	@Param			master_owner	query		[]string	false	"master owners, repeat for several"	collectionFormat(multi)
+//	@Param			writer			query		[]string	false	"writers in the publishing splits, repeat for several"	collectionFormat(multi)
+//	@Param			publisher		query		[]string	false	"publishers in the publishing splits, repeat for several"	collectionFormat(multi)
+//	@Param			isrc			query		string	false	"ISRC, separators allowed"
+//	@Param			iswc			query		string	false	"ISWC, separators allowed, e.g. T-034.524.680-1"
+//	@Param			X-Territory	header		string	false	"ISO 3166-1 alpha-2 territory of the caller, e.g. VN, unless set on the API key"
+//
+//	@Success		200				{file}		file
+//	@Failure		400				{object}	app.Response
+//	@Failure		500				{object}	app.Response
+//	@Router			/tracks/export [get]
+func ExportTracks(c *gin.Context) {
+	appG := app.Gin{C: c}
+
+	var request dto.TrackExportRequest
+	if err := c.BindQuery(&request); err != nil {
+		appG.Response400(e.INVALID_PARAMS, "Bind query failed: "+err.Error())
+		return
+	}
+
+	// Validate request input
+	if err := utils.Validator.Struct(request); err != nil {
+		appG.Response400(e.INVALID_PARAMS, "Validate query failed: "+err.Error())
+		return
+	}
+
+	filter, err := trackFilterFromRequest(request.TrackFilterRequest)
+	if err != nil {
+		appG.Response400(e.INVALID_PARAMS, err.Error())
+		return
+	}
+	filter.Availability = callerAvailability(c)
+
+	artists, err := models.Repository.Track.ArtistNames(context.Background())
+	if err != nil {
+		appG.Response500(e.ERROR, "Get artist names failed with err: "+err.Error())
+		return
+	}
+
+	w, err := startExport(c, request.Format, "tracks", trackExportColumns)
+	if err != nil {
+		appG.Response500(e.ERROR, "Start export failed with err: "+err.Error())
+		return
+	}
+	err = models.Repository.Track.Export(context.Background(), filter, func(track *models.Track) error {
+		export := newTrackExport(track, artists)
+		return w.write(export, trackExportCells(export))
+	})
+	w.finish("tracks", err)
+}
+
+// ExportPlaylists godoc
+//
+//	@Summary		Export playlists
+//	@Description	Download every playlist with its live tracks in order, oldest playlist first. JSON Lines
+//	@Description	holds one playlist per line with its tracks, CSV and XLSX one row per track of a playlist
+//	@Description	(a single row without track for an empty playlist). Tracks not available to the caller
+//	@Description	are left out.
+//	@Tags			playlist
+//	@Accept			json
+//	@Produce		text/csv,application/x-ndjson,application/vnd.openxmlformats-officedocument.spreadsheetml.sheet
+//
+//	@Param			format			query		string	false	"csv (default), jsonl or xlsx"
+//	@Param			X-Territory	header		string	false	"ISO 3166-1 alpha-2 territory of the caller, e.g. VN, unless set on the API key"
+//
+//	@Success		200				{file}		file
+//	@Failure		400				{object}	app.Response
+//	@Failure		500				{object}	app.Response
+//	@Router			/playlists/export [get]
+func ExportPlaylists(c *gin.Context) {
+	appG := app.Gin{C: c}
+
+	var request dto.PlaylistExportRequest
+	if err := c.BindQuery(&request); err != nil {
+		appG.Response400(e.INVALID_PARAMS, "Bind query failed: "+err.Error())
+		return
+	}
+
+	// Validate request input
+	if err := utils.Validator.Struct(request); err != nil {
+		appG.Response400(e.INVALID_PARAMS, "Validate query failed: "+err.Error())
+		return
+	}
+
+	artists, err := models.Repository.Track.ArtistNames(context.Background())
+	if err != nil {
+		appG.Response500(e.ERROR, "Get artist names failed with err: "+err.Error())
+		return
+	}
+
+	availability := callerAvailability(c)
+	w, err := startExport(c, request.Format, "playlists", playlistExportColumns)
+	if err != nil {
+		appG.Response500(e.ERROR, "Start export failed with err: "+err.Error())
+		return
+	}
+	err = models.Repository.Playlist.Export(context.Background(), func(playlist *models.Playlist) error {
+		export, err := newPlaylistExport(playlist, artists, availability)
+		if err != nil {
+			return err
+		}
+
+		head := []interface{}{export.ID, export.Title, export.AlbumCover}
+		rows := [][]interface{}{head}
+		if len(export.Tracks) > 0 {
+			rows = rows[:0]
+		}
+		for i, track := range export.Tracks {
+			row := append(append([]interface{}{}, head...), i+1)
+			rows = append(rows, append(row, trackExportCells(track)...))
+		}
+		return w.write(export, rows...)
+	})
+	w.finish("playlists", err)
+}
+
+// newPlaylistExport returns playlist as exported with its live tracks
+// available to the caller in order
+func newPlaylistExport(playlist *models.Playlist, artists map[string]string, availability *models.Availability) (*dto.PlaylistExport, error) {
+	ids := []primitive.ObjectID{}
+	for _, trackID := range playlist.TrackIDs {
+		if id, err := primitive.ObjectIDFromHex(trackID); err == nil {
+			ids = append(ids, id)
+		}
+	}
+	tracks, err := models.Repository.Track.FindByIDs(context.Background(), ids)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[string]*models.Track, len(tracks))
+	for _, track := range tracks {
+		byID[track.ID.Hex()] = track
+	}
+
+	export := &dto.PlaylistExport{
+		ID:         playlist.ID.Hex(),
+		Title:      playlist.Title,
+		AlbumCover: playlist.AlbumCover,
+		CreateAt:   playlist.CreateAt,
+		UpdateAt:   playlist.UpdateAt,
+		Tracks:     []*dto.TrackExport{},
+	}
+	for _, trackID := range playlist.TrackIDs {
+		if track, ok := byID[trackID]; ok && availability.Allows(track) {
+			export.Tracks = append(export.Tracks, newTrackExport(track, artists))
+		}
+	}
+	return export, nil
+}
@@ -0,0 +1,306 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"reflect"
+	"sort"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rolexkdev/emvn-music-library-server/common/app"
+	"github.com/rolexkdev/emvn-music-library-server/common/e"
+	"github.com/rolexkdev/emvn-music-library-server/common/utils"
+	"github.com/rolexkdev/emvn-music-library-server/dto"
+	"github.com/rolexkdev/emvn-music-library-server/internal/models"
+	"github.com/rolexkdev/emvn-music-library-server/middleware"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// fieldMap returns the JSON members of v, nil for a nil v
+func fieldMap(v interface{}) map[string]interface{} {
+	if v == nil {
+		return nil
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil
+	}
+	var fields map[string]interface{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil
+	}
+	return fields
+}
+
+// diffFields lists the fields whose value differs between before and after,
+// by name
+func diffFields(before, after map[string]interface{}) []*models.FieldChange {
+	names := map[string]bool{}
+	for name := range before {
+		names[name] = true
+	}
+	for name := range after {
+		names[name] = true
+	}
+
+	changes := []*models.FieldChange{}
+	for name := range names {
+		if !reflect.DeepEqual(before[name], after[name]) {
+			changes = append(changes, &models.FieldChange{Field: name, Old: before[name], New: after[name]})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Field < changes[j].Field })
+	return changes
+}
+
+// recordRevision stores a change of a record in its history. before and after
+// are the editable fields of the record, before is nil for a new record. An
+// update changing nothing is not recorded. A failure only loses history, so it
+// is logged.
+func recordRevision(c *gin.Context, revision *models.Revision, before, after interface{}) {
+	state := fieldMap(after)
+	revision.Changes = diffFields(fieldMap(before), state)
+	revision.State = state
+	revision.Actor = middleware.GetCaller(c).Name
+	if revision.Action == models.RevisionUpdate && len(revision.Changes) == 0 {
+		return
+	}
+
+	if _, err := models.Repository.Revision.Create(context.Background(), revision); err != nil {
+		log.Printf("Record %s revision %d of %s failed with error: %v", revision.Kind, revision.Version, revision.TargetID.Hex(), err)
+	}
+}
+
+// findRevision reads the revision requested by the rev path parameter and
+// decodes the editable fields it recorded into state. On failure the response
+// is written and false returned.
+func findRevision(appG app.Gin, kind string, targetID primitive.ObjectID, state interface{}) (int64, bool) {
+	rev, err := strconv.ParseInt(appG.C.Param("rev"), 10, 64)
+	if err != nil || rev < 1 {
+		appG.Response400(e.INVALID_PARAMS, "rev must be a positive integer")
+		return 0, false
+	}
+
+	revision, err := models.Repository.Revision.FindByVersion(context.Background(), kind, targetID, rev)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			appG.Response404(e.NOTFOUND, "Revision not exist")
+			return 0, false
+		}
+		appG.Response500(e.ERROR, "Get revision failed with err: "+err.Error())
+		return 0, false
+	}
+
+	data, err := json.Marshal(revision.State)
+	if err == nil {
+		err = json.Unmarshal(data, state)
+	}
+	if err != nil {
+		appG.Response500(e.ERROR, "Decode revision failed with err: "+err.Error())
+		return 0, false
+	}
+	// Validation rules may have changed since the revision was recorded
+	if err := utils.Validator.Struct(state); err != nil {
+		appG.Response400(e.INVALID_PARAMS, "Revision is not valid anymore: "+err.Error())
+		return 0, false
+	}
+	return rev, true
+}
+
+// GetTrackHistory godoc
+//
+//	@Summary		Get the history of a track
+//	@Description	List the revisions of a track, newest first: version produced, action, actor, time and changed fields
+//	@Tags			track
+//	@Accept			json
+//	@Produce		json
+//
+//	@Param			id		    path		string	true	"track id"
+//
+//	@Success		200				{object}	app.Response
+//	@Failure		404				{object}	app.Response
+//	@Failure		500				{object}	app.Response
+//	@Router			/tracks/{id}/history [get]
+func GetTrackHistory(c *gin.Context) {
+	appG := app.Gin{C: c}
+	trackID := c.Param("id")
+
+	// convert track_id string to objectID
+	objID, err := primitive.ObjectIDFromHex(trackID)
+	if err != nil {
+		appG.Response500(e.ERROR, "convert id string to objectID failed"+err.Error())
+		return
+	}
+
+	_, err = models.Repository.Track.FindByID(context.Background(), objID)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			appG.Response404(e.NOTFOUND, "Track not exist")
+			return
+		}
+		appG.Response500(e.ERROR, "Get track by id failed with err: "+err.Error())
+		return
+	}
+
+	revisions, err := models.Repository.Revision.FindByTarget(context.Background(), models.RevisionTrack, objID)
+	if err != nil {
+		appG.Response500(e.ERROR, "Get track history failed with err: "+err.Error())
+		return
+	}
+
+	appG.Response200(revisions)
+}
+
+// RestoreTrackRevision godoc
+//
+//	@Summary		Roll a track back to a revision
+//	@Description	Set the editable fields of a track back to their value after revision rev, recorded as a new revision
+//	@Tags			track
+//	@Accept			json
+//	@Produce		json
+//
+//	@Param			id		    path		string	true	"track id"
+//	@Param			rev		    path		int		true	"version produced by the revision"
+//	@Param			If-Match	header		string	true	"ETag of the record as last read"
+//
+//	@Success		200				{object}	app.Response
+//	@Failure		400				{object}	app.Response
+//	@Failure		404				{object}	app.Response
+//	@Failure		412				{object}	app.Response
+//	@Failure		428				{object}	app.Response
+//	@Failure		500				{object}	app.Response
+//	@Router			/tracks/{id}/history/{rev}/restore [post]
+func RestoreTrackRevision(c *gin.Context) {
+	appG := app.Gin{C: c}
+	trackID := c.Param("id")
+
+	// convert track_id string to objectID
+	objID, err := primitive.ObjectIDFromHex(trackID)
+	if err != nil {
+		appG.Response500(e.ERROR, "convert id string to objectID failed"+err.Error())
+		return
+	}
+
+	track, err := models.Repository.Track.FindByID(context.Background(), objID)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			appG.Response404(e.NOTFOUND, "Track not exist")
+			return
+		}
+		appG.Response500(e.ERROR, "Get track by id failed with err: "+err.Error())
+		return
+	}
+
+	if !checkIfMatch(appG, track.Version) {
+		return
+	}
+
+	var request dto.UpdateTrackRequest
+	rev, ok := findRevision(appG, models.RevisionTrack, objID, &request)
+	if !ok {
+		return
+	}
+
+	saveTrack(appG, track, request, &models.Revision{Action: models.RevisionRollback, RollbackTo: rev})
+}
+
+// GetPlaylistHistory godoc
+//
+//	@Summary		Get the history of a playlist
+//	@Description	List the revisions of a playlist, newest first: version produced, action, actor, time and changed fields
+//	@Tags			playlist
+//	@Accept			json
+//	@Produce		json
+//
+//	@Param			id		    path		string	true	"playlist id"
+//
+//	@Success		200				{object}	app.Response
+//	@Failure		404				{object}	app.Response
+//	@Failure		500				{object}	app.Response
+//	@Router			/playlists/{id}/history [get]
+func GetPlaylistHistory(c *gin.Context) {
+	appG := app.Gin{C: c}
+	playlistID := c.Param("id")
+
+	// convert playlist_id string to objectID
+	objID, err := primitive.ObjectIDFromHex(playlistID)
+	if err != nil {
+		appG.Response500(e.ERROR, "convert id string to objectID failed"+err.Error())
+		return
+	}
+
+	_, err = models.Repository.Playlist.FindByID(context.Background(), objID)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			appG.Response404(e.NOTFOUND, "Playlist not exist")
+			return
+		}
+		appG.Response500(e.ERROR, "Get playlist by id failed with err: "+err.Error())
+		return
+	}
+
+	revisions, err := models.Repository.Revision.FindByTarget(context.Background(), models.RevisionPlaylist, objID)
+	if err != nil {
+		appG.Response500(e.ERROR, "Get playlist history failed with err: "+err.Error())
+		return
+	}
+
+	appG.Response200(revisions)
+}
+
+// RestorePlaylistRevision godoc
+//
+//	@Summary		Roll a playlist back to a revision
+//	@Description	Set the title, album cover and track list of a playlist back to their value after revision rev, recorded as a new revision
+//	@Tags			playlist
+//	@Accept			json
+//	@Produce		json
+//
+//	@Param			id		    path		string	true	"playlist id"
+//	@Param			rev		    path		int		true	"version produced by the revision"
+//	@Param			If-Match	header		string	true	"ETag of the record as last read"
+//
+//	@Success		200				{object}	app.Response
+//	@Failure		400				{object}	app.Response
+//	@Failure		404				{object}	app.Response
+//	@Failure		412				{object}	app.Response
+//	@Failure		428				{object}	app.Response
+//	@Failure		500				{object}	app.Response
+//	@Router			/playlists/{id}/history/{rev}/restore [post]
+func RestorePlaylistRevision(c *gin.Context) {
+	appG := app.Gin{C: c}
+	playlistID := c.Param("id")
+
+	// convert playlist_id string to objectID
+	objID, err := primitive.ObjectIDFromHex(playlistID)
+	if err != nil {
+		appG.Response500(e.ERROR, "convert id string to objectID failed"+err.Error())
+		return
+	}
+
+	playlist, err := models.Repository.Playlist.FindByID(context.Background(), objID)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			appG.Response404(e.NOTFOUND, "Playlist not exist")
+			return
+		}
+		appG.Response500(e.ERROR, "Get playlist by id failed with err: "+err.Error())
+		return
+	}
+
+	if !checkIfMatch(appG, playlist.Version) {
+		return
+	}
+
+	var request dto.UpdatePlaylistRequest
+	rev, ok := findRevision(appG, models.RevisionPlaylist, objID, &request)
+	if !ok {
+		return
+	}
+
+	savePlaylist(appG, playlist, request, &models.Revision{Action: models.RevisionRollback, RollbackTo: rev})
+}
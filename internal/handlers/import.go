@@ -0,0 +1,341 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rolexkdev/emvn-music-library-server/common/app"
+	"github.com/rolexkdev/emvn-music-library-server/common/e"
+	"github.com/rolexkdev/emvn-music-library-server/common/utils"
+	"github.com/rolexkdev/emvn-music-library-server/dto"
+	"github.com/rolexkdev/emvn-music-library-server/internal/catalog"
+	"github.com/rolexkdev/emvn-music-library-server/internal/models"
+)
+
+// importItem is the planned write of an imported row
+type importItem struct {
+	row     *catalog.Row
+	result  *dto.ImportRow
+	request dto.UpdateTrackRequest
+	// Existing track the row updates, nil for a new track
+	track *models.Track
+}
+
+// validationErrors returns one message per failed field of err
+func validationErrors(err error) []string {
+	return strings.Split(strings.TrimSpace(err.Error()), "\n")
+}
+
+// trackMatcher finds the existing track an imported row describes
+type trackMatcher struct {
+	byISRC  map[string][]*models.Track
+	byTitle map[string][]*models.Track
+}
+
+func newTrackMatcher(tracks []*models.Track) *trackMatcher {
+	m := &trackMatcher{byISRC: map[string][]*models.Track{}, byTitle: map[string][]*models.Track{}}
+	for _, track := range tracks {
+		if track.ISRC != "" {
+			m.byISRC[track.ISRC] = append(m.byISRC[track.ISRC], track)
+		}
+		if track.SearchKeys != nil {
+			m.byTitle[track.SearchKeys.Title] = append(m.byTitle[track.SearchKeys.Title], track)
+		}
+	}
+	return m
+}
+
+// match returns the track with the ISRC of request, or else with its title and
+// artist id or name. A track with another ISRC is another recording.
+func (m *trackMatcher) match(request dto.UpdateTrackRequest) (*models.Track, string, error) {
+	if request.ISRC != "" {
+		tracks := m.byISRC[request.ISRC]
+		if len(tracks) > 1 {
+			return nil, "", fmt.Errorf("ISRC %s matches %d tracks", request.ISRC, len(tracks))
+		}
+		if len(tracks) == 1 {
+			return tracks[0], dto.MatchISRC, nil
+		}
+	}
+
+	if request.Title == "" || (request.ArtistID == "" && request.ArtistName == "") {
+		return nil, "", nil
+	}
+	artistName := utils.NormalizeSearchText(request.ArtistName)
+	matches := []*models.Track{}
+	for _, track := range m.byTitle[utils.NormalizeSearchText(request.Title)] {
+		if track.ISRC != "" && request.ISRC != "" {
+			continue
+		}
+		if (request.ArtistID != "" && track.ArtistID == request.ArtistID) ||
+			(artistName != "" && track.SearchKeys.ArtistName == artistName) {
+			matches = append(matches, track)
+		}
+	}
+	if len(matches) > 1 {
+		return nil, "", fmt.Errorf("title and artist match %d tracks, add the ISRC to tell them apart", len(matches))
+	}
+	if len(matches) == 1 {
+		return matches[0], dto.MatchTitleArtist, nil
+	}
+	return nil, "", nil
+}
+
+// planImport works out what importing rows does: every row creates a track,
+// updates the track it matches, leaves it unchanged or has errors
+func planImport(rows []*catalog.Row) ([]*importItem, error) {
+	items := make([]*importItem, len(rows))
+	isrcs := []string{}
+	titles := []string{}
+	for i, row := range rows {
+		item := &importItem{row: row, result: &dto.ImportRow{Line: row.Line}}
+		item.result.Errors = row.Apply(&item.request)
+		if item.request.ISRC != "" {
+			isrcs = append(isrcs, item.request.ISRC)
+		}
+		if item.request.Title != "" {
+			titles = append(titles, utils.NormalizeSearchText(item.request.Title))
+		}
+		items[i] = item
+	}
+
+	tracks, err := models.Repository.Track.FindMatching(context.Background(), isrcs, titles)
+	if err != nil {
+		return nil, err
+	}
+	matcher := newTrackMatcher(tracks)
+
+	// Two rows writing the same track would overwrite each other
+	seenTracks := map[string]int{}
+	seenRows := map[string]int{}
+	for _, item := range items {
+		result := item.result
+		if len(result.Errors) > 0 {
+			result.Action = dto.ImportError
+			continue
+		}
+
+		track, matchedBy, err := matcher.match(item.request)
+		if err != nil {
+			result.Action = dto.ImportError
+			result.Errors = []string{err.Error()}
+			continue
+		}
+
+		if track == nil {
+			key := "isrc:" + item.request.ISRC
+			if item.request.ISRC == "" {
+				key = utils.NormalizeSearchText(item.request.Title) + "|" + item.request.ArtistID + "|" + utils.NormalizeSearchText(item.request.ArtistName)
+			}
+			if line, ok := seenRows[key]; ok {
+				result.Action = dto.ImportError
+				result.Errors = []string{fmt.Sprintf("same track as line %d", line)}
+				continue
+			}
+			seenRows[key] = result.Line
+
+			if err := utils.Validator.Struct(dto.CreateTrackRequest(item.request)); err != nil {
+				result.Action = dto.ImportError
+				result.Errors = validationErrors(err)
+				continue
+			}
+			result.Action = dto.ImportCreate
+			continue
+		}
+
+		result.TrackID = track.ID.Hex()
+		result.MatchedBy = matchedBy
+		if line, ok := seenTracks[result.TrackID]; ok {
+			result.Action = dto.ImportError
+			result.Errors = []string{fmt.Sprintf("same track as line %d", line)}
+			continue
+		}
+		seenTracks[result.TrackID] = result.Line
+
+		// Columns left empty keep the value of the existing track
+		item.track = track
+		item.request = trackRequest(track)
+		item.row.Apply(&item.request)
+		if err := utils.Validator.Struct(item.request); err != nil {
+			result.Action = dto.ImportError
+			result.Errors = validationErrors(err)
+			continue
+		}
+		result.Changes = diffFields(fieldMap(trackRequest(track)), fieldMap(item.request))
+		if len(result.Changes) == 0 {
+			result.Action = dto.ImportUnchanged
+		} else {
+			result.Action = dto.ImportUpdate
+		}
+	}
+	return items, nil
+}
+
+// ImportTracks godoc
+//
+//	@Summary		Import tracks from a CSV file
+//	@Description	Read a CSV file whose first line holds the column headers. mapping gives the column of
+//	@Description	each field of dto.CreateTrackRequest, e.g. {"title": "Track Title", "isrc": "ISRC"}; fields
+//	@Description	left out are read from the column named like them. Rows update the track with their ISRC,
+//	@Description	or else with their title and artist, and create the others. Without commit the import is
+//	@Description	a dry run reporting the creates, updates and errors of every row. A commit writes nothing
+//	@Description	when a row has errors, and writes every row or none in a transaction, failing with 409
+//	@Description	when a track changed since it was matched and with 500 when a write failed otherwise.
+//	@Description	Without transactions (a standalone mongod) the rows are written in order up to the
+//	@Description	first failure, the report tells which ones were.
+//	@Tags			track
+//	@Accept			multipart/form-data
+//	@Produce		json
+//
+//	@Param			file			formData	file	true	"CSV file"
+//	@Param			mapping			formData	string	false	"JSON object of field to column header"
+//	@Param			delimiter		formData	string	false	"column delimiter, a comma by default"
+//	@Param			commit			formData	bool	false	"write the tracks, a dry run by default"
+//
+//	@Success		200				{object}	app.Response
+//	@Failure		400				{object}	app.Response
+//	@Failure		409				{object}	app.Response
+//	@Failure		500				{object}	app.Response
+//	@Router			/tracks/import [post]
+func ImportTracks(c *gin.Context) {
+	appG := app.Gin{C: c}
+
+	var request dto.ImportTracksRequest
+	if err := c.ShouldBind(&request); err != nil {
+		appG.Response400(e.INVALID_PARAMS, "Bind form failed: "+err.Error())
+		return
+	}
+
+	// Validate request input
+	if err := utils.Validator.Struct(request); err != nil {
+		appG.Response400(e.INVALID_PARAMS, "Validate form failed: "+err.Error())
+		return
+	}
+
+	mapping := catalog.Mapping{}
+	if request.Mapping != "" {
+		if err := json.Unmarshal([]byte(request.Mapping), &mapping); err != nil {
+			appG.Response400(e.INVALID_PARAMS, "mapping must be a JSON object of field to column header: "+err.Error())
+			return
+		}
+	}
+	delimiter, _ := utf8.DecodeRuneInString(request.Delimiter)
+	if request.Delimiter == "" {
+		delimiter = 0
+	}
+
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		appG.Response400(e.INVALID_PARAMS, "CSV file is required in the file field")
+		return
+	}
+	file, err := fileHeader.Open()
+	if err != nil {
+		appG.Response500(e.ERROR, "Error opening file")
+		return
+	}
+	defer file.Close()
+
+	rows, err := catalog.ReadCSV(file, delimiter, mapping)
+	if err != nil {
+		appG.Response400(e.INVALID_PARAMS, "Read CSV failed: "+err.Error())
+		return
+	}
+
+	items, err := planImport(rows)
+	if err != nil {
+		appG.Response500(e.ERROR, "Match imported tracks failed with err: "+err.Error())
+		return
+	}
+
+	report := dto.ImportReport{Rows: len(items), Results: make([]*dto.ImportRow, len(items))}
+	for i, item := range items {
+		report.Results[i] = item.result
+		switch item.result.Action {
+		case dto.ImportCreate:
+			report.Create++
+		case dto.ImportUpdate:
+			report.Update++
+		case dto.ImportUnchanged:
+			report.Unchanged++
+		default:
+			report.Errors++
+		}
+	}
+
+	if !request.Commit {
+		appG.Response200(report)
+		return
+	}
+	if report.Errors > 0 {
+		appG.Response400(e.INVALID_PARAMS, report)
+		return
+	}
+
+	writes := []*models.TrackWrite{}
+	written := []*importItem{}
+	for _, item := range items {
+		switch item.result.Action {
+		case dto.ImportCreate:
+			write := &models.TrackWrite{Op: models.BulkCreate, Track: &models.Track{}}
+			applyTrackRequest(write.Track, item.request)
+			writes = append(writes, write)
+		case dto.ImportUpdate:
+			write := &models.TrackWrite{Op: models.BulkUpdate, ID: item.track.ID, Version: item.track.Version, Track: &models.Track{ID: item.track.ID}}
+			applyTrackRequest(write.Track, item.request)
+			writes = append(writes, write)
+		default:
+			continue
+		}
+		written = append(written, item)
+	}
+
+	// A track changed since it was matched fails the whole import. A standalone
+	// mongod has no transactions, the rows are then written in order up to the
+	// first failure.
+	errs := []error{}
+	if len(writes) > 0 {
+		errs, err = models.Repository.Track.BulkWrite(context.Background(), writes, true, true)
+		if errors.Is(err, models.ErrNoTransactions) {
+			errs, err = models.Repository.Track.BulkWrite(context.Background(), writes, true, false)
+		}
+		if err != nil {
+			appG.Response500(e.ERROR, "Import tracks failed with err: "+err.Error())
+			return
+		}
+	}
+	conflict, failed := false, false
+	for i, write := range writes {
+		result := written[i].result
+		if err := errs[i]; err != nil {
+			result.Status = bulkFailure(err)
+			result.Errors = []string{err.Error()}
+			report.Errors++
+			switch {
+			case errors.Is(err, models.ErrVersionConflict):
+				conflict = true
+			case !errors.Is(err, models.ErrNotWritten):
+				failed = true
+			}
+			continue
+		}
+		result.Status, _ = trackWritten(c, write, written[i].track)
+		result.TrackID = write.ID.Hex()
+	}
+	if failed {
+		appG.Response500(e.ERROR, report)
+		return
+	}
+	if conflict {
+		appG.Response409(e.CONFLICT, report)
+		return
+	}
+
+	report.Committed = true
+	appG.Response200(report)
+}
@@ -0,0 +1,146 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rolexkdev/emvn-music-library-server/common/app"
+	"github.com/rolexkdev/emvn-music-library-server/common/e"
+	"github.com/rolexkdev/emvn-music-library-server/common/utils"
+	"github.com/rolexkdev/emvn-music-library-server/dto"
+	"github.com/rolexkdev/emvn-music-library-server/internal/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// GetJob godoc
+//
+//	@Summary		Get a job
+//	@Description	Get status and progress of a background processing job
+//	@Tags			job
+//	@Accept			json
+//	@Produce		json
+//
+//	@Param			id		    path		string	true	"job id"
+//
+//	@Success		200				{object}	app.Response
+//	@Failure		404				{object}	app.Response
+//	@Failure		500				{object}	app.Response
+//	@Router			/jobs/{id} [get]
+func GetJob(c *gin.Context) {
+	appG := app.Gin{C: c}
+	jobID := c.Param("id")
+
+	// convert job_id string to objectID
+	objID, err := primitive.ObjectIDFromHex(jobID)
+	if err != nil {
+		appG.Response500(e.ERROR, "convert id string to objectID failed"+err.Error())
+		return
+	}
+
+	job, err := models.Repository.Job.FindByID(context.Background(), objID)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			appG.Response404(e.NOTFOUND, "Job not exist")
+			return
+		}
+		appG.Response500(e.ERROR, "Get job by id failed with err: "+err.Error())
+		return
+	}
+
+	appG.Response200(job)
+}
+
+// GetJobs godoc
+//
+//	@Summary		Get list jobs
+//	@Description	Get the latest 100 jobs, e.g. every job of a track with target={track id} or the dead letters with status=dead
+//	@Tags			job
+//	@Accept			json
+//	@Produce		json
+//
+//	@Param			type		query		string	false	"job type"
+//	@Param			target		query		string	false	"track id or file name the job works on"
+//	@Param			status		query		string	false	"pending, running, succeeded or dead"
+//
+//	@Success		200				{object}	app.Response
+//	@Failure		400				{object}	app.Response
+//	@Failure		500				{object}	app.Response
+//	@Router			/jobs [get]
+func GetJobs(c *gin.Context) {
+	appG := app.Gin{C: c}
+
+	var request dto.JobFilterRequest
+	if err := c.BindQuery(&request); err != nil {
+		appG.Response400(e.INVALID_PARAMS, "Bind query failed: "+err.Error())
+		return
+	}
+
+	// Validate request input
+	if err := utils.Validator.Struct(request); err != nil {
+		appG.Response400(e.INVALID_PARAMS, "Validate query failed: "+err.Error())
+		return
+	}
+
+	jobs, err := models.Repository.Job.FindMany(context.Background(), &models.JobFilter{
+		Type:   request.Type,
+		Target: request.Target,
+		Status: models.JobStatus(request.Status),
+	})
+	if err != nil {
+		appG.Response500(e.ERROR, "Get jobs failed with err: "+err.Error())
+		return
+	}
+
+	appG.Response200(jobs)
+}
+
+// RetryJob godoc
+//
+//	@Summary		Retry a dead job
+//	@Description	Put a job that exhausted its attempts back in the queue
+//	@Tags			job
+//	@Accept			json
+//	@Produce		json
+//
+//	@Param			id		    path		string	true	"job id"
+//
+//	@Success		200				{object}	app.Response
+//	@Failure		404				{object}	app.Response
+//	@Failure		409				{object}	app.Response
+//	@Failure		500				{object}	app.Response
+//	@Router			/jobs/{id}/retry [post]
+func RetryJob(c *gin.Context) {
+	appG := app.Gin{C: c}
+	jobID := c.Param("id")
+
+	// convert job_id string to objectID
+	objID, err := primitive.ObjectIDFromHex(jobID)
+	if err != nil {
+		appG.Response500(e.ERROR, "convert id string to objectID failed"+err.Error())
+		return
+	}
+
+	err = models.Repository.Job.Requeue(context.Background(), objID)
+	if err != nil {
+		if errors.Is(err, models.ErrNotFound) {
+			appG.Response404(e.NOTFOUND, "Dead job not exist")
+			return
+		}
+		if errors.Is(err, models.ErrJobPending) {
+			appG.Response409(e.CONFLICT, "An identical job is already pending")
+			return
+		}
+		appG.Response500(e.ERROR, "Retry job failed with err: "+err.Error())
+		return
+	}
+
+	job, err := models.Repository.Job.FindByID(context.Background(), objID)
+	if err != nil {
+		appG.Response500(e.ERROR, "Get job failed with err: "+err.Error())
+		return
+	}
+
+	appG.Response200(job)
+}
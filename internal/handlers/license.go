@@ -0,0 +1,499 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rolexkdev/emvn-music-library-server/common/app"
+	"github.com/rolexkdev/emvn-music-library-server/common/e"
+	"github.com/rolexkdev/emvn-music-library-server/common/utils"
+	"github.com/rolexkdev/emvn-music-library-server/dto"
+	"github.com/rolexkdev/emvn-music-library-server/internal/licensing"
+	"github.com/rolexkdev/emvn-music-library-server/internal/models"
+	"github.com/rolexkdev/emvn-music-library-server/middleware"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// CreateLicenseRequest godoc
+//
+//	@Summary		Request a sync license
+//	@Description	Request a sync license of tracks or of the tracks of a playlist, for a media type, usage, territories and term. The tracks must be available in every territory, WW alone asks for a worldwide license. The response holds an estimate computed from the pricing rules, the final price comes with the quote.
+//	@Tags			license
+//	@Accept			json
+//	@Produce		json
+//
+//	@Param			X-API-Key	header	string	true	"API key"
+//	@Param			input		body		dto.CreateLicenseRequest	true	"Create License Request input"
+//
+//	@Success		201				{object}	app.Response
+//	@Failure		400				{object}	app.Response
+//	@Failure		403				{object}	app.Response
+//	@Failure		404				{object}	app.Response
+//	@Failure		500				{object}	app.Response
+//	@Router			/licenses/requests [post]
+func CreateLicenseRequest(c *gin.Context) {
+	appG := app.Gin{C: c}
+
+	var request dto.CreateLicenseRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		appG.Response400(e.INVALID_PARAMS, "Parse JSON body failed: "+err.Error())
+		return
+	}
+	if err := utils.Validator.Struct(request); err != nil {
+		appG.Response400(e.INVALID_PARAMS, "Validate JSON body failed: "+err.Error())
+		return
+	}
+	if request.PlaylistID != "" && len(request.TrackIDs) > 0 {
+		appG.Response400(e.INVALID_PARAMS, "Send either track_ids or playlist_id")
+		return
+	}
+	territories := normalizeTerritories(request.Territories)
+	if len(territories) > 1 && contains(territories, models.Worldwide) {
+		appG.Response400(e.INVALID_PARAMS, "Territory "+models.Worldwide+" stands for worldwide and cannot be combined with other territories")
+		return
+	}
+
+	trackIDs := request.TrackIDs
+	if request.PlaylistID != "" {
+		objID, err := primitive.ObjectIDFromHex(request.PlaylistID)
+		if err != nil {
+			appG.Response400(e.INVALID_PARAMS, "playlist_id must be a playlist id")
+			return
+		}
+		playlist, err := models.Repository.Playlist.FindByID(context.Background(), objID)
+		if err != nil {
+			if errors.Is(err, mongo.ErrNoDocuments) {
+				appG.Response404(e.NOTFOUND, "Playlist not exist")
+				return
+			}
+			appG.Response500(e.ERROR, "Get playlist by id failed with err: "+err.Error())
+			return
+		}
+		if len(playlist.TrackIDs) == 0 {
+			appG.Response400(e.INVALID_PARAMS, "Playlist has no tracks to license")
+			return
+		}
+		trackIDs = playlist.TrackIDs
+	}
+
+	tracks, err := licenseTracks(trackIDs)
+	if err != nil {
+		var missing *missingTrackError
+		if errors.As(err, &missing) {
+			appG.Response400(e.INVALID_PARAMS, err.Error())
+			return
+		}
+		appG.Response500(e.ERROR, "Get tracks failed with err: "+err.Error())
+		return
+	}
+	// Tracks hidden from the caller cannot be licensed by it either
+	availability := callerAvailability(c)
+	for _, track := range tracks {
+		if !availability.Allows(track) {
+			appG.Response400(e.INVALID_PARAMS, (&missingTrackError{ID: track.ID.Hex()}).Error())
+			return
+		}
+		for _, territory := range territories {
+			code := territory
+			if code == models.Worldwide {
+				code = ""
+			}
+			if !track.Availability.AllowsTerritory(code) {
+				appG.Response400(e.INVALID_PARAMS, fmt.Sprintf("Track %s is not available in %s", track.ID.Hex(), territory))
+				return
+			}
+		}
+	}
+
+	now := time.Now()
+	caller := middleware.GetCaller(c)
+	license := &models.LicenseRequest{
+		Status:    models.LicenseRequested,
+		Requester: caller.Name,
+		Contact: models.LicenseContact{
+			Name:    request.Contact.Name,
+			Email:   request.Contact.Email,
+			Company: request.Contact.Company,
+		},
+		Project:     request.Project,
+		Description: request.Description,
+		PlaylistID:  request.PlaylistID,
+		TrackIDs:    trackIDsOf(tracks),
+		MediaType:   request.MediaType,
+		Usage:       request.Usage,
+		Territories: territories,
+		TermMonths:  *request.TermMonths,
+		StartDate:   request.StartDate,
+		History: []*models.LicenseEvent{
+			{Status: models.LicenseRequested, Actor: caller.Name, At: now},
+		},
+	}
+	license.Estimate, err = licensing.Quote(license, tracks)
+	if err != nil {
+		appG.Response400(e.INVALID_PARAMS, err.Error())
+		return
+	}
+
+	licenseCreated, err := models.Repository.License.Create(context.Background(), license)
+	if err != nil {
+		appG.Response500(e.ERROR, "create license request failed with error: "+err.Error())
+		return
+	}
+
+	middleware.SetAuditTarget(c, licenseCreated.ID.Hex())
+	appG.Response201(licenseCreated)
+}
+
+// GetLicenseRequests godoc
+//
+//	@Summary		Get list license requests
+//	@Description	Get list license requests, newest first. Admins get every request, other callers the requests they made.
+//	@Tags			license
+//	@Accept			json
+//	@Produce		json
+//
+//	@Param			X-API-Key	header	string	true	"API key"
+//	@Param			status		query	string	false	"Only the requests in this status"	Enums(requested, quoted, approved, rejected, signed)
+//
+//	@Success		200				{object}	app.Response
+//	@Failure		400				{object}	app.Response
+//	@Failure		403				{object}	app.Response
+//	@Failure		500				{object}	app.Response
+//	@Router			/licenses/requests [get]
+func GetLicenseRequests(c *gin.Context) {
+	appG := app.Gin{C: c}
+
+	var request dto.LicenseFilterRequest
+	if err := c.BindQuery(&request); err != nil {
+		appG.Response400(e.INVALID_PARAMS, "Bind query failed: "+err.Error())
+		return
+	}
+	if err := utils.Validator.Struct(request); err != nil {
+		appG.Response400(e.INVALID_PARAMS, "Validate query failed: "+err.Error())
+		return
+	}
+
+	filter := &models.LicenseFilter{Status: models.LicenseStatus(request.Status)}
+	if !middleware.IsAdmin(c) {
+		filter.Requester = middleware.GetCaller(c).Name
+	}
+	licenses, err := models.Repository.License.FindMany(context.Background(), filter)
+	if err != nil {
+		appG.Response500(e.ERROR, "Get license requests failed with err: "+err.Error())
+		return
+	}
+
+	appG.Response200(licenses)
+}
+
+// GetLicenseRequest godoc
+//
+//	@Summary		Get license request by id
+//	@Description	Get license request by id with its estimate, quote and history
+//	@Tags			license
+//	@Accept			json
+//	@Produce		json
+//
+//	@Param			X-API-Key	header	string	true	"API key"
+//	@Param			id		path		string	true	"License request ID"
+//
+//	@Success		200				{object}	app.Response
+//	@Failure		403				{object}	app.Response
+//	@Failure		404				{object}	app.Response
+//	@Failure		500				{object}	app.Response
+//	@Router			/licenses/requests/{id} [get]
+func GetLicenseRequest(c *gin.Context) {
+	appG := app.Gin{C: c}
+
+	license, ok := findLicenseRequest(&appG)
+	if !ok {
+		return
+	}
+
+	appG.Response200(license)
+}
+
+// QuoteLicenseRequest godoc
+//
+//	@Summary		Quote a license request
+//	@Description	Price a requested or quoted license request with the pricing rules and send the quote, total replaces the computed total. The quote can be approved until it expires.
+//	@Tags			license
+//	@Accept			json
+//	@Produce		json
+//
+//	@Param			X-API-Key	header	string	true	"Admin API key"
+//	@Param			id		path		string	true	"License request ID"
+//	@Param			input	body		dto.QuoteLicenseRequest	true	"Quote License Request input"
+//
+//	@Success		200				{object}	app.Response
+//	@Failure		400				{object}	app.Response
+//	@Failure		403				{object}	app.Response
+//	@Failure		404				{object}	app.Response
+//	@Failure		409				{object}	app.Response
+//	@Failure		500				{object}	app.Response
+//	@Router			/licenses/requests/{id}/quote [post]
+func QuoteLicenseRequest(c *gin.Context) {
+	appG := app.Gin{C: c}
+
+	var request dto.QuoteLicenseRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		appG.Response400(e.INVALID_PARAMS, "Parse JSON body failed: "+err.Error())
+		return
+	}
+	if err := utils.Validator.Struct(request); err != nil {
+		appG.Response400(e.INVALID_PARAMS, "Validate JSON body failed: "+err.Error())
+		return
+	}
+
+	license, ok := findLicenseRequest(&appG)
+	if !ok {
+		return
+	}
+	if !models.CanTransition(license.Status, models.LicenseQuoted) {
+		appG.Response409(e.CONFLICT, "License request is "+string(license.Status)+", it cannot be quoted")
+		return
+	}
+
+	tracks, err := licenseTracks(license.TrackIDs)
+	if err != nil {
+		var missing *missingTrackError
+		if errors.As(err, &missing) {
+			appG.Response409(e.CONFLICT, err.Error())
+			return
+		}
+		appG.Response500(e.ERROR, "Get tracks failed with err: "+err.Error())
+		return
+	}
+	// The rules may have changed since the request was made
+	quote, err := licensing.Quote(license, tracks)
+	if err != nil {
+		appG.Response409(e.CONFLICT, err.Error())
+		return
+	}
+	now := time.Now()
+	if request.Total != nil {
+		licensing.Adjust(quote, *request.Total)
+	}
+	quote.Note = request.Note
+	quote.QuotedAt = now
+	quote.ValidUntil = now.Add(licensing.QuoteValidity())
+
+	event := &models.LicenseEvent{
+		Status: models.LicenseQuoted,
+		Actor:  middleware.GetCaller(c).Name,
+		At:     now,
+		Note:   request.Note,
+	}
+	if !transitionLicense(&appG, license, event, quote) {
+		return
+	}
+
+	appG.Response200(license)
+}
+
+// UpdateLicenseStatus godoc
+//
+//	@Summary		Approve, reject or sign a license request
+//	@Description	Move a license request along its workflow: requested, quoted, approved, signed, or rejected before it is approved. The requester or an admin approves an unexpired quote or rejects the request, only an admin marks an approved license signed.
+//	@Tags			license
+//	@Accept			json
+//	@Produce		json
+//
+//	@Param			X-API-Key	header	string	true	"API key"
+//	@Param			id		path		string	true	"License request ID"
+//	@Param			input	body		dto.LicenseStatusRequest	true	"License Status Request input"
+//
+//	@Success		200				{object}	app.Response
+//	@Failure		400				{object}	app.Response
+//	@Failure		403				{object}	app.Response
+//	@Failure		404				{object}	app.Response
+//	@Failure		409				{object}	app.Response
+//	@Failure		412				{object}	app.Response
+//	@Failure		500				{object}	app.Response
+//	@Router			/licenses/requests/{id}/status [post]
+func UpdateLicenseStatus(c *gin.Context) {
+	appG := app.Gin{C: c}
+
+	var request dto.LicenseStatusRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		appG.Response400(e.INVALID_PARAMS, "Parse JSON body failed: "+err.Error())
+		return
+	}
+	if err := utils.Validator.Struct(request); err != nil {
+		appG.Response400(e.INVALID_PARAMS, "Validate JSON body failed: "+err.Error())
+		return
+	}
+
+	license, ok := findLicenseRequest(&appG)
+	if !ok {
+		return
+	}
+	status := models.LicenseStatus(request.Status)
+	if status == models.LicenseSigned && !middleware.IsAdmin(c) {
+		appG.Response403(e.FORBIDDEN, "Signing a license requires an admin API key")
+		return
+	}
+	if !models.CanTransition(license.Status, status) {
+		appG.Response409(e.CONFLICT, "License request is "+string(license.Status)+", it cannot be "+string(status))
+		return
+	}
+	now := time.Now()
+	if status == models.LicenseApproved && now.After(license.Quote.ValidUntil) {
+		appG.Response412(e.PRECONDITION_FAILED, "Quote expired on "+license.Quote.ValidUntil.UTC().Format(time.RFC3339)+", ask for a new quote")
+		return
+	}
+
+	event := &models.LicenseEvent{
+		Status: status,
+		Actor:  middleware.GetCaller(c).Name,
+		At:     now,
+		Note:   request.Note,
+	}
+	if !transitionLicense(&appG, license, event, nil) {
+		return
+	}
+
+	appG.Response200(license)
+}
+
+// GetLicenseSummary godoc
+//
+//	@Summary		Get license summary
+//	@Description	Download the license summary document of a quoted license request as Markdown: grant, tracks with their rights holders, fee and history. It is marked as a draft until the license is signed.
+//	@Tags			license
+//	@Produce		text/markdown
+//
+//	@Param			X-API-Key	header	string	true	"API key"
+//	@Param			id		path		string	true	"License request ID"
+//
+//	@Success		200				{string}	string	"License summary"
+//	@Failure		403				{object}	app.Response
+//	@Failure		404				{object}	app.Response
+//	@Failure		409				{object}	app.Response
+//	@Failure		500				{object}	app.Response
+//	@Router			/licenses/requests/{id}/summary [get]
+func GetLicenseSummary(c *gin.Context) {
+	appG := app.Gin{C: c}
+
+	license, ok := findLicenseRequest(&appG)
+	if !ok {
+		return
+	}
+	if license.Quote == nil {
+		appG.Response409(e.CONFLICT, "License request is not quoted yet")
+		return
+	}
+
+	c.Writer.Header().Set("Content-Type", "text/markdown; charset=utf-8")
+	c.Writer.Header().Set("Content-Disposition", "attachment; filename=\"license-"+license.ID.Hex()+".md\"")
+	c.Status(http.StatusOK)
+	if err := licensing.WriteSummary(c.Writer, license); err != nil {
+		log.Printf("Write license summary %s failed with error: %v", license.ID.Hex(), err)
+	}
+}
+
+// findLicenseRequest returns the license request of the id path parameter.
+// Callers other than admins only find the requests they made, otherwise the
+// error response is written and ok is false.
+func findLicenseRequest(appG *app.Gin) (license *models.LicenseRequest, ok bool) {
+	objID, err := primitive.ObjectIDFromHex(appG.C.Param("id"))
+	if err != nil {
+		appG.Response500(e.ERROR, "convert id string to objectID failed"+err.Error())
+		return nil, false
+	}
+	license, err = models.Repository.License.FindByID(context.Background(), objID)
+	if err == nil && !middleware.IsAdmin(appG.C) && license.Requester != middleware.GetCaller(appG.C).Name {
+		err = mongo.ErrNoDocuments
+	}
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			appG.Response404(e.NOTFOUND, "License request not exist")
+			return nil, false
+		}
+		appG.Response500(e.ERROR, "Get license request by id failed with err: "+err.Error())
+		return nil, false
+	}
+	return license, true
+}
+
+// transitionLicense records event on license, otherwise the error response is
+// written and it returns false
+func transitionLicense(appG *app.Gin, license *models.LicenseRequest, event *models.LicenseEvent, quote *models.LicenseQuote) bool {
+	err := models.Repository.License.Transition(context.Background(), license, event, quote)
+	if err != nil {
+		if errors.Is(err, models.ErrVersionConflict) {
+			appG.Response409(e.CONFLICT, "License request changed meanwhile, get it and try again")
+			return false
+		}
+		appG.Response500(e.ERROR, "update license request failed with error: "+err.Error())
+		return false
+	}
+	return true
+}
+
+// missingTrackError reports a licensed track that does not exist
+type missingTrackError struct {
+	ID string
+}
+
+func (err *missingTrackError) Error() string {
+	return "Track " + err.ID + " not exist"
+}
+
+// licenseTracks returns the live tracks of ids in order, without duplicates.
+// It fails with a *missingTrackError when one of them does not exist.
+func licenseTracks(ids []string) ([]*models.Track, error) {
+	objIDs := make([]primitive.ObjectID, 0, len(ids))
+	for _, id := range ids {
+		objID, err := primitive.ObjectIDFromHex(id)
+		if err != nil {
+			return nil, &missingTrackError{ID: id}
+		}
+		objIDs = append(objIDs, objID)
+	}
+	found, err := models.Repository.Track.FindByIDs(context.Background(), objIDs)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[primitive.ObjectID]*models.Track, len(found))
+	for _, track := range found {
+		byID[track.ID] = track
+	}
+
+	tracks := make([]*models.Track, 0, len(found))
+	for _, objID := range objIDs {
+		track, ok := byID[objID]
+		if !ok {
+			return nil, &missingTrackError{ID: objID.Hex()}
+		}
+		if track != nil {
+			tracks = append(tracks, track)
+			// Later duplicates are skipped
+			byID[objID] = nil
+		}
+	}
+	return tracks, nil
+}
+
+func trackIDsOf(tracks []*models.Track) []string {
+	ids := make([]string, 0, len(tracks))
+	for _, track := range tracks {
+		ids = append(ids, track.ID.Hex())
+	}
+	return ids
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,63 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rolexkdev/emvn-music-library-server/common/app"
+	"github.com/rolexkdev/emvn-music-library-server/common/e"
+	"github.com/rolexkdev/emvn-music-library-server/common/utils"
+)
+
+// applyPatch applies the PATCH request body, a JSON Merge Patch or a JSON
+// Patch depending on its content type, to the JSON form of current and decodes
+// the validated result into patched. On failure the response is written and
+// false returned.
+func applyPatch(c *gin.Context, current, patched interface{}) bool {
+	appG := app.Gin{C: c}
+
+	patch, err := c.GetRawData()
+	if err != nil {
+		appG.Response400(e.INVALID_PARAMS, "Read body failed: "+err.Error())
+		return false
+	}
+	doc, err := json.Marshal(current)
+	if err != nil {
+		appG.Response500(e.ERROR, "Encode document failed: "+err.Error())
+		return false
+	}
+
+	switch c.ContentType() {
+	case utils.MergePatchContentType:
+		doc, err = utils.MergePatch(doc, patch)
+	case utils.JSONPatchContentType:
+		doc, err = utils.JSONPatch(doc, patch)
+	default:
+		appG.Response415(e.UNSUPPORTED_MEDIA_TYPE, "Content-Type must be "+utils.MergePatchContentType+" or "+utils.JSONPatchContentType)
+		return false
+	}
+	if errors.Is(err, utils.ErrPatchTestFailed) {
+		appG.Response409(e.CONFLICT, err.Error())
+		return false
+	}
+	if err != nil {
+		appG.Response400(e.INVALID_PARAMS, "Apply patch failed: "+err.Error())
+		return false
+	}
+
+	// The patched document must still be a valid request, members the model
+	// does not have are refused
+	decoder := json.NewDecoder(bytes.NewReader(doc))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(patched); err != nil {
+		appG.Response400(e.INVALID_PARAMS, "Patched document is invalid: "+err.Error())
+		return false
+	}
+	if err := utils.Validator.Struct(patched); err != nil {
+		appG.Response400(e.INVALID_PARAMS, "Validate patched document failed: "+err.Error())
+		return false
+	}
+	return true
+}
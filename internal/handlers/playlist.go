@@ -12,6 +12,7 @@ import (
 	"github.com/rolexkdev/emvn-music-library-server/common/utils"
 	"github.com/rolexkdev/emvn-music-library-server/dto"
 	"github.com/rolexkdev/emvn-music-library-server/internal/models"
+	"github.com/rolexkdev/emvn-music-library-server/middleware"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 )
@@ -59,6 +60,15 @@ func CreatePlaylist(c *gin.Context) {
 		return
 	}
 
+	recordRevision(c, &models.Revision{
+		Kind:     models.RevisionPlaylist,
+		TargetID: playlistCreated.ID,
+		Version:  playlistCreated.Version,
+		Action:   models.RevisionCreate,
+	}, nil, playlistRequest(playlistCreated))
+
+	middleware.SetAuditTarget(c, playlistCreated.ID.Hex())
+	c.Header("ETag", versionETag(playlistCreated.Version))
 	appG.Response201(playlistCreated)
 }
 
@@ -71,8 +81,10 @@ func CreatePlaylist(c *gin.Context) {
 //	@Produce		json
 //
 //	@Param			id		    path		string	true	"playlist id"
+//	@Param			If-None-Match	header		string	false	"ETag of the cached representation"
 //
 //	@Success		200				{object}	app.Response
+//	@Success		304				"not modified"
 //	@Failure		404				{object}	app.Response
 //	@Failure		500				{object}	app.Response
 //	@Router			/playlists/{id} [get]
@@ -98,6 +110,10 @@ func GetPlaylist(c *gin.Context) {
 		return
 	}
 
+	if notModified(c, versionETag(playlist.Version)) {
+		return
+	}
+
 	appG.Response200(playlist)
 }
 
@@ -109,8 +125,10 @@ func GetPlaylist(c *gin.Context) {
 //	@Accept			json
 //	@Produce		json
 //
+//	@Param			If-None-Match	header		string	false	"ETag of the cached representation"
 //
 //	@Success		200				{object}	app.Response
+//	@Success		304				"not modified"
 //	@Failure		500				{object}	app.Response
 //	@Router			/playlists/ [get]
 func GetPlaylists(c *gin.Context) {
@@ -122,6 +140,10 @@ func GetPlaylists(c *gin.Context) {
 		return
 	}
 
+	if notModified(c, listETag(playlists, func(p *models.Playlist) (primitive.ObjectID, int64) { return p.ID, p.Version })) {
+		return
+	}
+
 	appG.Response200(playlists)
 }
 
@@ -134,9 +156,12 @@ func GetPlaylists(c *gin.Context) {
 //	@Produce		json
 //
 //	@Param			id		    path		string	true	"playlist id"
+//	@Param			If-Match	header		string	true	"ETag of the record as last read"
 //
 //	@Success		200				{object}	app.Response
 //	@Success		400				{object}	app.Response
+//	@Failure		412				{object}	app.Response
+//	@Failure		428				{object}	app.Response
 //	@Failure		500				{object}	app.Response
 //	@Router			/playlists/{id} [delete]
 func DeletePlaylist(c *gin.Context) {
@@ -151,7 +176,7 @@ func DeletePlaylist(c *gin.Context) {
 	}
 	fmt.Println("objID: ", objID)
 
-	_, err = models.Repository.Playlist.FindByID(context.Background(), objID)
+	playlist, err := models.Repository.Playlist.FindByID(context.Background(), objID)
 	if err != nil {
 		if errors.Is(err, mongo.ErrNoDocuments) {
 			appG.Response404(e.NOTFOUND, "Playlist not exist")
@@ -161,28 +186,46 @@ func DeletePlaylist(c *gin.Context) {
 		return
 	}
 
-	err = models.Repository.Playlist.Delete(context.Background(), objID)
+	if !checkIfMatch(appG, playlist.Version) {
+		return
+	}
+
+	err = models.Repository.Playlist.Delete(context.Background(), objID, playlist.Version)
+	if errors.Is(err, models.ErrVersionConflict) {
+		appG.Response412(e.PRECONDITION_FAILED, "Playlist was modified, get it again and retry")
+		return
+	}
 	if err != nil {
 		appG.Response500(e.ERROR, "Delete a playlist by id failed with err: "+err.Error())
 		return
 	}
+	recordRevision(c, &models.Revision{
+		Kind:     models.RevisionPlaylist,
+		TargetID: playlist.ID,
+		Version:  playlist.Version + 1,
+		Action:   models.RevisionDelete,
+	}, playlistRequest(playlist), playlistRequest(playlist))
 
 	appG.Response200("success")
 }
 
 // UpdatePlaylist godoc
 //
-//	@Summary		Update a playlist by id
-//	@Description	Update a playlist by id
+//	@Summary		Replace a playlist by id
+//	@Description	Replace the title, album cover and track list of a playlist, omitted optional fields are cleared
 //	@Tags			playlist
 //	@Accept			json
 //	@Produce		json
 //
 //	@Param			id		    path		string	                     true	"playlist id"
 //	@Param			input		body		dto.UpdatePlaylistRequest	true	"Update Playlist Request input"
+//	@Param			If-Match	header		string	true	"ETag of the record as last read"
 //
 //	@Success		200				{object}	app.Response
+//	@Failure		400				{object}	app.Response
 //	@Failure		404				{object}	app.Response
+//	@Failure		412				{object}	app.Response
+//	@Failure		428				{object}	app.Response
 //	@Failure		500				{object}	app.Response
 //	@Router			/playlists/{id} [PUT]
 func UpdatePlaylist(c *gin.Context) {
@@ -220,42 +263,128 @@ func UpdatePlaylist(c *gin.Context) {
 		return
 	}
 
-	if request.AlbumCover != nil {
-		playlist.AlbumCover = *request.AlbumCover
+	if !checkIfMatch(appG, playlist.Version) {
+		return
 	}
 
-	if request.Title != nil {
-		playlist.Title = *request.Title
+	savePlaylist(appG, playlist, request, &models.Revision{Action: models.RevisionUpdate})
+}
+
+// PatchPlaylist godoc
+//
+//	@Summary		Partially update a playlist by id
+//	@Description	Update a playlist with a JSON Merge Patch (RFC 7396, Content-Type application/merge-patch+json)
+//	@Description	or a JSON Patch (RFC 6902, Content-Type application/json-patch+json) applied to the
+//	@Description	fields of dto.UpdatePlaylistRequest, e.g. [{"op":"add","path":"/track_ids/-","value":"{track id}"}]
+//	@Tags			playlist
+//	@Accept			application/merge-patch+json,application/json-patch+json
+//	@Produce		json
+//
+//	@Param			id		    path		string	true	"playlist id"
+//	@Param			input		body		object	true	"merge patch object or JSON Patch operations"
+//	@Param			If-Match	header		string	true	"ETag of the record as last read"
+//
+//	@Success		200				{object}	app.Response
+//	@Failure		400				{object}	app.Response
+//	@Failure		404				{object}	app.Response
+//	@Failure		409				{object}	app.Response
+//	@Failure		415				{object}	app.Response
+//	@Failure		412				{object}	app.Response
+//	@Failure		428				{object}	app.Response
+//	@Failure		500				{object}	app.Response
+//	@Router			/playlists/{id} [PATCH]
+func PatchPlaylist(c *gin.Context) {
+	appG := app.Gin{C: c}
+	playlistID := c.Param("id")
+
+	// convert playlist_id string to objectID
+	objID, err := primitive.ObjectIDFromHex(playlistID)
+	if err != nil {
+		appG.Response500(e.ERROR, "convert id string to objectID failed"+err.Error())
+		return
 	}
 
-	err = models.Repository.Playlist.Update(context.Background(), playlist)
+	playlist, err := models.Repository.Playlist.FindByID(context.Background(), objID)
 	if err != nil {
-		appG.Response500(e.ERROR, "Delete a playlist by id failed with err: "+err.Error())
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			appG.Response404(e.NOTFOUND, "Playlist not exist")
+			return
+		}
+		appG.Response500(e.ERROR, "Get playlist by id failed with err: "+err.Error())
+		return
+	}
+
+	if !checkIfMatch(appG, playlist.Version) {
+		return
+	}
+
+	var request dto.UpdatePlaylistRequest
+	if !applyPatch(c, playlistRequest(playlist), &request) {
+		return
+	}
+
+	savePlaylist(appG, playlist, request, &models.Revision{Action: models.RevisionUpdate})
+}
+
+// playlistRequest returns the editable fields of playlist
+func playlistRequest(playlist *models.Playlist) dto.UpdatePlaylistRequest {
+	return dto.UpdatePlaylistRequest{
+		Title:      playlist.Title,
+		AlbumCover: playlist.AlbumCover,
+		TrackIDs:   playlist.TrackIDs,
+	}
+}
+
+// savePlaylist replaces the editable fields of playlist with request, records
+// the change as revision and responds with the updated playlist
+func savePlaylist(appG app.Gin, playlist *models.Playlist, request dto.UpdatePlaylistRequest, revision *models.Revision) {
+	before := playlistRequest(playlist)
+	playlist.Title = request.Title
+	playlist.AlbumCover = request.AlbumCover
+	playlist.TrackIDs = request.TrackIDs
+
+	err := models.Repository.Playlist.Update(context.Background(), playlist)
+	if errors.Is(err, models.ErrVersionConflict) {
+		appG.Response412(e.PRECONDITION_FAILED, "Playlist was modified, get it again and retry")
+		return
+	}
+	if err != nil {
+		appG.Response500(e.ERROR, "Update a playlist by id failed with err: "+err.Error())
 		return
 	}
+	revision.Kind = models.RevisionPlaylist
+	revision.TargetID = playlist.ID
+	revision.Version = playlist.Version
+	recordRevision(appG.C, revision, before, request)
 
-	playlistUpdated, err := models.Repository.Playlist.FindByID(context.Background(), objID)
+	playlistUpdated, err := models.Repository.Playlist.FindByID(context.Background(), playlist.ID)
 	if err != nil {
 		appG.Response500(e.ERROR, "Get playlist updated failed with err: "+err.Error())
 		return
 	}
 
+	appG.C.Header("ETag", versionETag(playlistUpdated.Version))
 	appG.Response200(playlistUpdated)
 }
 
 // UpdatePlaylistTrack godoc
 //
 //	@Summary		Add or remove a track for playlist
-//	@Description	Add or remove a track for playlist
+//	@Description	Add or remove a track for playlist, only tracks available to the caller can be added
 //	@Tags			playlist
 //	@Accept			json
 //	@Produce		json
 //
 //	@Param			id		    path		string	                        true	"playlist id"
 //	@Param			input		body		dto.UpdatePlaylistTrackRequest	true	"Update Playlist Track Request input"
+//	@Param			If-Match	header		string	true	"ETag of the record as last read"
+//	@Param			X-Territory	header		string	false	"ISO 3166-1 alpha-2 territory of the caller, e.g. VN, unless set on the API key"
 //
 //	@Success		200				{object}	app.Response
+//	@Failure		400				{object}	app.Response
 //	@Failure		404				{object}	app.Response
+//	@Failure		412				{object}	app.Response
+//	@Failure		428				{object}	app.Response
 //	@Failure		500				{object}	app.Response
 //	@Router			/playlists/{id}/tracks [POST]
 func UpdatePlaylistTrack(c *gin.Context) {
@@ -293,37 +422,50 @@ func UpdatePlaylistTrack(c *gin.Context) {
 		return
 	}
 
-	if request.IsDelete != nil {
-		if *request.IsDelete {
-			utils.RemoveElementFromArray(&playlist.TrackIDs, request.TrackID)
-		} else {
-			playlist.TrackIDs = append(playlist.TrackIDs, request.TrackID)
-		}
+	if !checkIfMatch(appG, playlist.Version) {
+		return
 	}
 
-	err = models.Repository.Playlist.Update(context.Background(), playlist)
-	if err != nil {
-		appG.Response500(e.ERROR, "Delete a playlist by id failed with err: "+err.Error())
-		return
+	// Only tracks the caller may play can be added
+	if !*request.IsDelete {
+		trackObjID, err := primitive.ObjectIDFromHex(request.TrackID)
+		if err != nil {
+			appG.Response400(e.INVALID_PARAMS, "convert track id string to objectID failed: "+err.Error())
+			return
+		}
+		track, err := models.Repository.Track.FindByID(context.Background(), trackObjID)
+		if errors.Is(err, mongo.ErrNoDocuments) || (err == nil && !callerAvailability(c).Allows(track)) {
+			appG.Response404(e.NOTFOUND, "Track not exist")
+			return
+		}
+		if err != nil {
+			appG.Response500(e.ERROR, "Get track by id failed with err: "+err.Error())
+			return
+		}
 	}
 
-	playlistUpdated, err := models.Repository.Playlist.FindByID(context.Background(), objID)
-	if err != nil {
-		appG.Response500(e.ERROR, "Get playlist updated failed with err: "+err.Error())
-		return
+	update := playlistRequest(playlist)
+	update.TrackIDs = append([]string(nil), playlist.TrackIDs...)
+	if request.IsDelete != nil {
+		if *request.IsDelete {
+			utils.RemoveElementFromArray(&update.TrackIDs, request.TrackID)
+		} else {
+			update.TrackIDs = append(update.TrackIDs, request.TrackID)
+		}
 	}
 
-	appG.Response200(playlistUpdated)
+	savePlaylist(appG, playlist, update, &models.Revision{Action: models.RevisionUpdate})
 }
 
 // GenerateM3UPlaylist godoc
 //
 //	@Summary		Generate M3U playlist
-//	@Description	Generate M3U playlist
+//	@Description	Generate M3U playlist of the tracks available to the caller
 //	@Tags			playlist
 //	@Produce		audio/x-mpegurl
 //
 //	@Param			id	path	string	true	"Playlist ID"
+//	@Param			X-Territory	header	string	false	"ISO 3166-1 alpha-2 territory of the caller, e.g. VN, unless set on the API key"
 //
 //	@Success		200	            {string}	string	"M3U playlist"
 //	@Failure		400				{object}	app.Response
@@ -356,6 +498,8 @@ func GenerateM3UPlaylist(c *gin.Context) {
 
 	var m3uContent string
 
+	// Tracks the caller may not play are left out
+	availability := callerAvailability(c)
 	for _, trackID := range playlist.TrackIDs {
 		// convert track_id string to objectID
 		objID, err := primitive.ObjectIDFromHex(trackID)
@@ -366,7 +510,7 @@ func GenerateM3UPlaylist(c *gin.Context) {
 		track, err := models.Repository.Track.FindByID(context.Background(), objID)
 		if err != nil {
 			log.Printf("Get track by id: %s failed with error: %v", trackID, err)
-		} else {
+		} else if availability.Allows(track) {
 			m3uContent += track.FileURL + "\n"
 		}
 
@@ -2,26 +2,96 @@ package handlers
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/rolexkdev/emvn-music-library-server/common/app"
 	"github.com/rolexkdev/emvn-music-library-server/common/e"
+	"github.com/rolexkdev/emvn-music-library-server/common/utils"
 	"github.com/rolexkdev/emvn-music-library-server/dto"
 	"github.com/rolexkdev/emvn-music-library-server/internal/models"
+	"github.com/rolexkdev/emvn-music-library-server/internal/search"
+	"github.com/rolexkdev/emvn-music-library-server/middleware"
+	"go.mongodb.org/mongo-driver/mongo"
 )
 
+// Number of suggestions returned when the client does not ask
+const defaultSuggestLimit = 8
+
+// Number of results per type returned when the client does not ask
+const defaultSearchLimit = 20
+
+// searchPage reads the result types and the page requested. A cursor continues
+// the results of a single type.
+func searchPage(req dto.SearchRequest) ([]string, search.Page, error) {
+	page := search.Page{Limit: defaultSearchLimit}
+	if req.Limit > 0 {
+		page.Limit = req.Limit
+	}
+	if page.Limit > search.MaxResults {
+		return nil, page, fmt.Errorf("limit must not exceed %d", search.MaxResults)
+	}
+
+	types, err := search.ParseTypes(req.Type)
+	if err != nil {
+		return nil, page, err
+	}
+	if req.Cursor != "" {
+		if len(types) != 1 {
+			return nil, page, errors.New("cursor requires a single type")
+		}
+		page.Offset, err = search.DecodeCursor(req.Cursor, types[0])
+		if err != nil {
+			return nil, page, err
+		}
+	}
+	return types, page, nil
+}
+
 // Search godoc
 //
 //	@Summary		Search tracks and playlist
-//	@Description	Search tracks and playlist
+//	@Description	Full-text search of tracks and playlists ranked by relevance.
+//	@Description	Track matches in the title weigh more than in artist name, album and genre.
+//	@Description	The query is taken literally unless an admin sets mode=regex, the pattern then
+//	@Description	uses the RE2 syntax without nested repetitions.
+//	@Description	mode=advanced parses a query language, e.g. genre:pop artist:"Hoang Dung" year:2019..2021 duration:<240 -remix
+//	@Description	With fuzzy=true close matches tolerating typos follow the exact ones.
+//	@Description	Facets count the matching tracks by genre, release year, duration and artist.
+//	@Description	Every result type is paged on its own with a total and a next_cursor, pass
+//	@Description	the cursor back with the same type to get the following page.
 //	@Tags			search
 //	@Accept			json
 //	@Produce		json
 //
 //	@Param			query		    query		string	true	"search string"
+//	@Param			mode			query		string	false	"text (default), advanced or regex, regex requires an admin API key"
+//	@Param			fuzzy			query		bool	false	"also return matches with typos"
+//	@Param			type			query		string	false	"comma separated result types: tracks, playlists, albums, artists (default all)"
+//	@Param			limit			query		int		false	"results per type, default 20, at most SEARCH_MAX_RESULTS"
+//	@Param			cursor			query		string	false	"next_cursor of the previous page, requires a single type"
+//	@Param			min_bpm			query		number	false	"minimum tempo"
+//	@Param			max_bpm			query		number	false	"maximum tempo"
+//	@Param			key				query		string	false	"musical key, e.g. A minor, Am, Bb"
+//	@Param			min_loudness	query		number	false	"minimum integrated loudness (LUFS)"
+//	@Param			max_loudness	query		number	false	"maximum integrated loudness (LUFS)"
+//	@Param			genre			query		[]string	false	"genres, repeat for several"	collectionFormat(multi)
+//	@Param			year			query		[]int		false	"release years, repeat for several"	collectionFormat(multi)
+//	@Param			duration		query		[]string	false	"duration buckets: under_2m, 2m_4m, 4m_6m, over_6m"	collectionFormat(multi)
+//	@Param			artist_id		query		[]string	false	"artist ids, repeat for several"	collectionFormat(multi)
+//	@Param			label			query		[]string	false	"labels, repeat for several"	collectionFormat(multi)
+//	@Param			master_owner	query		[]string	false	"master owners, repeat for several"	collectionFormat(multi)
+//	@Param			writer			query		[]string	false	"writers in the publishing splits, repeat for several"	collectionFormat(multi)
+//	@Param			publisher		query		[]string	false	"publishers in the publishing splits, repeat for several"	collectionFormat(multi)
+//	@Param			isrc			query		string	false	"ISRC, separators allowed"
+//	@Param			iswc			query		string	false	"ISWC, separators allowed, e.g. T-034.524.680-1"
+//	@Param			X-Territory	header		string	false	"ISO 3166-1 alpha-2 territory of the caller, e.g. VN, unless set on the API key"
 //
 //	@Success		200				{object}	app.Response
 //	@Failure		400				{object}	app.Response
+//	@Failure		403				{object}	app.Response
 //	@Failure		500				{object}	app.Response
 //	@Router			/search [get]
 func Search(c *gin.Context) {
@@ -32,20 +102,118 @@ func Search(c *gin.Context) {
 		return
 	}
 
-	tracks, err := models.Repository.Track.Search(context.Background(), req.Query)
+	if err := utils.Validator.Struct(req); err != nil {
+		appG.Response400(e.INVALID_PARAMS, err.Error())
+		return
+	}
+
+	mode, err := search.ParseMode(req.Mode)
+	if err != nil {
+		appG.Response400(e.INVALID_PARAMS, err.Error())
+		return
+	}
+	if mode == search.ModeRegex && !middleware.IsAdmin(c) {
+		appG.Response403(e.FORBIDDEN, "Regex search requires an admin API key")
+		return
+	}
+
+	query := search.Query{Text: strings.TrimSpace(req.Query), Mode: mode, Fuzzy: req.Fuzzy}
+	if err := query.Validate(); err != nil {
+		appG.Response400(e.INVALID_PARAMS, err.Error())
+		return
+	}
+
+	filter, err := trackFilterFromRequest(req.TrackFilterRequest)
+	if err != nil {
+		appG.Response400(e.INVALID_PARAMS, err.Error())
+		return
+	}
+	filter.Availability = callerAvailability(c)
+
+	types, page, err := searchPage(req)
 	if err != nil {
-		appG.Response500(e.ERROR, "Search tracks failed"+err.Error())
+		appG.Response400(e.INVALID_PARAMS, err.Error())
 		return
 	}
 
-	playlists, err := models.Repository.Playlist.Search(context.Background(), req.Query)
+	ctx := context.Background()
+	var resp dto.SearchResponse
+	for _, resultType := range types {
+		switch resultType {
+		case search.TypeTracks:
+			resp.Tracks, err = search.Default.SearchTracks(ctx, query, filter, page)
+			if err == nil {
+				resp.Facets, err = search.Default.Facets(ctx, query, filter)
+			}
+		case search.TypePlaylists:
+			resp.Playlists, err = search.Default.SearchPlaylists(ctx, query, page)
+		case search.TypeAlbums:
+			resp.Albums, err = search.Default.SearchAlbums(ctx, query, filter, page)
+		case search.TypeArtists:
+			resp.Artists, err = search.Default.SearchArtists(ctx, query, filter, page)
+		}
+		if errors.Is(err, search.ErrInvalidQuery) {
+			appG.Response400(e.INVALID_PARAMS, err.Error())
+			return
+		}
+		if err != nil {
+			appG.Response500(e.ERROR, "Search "+resultType+" failed"+err.Error())
+			return
+		}
+	}
+
+	appG.Response200(resp)
+}
+
+// Suggest godoc
+//
+//	@Summary		Search-as-you-type suggestions
+//	@Description	Complete the typed prefix with track titles, artist names, albums and playlist titles.
+//	@Description	Accents and case are ignored, a word of the suggestion starts with the prefix.
+//	@Description	Track values only come from the tracks available to the caller.
+//	@Tags			search
+//	@Accept			json
+//	@Produce		json
+//
+//	@Param			q		query		string	true	"prefix typed by the user, at least 2 characters"
+//	@Param			limit	query		int		false	"number of suggestions, 1 to 20, default 8"
+//	@Param			X-Territory	header	string	false	"ISO 3166-1 alpha-2 territory of the caller, e.g. VN, unless set on the API key"
+//
+//	@Success		200				{object}	app.Response
+//	@Failure		400				{object}	app.Response
+//	@Failure		500				{object}	app.Response
+//	@Router			/search/suggest [get]
+func Suggest(c *gin.Context) {
+	appG := app.Gin{C: c}
+	var req dto.SuggestRequest
+	if err := c.BindQuery(&req); err != nil {
+		appG.Response400(e.INVALID_PARAMS, "Bind query failed"+err.Error())
+		return
+	}
+	if err := utils.Validator.Struct(req); err != nil {
+		appG.Response400(e.INVALID_PARAMS, "Validate query failed"+err.Error())
+		return
+	}
+	if req.Limit == 0 {
+		req.Limit = defaultSuggestLimit
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), search.SuggestTimeout)
+	defer cancel()
+
+	suggestions, err := search.Default.Suggest(ctx, req.Query, &models.TrackFilter{Availability: callerAvailability(c)}, req.Limit)
+	if errors.Is(err, context.DeadlineExceeded) || mongo.IsTimeout(err) {
+		// Out of budget, the next keystroke will ask again
+		suggestions, err = []*search.Suggestion{}, nil
+	}
+	if errors.Is(err, search.ErrInvalidQuery) {
+		appG.Response400(e.INVALID_PARAMS, err.Error())
+		return
+	}
 	if err != nil {
-		appG.Response500(e.ERROR, "Search playlist failed"+err.Error())
+		appG.Response500(e.ERROR, "Suggest failed"+err.Error())
 		return
 	}
 
-	appG.Response200(dto.SearchResponse{
-		Tracks:    tracks,
-		Playlists: playlists,
-	})
+	appG.Response200(dto.SuggestResponse{Suggestions: suggestions})
 }
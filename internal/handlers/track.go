@@ -4,13 +4,17 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"log"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/rolexkdev/emvn-music-library-server/common/app"
 	"github.com/rolexkdev/emvn-music-library-server/common/e"
 	"github.com/rolexkdev/emvn-music-library-server/common/utils"
 	"github.com/rolexkdev/emvn-music-library-server/dto"
+	"github.com/rolexkdev/emvn-music-library-server/internal/jobs"
 	"github.com/rolexkdev/emvn-music-library-server/internal/models"
+	"github.com/rolexkdev/emvn-music-library-server/middleware"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 )
@@ -46,16 +50,8 @@ func CreateTrack(c *gin.Context) {
 		return
 	}
 
-	track := &models.Track{
-		Name:        request.Name,
-		Title:       request.Title,
-		ArtistID:    request.ArtistID,
-		Album:       request.Album,
-		Genre:       request.Genre,
-		ReleaseDate: request.ReleaseDate,
-		Duration:    request.Duration,
-		FileURL:     request.FileURL,
-	}
+	track := &models.Track{}
+	applyTrackRequest(track, dto.UpdateTrackRequest(request))
 
 	trackCreated, err := models.Repository.Track.Create(context.Background(), track)
 	if err != nil {
@@ -63,20 +59,33 @@ func CreateTrack(c *gin.Context) {
 		return
 	}
 
+	enqueueTrackProcessing(trackCreated)
+	recordRevision(c, &models.Revision{
+		Kind:     models.RevisionTrack,
+		TargetID: trackCreated.ID,
+		Version:  trackCreated.Version,
+		Action:   models.RevisionCreate,
+	}, nil, trackRequest(trackCreated))
+
+	middleware.SetAuditTarget(c, trackCreated.ID.Hex())
+	c.Header("ETag", versionETag(trackCreated.Version))
 	appG.Response201(trackCreated)
 }
 
 // GetTrack godoc
 //
 //	@Summary		Get a track
-//	@Description	Get a track
+//	@Description	Get a track available to the caller
 //	@Tags			track
 //	@Accept			json
 //	@Produce		json
 //
 //	@Param			id		    path		string	true	"track id"
+//	@Param			X-Territory	header		string	false	"ISO 3166-1 alpha-2 territory of the caller, e.g. VN, unless set on the API key"
+//	@Param			If-None-Match	header		string	false	"ETag of the cached representation"
 //
 //	@Success		200				{object}	app.Response
+//	@Success		304				"not modified"
 //	@Failure		404				{object}	app.Response
 //	@Failure		500				{object}	app.Response
 //	@Router			/tracks/{id} [get]
@@ -101,6 +110,15 @@ func GetTrack(c *gin.Context) {
 		appG.Response500(e.ERROR, "Get track by id failed with err: "+err.Error())
 		return
 	}
+	// Tracks the caller may not play are hidden like missing ones
+	if !callerAvailability(c).Allows(track) {
+		appG.Response404(e.NOTFOUND, "Track not exist")
+		return
+	}
+
+	if notModified(c, versionETag(track.Version)) {
+		return
+	}
 
 	appG.Response200(track)
 }
@@ -113,19 +131,55 @@ func GetTrack(c *gin.Context) {
 //	@Accept			json
 //	@Produce		json
 //
+//	@Param			min_bpm			query		number	false	"minimum tempo"
+//	@Param			max_bpm			query		number	false	"maximum tempo"
+//	@Param			key				query		string	false	"musical key, e.g. A minor, Am, Bb"
+//	@Param			min_loudness	query		number	false	"minimum integrated loudness (LUFS)"
+//	@Param			max_loudness	query		number	false	"maximum integrated loudness (LUFS)"
+//	@Param			genre			query		[]string	false	"genres, repeat for several"	collectionFormat(multi)
+//	@Param			year			query		[]int		false	"release years, repeat for several"	collectionFormat(multi)
+//	@Param			duration		query		[]string	false	"duration buckets: under_2m, 2m_4m, 4m_6m, over_6m"	collectionFormat(multi)
+//	@Param			artist_id		query		[]string	false	"artist ids, repeat for several"	collectionFormat(multi)
+//	@Param			label			query		[]string	false	"labels, repeat for several"	collectionFormat(multi)
+//	@Param			master_owner	query		[]string	false	"master owners, repeat for several"	collectionFormat(multi)
+//	@Param			writer			query		[]string	false	"writers in the publishing splits, repeat for several"	collectionFormat(multi)
+//	@Param			publisher		query		[]string	false	"publishers in the publishing splits, repeat for several"	collectionFormat(multi)
+//	@Param			isrc			query		string	false	"ISRC, separators allowed"
+//	@Param			iswc			query		string	false	"ISWC, separators allowed, e.g. T-034.524.680-1"
+//	@Param			X-Territory	header		string	false	"ISO 3166-1 alpha-2 territory of the caller, e.g. VN, unless set on the API key"
+//	@Param			If-None-Match	header		string	false	"ETag of the cached representation"
 //
 //	@Success		200				{object}	app.Response
+//	@Success		304				"not modified"
+//	@Failure		400				{object}	app.Response
 //	@Failure		500				{object}	app.Response
 //	@Router			/tracks/ [get]
 func GetTracks(c *gin.Context) {
 	appG := app.Gin{C: c}
 
-	tracks, err := models.Repository.Track.FindMany(context.Background())
+	var request dto.TrackFilterRequest
+	if err := c.BindQuery(&request); err != nil {
+		appG.Response400(e.INVALID_PARAMS, "Bind query failed: "+err.Error())
+		return
+	}
+
+	filter, err := trackFilterFromRequest(request)
+	if err != nil {
+		appG.Response400(e.INVALID_PARAMS, err.Error())
+		return
+	}
+	filter.Availability = callerAvailability(c)
+
+	tracks, err := models.Repository.Track.FindMany(context.Background(), filter)
 	if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
 		appG.Response500(e.ERROR, "Get track by id failed with err: "+err.Error())
 		return
 	}
 
+	if notModified(c, listETag(tracks, func(t *models.Track) (primitive.ObjectID, int64) { return t.ID, t.Version })) {
+		return
+	}
+
 	appG.Response200(tracks)
 }
 
@@ -138,9 +192,12 @@ func GetTracks(c *gin.Context) {
 //	@Produce		json
 //
 //	@Param			id		    path		string	true	"track id"
+//	@Param			If-Match	header		string	true	"ETag of the record as last read"
 //
 //	@Success		200				{object}	app.Response
 //	@Success		404				{object}	app.Response
+//	@Failure		412				{object}	app.Response
+//	@Failure		428				{object}	app.Response
 //	@Failure		500				{object}	app.Response
 //	@Router			/tracks/{id} [delete]
 func DeleteTrack(c *gin.Context) {
@@ -155,7 +212,7 @@ func DeleteTrack(c *gin.Context) {
 	}
 	fmt.Println("objID: ", objID)
 
-	_, err = models.Repository.Track.FindByID(context.Background(), objID)
+	track, err := models.Repository.Track.FindByID(context.Background(), objID)
 	if err != nil {
 		if errors.Is(err, mongo.ErrNoDocuments) {
 			appG.Response404(e.NOTFOUND, "Track not exist")
@@ -165,27 +222,45 @@ func DeleteTrack(c *gin.Context) {
 		return
 	}
 
-	err = models.Repository.Track.Delete(context.Background(), objID)
+	if !checkIfMatch(appG, track.Version) {
+		return
+	}
+
+	err = models.Repository.Track.Delete(context.Background(), objID, track.Version)
+	if errors.Is(err, models.ErrVersionConflict) {
+		appG.Response412(e.PRECONDITION_FAILED, "Track was modified, get it again and retry")
+		return
+	}
 	if err != nil {
 		appG.Response500(e.ERROR, "Delete a track by id failed with err: "+err.Error())
 		return
 	}
+	recordRevision(c, &models.Revision{
+		Kind:     models.RevisionTrack,
+		TargetID: track.ID,
+		Version:  track.Version + 1,
+		Action:   models.RevisionDelete,
+	}, trackRequest(track), trackRequest(track))
 
 	appG.Response200("success")
 }
 
 // UpdateTrack godoc
 //
-//	@Summary		Update a track by id
-//	@Description	Update a track by id
+//	@Summary		Replace a track by id
+//	@Description	Replace every editable field of a track, omitted optional fields are cleared
 //	@Tags			track
 //	@Accept			json
 //	@Produce		json
 //
 //	@Param			id		    path		string	true	"track id"
 //	@Param			input		    body		dto.UpdateTrackRequest	true	"Update Track Request input"
+//	@Param			If-Match	header		string	true	"ETag of the record as last read"
 //
 //	@Success		200				{object}	app.Response
+//	@Failure		400				{object}	app.Response
+//	@Failure		412				{object}	app.Response
+//	@Failure		428				{object}	app.Response
 //	@Failure		500				{object}	app.Response
 //	@Router			/tracks/{id} [PUT]
 func UpdateTrack(c *gin.Context) {
@@ -223,41 +298,183 @@ func UpdateTrack(c *gin.Context) {
 		return
 	}
 
-	if request.Name != nil {
-		track.Name = *request.Name
+	if !checkIfMatch(appG, track.Version) {
+		return
 	}
 
-	if request.Album != nil {
-		track.Album = *request.Album
+	saveTrack(appG, track, request, &models.Revision{Action: models.RevisionUpdate})
+}
+
+// PatchTrack godoc
+//
+//	@Summary		Partially update a track by id
+//	@Description	Update a track with a JSON Merge Patch (RFC 7396, Content-Type application/merge-patch+json)
+//	@Description	or a JSON Patch (RFC 6902, Content-Type application/json-patch+json) applied to the
+//	@Description	fields of dto.UpdateTrackRequest. A merge patch member set to null clears the field.
+//	@Tags			track
+//	@Accept			application/merge-patch+json,application/json-patch+json
+//	@Produce		json
+//
+//	@Param			id		    path		string	true	"track id"
+//	@Param			input		    body		object	true	"merge patch object or JSON Patch operations"
+//	@Param			If-Match	header		string	true	"ETag of the record as last read"
+//
+//	@Success		200				{object}	app.Response
+//	@Failure		400				{object}	app.Response
+//	@Failure		404				{object}	app.Response
+//	@Failure		409				{object}	app.Response
+//	@Failure		415				{object}	app.Response
+//	@Failure		412				{object}	app.Response
+//	@Failure		428				{object}	app.Response
+//	@Failure		500				{object}	app.Response
+//	@Router			/tracks/{id} [PATCH]
+func PatchTrack(c *gin.Context) {
+	appG := app.Gin{C: c}
+	trackID := c.Param("id")
+
+	// convert track_id string to objectID
+	objID, err := primitive.ObjectIDFromHex(trackID)
+	if err != nil {
+		appG.Response500(e.ERROR, "convert id string to objectID failed"+err.Error())
+		return
+	}
+
+	track, err := models.Repository.Track.FindByID(context.Background(), objID)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			appG.Response404(e.NOTFOUND, "Track not exist")
+			return
+		}
+		appG.Response500(e.ERROR, "Get track by id failed with err: "+err.Error())
+		return
 	}
 
-	if request.ArtistID != nil {
-		track.ArtistID = *request.ArtistID
+	if !checkIfMatch(appG, track.Version) {
+		return
+	}
+
+	var request dto.UpdateTrackRequest
+	if !applyPatch(c, trackRequest(track), &request) {
+		return
 	}
 
-	if request.Title != nil {
-		track.Title = *request.Title
+	saveTrack(appG, track, request, &models.Revision{Action: models.RevisionUpdate})
+}
+
+// trackRequest returns the editable fields of track
+func trackRequest(track *models.Track) dto.UpdateTrackRequest {
+	return dto.UpdateTrackRequest{
+		Name:         track.Name,
+		Title:        track.Title,
+		ArtistID:     track.ArtistID,
+		ArtistName:   track.ArtistName,
+		Album:        track.Album,
+		Genre:        track.Genre,
+		ReleaseDate:  track.ReleaseDate,
+		Duration:     track.Duration,
+		FileURL:      track.FileURL,
+		ISRC:         track.ISRC,
+		ISWC:         track.ISWC,
+		PLine:        track.PLine,
+		CLine:        track.CLine,
+		Label:        track.Label,
+		MasterOwner:  track.MasterOwner,
+		Publishing:   publishingRequest(track.Publishing),
+		Availability: availabilityRequest(track.Availability),
+		Tier:         track.Tier,
 	}
+}
+
+// applyTrackRequest sets the editable fields of track to request
+func applyTrackRequest(track *models.Track, request dto.UpdateTrackRequest) {
+	track.Name = request.Name
+	track.Title = request.Title
+	track.ArtistID = request.ArtistID
+	track.ArtistName = request.ArtistName
+	track.Album = request.Album
+	track.Genre = request.Genre
+	track.ReleaseDate = request.ReleaseDate
+	track.Duration = request.Duration
+	track.FileURL = request.FileURL
+	track.ISRC = utils.NormalizeISRC(request.ISRC)
+	track.ISWC = utils.NormalizeISWC(request.ISWC)
+	track.PLine = request.PLine
+	track.CLine = request.CLine
+	track.Label = request.Label
+	track.MasterOwner = request.MasterOwner
+	track.Publishing = publishingSplits(request.Publishing)
+	track.Availability = trackAvailability(request.Availability)
+	track.Tier = strings.ToLower(strings.TrimSpace(request.Tier))
+}
 
-	if request.FileURL != nil {
-		track.FileURL = *request.FileURL
+// publishingRequest returns the publishing splits of a track as requested,
+// nil when it has none
+func publishingRequest(splits []*models.PublishingSplit) []*dto.PublishingSplit {
+	if len(splits) == 0 {
+		return nil
 	}
+	request := make([]*dto.PublishingSplit, 0, len(splits))
+	for _, split := range splits {
+		request = append(request, &dto.PublishingSplit{Name: split.Name, Role: split.Role, IPI: split.IPI, Share: split.Share})
+	}
+	return request
+}
 
-	if request.Genre != nil {
-		track.Genre = *request.Genre
+// publishingSplits returns the requested publishing splits of a track, nil
+// when there are none
+func publishingSplits(request []*dto.PublishingSplit) []*models.PublishingSplit {
+	if len(request) == 0 {
+		return nil
+	}
+	splits := make([]*models.PublishingSplit, 0, len(request))
+	for _, split := range request {
+		splits = append(splits, &models.PublishingSplit{Name: split.Name, Role: split.Role, IPI: split.IPI, Share: split.Share})
 	}
+	return splits
+}
+
+// saveTrack replaces the editable fields of track with request, records the
+// change as revision and responds with the updated track
+func saveTrack(appG app.Gin, track *models.Track, request dto.UpdateTrackRequest, revision *models.Revision) {
+	before := trackRequest(track)
+	fileChanged := request.FileURL != track.FileURL
 
-	err = models.Repository.Track.Update(context.Background(), track)
+	applyTrackRequest(track, request)
+
+	err := models.Repository.Track.Update(context.Background(), track)
+	if errors.Is(err, models.ErrVersionConflict) {
+		appG.Response412(e.PRECONDITION_FAILED, "Track was modified, get it again and retry")
+		return
+	}
 	if err != nil {
-		appG.Response500(e.ERROR, "Delete a track by id failed with err: "+err.Error())
+		appG.Response500(e.ERROR, "Update a track by id failed with err: "+err.Error())
 		return
 	}
+	revision.Kind = models.RevisionTrack
+	revision.TargetID = track.ID
+	revision.Version = track.Version
+	recordRevision(appG.C, revision, before, request)
 
-	trackUpdated, err := models.Repository.Track.FindByID(context.Background(), objID)
+	trackUpdated, err := models.Repository.Track.FindByID(context.Background(), track.ID)
 	if err != nil {
 		appG.Response500(e.ERROR, "Get track updated failed with err: "+err.Error())
 		return
 	}
 
+	if fileChanged {
+		enqueueTrackProcessing(trackUpdated)
+	}
+
+	appG.C.Header("ETag", versionETag(trackUpdated.Version))
 	appG.Response200(trackUpdated)
 }
+
+// enqueueTrackProcessing queues the jobs deriving metadata, waveform and analysis
+// from the track audio. The track is usable without them, so failures are only logged.
+func enqueueTrackProcessing(track *models.Track) {
+	for _, jobType := range []string{jobs.TypeTrackMetadata, jobs.TypeTrackAnalysis, jobs.TypeTrackWaveform} {
+		if _, err := jobs.EnqueueFrom(context.Background(), jobType, track.ID.Hex(), track.FileURL); err != nil {
+			log.Printf("Queue %s for track %s failed with error: %v", jobType, track.ID.Hex(), err)
+		}
+	}
+}
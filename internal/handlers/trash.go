@@ -0,0 +1,181 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rolexkdev/emvn-music-library-server/common/app"
+	"github.com/rolexkdev/emvn-music-library-server/common/e"
+	"github.com/rolexkdev/emvn-music-library-server/common/utils"
+	"github.com/rolexkdev/emvn-music-library-server/dto"
+	"github.com/rolexkdev/emvn-music-library-server/internal/jobs"
+	"github.com/rolexkdev/emvn-music-library-server/internal/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// GetTrash godoc
+//
+//	@Summary		Get the trash
+//	@Description	List the deleted tracks and playlists, most recently deleted first, with the time they are purged
+//	@Tags			trash
+//	@Accept			json
+//	@Produce		json
+//
+//	@Param			type		query		string	false	"tracks or playlists, both by default"
+//
+//	@Success		200				{object}	app.Response
+//	@Failure		400				{object}	app.Response
+//	@Failure		500				{object}	app.Response
+//	@Router			/trash [get]
+func GetTrash(c *gin.Context) {
+	appG := app.Gin{C: c}
+
+	var request dto.TrashRequest
+	if err := c.BindQuery(&request); err != nil {
+		appG.Response400(e.INVALID_PARAMS, "Bind query failed: "+err.Error())
+		return
+	}
+
+	// Validate request input
+	if err := utils.Validator.Struct(request); err != nil {
+		appG.Response400(e.INVALID_PARAMS, "Validate query failed: "+err.Error())
+		return
+	}
+
+	var response dto.TrashResponse
+	if request.Type == "" || request.Type == "tracks" {
+		tracks, err := models.Repository.Track.FindDeleted(context.Background())
+		if err != nil {
+			appG.Response500(e.ERROR, "Get deleted tracks failed with err: "+err.Error())
+			return
+		}
+		response.Tracks = []*dto.TrashedTrack{}
+		for _, track := range tracks {
+			response.Tracks = append(response.Tracks, &dto.TrashedTrack{
+				Track:     track,
+				DeletedAt: track.DeleteAt,
+				PurgeAt:   jobs.PurgeAt(track.DeleteAt),
+			})
+		}
+	}
+	if request.Type == "" || request.Type == "playlists" {
+		playlists, err := models.Repository.Playlist.FindDeleted(context.Background())
+		if err != nil {
+			appG.Response500(e.ERROR, "Get deleted playlists failed with err: "+err.Error())
+			return
+		}
+		response.Playlists = []*dto.TrashedPlaylist{}
+		for _, playlist := range playlists {
+			response.Playlists = append(response.Playlists, &dto.TrashedPlaylist{
+				Playlist:  playlist,
+				DeletedAt: playlist.DeleteAt,
+				PurgeAt:   jobs.PurgeAt(playlist.DeleteAt),
+			})
+		}
+	}
+
+	appG.Response200(response)
+}
+
+// RestoreTrack godoc
+//
+//	@Summary		Restore a deleted track
+//	@Description	Take a track out of the trash before it is purged
+//	@Tags			trash
+//	@Accept			json
+//	@Produce		json
+//
+//	@Param			id		    path		string	true	"track id"
+//
+//	@Success		200				{object}	app.Response
+//	@Failure		404				{object}	app.Response
+//	@Failure		500				{object}	app.Response
+//	@Router			/tracks/{id}/restore [post]
+func RestoreTrack(c *gin.Context) {
+	appG := app.Gin{C: c}
+	trackID := c.Param("id")
+
+	// convert track_id string to objectID
+	objID, err := primitive.ObjectIDFromHex(trackID)
+	if err != nil {
+		appG.Response500(e.ERROR, "convert id string to objectID failed"+err.Error())
+		return
+	}
+
+	err = models.Repository.Track.Restore(context.Background(), objID)
+	if err != nil {
+		if errors.Is(err, models.ErrNotFound) {
+			appG.Response404(e.NOTFOUND, "Track not in trash")
+			return
+		}
+		appG.Response500(e.ERROR, "Restore track failed with err: "+err.Error())
+		return
+	}
+
+	track, err := models.Repository.Track.FindByID(context.Background(), objID)
+	if err != nil {
+		appG.Response500(e.ERROR, "Get track restored failed with err: "+err.Error())
+		return
+	}
+	recordRevision(c, &models.Revision{
+		Kind:     models.RevisionTrack,
+		TargetID: track.ID,
+		Version:  track.Version,
+		Action:   models.RevisionRestore,
+	}, trackRequest(track), trackRequest(track))
+
+	c.Header("ETag", versionETag(track.Version))
+	appG.Response200(track)
+}
+
+// RestorePlaylist godoc
+//
+//	@Summary		Restore a deleted playlist
+//	@Description	Take a playlist out of the trash before it is purged
+//	@Tags			trash
+//	@Accept			json
+//	@Produce		json
+//
+//	@Param			id		    path		string	true	"playlist id"
+//
+//	@Success		200				{object}	app.Response
+//	@Failure		404				{object}	app.Response
+//	@Failure		500				{object}	app.Response
+//	@Router			/playlists/{id}/restore [post]
+func RestorePlaylist(c *gin.Context) {
+	appG := app.Gin{C: c}
+	playlistID := c.Param("id")
+
+	// convert playlist_id string to objectID
+	objID, err := primitive.ObjectIDFromHex(playlistID)
+	if err != nil {
+		appG.Response500(e.ERROR, "convert id string to objectID failed"+err.Error())
+		return
+	}
+
+	err = models.Repository.Playlist.Restore(context.Background(), objID)
+	if err != nil {
+		if errors.Is(err, models.ErrNotFound) {
+			appG.Response404(e.NOTFOUND, "Playlist not in trash")
+			return
+		}
+		appG.Response500(e.ERROR, "Restore playlist failed with err: "+err.Error())
+		return
+	}
+
+	playlist, err := models.Repository.Playlist.FindByID(context.Background(), objID)
+	if err != nil {
+		appG.Response500(e.ERROR, "Get playlist restored failed with err: "+err.Error())
+		return
+	}
+	recordRevision(c, &models.Revision{
+		Kind:     models.RevisionPlaylist,
+		TargetID: playlist.ID,
+		Version:  playlist.Version,
+		Action:   models.RevisionRestore,
+	}, playlistRequest(playlist), playlistRequest(playlist))
+
+	c.Header("ETag", versionETag(playlist.Version))
+	appG.Response200(playlist)
+}
@@ -1,17 +1,29 @@
 package handlers
 
 import (
+	"context"
 	"fmt"
+	"log"
 	"os"
 	"path/filepath"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
 	"github.com/rolexkdev/emvn-music-library-server/common/app"
 	"github.com/rolexkdev/emvn-music-library-server/common/e"
 	"github.com/rolexkdev/emvn-music-library-server/common/utils"
 	"github.com/rolexkdev/emvn-music-library-server/dto"
+	"github.com/rolexkdev/emvn-music-library-server/internal/jobs"
+	"github.com/rolexkdev/emvn-music-library-server/internal/media"
+	"github.com/rolexkdev/emvn-music-library-server/internal/models"
 )
 
+// uploadURL returns the URL serving the uploaded file called filename
+func uploadURL(c *gin.Context, filename string) string {
+	// Hard code =((
+	return fmt.Sprintf("http://%s/api/v1/uploads/%s", c.Request.Host, filename)
+}
+
 // UploadFile godoc
 //
 //	@Summary		Upload files
@@ -39,6 +51,7 @@ func UploadFile(c *gin.Context) {
 	}
 
 	uploadedFiles := make([]string, 0)
+	jobIDs := make([]string, 0)
 
 	for _, fileHeader := range fileHeaders {
 		file, err := fileHeader.Open()
@@ -55,26 +68,42 @@ func UploadFile(c *gin.Context) {
 			return
 		}
 
+		// Album covers are served as square thumbnails, a failure here is not fatal
+		// because RetrieveFile generates missing sizes on demand
+		if media.IsImage(fileHeader.Filename) {
+			job, err := jobs.Enqueue(context.Background(), jobs.TypeThumbnails, fileHeader.Filename)
+			if err != nil {
+				log.Printf("Queue thumbnails for %s failed with error: %v", fileHeader.Filename, err)
+			} else {
+				jobIDs = append(jobIDs, job.ID.Hex())
+			}
+		}
+
 		// Construct the URL to access the file
-		// Hard code =((
-		url := fmt.Sprintf("http://%s/api/v1/uploads/%s", c.Request.Host, fileHeader.Filename)
-		uploadedFiles = append(uploadedFiles, url)
+		uploadedFiles = append(uploadedFiles, uploadURL(c, fileHeader.Filename))
 	}
 
 	appG.Response201(dto.FileUploadResponse{
 		FileURLs: uploadedFiles,
+		JobIDs:   jobIDs,
 	})
 }
 
 // RetrieveFile godoc
 //
 //	@Summary		Retrieve file
-//	@Description	Get a file by its filename
+//	@Description	Get a file by its filename, images can be requested as a square thumbnail with size.
+//	@Description	The audio of tracks is refused with 451 when none of its tracks is available to the caller.
 //	@Tags			upload
 //	@Produce		plain
 //	@Param			filename	path	string	true	"Filename"
+//	@Param			size		query	int		false	"Thumbnail edge length in pixels (images only)"
+//	@Param			X-Territory	header	string	false	"ISO 3166-1 alpha-2 territory of the caller, e.g. VN"
 //	@Success		200				{object}	app.Response
+//	@Failure		400				{object}	app.Response
 //	@Failure		404				{object}	app.Response
+//	@Failure		451				{object}	app.Response
+//	@Failure		500				{object}	app.Response
 //	@Router			/uploads/{filename} [get]
 func RetrieveFile(c *gin.Context) {
 	appG := app.Gin{C: c}
@@ -87,6 +116,40 @@ func RetrieveFile(c *gin.Context) {
 		return
 	}
 
+	// The audio of tracks is only streamed where and when one of them is
+	// available, images are never the file of a track
+	if !media.IsImage(filename) {
+		tracks, err := models.Repository.Track.FindByUploadName(context.Background(), filename)
+		if err != nil {
+			appG.Response500(e.ERROR, "Get tracks of file failed with error: "+err.Error())
+			return
+		}
+		if !anyAvailable(callerAvailability(c), tracks) {
+			appG.Response451(e.UNAVAILABLE, "Track is not available in your territory or at this time")
+			return
+		}
+	}
+
+	if sizeParam := c.Query("size"); sizeParam != "" {
+		size, err := strconv.Atoi(sizeParam)
+		if err != nil || !media.IsImage(filename) || !media.IsThumbnailSize(size) {
+			appG.Response400(e.INVALID_PARAMS, fmt.Sprintf("Unsupported size %q, available sizes: %v", sizeParam, media.ThumbnailSizes))
+			return
+		}
+
+		// Thumbnails live apart from the uploads so they never collide with them
+		filename = media.ThumbnailName(filename, size)
+		filePath = filepath.Join(utils.ThumbnailDir, filename)
+
+		// Files uploaded before thumbnails existed get their derivative on first request
+		if _, err := os.Stat(filePath); os.IsNotExist(err) {
+			if _, err := media.GenerateThumbnail(utils.UploadDir, utils.ThumbnailDir, c.Param("filename"), size); err != nil {
+				appG.Response500(e.ERROR, "Generate thumbnail failed with error: "+err.Error())
+				return
+			}
+		}
+	}
+
 	// Set the content type based on the file extension
 	contentType := utils.GetFileContentType(filename)
 	c.Writer.Header().Set("Content-Type", contentType)
@@ -0,0 +1,144 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rolexkdev/emvn-music-library-server/common/app"
+	"github.com/rolexkdev/emvn-music-library-server/common/e"
+	"github.com/rolexkdev/emvn-music-library-server/common/utils"
+	"github.com/rolexkdev/emvn-music-library-server/internal/jobs"
+	"github.com/rolexkdev/emvn-music-library-server/internal/media"
+	"github.com/rolexkdev/emvn-music-library-server/internal/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// GetTrackWaveform godoc
+//
+//	@Summary		Get track waveform
+//	@Description	Get min/max peak data of a track in audiowaveform JSON or binary (.dat) format.
+//	@Description	While the waveform is being generated the queued job is returned with status 202.
+//	@Description	A track not available to the caller is refused with 451.
+//	@Tags			track
+//	@Produce		json
+//	@Produce		application/octet-stream
+//
+//	@Param			id					path		string	true	"track id"
+//	@Param			format				query		string	false	"json (default) or dat"
+//	@Param			samples_per_pixel	query		int		false	"resolution, a multiple of the stored resolution"
+//	@Param			X-Territory			header		string	false	"ISO 3166-1 alpha-2 territory of the caller, e.g. VN, unless set on the API key"
+//
+//	@Success		200				{object}	media.Waveform
+//	@Success		202				{object}	app.Response
+//	@Failure		400				{object}	app.Response
+//	@Failure		404				{object}	app.Response
+//	@Failure		451				{object}	app.Response
+//	@Failure		500				{object}	app.Response
+//	@Router			/tracks/{id}/waveform [get]
+func GetTrackWaveform(c *gin.Context) {
+	appG := app.Gin{C: c}
+	trackID := c.Param("id")
+
+	format := c.DefaultQuery("format", "json")
+	if format != "json" && format != "dat" {
+		appG.Response400(e.INVALID_PARAMS, "format must be json or dat")
+		return
+	}
+
+	samplesPerPixel := 0
+	if value := c.Query("samples_per_pixel"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n <= 0 {
+			appG.Response400(e.INVALID_PARAMS, "samples_per_pixel must be a positive integer")
+			return
+		}
+		samplesPerPixel = n
+	}
+
+	// convert track_id string to objectID
+	objID, err := primitive.ObjectIDFromHex(trackID)
+	if err != nil {
+		appG.Response500(e.ERROR, "convert id string to objectID failed"+err.Error())
+		return
+	}
+
+	track, err := models.Repository.Track.FindByID(context.Background(), objID)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			appG.Response404(e.NOTFOUND, "Track not exist")
+			return
+		}
+		appG.Response500(e.ERROR, "Get track by id failed with err: "+err.Error())
+		return
+	}
+	if !callerAvailability(c).Allows(track) {
+		appG.Response451(e.UNAVAILABLE, "Track is not available in your territory or at this time")
+		return
+	}
+
+	jsonPath := filepath.Join(utils.WaveformDir, trackID+".json")
+	if !waveformIsCurrent(track, jsonPath) {
+		if !media.IsAudio(path.Base(track.FileURL)) {
+			appG.Response400(e.INVALID_PARAMS, "Waveform is not available for this track format")
+			return
+		}
+
+		job, err := jobs.EnqueueFrom(context.Background(), jobs.TypeTrackWaveform, track.ID.Hex(), track.FileURL)
+		if err != nil {
+			appG.Response500(e.ERROR, "Queue waveform generation failed with err: "+err.Error())
+			return
+		}
+		appG.Response202(job)
+		return
+	}
+
+	// The stored resolution is served straight from disk
+	if samplesPerPixel == 0 || samplesPerPixel == track.Waveform.SamplesPerPixel {
+		if format == "dat" {
+			c.Writer.Header().Set("Content-Type", "application/octet-stream")
+			c.File(filepath.Join(utils.WaveformDir, trackID+".dat"))
+			return
+		}
+		c.Writer.Header().Set("Content-Type", "application/json")
+		c.File(jsonPath)
+		return
+	}
+
+	waveform, err := media.ReadWaveformJSON(jsonPath)
+	if err != nil {
+		appG.Response500(e.ERROR, "Read waveform failed with err: "+err.Error())
+		return
+	}
+	waveform, err = waveform.Resample(samplesPerPixel)
+	if err != nil {
+		appG.Response400(e.INVALID_PARAMS, err.Error())
+		return
+	}
+
+	if format == "dat" {
+		c.Writer.Header().Set("Content-Type", "application/octet-stream")
+		c.Status(http.StatusOK)
+		if err := waveform.WriteDat(c.Writer); err != nil {
+			c.Error(err)
+		}
+		return
+	}
+	c.JSON(http.StatusOK, waveform)
+}
+
+// waveformIsCurrent reports whether the stored waveform was generated from the
+// current file_url and is still on disk
+func waveformIsCurrent(track *models.Track, jsonPath string) bool {
+	if track.Waveform == nil || track.Waveform.SourceURL != track.FileURL {
+		return false
+	}
+	_, err := os.Stat(jsonPath)
+	return err == nil
+}
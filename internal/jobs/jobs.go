@@ -0,0 +1,216 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math/rand"
+	"os"
+	"runtime/debug"
+	"time"
+
+	"github.com/rolexkdev/emvn-music-library-server/config"
+	"github.com/rolexkdev/emvn-music-library-server/internal/models"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const (
+	// Longest delay between two attempts of a job
+	maxRetryBackoff = time.Hour
+	// How often a running job reports progress and extends its lease
+	heartbeatInterval = 2 * time.Second
+)
+
+// Handler runs a job, it should call progress with a percentage from time to
+// time so the lease of long running jobs does not expire
+type Handler func(ctx context.Context, job *models.Job, progress func(percent int)) error
+
+var (
+	conf     config.JobsConfig
+	trash    config.TrashConfig
+	handlers = map[string]Handler{}
+)
+
+// permanentError marks failures that retrying cannot fix
+type permanentError struct {
+	err error
+}
+
+func (p *permanentError) Error() string {
+	return p.err.Error()
+}
+
+func (p *permanentError) Unwrap() error {
+	return p.err
+}
+
+// Permanent wraps err so the job goes straight to the dead letters
+func Permanent(err error) error {
+	return &permanentError{err: err}
+}
+
+func Setup(c *config.Config) {
+	conf = c.Jobs
+	trash = c.Trash
+
+	if err := models.Repository.Job.EnsureIndexes(context.Background()); err != nil {
+		log.Fatalf("jobs.Setup err: %v", err)
+	}
+}
+
+// Register makes jobType runnable by the worker pool
+func Register(jobType string, handler Handler) {
+	handlers[jobType] = handler
+}
+
+// Enqueue schedules jobType for target, an identical job that is already
+// waiting or running is returned instead of a new one
+func Enqueue(ctx context.Context, jobType, target string) (*models.Job, error) {
+	return EnqueueFrom(ctx, jobType, target, "")
+}
+
+// EnqueueFrom schedules jobType for target as it is made of source, e.g. a
+// track playing the file_url source. A job running from another source does
+// not stand for it, a new job is queued to run after it.
+func EnqueueFrom(ctx context.Context, jobType, target, source string) (*models.Job, error) {
+	if _, ok := handlers[jobType]; !ok {
+		return nil, fmt.Errorf("unknown job type %q", jobType)
+	}
+
+	return models.Repository.Job.Enqueue(ctx, &models.Job{
+		Type:        jobType,
+		Target:      target,
+		Source:      source,
+		MaxAttempts: conf.MaxAttempts,
+	})
+}
+
+// Start launches the configured number of workers, they stop when ctx is done
+func Start(ctx context.Context) {
+	hostname, _ := os.Hostname()
+	for i := 0; i < conf.Workers; i++ {
+		owner := fmt.Sprintf("%s-%d-%d", hostname, os.Getpid(), i)
+		go work(ctx, owner)
+	}
+	log.Printf("Started %d job workers", conf.Workers)
+
+	go schedule(ctx, trash.PurgeInterval, TypePurgeTrash, TrashTarget)
+}
+
+// schedule enqueues jobType for target right away and then every interval
+// until ctx is done. Enqueue skips a run while the previous one is pending, so
+// several servers can schedule the same job.
+func schedule(ctx context.Context, interval time.Duration, jobType, target string) {
+	if interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if _, err := Enqueue(ctx, jobType, target); err != nil && ctx.Err() == nil {
+			log.Printf("Schedule %s job failed with error: %v", jobType, err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func work(ctx context.Context, owner string) {
+	for ctx.Err() == nil {
+		job, err := models.Repository.Job.Lease(ctx, owner, conf.VisibilityTimeout)
+		if err != nil {
+			if !errors.Is(err, mongo.ErrNoDocuments) && ctx.Err() == nil {
+				log.Printf("Lease job failed with error: %v", err)
+			}
+			select {
+			case <-ctx.Done():
+			case <-time.After(conf.PollInterval):
+			}
+			continue
+		}
+
+		run(ctx, job)
+	}
+}
+
+func run(ctx context.Context, job *models.Job) {
+	handler, ok := handlers[job.Type]
+	if !ok {
+		finish(ctx, job, Permanent(fmt.Errorf("unknown job type %q", job.Type)))
+		return
+	}
+
+	// A worker died while holding the lease on the last attempt
+	if job.Attempts > job.MaxAttempts {
+		finish(ctx, job, errors.New("too many attempts"))
+		return
+	}
+
+	jobCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	lastHeartbeat := time.Now()
+	progress := func(percent int) {
+		if time.Since(lastHeartbeat) < heartbeatInterval {
+			return
+		}
+		lastHeartbeat = time.Now()
+
+		err := models.Repository.Job.Heartbeat(ctx, job, percent, conf.VisibilityTimeout)
+		if errors.Is(err, models.ErrLeaseLost) {
+			// Another worker took over, stop wasting work
+			cancel()
+		} else if err != nil {
+			log.Printf("Heartbeat job %s failed with error: %v", job.ID.Hex(), err)
+		}
+	}
+
+	finish(ctx, job, call(jobCtx, handler, job, progress))
+}
+
+// call runs handler and turns a panic into an error
+func call(ctx context.Context, handler Handler, job *models.Job, progress func(int)) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v\n%s", r, debug.Stack())
+		}
+	}()
+	return handler(ctx, job, progress)
+}
+
+// finish records the outcome of a job: success, retry with backoff or dead letter
+func finish(ctx context.Context, job *models.Job, jobErr error) {
+	var err error
+	var permanent *permanentError
+	switch {
+	case jobErr == nil:
+		err = models.Repository.Job.Complete(ctx, job)
+	case errors.As(jobErr, &permanent) || job.Attempts >= job.MaxAttempts:
+		log.Printf("Job %s (%s %s) failed permanently: %v", job.ID.Hex(), job.Type, job.Target, jobErr)
+		err = models.Repository.Job.Bury(ctx, job, jobErr)
+	default:
+		log.Printf("Job %s (%s %s) failed, attempt %d of %d: %v", job.ID.Hex(), job.Type, job.Target, job.Attempts, job.MaxAttempts, jobErr)
+		err = models.Repository.Job.Retry(ctx, job, jobErr, time.Now().Add(backoff(job.Attempts)))
+	}
+
+	if err != nil && !errors.Is(err, models.ErrLeaseLost) {
+		log.Printf("Record result of job %s failed with error: %v", job.ID.Hex(), err)
+	}
+}
+
+// backoff doubles the retry delay with every attempt and adds up to 20% jitter
+func backoff(attempts int) time.Duration {
+	delay := conf.RetryBackoff
+	for i := 1; i < attempts && delay < maxRetryBackoff; i++ {
+		delay *= 2
+	}
+	if delay > maxRetryBackoff {
+		delay = maxRetryBackoff
+	}
+	return delay + time.Duration(rand.Int63n(int64(delay)/5+1))
+}
@@ -0,0 +1,322 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/rolexkdev/emvn-music-library-server/common/utils"
+	"github.com/rolexkdev/emvn-music-library-server/internal/media"
+	"github.com/rolexkdev/emvn-music-library-server/internal/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Job types
+const (
+	// Target is the uploaded image file name
+	TypeThumbnails = "thumbnails"
+	// Target is the track id
+	TypeTrackMetadata = "track_metadata"
+	TypeTrackWaveform = "track_waveform"
+	TypeTrackAnalysis = "track_analysis"
+	// Target is always TrashTarget
+	TypePurgeTrash = "purge_trash"
+)
+
+// Target of the scheduled trash purge
+const TrashTarget = "trash"
+
+func init() {
+	Register(TypeThumbnails, generateThumbnails)
+	Register(TypeTrackMetadata, extractTrackMetadata)
+	Register(TypeTrackWaveform, generateTrackWaveform)
+	Register(TypeTrackAnalysis, analyzeTrack)
+	Register(TypePurgeTrash, purgeTrash)
+}
+
+// PurgeAt returns when a record deleted at deletedAt is purged from the trash
+func PurgeAt(deletedAt time.Time) time.Time {
+	return deletedAt.Add(trash.Retention)
+}
+
+// purgeTrash permanently removes the tracks and playlists deleted longer than
+// the trash retention ago, purged tracks are taken out of the playlists. The
+// references and history go before the records, so a retry after a failure
+// finds the same records again.
+func purgeTrash(ctx context.Context, job *models.Job, progress func(int)) error {
+	deletedBefore := time.Now().Add(-trash.Retention)
+
+	trackIDs, err := models.Repository.Track.FindExpired(ctx, deletedBefore)
+	if err != nil {
+		return err
+	}
+	ids := make([]string, len(trackIDs))
+	for i, id := range trackIDs {
+		ids[i] = id.Hex()
+	}
+	if err := models.Repository.Playlist.RemoveTracks(ctx, ids); err != nil {
+		return err
+	}
+	if err := models.Repository.Revision.DeleteByTargets(ctx, models.RevisionTrack, trackIDs); err != nil {
+		return err
+	}
+	if err := models.Repository.Track.Purge(ctx, trackIDs, deletedBefore); err != nil {
+		return err
+	}
+	progress(50)
+
+	playlistIDs, err := models.Repository.Playlist.FindExpired(ctx, deletedBefore)
+	if err != nil {
+		return err
+	}
+	if err := models.Repository.Revision.DeleteByTargets(ctx, models.RevisionPlaylist, playlistIDs); err != nil {
+		return err
+	}
+	if err := models.Repository.Playlist.Purge(ctx, playlistIDs, deletedBefore); err != nil {
+		return err
+	}
+	if len(trackIDs) > 0 || len(playlistIDs) > 0 {
+		log.Printf("Purged %d tracks and %d playlists from the trash", len(trackIDs), len(playlistIDs))
+	}
+	return nil
+}
+
+func generateThumbnails(ctx context.Context, job *models.Job, progress func(int)) error {
+	err := media.GenerateThumbnails(utils.UploadDir, utils.ThumbnailDir, job.Target)
+	if os.IsNotExist(err) {
+		return Permanent(err)
+	}
+	return err
+}
+
+func extractTrackMetadata(ctx context.Context, job *models.Job, progress func(int)) error {
+	track, path, err := findTrackFile(ctx, job.Target)
+	if err != nil {
+		return err
+	}
+
+	info, err := media.ReadAudioInfo(path)
+	if err != nil {
+		// A corrupt or unknown file will not get better by retrying
+		return Permanent(fmt.Errorf("metadata of %s: %w", filepath.Base(path), err))
+	}
+
+	audioInfo := &models.TrackAudioInfo{
+		SourceURL:   track.FileURL,
+		Format:      info.Format,
+		MimeType:    utils.GetFileContentType(path),
+		Duration:    info.Duration.Milliseconds(),
+		SampleRate:  info.SampleRate,
+		BitDepth:    info.BitDepth,
+		Channels:    info.Channels,
+		Bitrate:     info.Bitrate,
+		Tags:        info.Tags,
+		ExtractedAt: time.Now(),
+	}
+
+	// Embedded cover art is stored next to the audio file like any uploaded
+	// image, unless that would replace a file the track does not own. It is
+	// staged first and only takes its name once the track points at it, so a
+	// retry finds the file it owns.
+	var artwork, staged string
+	if info.Picture != nil {
+		base := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+		name := base + "_artwork" + info.Picture.Extension()
+		if err := checkArtworkTarget(name, track); err != nil {
+			log.Printf("Keep embedded artwork of track %s failed with error: %v", track.ID.Hex(), err)
+		} else {
+			if staged, err = stageArtwork(info.Picture.Data); err != nil {
+				return err
+			}
+			defer os.Remove(staged)
+			if audioInfo.ArtworkURL, err = utils.SiblingUploadURL(track.FileURL, name); err != nil {
+				return err
+			}
+			artwork = name
+		}
+	}
+
+	before, err := models.Repository.Track.SetAudioInfo(ctx, track.ID, audioInfo)
+	if err != nil {
+		return dropStale(track, err)
+	}
+
+	// The measured duration replaces an editable field, so it is part of the history
+	if before.Duration != audioInfo.Duration {
+		change := &models.FieldChange{Field: "duration", Old: before.Duration, New: audioInfo.Duration}
+		if err := models.Repository.Revision.RecordSystemChange(ctx, models.RevisionTrack, track.ID, before.Version+1, models.RevisionMeasure, change); err != nil {
+			log.Printf("Record measured duration of track %s failed with error: %v", track.ID.Hex(), err)
+		}
+	}
+
+	if artwork != "" {
+		if err := os.Rename(staged, filepath.Join(utils.UploadDir, artwork)); err != nil {
+			return err
+		}
+		if _, err := Enqueue(ctx, TypeThumbnails, artwork); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkArtworkTarget returns an error when the embedded artwork of track
+// cannot be stored as the upload called name: another file has that name and
+// it is not the current artwork of track
+func checkArtworkTarget(name string, track *models.Track) error {
+	filePath, err := utils.UploadPath(name)
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(filePath); errors.Is(err, os.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	if track.AudioInfo != nil && track.AudioInfo.ArtworkURL != "" {
+		if current, err := utils.UploadNameFromURL(track.AudioInfo.ArtworkURL); err == nil && current == name {
+			return nil
+		}
+	}
+	return fmt.Errorf("file %s already exists in the uploads", name)
+}
+
+// stageArtwork writes data to a temporary file of the uploads folder, to be
+// renamed into place
+func stageArtwork(data []byte) (string, error) {
+	if err := os.MkdirAll(utils.UploadDir, 0o755); err != nil {
+		return "", err
+	}
+	out, err := os.CreateTemp(utils.UploadDir, ".artwork-*.tmp")
+	if err != nil {
+		return "", err
+	}
+	if _, err := out.Write(data); err != nil {
+		out.Close()
+		os.Remove(out.Name())
+		return "", err
+	}
+	if err := out.Close(); err != nil {
+		os.Remove(out.Name())
+		return "", err
+	}
+	return out.Name(), nil
+}
+
+func generateTrackWaveform(ctx context.Context, job *models.Job, progress func(int)) error {
+	track, audio, err := openTrackAudio(ctx, job.Target, progress)
+	if err != nil {
+		return err
+	}
+	defer audio.Close()
+
+	waveform, err := media.GenerateWaveform(audio, utils.WaveformDir, track.ID.Hex())
+	if err != nil {
+		return fmt.Errorf("waveform of %s: %w", track.FileURL, err)
+	}
+
+	return dropStale(track, models.Repository.Track.SetWaveform(ctx, track.ID, &models.TrackWaveform{
+		SourceURL:       track.FileURL,
+		SampleRate:      waveform.SampleRate,
+		SamplesPerPixel: waveform.SamplesPerPixel,
+		Bits:            waveform.Bits,
+		Length:          waveform.Length,
+		GeneratedAt:     time.Now(),
+	}))
+}
+
+func analyzeTrack(ctx context.Context, job *models.Job, progress func(int)) error {
+	track, audio, err := openTrackAudio(ctx, job.Target, progress)
+	if err != nil {
+		return err
+	}
+	defer audio.Close()
+
+	analysis, err := media.Analyze(audio)
+	if err != nil {
+		return fmt.Errorf("analysis of %s: %w", track.FileURL, err)
+	}
+
+	return dropStale(track, models.Repository.Track.SetAnalysis(ctx, track.ID, &models.TrackAnalysis{
+		SourceURL:  track.FileURL,
+		Loudness:   analysis.Loudness,
+		TruePeak:   analysis.TruePeak,
+		BPM:        analysis.BPM,
+		Key:        analysis.Key,
+		AnalyzedAt: time.Now(),
+	}))
+}
+
+// dropStale ignores the failure to store a result because the track was
+// deleted or plays another file since the job read it, the job queued for
+// the new file stores its own
+func dropStale(track *models.Track, err error) error {
+	if errors.Is(err, models.ErrNotFound) {
+		log.Printf("Drop result of %s for track %s, it no longer plays that file", track.FileURL, track.ID.Hex())
+		return nil
+	}
+	return err
+}
+
+// findTrackFile loads the track and resolves its uploaded audio file. Errors
+// that retrying cannot fix are marked permanent.
+func findTrackFile(ctx context.Context, trackID string) (*models.Track, string, error) {
+	objID, err := primitive.ObjectIDFromHex(trackID)
+	if err != nil {
+		return nil, "", Permanent(err)
+	}
+
+	track, err := models.Repository.Track.FindByID(ctx, objID)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, "", Permanent(fmt.Errorf("track %s not found", trackID))
+		}
+		return nil, "", err
+	}
+
+	path, err := utils.UploadPathFromURL(track.FileURL)
+	if err != nil {
+		return nil, "", Permanent(err)
+	}
+	return track, path, nil
+}
+
+// openTrackAudio loads the track and opens its audio file for decoding
+func openTrackAudio(ctx context.Context, trackID string, progress func(int)) (*models.Track, *progressAudio, error) {
+	track, path, err := findTrackFile(ctx, trackID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	audio, err := media.OpenAudio(path)
+	if err != nil {
+		if errors.Is(err, media.ErrUnsupportedAudio) {
+			return nil, nil, Permanent(fmt.Errorf("%s: %w", filepath.Base(path), err))
+		}
+		return nil, nil, err
+	}
+
+	return track, &progressAudio{AudioFile: audio, ctx: ctx, progress: progress}, nil
+}
+
+// progressAudio reports decoding progress and stops decoding once the job is cancelled
+type progressAudio struct {
+	*media.AudioFile
+	ctx      context.Context
+	progress func(int)
+}
+
+func (p *progressAudio) Read(buf []float64) (int, error) {
+	if err := p.ctx.Err(); err != nil {
+		return 0, err
+	}
+	p.progress(p.Progress())
+	return p.AudioFile.Read(buf)
+}
@@ -0,0 +1,42 @@
+// Package licensing prices sync license requests and writes their summaries
+package licensing
+
+import (
+	"log"
+	"time"
+
+	"github.com/go-playground/validator"
+	"github.com/rolexkdev/emvn-music-library-server/config"
+)
+
+var (
+	// Rules quotes are priced with
+	pricing = &Pricing{}
+	// How long a quote can be approved after it is sent
+	quoteValidity = 30 * 24 * time.Hour
+	// Licensor named on license summaries
+	licensor = "EMVN"
+)
+
+func Setup(c *config.Config) {
+	p, err := LoadPricing(c.License.PricingFile)
+	if err != nil {
+		log.Fatalf("licensing.Setup err: %v", err)
+	}
+	pricing = p
+	quoteValidity = c.License.QuoteValidity
+	licensor = c.License.Licensor
+}
+
+// RegisterValidation teaches v the tier tag, which accepts the tiers of the
+// pricing rules without regard to case
+func RegisterValidation(v *validator.Validate) {
+	v.RegisterValidation("tier", func(fl validator.FieldLevel) bool {
+		return pricing.HasTier(fl.Field().String())
+	})
+}
+
+// QuoteValidity returns how long a quote sent now can be approved
+func QuoteValidity() time.Duration {
+	return quoteValidity
+}
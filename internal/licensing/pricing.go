@@ -0,0 +1,261 @@
+package licensing
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/rolexkdev/emvn-music-library-server/internal/models"
+)
+
+// Pricing holds the rules license quotes are computed with. The price of a
+// track is the base price of its tier times the multipliers of the media
+// type, usage, territories and term of the request, no less than the minimum
+// of the tier. Requests of many tracks get a volume discount.
+type Pricing struct {
+	// ISO 4217 code of the prices
+	Currency string `json:"currency"`
+	// Tier of the tracks without one, or with one the rules no longer have
+	DefaultTier string           `json:"default_tier"`
+	Tiers       map[string]*Tier `json:"tiers"`
+	// Multipliers by media type and by usage, the only ones a request may ask
+	Media  map[string]float64 `json:"media"`
+	Usages map[string]float64 `json:"usages"`
+	// Multipliers by number of territories
+	Territories TerritoryPricing `json:"territories"`
+	// Multipliers by term, the shortest term covering the request applies
+	Terms []*TermPricing `json:"terms"`
+	// Percent off the subtotal by number of tracks, the largest reached applies
+	Discounts []*Discount `json:"discounts"`
+}
+
+type Tier struct {
+	Base    float64 `json:"base"`
+	Minimum float64 `json:"minimum"`
+	// Base prices by media type replacing Base
+	Media map[string]float64 `json:"media,omitempty"`
+}
+
+type TerritoryPricing struct {
+	// Multiplier of a single territory
+	First float64 `json:"first"`
+	// Added to the multiplier by every other territory
+	Additional float64 `json:"additional"`
+	// Multiplier of a worldwide license, also the most a list of territories
+	// costs
+	Worldwide float64 `json:"worldwide"`
+}
+
+type TermPricing struct {
+	// Length of the term, 0 for a perpetual license
+	Months     int     `json:"months"`
+	Multiplier float64 `json:"multiplier"`
+}
+
+type Discount struct {
+	MinTracks int     `json:"min_tracks"`
+	Percent   float64 `json:"percent"`
+}
+
+// LoadPricing reads and checks the pricing rules of a JSON file
+func LoadPricing(path string) (*Pricing, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var p Pricing
+	if err := json.Unmarshal(data, &p); err != nil {
+		return nil, fmt.Errorf("parse pricing %s: %w", path, err)
+	}
+	if err := p.prepare(); err != nil {
+		return nil, fmt.Errorf("pricing %s: %w", path, err)
+	}
+	return &p, nil
+}
+
+// prepare checks the rules, lowercases the tier names and sorts the terms
+// and discounts
+func (p *Pricing) prepare() error {
+	if p.Currency == "" {
+		return fmt.Errorf("currency is required")
+	}
+	tiers := make(map[string]*Tier, len(p.Tiers))
+	for name, tier := range p.Tiers {
+		if tier == nil || tier.Base < 0 || tier.Minimum < 0 {
+			return fmt.Errorf("tier %q has a negative price", name)
+		}
+		tiers[strings.ToLower(name)] = tier
+	}
+	p.Tiers = tiers
+	p.DefaultTier = strings.ToLower(p.DefaultTier)
+	if p.Tiers[p.DefaultTier] == nil {
+		return fmt.Errorf("default tier %q is not a tier", p.DefaultTier)
+	}
+	if len(p.Media) == 0 || len(p.Usages) == 0 {
+		return fmt.Errorf("media and usages are required")
+	}
+	for name, multiplier := range p.Media {
+		if multiplier <= 0 {
+			return fmt.Errorf("media %q multiplier must be positive", name)
+		}
+	}
+	for name, multiplier := range p.Usages {
+		if multiplier <= 0 {
+			return fmt.Errorf("usage %q multiplier must be positive", name)
+		}
+	}
+	if p.Territories.First <= 0 || p.Territories.Additional < 0 || p.Territories.Worldwide <= 0 {
+		return fmt.Errorf("territory multipliers must be positive")
+	}
+	if len(p.Terms) == 0 {
+		return fmt.Errorf("terms are required")
+	}
+	for _, term := range p.Terms {
+		if term.Months < 0 || term.Multiplier <= 0 {
+			return fmt.Errorf("term of %d months is invalid", term.Months)
+		}
+	}
+	for _, discount := range p.Discounts {
+		if discount.MinTracks < 1 || discount.Percent < 0 || discount.Percent > 100 {
+			return fmt.Errorf("discount from %d tracks is invalid", discount.MinTracks)
+		}
+	}
+
+	// Perpetual terms last, they cover any length
+	sort.Slice(p.Terms, func(i, j int) bool {
+		if p.Terms[i].Months == 0 || p.Terms[j].Months == 0 {
+			return p.Terms[j].Months == 0 && p.Terms[i].Months != 0
+		}
+		return p.Terms[i].Months < p.Terms[j].Months
+	})
+	sort.Slice(p.Discounts, func(i, j int) bool {
+		return p.Discounts[i].MinTracks < p.Discounts[j].MinTracks
+	})
+	return nil
+}
+
+// Quote prices tracks for request with the configured rules
+func Quote(request *models.LicenseRequest, tracks []*models.Track) (*models.LicenseQuote, error) {
+	return pricing.Quote(request, tracks)
+}
+
+// Quote prices tracks for request. It fails when the request asks for a
+// media type, usage or term the rules do not price.
+func (p *Pricing) Quote(request *models.LicenseRequest, tracks []*models.Track) (*models.LicenseQuote, error) {
+	media, ok := p.Media[request.MediaType]
+	if !ok {
+		return nil, fmt.Errorf("unknown media type %q, expected one of %s", request.MediaType, keys(p.Media))
+	}
+	usage, ok := p.Usages[request.Usage]
+	if !ok {
+		return nil, fmt.Errorf("unknown usage %q, expected one of %s", request.Usage, keys(p.Usages))
+	}
+	term, err := p.term(request.TermMonths)
+	if err != nil {
+		return nil, err
+	}
+	multiplier := media * usage * p.territory(request.Territories) * term
+
+	quote := &models.LicenseQuote{Currency: p.Currency, Lines: []*models.LicenseQuoteLine{}}
+	for _, track := range tracks {
+		name, tier := p.tier(track.Tier)
+		base := tier.Base
+		if price, ok := tier.Media[request.MediaType]; ok {
+			base = price
+		}
+		price := math.Max(roundCents(base*multiplier), tier.Minimum)
+		quote.Lines = append(quote.Lines, &models.LicenseQuoteLine{
+			TrackID:     track.ID.Hex(),
+			Title:       track.Title,
+			ArtistName:  track.ArtistName,
+			ISRC:        track.ISRC,
+			ISWC:        track.ISWC,
+			MasterOwner: track.MasterOwner,
+			Publishing:  track.Publishing,
+			Tier:        name,
+			BasePrice:   base,
+			Multiplier:  multiplier,
+			Price:       price,
+		})
+		quote.Subtotal += price
+	}
+	quote.Subtotal = roundCents(quote.Subtotal)
+	quote.DiscountPercent = p.discount(len(tracks))
+	quote.Discount = roundCents(quote.Subtotal * quote.DiscountPercent / 100)
+	quote.Total = roundCents(quote.Subtotal - quote.Discount)
+	return quote, nil
+}
+
+// Adjust sets the total of quote by hand. The difference with the computed
+// total is kept as an adjustment so the fee still adds up.
+func Adjust(quote *models.LicenseQuote, total float64) {
+	quote.Adjustment = roundCents(total - quote.Total)
+	quote.Total = total
+	quote.Adjusted = true
+}
+
+// HasTier reports whether the rules have a tier called name
+func (p *Pricing) HasTier(name string) bool {
+	_, ok := p.Tiers[strings.ToLower(strings.TrimSpace(name))]
+	return ok
+}
+
+// tier returns the tier called name, the default tier when there is none
+func (p *Pricing) tier(name string) (string, *Tier) {
+	if tier, ok := p.Tiers[name]; ok {
+		return name, tier
+	}
+	return p.DefaultTier, p.Tiers[p.DefaultTier]
+}
+
+// territory returns the multiplier of a license in territories
+func (p *Pricing) territory(territories []string) float64 {
+	for _, territory := range territories {
+		if territory == models.Worldwide {
+			return p.Territories.Worldwide
+		}
+	}
+	multiplier := p.Territories.First + p.Territories.Additional*float64(len(territories)-1)
+	return math.Min(multiplier, p.Territories.Worldwide)
+}
+
+// term returns the multiplier of the shortest term covering months
+func (p *Pricing) term(months int) (float64, error) {
+	for _, term := range p.Terms {
+		if term.Months == 0 || (months != 0 && months <= term.Months) {
+			return term.Multiplier, nil
+		}
+	}
+	if months == 0 {
+		return 0, fmt.Errorf("perpetual licenses are not offered")
+	}
+	return 0, fmt.Errorf("terms longer than %d months are not offered", p.Terms[len(p.Terms)-1].Months)
+}
+
+// discount returns the percent off a license of count tracks
+func (p *Pricing) discount(count int) float64 {
+	percent := 0.0
+	for _, discount := range p.Discounts {
+		if count >= discount.MinTracks {
+			percent = discount.Percent
+		}
+	}
+	return percent
+}
+
+func roundCents(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
+
+// keys returns the names of values sorted and comma separated
+func keys(values map[string]float64) string {
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
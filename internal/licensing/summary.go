@@ -0,0 +1,151 @@
+package licensing
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/rolexkdev/emvn-music-library-server/internal/models"
+)
+
+// summaryTemplate lays a license request out as a Markdown document, a draft
+// until the license is signed
+var summaryTemplate = template.Must(template.New("summary").Funcs(template.FuncMap{
+	"cell":       cell,
+	"date":       date,
+	"money":      money,
+	"publishing": publishing,
+	"inc":        func(i int) int { return i + 1 },
+}).Parse(`# Sync License Summary{{if not .Signed}} (DRAFT){{end}}
+
+| | |
+|---|---|
+| License request | {{.Request.ID.Hex}} |
+| Status | {{.Request.Status}} |
+| Licensor | {{cell .Licensor}} |
+| Licensee | {{cell .Licensee}} |
+| Project | {{cell .Request.Project}} |
+| Media | {{cell .Request.MediaType}} |
+| Usage | {{cell .Request.Usage}} |
+| Territories | {{.Territories}} |
+| Term | {{.Term}} |
+| Start | {{.Start}} |
+{{- if .End}}
+| End | {{.End}} |
+{{- end}}
+{{if .Request.Description}}
+{{.Request.Description}}
+{{end}}
+## Tracks
+
+| # | Title | Artist | ISRC | Master owner | Publishing | Tier | Fee |
+|---|---|---|---|---|---|---|---:|
+{{- range $i, $line := .Quote.Lines}}
+| {{inc $i}} | {{cell $line.Title}} | {{cell $line.ArtistName}} | {{$line.ISRC}} | {{cell $line.MasterOwner}} | {{cell (publishing $line.Publishing)}} | {{$line.Tier}} | {{money $.Quote.Currency $line.Price}} |
+{{- end}}
+
+## Fee
+
+| | |
+|---|---:|
+| Subtotal | {{money .Quote.Currency .Quote.Subtotal}} |
+{{- if .Quote.Discount}}
+| Volume discount ({{.Quote.DiscountPercent}}%) | -{{money .Quote.Currency .Quote.Discount}} |
+{{- end}}
+{{- if .Quote.Adjusted}}
+| Adjustment | {{money .Quote.Currency .Quote.Adjustment}} |
+{{- end}}
+| **Total** | **{{money .Quote.Currency .Quote.Total}}** |
+{{if .Quote.Note}}
+{{.Quote.Note}}
+{{end}}
+{{- if not .Quote.ValidUntil.IsZero}}
+Quote valid until {{date .Quote.ValidUntil}}.
+{{end}}
+## History
+{{range .Request.History}}
+- {{date .At}} {{.Status}} by {{.Actor}}{{if .Note}}: {{.Note}}{{end}}
+{{- end}}
+`))
+
+type summary struct {
+	Request     *models.LicenseRequest
+	Quote       *models.LicenseQuote
+	Signed      bool
+	Licensor    string
+	Licensee    string
+	Territories string
+	Term        string
+	Start       string
+	End         string
+}
+
+// WriteSummary writes the license summary document of a quoted request as
+// Markdown
+func WriteSummary(w io.Writer, request *models.LicenseRequest) error {
+	if request.Quote == nil {
+		return fmt.Errorf("license request %s is not quoted", request.ID.Hex())
+	}
+
+	s := &summary{
+		Request:     request,
+		Quote:       request.Quote,
+		Signed:      request.Status == models.LicenseSigned,
+		Licensor:    licensor,
+		Licensee:    licensee(request.Contact),
+		Territories: territories(request.Territories),
+		Term:        "Perpetual",
+		Start:       "On signature",
+	}
+	if request.TermMonths > 0 {
+		s.Term = fmt.Sprintf("%d months", request.TermMonths)
+	}
+	if request.StartDate != 0 {
+		start := time.UnixMilli(request.StartDate)
+		s.Start = date(start)
+		if request.TermMonths > 0 {
+			s.End = date(start.AddDate(0, request.TermMonths, 0))
+		}
+	}
+	return summaryTemplate.Execute(w, s)
+}
+
+func licensee(contact models.LicenseContact) string {
+	name := contact.Name
+	if contact.Company != "" {
+		name += ", " + contact.Company
+	}
+	return name + " <" + contact.Email + ">"
+}
+
+func territories(codes []string) string {
+	if len(codes) == 1 && codes[0] == models.Worldwide {
+		return "Worldwide"
+	}
+	return strings.Join(codes, ", ")
+}
+
+// publishing lists the publishing splits of a track as "name (role share%)"
+func publishing(splits []*models.PublishingSplit) string {
+	parts := make([]string, 0, len(splits))
+	for _, split := range splits {
+		parts = append(parts, fmt.Sprintf("%s (%s %g%%)", split.Name, split.Role, split.Share))
+	}
+	return strings.Join(parts, "; ")
+}
+
+// cell escapes a value for a Markdown table cell
+func cell(value string) string {
+	value = strings.ReplaceAll(value, "|", `\|`)
+	return strings.Join(strings.Fields(value), " ")
+}
+
+func date(t time.Time) string {
+	return t.UTC().Format("2006-01-02")
+}
+
+func money(currency string, amount float64) string {
+	return fmt.Sprintf("%.2f %s", amount, currency)
+}
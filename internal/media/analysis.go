@@ -0,0 +1,349 @@
+package media
+
+import (
+	"io"
+	"math"
+	"strings"
+)
+
+const (
+	// Tempo and key are estimated on a mono mixdown decimated to about this rate
+	analysisSampleRate = 11025
+	analysisReadSize   = 4096
+
+	onsetFrameSize = 1024
+	onsetHopSize   = 128
+	minBPM         = 60.0
+	maxBPM         = 200.0
+	// Tempo prior centred on 120 BPM with a one octave standard deviation
+	preferredBPM = 120.0
+
+	chromaFrameSize = 8192
+	chromaHopSize   = 4096
+	chromaMinFreq   = 55.0
+	chromaMaxFreq   = 2000.0
+)
+
+var pitchClasses = []string{"C", "C#", "D", "D#", "E", "F", "F#", "G", "G#", "A", "A#", "B"}
+
+// Krumhansl-Kessler key profiles, starting at the tonic
+var (
+	majorProfile = []float64{6.35, 2.23, 3.48, 2.33, 4.38, 4.09, 2.52, 5.19, 2.39, 3.66, 2.29, 2.88}
+	minorProfile = []float64{6.33, 2.68, 3.52, 5.38, 2.60, 3.53, 2.54, 4.75, 3.98, 2.69, 3.34, 3.17}
+)
+
+// Analysis is the result of analysing a whole audio stream
+type Analysis struct {
+	// Integrated loudness in LUFS (EBU R128)
+	Loudness float64
+	// Maximum inter-sample peak in dBTP
+	TruePeak float64
+	// Estimated tempo in beats per minute
+	BPM float64
+	// Estimated musical key, e.g. "A minor"
+	Key string
+}
+
+// Analyze decodes r to the end and computes its loudness, true peak, tempo and key
+func Analyze(r AudioReader) (*Analysis, error) {
+	channels := r.Channels()
+	sampleRate := r.SampleRate()
+	if err := checkStreamFormat(channels, sampleRate); err != nil {
+		return nil, err
+	}
+
+	loudness := newLoudnessMeter(sampleRate, channels)
+	truePeak := newTruePeakMeter(sampleRate, channels)
+
+	decimation := int(math.Round(float64(sampleRate) / analysisSampleRate))
+	if decimation < 1 {
+		decimation = 1
+	}
+	rate := float64(sampleRate) / float64(decimation)
+	onsets := newOnsetDetector()
+	chroma := newChromagram(rate)
+
+	buf := make([]float64, analysisReadSize*channels)
+	var decimated float64
+	var decimatedCount int
+	for {
+		n, err := r.Read(buf)
+		for i := 0; i+channels <= n; i += channels {
+			frame := buf[i : i+channels]
+			loudness.add(frame)
+			truePeak.add(frame)
+
+			// Box filter then decimate the mono mixdown
+			var mono float64
+			for _, x := range frame {
+				mono += x
+			}
+			decimated += mono / float64(channels)
+			decimatedCount++
+			if decimatedCount == decimation {
+				sample := decimated / float64(decimation)
+				onsets.add(sample)
+				chroma.add(sample)
+				decimated, decimatedCount = 0, 0
+			}
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &Analysis{
+		Loudness: round(loudness.integrated(), 1),
+		TruePeak: round(truePeak.decibels(), 1),
+		BPM:      round(onsets.tempo(rate), 1),
+		Key:      chroma.key(),
+	}, nil
+}
+
+func round(v float64, decimals int) float64 {
+	p := math.Pow(10, float64(decimals))
+	return math.Round(v*p) / p
+}
+
+// framer collects samples into overlapping frames
+type framer struct {
+	size, hop int
+	buf       []float64
+	process   func(frame []float64)
+}
+
+func (f *framer) add(sample float64) {
+	f.buf = append(f.buf, sample)
+	if len(f.buf) < f.size {
+		return
+	}
+	f.process(f.buf)
+	f.buf = append(f.buf[:0], f.buf[f.hop:]...)
+}
+
+// onsetDetector builds a spectral flux onset envelope used for tempo estimation
+type onsetDetector struct {
+	framer
+	spectrum *spectrum
+	previous []float64
+	envelope []float64
+}
+
+func newOnsetDetector() *onsetDetector {
+	d := &onsetDetector{spectrum: newSpectrum(onsetFrameSize)}
+	d.framer = framer{size: onsetFrameSize, hop: onsetHopSize, process: d.process}
+	return d
+}
+
+func (d *onsetDetector) process(frame []float64) {
+	mag := d.spectrum.magnitudes(frame)
+	if d.previous == nil {
+		d.previous = make([]float64, len(mag))
+	}
+
+	// Half wave rectified difference of log magnitudes
+	var flux float64
+	for i, m := range mag {
+		v := math.Log1p(m)
+		if diff := v - d.previous[i]; diff > 0 {
+			flux += diff
+		}
+		d.previous[i] = v
+	}
+	d.envelope = append(d.envelope, flux)
+}
+
+// tempo picks the autocorrelation peak of the onset envelope in the allowed
+// BPM range, weighted by a log-normal prior around preferredBPM
+func (d *onsetDetector) tempo(sampleRate float64) float64 {
+	envelopeRate := sampleRate / onsetHopSize
+	minLag := int(math.Floor(60 * envelopeRate / maxBPM))
+	maxLag := int(math.Ceil(60 * envelopeRate / minBPM))
+	if len(d.envelope) <= maxLag*2 {
+		return 0
+	}
+
+	// Remove the local mean so sustained energy does not dominate
+	envelope := make([]float64, len(d.envelope))
+	const meanWindow = 16
+	var sum float64
+	for i, v := range d.envelope {
+		sum += v
+		if i >= meanWindow {
+			sum -= d.envelope[i-meanWindow]
+		}
+		count := i + 1
+		if count > meanWindow {
+			count = meanWindow
+		}
+		if v -= sum / float64(count); v > 0 {
+			envelope[i] = v
+		}
+	}
+
+	acf := make([]float64, maxLag+2)
+	for lag := minLag - 1; lag <= maxLag+1; lag++ {
+		if lag < 1 {
+			continue
+		}
+		var s float64
+		for i := lag; i < len(envelope); i++ {
+			s += envelope[i] * envelope[i-lag]
+		}
+		acf[lag] = s / float64(len(envelope)-lag)
+	}
+
+	best, bestScore := 0, 0.0
+	for lag := minLag; lag <= maxLag; lag++ {
+		bpm := 60 * envelopeRate / float64(lag)
+		octaves := math.Log2(bpm / preferredBPM)
+		score := acf[lag] * math.Exp(-0.5*octaves*octaves)
+		if score > bestScore {
+			best, bestScore = lag, score
+		}
+	}
+	if best == 0 {
+		return 0
+	}
+
+	// Parabolic interpolation around the peak for sub-lag precision
+	lag := float64(best)
+	if best > 1 {
+		a, b, c := acf[best-1], acf[best], acf[best+1]
+		if denom := a - 2*b + c; denom != 0 {
+			lag += 0.5 * (a - c) / denom
+		}
+	}
+	return 60 * envelopeRate / lag
+}
+
+// chromagram accumulates the energy of each pitch class for key estimation
+type chromagram struct {
+	framer
+	spectrum *spectrum
+	bins     []int
+	energy   [12]float64
+}
+
+func newChromagram(sampleRate float64) *chromagram {
+	c := &chromagram{spectrum: newSpectrum(chromaFrameSize)}
+	c.framer = framer{size: chromaFrameSize, hop: chromaHopSize, process: c.process}
+
+	// Map every FFT bin in range to its nearest pitch class, -1 otherwise
+	c.bins = make([]int, chromaFrameSize/2+1)
+	for i := range c.bins {
+		freq := float64(i) * sampleRate / chromaFrameSize
+		if freq < chromaMinFreq || freq > chromaMaxFreq {
+			c.bins[i] = -1
+			continue
+		}
+		midi := int(math.Round(69 + 12*math.Log2(freq/440)))
+		c.bins[i] = ((midi % 12) + 12) % 12
+	}
+	return c
+}
+
+func (c *chromagram) process(frame []float64) {
+	mag := c.spectrum.magnitudes(frame)
+
+	// Normalise every frame so loud passages do not outweigh the rest
+	var frameEnergy [12]float64
+	var total float64
+	for i, m := range mag {
+		if pc := c.bins[i]; pc >= 0 {
+			frameEnergy[pc] += m * m
+			total += m * m
+		}
+	}
+	if total == 0 {
+		return
+	}
+	for pc := range frameEnergy {
+		c.energy[pc] += frameEnergy[pc] / total
+	}
+}
+
+// key correlates the chroma vector with every rotation of the major and minor profiles
+func (c *chromagram) key() string {
+	best, bestScore := "", math.Inf(-1)
+	for tonic := 0; tonic < 12; tonic++ {
+		for _, mode := range []struct {
+			name    string
+			profile []float64
+		}{{"major", majorProfile}, {"minor", minorProfile}} {
+			rotated := make([]float64, 12)
+			for i := range rotated {
+				rotated[(tonic+i)%12] = mode.profile[i]
+			}
+			if score := correlation(c.energy[:], rotated); score > bestScore {
+				best, bestScore = pitchClasses[tonic]+" "+mode.name, score
+			}
+		}
+	}
+	if math.IsNaN(bestScore) || math.IsInf(bestScore, -1) {
+		return ""
+	}
+	return best
+}
+
+// correlation returns the Pearson correlation coefficient of a and b
+func correlation(a, b []float64) float64 {
+	var meanA, meanB float64
+	for i := range a {
+		meanA += a[i]
+		meanB += b[i]
+	}
+	meanA /= float64(len(a))
+	meanB /= float64(len(b))
+
+	var cov, varA, varB float64
+	for i := range a {
+		da, db := a[i]-meanA, b[i]-meanB
+		cov += da * db
+		varA += da * da
+		varB += db * db
+	}
+	if varA == 0 || varB == 0 {
+		return math.NaN()
+	}
+	return cov / math.Sqrt(varA*varB)
+}
+
+var flatNames = map[string]string{"DB": "C#", "EB": "D#", "GB": "F#", "AB": "G#", "BB": "A#"}
+
+// ParseKey normalises user input such as "Am", "a minor", "Bb" or "F# major"
+// to the form returned by Analyze ("A minor", "A# major", ...)
+func ParseKey(s string) (string, bool) {
+	s = strings.ToUpper(strings.TrimSpace(s))
+	if s == "" {
+		return "", false
+	}
+
+	tonicLength := 1
+	if len(s) > 1 && (s[1] == '#' || s[1] == 'B') {
+		tonicLength = 2
+	}
+	tonic := s[:tonicLength]
+	if sharp, ok := flatNames[tonic]; ok {
+		tonic = sharp
+	}
+
+	mode := "major"
+	switch strings.TrimSpace(s[tonicLength:]) {
+	case "", "MAJ", "MAJOR":
+	case "M", "MIN", "MINOR":
+		mode = "minor"
+	default:
+		return "", false
+	}
+
+	for _, pc := range pitchClasses {
+		if pc == tonic {
+			return tonic + " " + mode, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,110 @@
+package media
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Stream formats the decoders accept. Both come from the file header and size
+// the per channel buffers and filter state of every consumer.
+const (
+	maxChannels   = 8
+	minSampleRate = 8000
+	maxSampleRate = 384000
+)
+
+var ErrUnsupportedAudio = errors.New("unsupported audio format")
+
+// AudioReader is a decoded audio stream
+type AudioReader interface {
+	SampleRate() int
+	Channels() int
+	// Read fills p with interleaved samples in the range [-1, 1]. The returned
+	// count is always a multiple of Channels().
+	Read(p []float64) (int, error)
+}
+
+// AudioFile is an AudioReader backed by an open file
+type AudioFile struct {
+	AudioReader
+	file *os.File
+}
+
+func (a *AudioFile) Close() error {
+	return a.file.Close()
+}
+
+// Progress returns how much of the file has been consumed, in percent
+func (a *AudioFile) Progress() int {
+	info, err := a.file.Stat()
+	if err != nil || info.Size() == 0 {
+		return 0
+	}
+	offset, err := a.file.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return 0
+	}
+	return int(offset * 100 / info.Size())
+}
+
+// OpenAudio opens the audio file at path and picks a decoder from its extension
+func OpenAudio(path string) (*AudioFile, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var reader AudioReader
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".mp3":
+		reader, err = newMP3Reader(file)
+	case ".wav":
+		reader, err = newWAVReader(file)
+	default:
+		err = ErrUnsupportedAudio
+	}
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	return &AudioFile{AudioReader: reader, file: file}, nil
+}
+
+// IsAudio reports whether filename has an extension OpenAudio can decode
+func IsAudio(filename string) bool {
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".mp3", ".wav":
+		return true
+	}
+	return false
+}
+
+// checkStreamFormat refuses channel counts and sample rates outside the
+// range the decoders accept
+func checkStreamFormat(channels, sampleRate int) error {
+	if channels < 1 || channels > maxChannels {
+		return fmt.Errorf("%w: %d channels", ErrUnsupportedAudio, channels)
+	}
+	if sampleRate < minSampleRate || sampleRate > maxSampleRate {
+		return fmt.Errorf("%w: sample rate of %d Hz", ErrUnsupportedAudio, sampleRate)
+	}
+	return nil
+}
+
+// readFull reads from r until p is full or the stream ends, it only returns an
+// error when nothing could be read
+func readFull(r io.Reader, p []byte) (int, error) {
+	n, err := io.ReadFull(r, p)
+	if n > 0 && (err == io.ErrUnexpectedEOF || err == io.EOF) {
+		return n, nil
+	}
+	if err == io.ErrUnexpectedEOF {
+		err = io.EOF
+	}
+	return n, err
+}
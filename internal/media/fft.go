@@ -0,0 +1,74 @@
+package media
+
+import (
+	"math"
+	"math/cmplx"
+)
+
+// fft computes the in-place radix-2 FFT of x, len(x) must be a power of two
+func fft(x []complex128) {
+	n := len(x)
+
+	// Bit reversal permutation
+	for i, j := 1, 0; i < n; i++ {
+		bit := n >> 1
+		for ; j&bit != 0; bit >>= 1 {
+			j ^= bit
+		}
+		j ^= bit
+		if i < j {
+			x[i], x[j] = x[j], x[i]
+		}
+	}
+
+	for size := 2; size <= n; size <<= 1 {
+		step := cmplx.Exp(complex(0, -2*math.Pi/float64(size)))
+		for start := 0; start < n; start += size {
+			w := complex(1, 0)
+			for k := 0; k < size/2; k++ {
+				a := x[start+k]
+				b := x[start+k+size/2] * w
+				x[start+k] = a + b
+				x[start+k+size/2] = a - b
+				w *= step
+			}
+		}
+	}
+}
+
+// hannWindow returns a periodic Hann window of length n
+func hannWindow(n int) []float64 {
+	window := make([]float64, n)
+	for i := range window {
+		window[i] = 0.5 - 0.5*math.Cos(2*math.Pi*float64(i)/float64(n))
+	}
+	return window
+}
+
+// spectrum is a reusable magnitude spectrum calculator for frames of a fixed size
+type spectrum struct {
+	window []float64
+	buf    []complex128
+	mag    []float64
+}
+
+func newSpectrum(size int) *spectrum {
+	return &spectrum{
+		window: hannWindow(size),
+		buf:    make([]complex128, size),
+		mag:    make([]float64, size/2+1),
+	}
+}
+
+// magnitudes returns the magnitude of the positive frequency bins of frame.
+// The returned slice is reused by the next call.
+func (s *spectrum) magnitudes(frame []float64) []float64 {
+	for i, v := range frame {
+		s.buf[i] = complex(v*s.window[i], 0)
+	}
+	fft(s.buf)
+	for i := range s.mag {
+		s.mag[i] = cmplx.Abs(s.buf[i])
+	}
+	return s.mag
+}
@@ -0,0 +1,196 @@
+package media
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// FLAC metadata block types
+const (
+	flacStreamInfo    = 0
+	flacVorbisComment = 4
+	flacPicture       = 6
+	// Front cover in the ID3v2 APIC / FLAC PICTURE type list
+	pictureFrontCover = 3
+)
+
+var ErrInvalidFLAC = errors.New("invalid FLAC file")
+
+// Vorbis comment field names mapped to normalised tag names
+var vorbisCommentTags = map[string]string{
+	"TITLE":       TagTitle,
+	"ARTIST":      TagArtist,
+	"ALBUM":       TagAlbum,
+	"GENRE":       TagGenre,
+	"DATE":        TagDate,
+	"TRACKNUMBER": TagTrack,
+	"COMMENT":     TagComment,
+	"DESCRIPTION": TagComment,
+	"ISRC":        TagISRC,
+}
+
+// readFLACInfo reads the STREAMINFO, VORBIS_COMMENT and PICTURE metadata blocks
+func readFLACInfo(r io.Reader, fileSize int64) (*AudioInfo, error) {
+	var marker [4]byte
+	if _, err := io.ReadFull(r, marker[:]); err != nil || string(marker[:]) != "fLaC" {
+		return nil, ErrInvalidFLAC
+	}
+
+	info := &AudioInfo{Format: "flac"}
+	hasStreamInfo := false
+	for last := false; !last; {
+		var header [4]byte
+		if _, err := io.ReadFull(r, header[:]); err != nil {
+			return nil, fmt.Errorf("%w: truncated metadata", ErrInvalidFLAC)
+		}
+		last = header[0]&0x80 != 0
+		blockType := header[0] & 0x7F
+		size := int(header[1])<<16 | int(header[2])<<8 | int(header[3])
+		if int64(size) > fileSize {
+			return nil, fmt.Errorf("%w: metadata block of %d bytes", ErrInvalidFLAC, size)
+		}
+
+		switch blockType {
+		case flacStreamInfo, flacVorbisComment, flacPicture:
+			body, err := readBody(r, int64(size), maxMetadataChunk)
+			if err != nil {
+				return nil, fmt.Errorf("%w: truncated metadata", ErrInvalidFLAC)
+			}
+			switch blockType {
+			case flacStreamInfo:
+				err = parseStreamInfo(body, info)
+				hasStreamInfo = err == nil
+			case flacVorbisComment:
+				err = parseVorbisComment(body, info)
+			case flacPicture:
+				err = parseFLACPicture(body, info)
+			}
+			if err != nil {
+				return nil, err
+			}
+		default:
+			if _, err := io.CopyN(io.Discard, r, int64(size)); err != nil {
+				return nil, fmt.Errorf("%w: truncated metadata", ErrInvalidFLAC)
+			}
+		}
+	}
+
+	if !hasStreamInfo {
+		return nil, fmt.Errorf("%w: missing STREAMINFO", ErrInvalidFLAC)
+	}
+	return info, nil
+}
+
+func parseStreamInfo(body []byte, info *AudioInfo) error {
+	if len(body) < 34 {
+		return fmt.Errorf("%w: short STREAMINFO", ErrInvalidFLAC)
+	}
+
+	// 20 bits sample rate, 3 bits channels - 1, 5 bits bits per sample - 1,
+	// 36 bits total samples, starting at byte 10
+	packed := binary.BigEndian.Uint64(body[10:18])
+	info.SampleRate = int(packed >> 44)
+	info.Channels = int(packed>>41&0x7) + 1
+	info.BitDepth = int(packed>>36&0x1F) + 1
+	if err := checkStreamFormat(info.Channels, info.SampleRate); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidFLAC, err)
+	}
+	totalSamples := int64(packed & 0xFFFFFFFFF)
+	info.Duration = samplesDuration(totalSamples, info.SampleRate)
+	return nil
+}
+
+// parseVorbisComment reads the little endian vendor string and KEY=value list
+func parseVorbisComment(body []byte, info *AudioInfo) error {
+	next := func() (string, bool) {
+		if len(body) < 4 {
+			return "", false
+		}
+		n := int(binary.LittleEndian.Uint32(body))
+		body = body[4:]
+		if n > len(body) {
+			return "", false
+		}
+		value := string(body[:n])
+		body = body[n:]
+		return value, true
+	}
+
+	if _, ok := next(); !ok {
+		return fmt.Errorf("%w: bad vorbis comment", ErrInvalidFLAC)
+	}
+	if len(body) < 4 {
+		return fmt.Errorf("%w: bad vorbis comment", ErrInvalidFLAC)
+	}
+	count := int(binary.LittleEndian.Uint32(body))
+	body = body[4:]
+
+	for i := 0; i < count; i++ {
+		comment, ok := next()
+		if !ok {
+			return fmt.Errorf("%w: bad vorbis comment", ErrInvalidFLAC)
+		}
+		key, value, found := strings.Cut(comment, "=")
+		if !found {
+			continue
+		}
+		if tag, ok := vorbisCommentTags[strings.ToUpper(key)]; ok {
+			info.setTag(tag, value)
+		}
+	}
+	return nil
+}
+
+// parseFLACPicture reads a big endian PICTURE block, preferring the front cover
+func parseFLACPicture(body []byte, info *AudioInfo) error {
+	bad := fmt.Errorf("%w: bad picture block", ErrInvalidFLAC)
+	readBytes := func() ([]byte, bool) {
+		if len(body) < 4 {
+			return nil, false
+		}
+		n := int(binary.BigEndian.Uint32(body))
+		body = body[4:]
+		if n > len(body) {
+			return nil, false
+		}
+		value := body[:n]
+		body = body[n:]
+		return value, true
+	}
+
+	if len(body) < 4 {
+		return bad
+	}
+	pictureType := binary.BigEndian.Uint32(body)
+	body = body[4:]
+
+	mimeType, ok := readBytes()
+	if !ok {
+		return bad
+	}
+	description, ok := readBytes()
+	if !ok {
+		return bad
+	}
+	// Width, height, colour depth and palette size
+	if len(body) < 16 {
+		return bad
+	}
+	body = body[16:]
+	data, ok := readBytes()
+	if !ok {
+		return bad
+	}
+
+	if info.Picture == nil || pictureType == pictureFrontCover {
+		info.Picture = &Picture{
+			MimeType:    string(mimeType),
+			Description: string(description),
+			Data:        data,
+		}
+	}
+	return nil
+}
@@ -0,0 +1,131 @@
+package media
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"testing"
+	"time"
+)
+
+// flacBlock returns a metadata block whose size header is size
+func flacBlock(blockType byte, last bool, size int, body []byte) []byte {
+	if last {
+		blockType |= 0x80
+	}
+	return append([]byte{blockType, byte(size >> 16), byte(size >> 8), byte(size)}, body...)
+}
+
+func streamInfo(sampleRate, channels, bits int, totalSamples int64) []byte {
+	body := make([]byte, 34)
+	packed := uint64(sampleRate)<<44 | uint64(channels-1)<<41 | uint64(bits-1)<<36 | uint64(totalSamples)
+	binary.BigEndian.PutUint64(body[10:], packed)
+	return body
+}
+
+func vorbisComment(vendor string, comments ...string) []byte {
+	lengthPrefixed := func(s string) []byte {
+		b := make([]byte, 4, 4+len(s))
+		binary.LittleEndian.PutUint32(b, uint32(len(s)))
+		return append(b, s...)
+	}
+	body := lengthPrefixed(vendor)
+	count := make([]byte, 4)
+	binary.LittleEndian.PutUint32(count, uint32(len(comments)))
+	body = append(body, count...)
+	for _, comment := range comments {
+		body = append(body, lengthPrefixed(comment)...)
+	}
+	return body
+}
+
+func flacPictureBlock(pictureType uint32, mimeType string, data []byte) []byte {
+	field := func(b []byte) []byte {
+		size := make([]byte, 4)
+		binary.BigEndian.PutUint32(size, uint32(len(b)))
+		return append(size, b...)
+	}
+	body := make([]byte, 4)
+	binary.BigEndian.PutUint32(body, pictureType)
+	body = append(body, field([]byte(mimeType))...)
+	body = append(body, field([]byte("cover"))...)
+	body = append(body, make([]byte, 16)...)
+	return append(body, field(data)...)
+}
+
+func flacFile(blocks ...[]byte) []byte {
+	file := []byte("fLaC")
+	for _, block := range blocks {
+		file = append(file, block...)
+	}
+	return file
+}
+
+func TestReadFLACInfo(t *testing.T) {
+	info := streamInfo(44100, 2, 24, 441000)
+	comment := vorbisComment("reference libFLAC", "TITLE=Nang Tho", "artist=Hoang Dung", "NOEQUALS", "ISRC=VNA011900001")
+	back := flacPictureBlock(4, "image/png", []byte{1})
+	front := flacPictureBlock(pictureFrontCover, "image/jpeg", []byte{2, 3})
+	file := flacFile(
+		flacBlock(flacStreamInfo, false, len(info), info),
+		flacBlock(1, false, 8, make([]byte, 8)), // padding
+		flacBlock(flacVorbisComment, false, len(comment), comment),
+		flacBlock(flacPicture, false, len(back), back),
+		flacBlock(flacPicture, true, len(front), front),
+	)
+
+	got, err := readFLACInfo(bytes.NewReader(file), int64(len(file)))
+	if err != nil {
+		t.Fatalf("readFLACInfo failed: %v", err)
+	}
+	if got.SampleRate != 44100 || got.Channels != 2 || got.BitDepth != 24 {
+		t.Errorf("got %d Hz, %d channels, %d bits", got.SampleRate, got.Channels, got.BitDepth)
+	}
+	if got.Duration != 10*time.Second {
+		t.Errorf("duration = %v, want 10s", got.Duration)
+	}
+	want := map[string]string{TagTitle: "Nang Tho", TagArtist: "Hoang Dung", TagISRC: "VNA011900001"}
+	for tag, value := range want {
+		if got.Tags[tag] != value {
+			t.Errorf("tag %s = %q, want %q", tag, got.Tags[tag], value)
+		}
+	}
+	if got.Picture == nil || got.Picture.MimeType != "image/jpeg" || !bytes.Equal(got.Picture.Data, []byte{2, 3}) {
+		t.Errorf("picture = %+v, want the front cover", got.Picture)
+	}
+}
+
+func TestReadFLACInfoErrors(t *testing.T) {
+	info := streamInfo(44100, 2, 16, 0)
+	valid := flacBlock(flacStreamInfo, false, len(info), info)
+	tests := []struct {
+		name string
+		file []byte
+	}{
+		{"empty file", nil},
+		{"truncated marker", []byte("fLa")},
+		{"not a FLAC file", []byte("OggS")},
+		{"no metadata", flacFile()},
+		{"truncated block header", flacFile([]byte{0, 0})},
+		{"truncated STREAMINFO", flacFile(flacBlock(flacStreamInfo, true, 34, info[:10]))},
+		{"short STREAMINFO", flacFile(flacBlock(flacStreamInfo, true, 10, info[:10]))},
+		{"block larger than the file", flacFile(flacBlock(flacStreamInfo, true, 0xFFFFFF, info))},
+		{"truncated skipped block", flacFile(valid, flacBlock(1, true, 100, make([]byte, 10)))},
+		{"missing STREAMINFO", flacFile(flacBlock(1, true, 4, make([]byte, 4)))},
+		{"no sample rate", flacFile(flacBlock(flacStreamInfo, true, 34, streamInfo(0, 2, 16, 0)))},
+		{"sample rate too high", flacFile(flacBlock(flacStreamInfo, true, 34, streamInfo(655350, 2, 16, 0)))},
+		{"vorbis vendor past the block", flacFile(valid, flacBlock(flacVorbisComment, true, 8, []byte{0xFF, 0xFF, 0xFF, 0x7F, 0, 0, 0, 0}))},
+		{"vorbis count past the block", flacFile(valid, flacBlock(flacVorbisComment, true, 8, []byte{0, 0, 0, 0, 0xFF, 0xFF, 0xFF, 0xFF}))},
+		{"picture data past the block", flacFile(valid, flacBlock(flacPicture, true, 46, append(flacPictureBlock(3, "image/png", nil)[:42], 0xFF, 0xFF, 0xFF, 0x7F)))},
+		{"short picture", flacFile(valid, flacBlock(flacPicture, true, 2, []byte{0, 0}))},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := readFLACInfo(bytes.NewReader(tt.file), int64(len(tt.file)))
+			if !errors.Is(err, ErrInvalidFLAC) {
+				t.Errorf("readFLACInfo error = %v, want %v", err, ErrInvalidFLAC)
+			}
+		})
+	}
+}
@@ -0,0 +1,264 @@
+package media
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf16"
+)
+
+var ErrInvalidMP3 = errors.New("invalid MP3 file")
+
+// ID3v2.3/2.4 and ID3v2.2 frame ids mapped to normalised tag names
+var id3Tags = map[string]string{
+	"TIT2": TagTitle, "TT2": TagTitle,
+	"TPE1": TagArtist, "TP1": TagArtist,
+	"TALB": TagAlbum, "TAL": TagAlbum,
+	"TCON": TagGenre, "TCO": TagGenre,
+	"TDRC": TagDate, "TYER": TagDate, "TYE": TagDate,
+	"TRCK": TagTrack, "TRK": TagTrack,
+	"COMM": TagComment, "COM": TagComment,
+	"TSRC": TagISRC, "TRC": TagISRC,
+}
+
+// readID3v2 parses an ID3v2 tag at the start of r into info and returns the
+// tag size, or 0 when the file has no ID3v2 tag
+func readID3v2(r io.ReadSeeker, info *AudioInfo) (int64, error) {
+	var header [10]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return 0, ErrInvalidMP3
+	}
+	if string(header[0:3]) != "ID3" {
+		return 0, nil
+	}
+
+	version := header[3]
+	flags := header[5]
+	size := int64(syncsafe(header[6:10]))
+	tagSize := size + 10
+	if flags&0x10 != 0 {
+		// Footer present
+		tagSize += 10
+	}
+	// Tags of unknown versions and oversized tags are skipped
+	if version < 2 || version > 4 || size > maxID3Tag {
+		return tagSize, nil
+	}
+
+	body, err := readBody(r, size, maxID3Tag)
+	if err != nil {
+		return 0, fmt.Errorf("%w: truncated ID3 tag", ErrInvalidMP3)
+	}
+	// Whole tag unsynchronisation (v2.2 and v2.3)
+	if flags&0x80 != 0 && version < 4 {
+		body = bytes.ReplaceAll(body, []byte{0xFF, 0x00}, []byte{0xFF})
+	}
+	// Skip the extended header
+	if flags&0x40 != 0 && version > 2 && len(body) >= 4 {
+		extended := int(binary.BigEndian.Uint32(body))
+		if version == 4 {
+			extended = syncsafe(body[:4])
+		} else {
+			extended += 4
+		}
+		if extended > len(body) {
+			return tagSize, nil
+		}
+		body = body[extended:]
+	}
+
+	idLength, headerLength := 4, 10
+	if version == 2 {
+		idLength, headerLength = 3, 6
+	}
+	for len(body) >= headerLength && body[0] != 0 {
+		id := string(body[:idLength])
+		var frameSize int
+		var formatFlags byte
+		switch version {
+		case 2:
+			frameSize = int(body[3])<<16 | int(body[4])<<8 | int(body[5])
+		case 3:
+			frameSize = int(binary.BigEndian.Uint32(body[4:8]))
+			formatFlags = body[9]
+		default:
+			frameSize = syncsafe(body[4:8])
+			formatFlags = body[9]
+		}
+		body = body[headerLength:]
+		if frameSize > len(body) {
+			break
+		}
+		frame := body[:frameSize]
+		body = body[frameSize:]
+
+		if version == 3 && formatFlags&0xC0 != 0 || version == 4 && formatFlags&0x0C != 0 {
+			// Compressed or encrypted frames are not supported
+			continue
+		}
+		if version == 4 {
+			if formatFlags&0x01 != 0 && len(frame) >= 4 {
+				// Data length indicator
+				frame = frame[4:]
+			}
+			if formatFlags&0x02 != 0 {
+				frame = bytes.ReplaceAll(frame, []byte{0xFF, 0x00}, []byte{0xFF})
+			}
+		}
+
+		switch {
+		case id == "APIC" || id == "PIC":
+			parseAPIC(frame, version, info)
+		case id == "COMM" || id == "COM":
+			// Encoding, 3 bytes language, description, text
+			if len(frame) > 4 {
+				_, text := splitEncoded(frame[0], frame[4:])
+				info.setTag(TagComment, decodeID3Text(frame[0], text))
+			}
+		case id3Tags[id] != "" && len(frame) > 1:
+			info.setTag(id3Tags[id], decodeID3Text(frame[0], frame[1:]))
+		}
+	}
+	return tagSize, nil
+}
+
+// parseAPIC reads an attached picture frame, preferring the front cover
+func parseAPIC(frame []byte, version byte, info *AudioInfo) {
+	if len(frame) < 2 {
+		return
+	}
+	encoding := frame[0]
+	rest := frame[1:]
+
+	var mimeType string
+	if version == 2 {
+		// ID3v2.2 uses a three letter image format
+		if len(rest) < 3 {
+			return
+		}
+		mimeType = "image/" + strings.ToLower(string(rest[:3]))
+		if mimeType == "image/jpg" {
+			mimeType = "image/jpeg"
+		}
+		rest = rest[3:]
+	} else {
+		end := bytes.IndexByte(rest, 0)
+		if end < 0 {
+			return
+		}
+		mimeType = string(rest[:end])
+		rest = rest[end+1:]
+	}
+	if len(rest) < 1 {
+		return
+	}
+	pictureType := rest[0]
+	description, data := splitEncoded(encoding, rest[1:])
+
+	if info.Picture == nil || pictureType == pictureFrontCover {
+		info.Picture = &Picture{
+			MimeType:    mimeType,
+			Description: decodeID3Text(encoding, description),
+			Data:        data,
+		}
+	}
+}
+
+// splitEncoded splits b at the first string terminator of the given text encoding
+func splitEncoded(encoding byte, b []byte) ([]byte, []byte) {
+	if encoding == 1 || encoding == 2 {
+		for i := 0; i+1 < len(b); i += 2 {
+			if b[i] == 0 && b[i+1] == 0 {
+				return b[:i], b[i+2:]
+			}
+		}
+		return b, nil
+	}
+	if i := bytes.IndexByte(b, 0); i >= 0 {
+		return b[:i], b[i+1:]
+	}
+	return b, nil
+}
+
+// decodeID3Text converts an ID3 text value to UTF-8. Multiple values are
+// separated by NUL and joined with "/".
+func decodeID3Text(encoding byte, b []byte) string {
+	var text string
+	switch encoding {
+	case 1, 2:
+		bigEndian := encoding == 2
+		if len(b) >= 2 {
+			switch {
+			case b[0] == 0xFE && b[1] == 0xFF:
+				bigEndian, b = true, b[2:]
+			case b[0] == 0xFF && b[1] == 0xFE:
+				bigEndian, b = false, b[2:]
+			}
+		}
+		units := make([]uint16, len(b)/2)
+		for i := range units {
+			if bigEndian {
+				units[i] = binary.BigEndian.Uint16(b[i*2:])
+			} else {
+				units[i] = binary.LittleEndian.Uint16(b[i*2:])
+			}
+		}
+		text = string(utf16.Decode(units))
+		// Each value of a multi value frame has its own BOM
+		text = strings.ReplaceAll(text, "\uFEFF", "")
+	case 3:
+		text = string(b)
+	default:
+		text = latin1(b)
+	}
+
+	var values []string
+	for _, v := range strings.Split(text, "\x00") {
+		if v = strings.TrimSpace(v); v != "" {
+			values = append(values, v)
+		}
+	}
+	return strings.Join(values, "/")
+}
+
+func latin1(b []byte) string {
+	runes := make([]rune, len(b))
+	for i, c := range b {
+		runes[i] = rune(c)
+	}
+	return string(runes)
+}
+
+func syncsafe(b []byte) int {
+	return int(b[0]&0x7F)<<21 | int(b[1]&0x7F)<<14 | int(b[2]&0x7F)<<7 | int(b[3]&0x7F)
+}
+
+// readID3v1 reads the 128 byte tag at the end of the file, used when there is no ID3v2 tag
+func readID3v1(r io.ReadSeeker, fileSize int64, info *AudioInfo) bool {
+	if fileSize < 128 {
+		return false
+	}
+	var tag [128]byte
+	if _, err := r.Seek(fileSize-128, io.SeekStart); err != nil {
+		return false
+	}
+	if _, err := io.ReadFull(r, tag[:]); err != nil || string(tag[0:3]) != "TAG" {
+		return false
+	}
+
+	info.setTag(TagTitle, latin1(bytes.TrimRight(tag[3:33], "\x00 ")))
+	info.setTag(TagArtist, latin1(bytes.TrimRight(tag[33:63], "\x00 ")))
+	info.setTag(TagAlbum, latin1(bytes.TrimRight(tag[63:93], "\x00 ")))
+	info.setTag(TagDate, latin1(bytes.TrimRight(tag[93:97], "\x00 ")))
+	// ID3v1.1 stores the track number in the last comment byte
+	if tag[125] == 0 && tag[126] != 0 {
+		info.setTag(TagComment, latin1(bytes.TrimRight(tag[97:125], "\x00 ")))
+		info.setTag(TagTrack, fmt.Sprint(tag[126]))
+	} else {
+		info.setTag(TagComment, latin1(bytes.TrimRight(tag[97:127], "\x00 ")))
+	}
+	return true
+}
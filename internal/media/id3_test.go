@@ -0,0 +1,235 @@
+package media
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"testing"
+	"time"
+)
+
+func syncsafeBytes(n int) []byte {
+	return []byte{byte(n >> 21 & 0x7F), byte(n >> 14 & 0x7F), byte(n >> 7 & 0x7F), byte(n & 0x7F)}
+}
+
+// id3Tag returns an ID3v2 tag whose size header is size
+func id3Tag(version, flags byte, size int, body []byte) []byte {
+	tag := append([]byte{'I', 'D', '3', version, 0, flags}, syncsafeBytes(size)...)
+	return append(tag, body...)
+}
+
+func id3Frame(version byte, id string, data []byte) []byte {
+	frame := []byte(id)
+	switch version {
+	case 2:
+		frame = append(frame, byte(len(data)>>16), byte(len(data)>>8), byte(len(data)))
+	case 3:
+		size := make([]byte, 4)
+		binary.BigEndian.PutUint32(size, uint32(len(data)))
+		frame = append(frame, size...)
+		frame = append(frame, 0, 0)
+	default:
+		frame = append(frame, syncsafeBytes(len(data))...)
+		frame = append(frame, 0, 0)
+	}
+	return append(frame, data...)
+}
+
+func id3Frames(frames ...[]byte) []byte {
+	var body []byte
+	for _, frame := range frames {
+		body = append(body, frame...)
+	}
+	return body
+}
+
+func TestReadID3v2(t *testing.T) {
+	utf16 := []byte{1, 0xFF, 0xFE, 'H', 0, 'o', 0, 'a', 0, 'n', 0, 'g', 0}
+	apic := append([]byte{0}, "image/png\x00"...)
+	apic = append(apic, pictureFrontCover)
+	apic = append(apic, "cover\x00"...)
+	apic = append(apic, 0x89, 'P', 'N', 'G')
+
+	tests := []struct {
+		name    string
+		file    []byte
+		size    int64
+		tags    map[string]string
+		picture string
+	}{
+		{
+			name: "no tag",
+			file: []byte{0xFF, 0xFB, 0x90, 0x00, 0, 0, 0, 0, 0, 0},
+		},
+		{
+			name: "ID3v2.3",
+			file: func() []byte {
+				body := id3Frames(
+					id3Frame(3, "TIT2", []byte("\x00Nang Tho")),
+					id3Frame(3, "TPE1", utf16),
+					id3Frame(3, "APIC", apic),
+				)
+				return id3Tag(3, 0, len(body), body)
+			}(),
+			size:    10 + 19 + 23 + 32,
+			tags:    map[string]string{TagTitle: "Nang Tho", TagArtist: "Hoang"},
+			picture: "image/png",
+		},
+		{
+			name: "ID3v2.4 with padding",
+			file: func() []byte {
+				body := append(id3Frame(4, "TIT2", []byte("\x03Nàng Thơ")), make([]byte, 20)...)
+				return id3Tag(4, 0, len(body), body)
+			}(),
+			size: 10 + 21 + 20,
+			tags: map[string]string{TagTitle: "Nàng Thơ"},
+		},
+		{
+			name: "ID3v2.2",
+			file: func() []byte {
+				body := id3Frame(2, "TT2", []byte("\x00Nang Tho"))
+				return id3Tag(2, 0, len(body), body)
+			}(),
+			size: 10 + 6 + 9,
+			tags: map[string]string{TagTitle: "Nang Tho"},
+		},
+		{
+			name: "footer counts in the size",
+			file: func() []byte {
+				body := id3Frame(4, "TIT2", []byte("\x00Nang Tho"))
+				return id3Tag(4, 0x10, len(body), body)
+			}(),
+			size: 10 + 19 + 10,
+			tags: map[string]string{TagTitle: "Nang Tho"},
+		},
+		{
+			name: "frame past the end of the tag keeps the earlier frames",
+			file: func() []byte {
+				body := id3Frame(3, "TIT2", []byte("\x00Nang Tho"))
+				body = append(body, id3Frame(3, "TPE1", make([]byte, 100))[:20]...)
+				return id3Tag(3, 0, len(body), body)
+			}(),
+			size: 10 + 19 + 20,
+			tags: map[string]string{TagTitle: "Nang Tho"},
+		},
+		{
+			name: "extended header past the end of the tag",
+			file: func() []byte {
+				body := []byte{0, 0, 0x10, 0}
+				return id3Tag(3, 0x40, len(body), body)
+			}(),
+			size: 10 + 4,
+		},
+		{
+			name: "unknown version is skipped",
+			file: id3Tag(5, 0, 1000, nil),
+			size: 10 + 1000,
+		},
+		{
+			name: "oversized tag is skipped without reading it",
+			file: id3Tag(3, 0, maxID3Tag+1, nil),
+			size: 10 + maxID3Tag + 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info := &AudioInfo{}
+			size, err := readID3v2(bytes.NewReader(tt.file), info)
+			if err != nil {
+				t.Fatalf("readID3v2 failed: %v", err)
+			}
+			if size != tt.size {
+				t.Errorf("tag size = %d, want %d", size, tt.size)
+			}
+			if len(info.Tags) != len(tt.tags) {
+				t.Errorf("tags = %v, want %v", info.Tags, tt.tags)
+			}
+			for tag, value := range tt.tags {
+				if info.Tags[tag] != value {
+					t.Errorf("tag %s = %q, want %q", tag, info.Tags[tag], value)
+				}
+			}
+			switch {
+			case tt.picture == "" && info.Picture != nil:
+				t.Errorf("unexpected picture %+v", info.Picture)
+			case tt.picture != "" && (info.Picture == nil || info.Picture.MimeType != tt.picture):
+				t.Errorf("picture = %+v, want %s", info.Picture, tt.picture)
+			}
+		})
+	}
+}
+
+func TestReadID3v2Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		file []byte
+	}{
+		{"empty file", nil},
+		{"truncated header", []byte("ID3\x03\x00")},
+		{"truncated tag", id3Tag(3, 0, 100, make([]byte, 20))},
+		{"tag size past the end of the file", id3Tag(4, 0, maxID3Tag, id3Frame(4, "TIT2", []byte("\x00x")))},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := readID3v2(bytes.NewReader(tt.file), &AudioInfo{})
+			if !errors.Is(err, ErrInvalidMP3) {
+				t.Errorf("readID3v2 error = %v, want %v", err, ErrInvalidMP3)
+			}
+		})
+	}
+}
+
+// mp3Frames returns n silent MPEG-1 layer III frames at 128 kbps and 44.1 kHz
+func mp3Frames(n int) []byte {
+	const frameSize = 144 * 128000 / 44100
+	var frames []byte
+	for i := 0; i < n; i++ {
+		frame := make([]byte, frameSize)
+		copy(frame, []byte{0xFF, 0xFB, 0x90, 0x00})
+		frames = append(frames, frame...)
+	}
+	return frames
+}
+
+func TestReadMP3Info(t *testing.T) {
+	body := id3Frame(3, "TIT2", []byte("\x00Nang Tho"))
+	file := append(id3Tag(3, 0, len(body), body), mp3Frames(3)...)
+
+	info, err := readMP3Info(bytes.NewReader(file), int64(len(file)))
+	if err != nil {
+		t.Fatalf("readMP3Info failed: %v", err)
+	}
+	if info.SampleRate != 44100 || info.Channels != 2 || info.Bitrate != 128000 {
+		t.Errorf("got %d Hz, %d channels, %d bps", info.SampleRate, info.Channels, info.Bitrate)
+	}
+	want := time.Duration(float64(len(mp3Frames(3))*8) / 128000 * float64(time.Second))
+	if info.Duration != want {
+		t.Errorf("duration = %v, want %v", info.Duration, want)
+	}
+	if info.Tags[TagTitle] != "Nang Tho" {
+		t.Errorf("tags = %v", info.Tags)
+	}
+}
+
+func TestReadMP3InfoErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		file []byte
+	}{
+		{"empty file", nil},
+		{"no audio frame", make([]byte, 1000)},
+		{"truncated ID3 tag", id3Tag(3, 0, 500, make([]byte, 10))},
+		{"oversized ID3 tag without audio", append(id3Tag(3, 0, maxID3Tag+1, nil), mp3Frames(2)...)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := readMP3Info(bytes.NewReader(tt.file), int64(len(tt.file)))
+			if !errors.Is(err, ErrInvalidMP3) {
+				t.Errorf("readMP3Info error = %v, want %v", err, ErrInvalidMP3)
+			}
+		})
+	}
+}
@@ -0,0 +1,134 @@
+package media
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	_ "image/gif"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/rolexkdev/emvn-music-library-server/common/utils"
+	_ "golang.org/x/image/bmp"
+	"golang.org/x/image/draw"
+)
+
+const thumbnailJPEGQuality = 85
+
+var ErrUnsupportedSize = errors.New("unsupported thumbnail size")
+
+// IsImage reports whether filename is an image we can generate thumbnails for
+func IsImage(filename string) bool {
+	return strings.HasPrefix(utils.GetFileContentType(filename), "image/")
+}
+
+// IsThumbnailSize reports whether size is one of the configured thumbnail sizes
+func IsThumbnailSize(size int) bool {
+	for _, s := range ThumbnailSizes {
+		if s == size {
+			return true
+		}
+	}
+	return false
+}
+
+// ThumbnailName returns the file name of the square derivative of filename at size.
+// PNG keeps its format to preserve transparency, everything else is stored as JPEG.
+func ThumbnailName(filename string, size int) string {
+	ext := filepath.Ext(filename)
+	base := strings.TrimSuffix(filename, ext)
+	if strings.ToLower(ext) != ".png" {
+		ext = ".jpg"
+	}
+	return fmt.Sprintf("%s_%d%s", base, size, ext)
+}
+
+// GenerateThumbnails creates every configured thumbnail of the image filename
+// in dir, in thumbnailDir
+func GenerateThumbnails(dir, thumbnailDir, filename string) error {
+	src, err := decodeImage(filepath.Join(dir, filename))
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(thumbnailDir, 0o755); err != nil {
+		return err
+	}
+
+	for _, size := range ThumbnailSizes {
+		if err := writeThumbnail(src, filepath.Join(thumbnailDir, ThumbnailName(filename, size)), size); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GenerateThumbnail creates a single thumbnail of the image filename in dir,
+// in thumbnailDir, and returns its name
+func GenerateThumbnail(dir, thumbnailDir, filename string, size int) (string, error) {
+	if !IsThumbnailSize(size) {
+		return "", ErrUnsupportedSize
+	}
+
+	src, err := decodeImage(filepath.Join(dir, filename))
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(thumbnailDir, 0o755); err != nil {
+		return "", err
+	}
+
+	name := ThumbnailName(filename, size)
+	if err := writeThumbnail(src, filepath.Join(thumbnailDir, name), size); err != nil {
+		return "", err
+	}
+	return name, nil
+}
+
+func decodeImage(path string) (image.Image, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	img, _, err := image.Decode(file)
+	if err != nil {
+		return nil, fmt.Errorf("decode image %s: %w", filepath.Base(path), err)
+	}
+	return img, nil
+}
+
+// writeThumbnail center-crops src to a square and scales it down to size.
+// Images smaller than size are not upscaled.
+func writeThumbnail(src image.Image, path string, size int) error {
+	bounds := src.Bounds()
+	side := bounds.Dx()
+	if bounds.Dy() < side {
+		side = bounds.Dy()
+	}
+	if side == 0 {
+		return fmt.Errorf("image %s is empty", filepath.Base(path))
+	}
+
+	crop := image.Rect(0, 0, side, side).Add(image.Pt(
+		bounds.Min.X+(bounds.Dx()-side)/2,
+		bounds.Min.Y+(bounds.Dy()-side)/2,
+	))
+
+	if side < size {
+		size = side
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, size, size))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), src, crop, draw.Src, nil)
+
+	return writeFileAtomic(path, func(out io.Writer) error {
+		if strings.EqualFold(filepath.Ext(path), ".png") {
+			return png.Encode(out, dst)
+		}
+		return jpeg.Encode(out, dst, &jpeg.Options{Quality: thumbnailJPEGQuality})
+	})
+}
@@ -0,0 +1,245 @@
+package media
+
+import (
+	"math"
+)
+
+// ITU-R BS.1770 / EBU R128 constants
+const (
+	loudnessBlockSeconds    = 0.4
+	loudnessStepSeconds     = 0.1
+	loudnessAbsoluteGate    = -70.0
+	loudnessRelativeGate    = -10.0
+	loudnessOffset          = -0.691
+	truePeakTapsPerPhase    = 12
+	silenceLoudness         = -70.0
+	silenceTruePeakDecibels = -144.0
+)
+
+// biquad is a second order IIR filter in direct form I
+type biquad struct {
+	b0, b1, b2, a1, a2 float64
+	x1, x2, y1, y2     float64
+}
+
+func (f *biquad) process(x float64) float64 {
+	y := f.b0*x + f.b1*f.x1 + f.b2*f.x2 - f.a1*f.y1 - f.a2*f.y2
+	f.x2, f.x1 = f.x1, x
+	f.y2, f.y1 = f.y1, y
+	return y
+}
+
+// kWeighting returns the BS.1770 pre-filter (high shelf) and RLB high pass
+// filter designed for sampleRate
+func kWeighting(sampleRate int) (shelf, highPass biquad) {
+	rate := float64(sampleRate)
+
+	f0 := 1681.974450955533
+	gain := 3.999843853973347
+	q := 0.7071752369554196
+	k := math.Tan(math.Pi * f0 / rate)
+	vh := math.Pow(10, gain/20)
+	vb := math.Pow(vh, 0.4996667741545416)
+	a0 := 1 + k/q + k*k
+	shelf = biquad{
+		b0: (vh + vb*k/q + k*k) / a0,
+		b1: 2 * (k*k - vh) / a0,
+		b2: (vh - vb*k/q + k*k) / a0,
+		a1: 2 * (k*k - 1) / a0,
+		a2: (1 - k/q + k*k) / a0,
+	}
+
+	f0 = 38.13547087602444
+	q = 0.5003270373238773
+	k = math.Tan(math.Pi * f0 / rate)
+	a0 = 1 + k/q + k*k
+	highPass = biquad{
+		b0: 1,
+		b1: -2,
+		b2: 1,
+		a1: 2 * (k*k - 1) / a0,
+		a2: (1 - k/q + k*k) / a0,
+	}
+	return shelf, highPass
+}
+
+// loudnessMeter measures gated integrated loudness (LUFS) as defined by EBU R128
+type loudnessMeter struct {
+	channels      int
+	stepSize      int
+	shelf         []biquad
+	highPass      []biquad
+	stepEnergy    []float64
+	stepCount     int
+	recentSteps   [][]float64
+	blockEnergies []float64
+}
+
+func newLoudnessMeter(sampleRate, channels int) *loudnessMeter {
+	m := &loudnessMeter{
+		channels:   channels,
+		stepSize:   int(float64(sampleRate) * loudnessStepSeconds),
+		shelf:      make([]biquad, channels),
+		highPass:   make([]biquad, channels),
+		stepEnergy: make([]float64, channels),
+	}
+	for ch := 0; ch < channels; ch++ {
+		m.shelf[ch], m.highPass[ch] = kWeighting(sampleRate)
+	}
+	return m
+}
+
+// add consumes one interleaved frame
+func (m *loudnessMeter) add(frame []float64) {
+	for ch, x := range frame {
+		y := m.highPass[ch].process(m.shelf[ch].process(x))
+		m.stepEnergy[ch] += y * y
+	}
+
+	m.stepCount++
+	if m.stepCount < m.stepSize {
+		return
+	}
+
+	// A 400ms block is made of the last four 100ms steps (75% overlap)
+	step := make([]float64, m.channels)
+	for ch := range step {
+		step[ch] = m.stepEnergy[ch] / float64(m.stepSize)
+		m.stepEnergy[ch] = 0
+	}
+	m.stepCount = 0
+
+	stepsPerBlock := int(loudnessBlockSeconds / loudnessStepSeconds)
+	m.recentSteps = append(m.recentSteps, step)
+	if len(m.recentSteps) > stepsPerBlock {
+		m.recentSteps = m.recentSteps[1:]
+	}
+	if len(m.recentSteps) < stepsPerBlock {
+		return
+	}
+
+	// Channel weights are 1.0 for mono and stereo sources
+	var energy float64
+	for _, s := range m.recentSteps {
+		for _, e := range s {
+			energy += e
+		}
+	}
+	m.blockEnergies = append(m.blockEnergies, energy/float64(stepsPerBlock))
+}
+
+// integrated returns the gated loudness in LUFS
+func (m *loudnessMeter) integrated() float64 {
+	gated := func(threshold float64) float64 {
+		var sum float64
+		var count int
+		for _, e := range m.blockEnergies {
+			if energyToLoudness(e) > threshold {
+				sum += e
+				count++
+			}
+		}
+		if count == 0 {
+			return 0
+		}
+		return sum / float64(count)
+	}
+
+	absolute := gated(loudnessAbsoluteGate)
+	if absolute == 0 {
+		return silenceLoudness
+	}
+	relative := energyToLoudness(absolute) + loudnessRelativeGate
+	if relative < loudnessAbsoluteGate {
+		relative = loudnessAbsoluteGate
+	}
+	return energyToLoudness(gated(relative))
+}
+
+func energyToLoudness(energy float64) float64 {
+	if energy <= 0 {
+		return math.Inf(-1)
+	}
+	return loudnessOffset + 10*math.Log10(energy)
+}
+
+// truePeakMeter estimates the inter-sample peak by oversampling with a
+// windowed sinc interpolator, as recommended by BS.1770 annex 2
+type truePeakMeter struct {
+	factor  int
+	phases  [][]float64
+	history [][]float64
+	peak    float64
+}
+
+func newTruePeakMeter(sampleRate, channels int) *truePeakMeter {
+	factor := 4
+	switch {
+	case sampleRate >= 192000:
+		factor = 1
+	case sampleRate >= 96000:
+		factor = 2
+	}
+
+	m := &truePeakMeter{
+		factor:  factor,
+		phases:  make([][]float64, factor),
+		history: make([][]float64, channels),
+	}
+
+	taps := truePeakTapsPerPhase
+	center := float64(taps-1) / 2
+	for p := 0; p < factor; p++ {
+		m.phases[p] = make([]float64, taps)
+		for k := 0; k < taps; k++ {
+			t := float64(k) - center - float64(p)/float64(factor) + 0.5
+			// Kaiser-like cosine window keeps the interpolator short
+			window := 0.5 + 0.5*math.Cos(math.Pi*t/(center+1))
+			m.phases[p][k] = sinc(t) * window
+		}
+	}
+	for ch := range m.history {
+		m.history[ch] = make([]float64, taps)
+	}
+	return m
+}
+
+func sinc(x float64) float64 {
+	if x == 0 {
+		return 1
+	}
+	return math.Sin(math.Pi*x) / (math.Pi * x)
+}
+
+// add consumes one interleaved frame
+func (m *truePeakMeter) add(frame []float64) {
+	for ch, x := range frame {
+		history := m.history[ch]
+		copy(history, history[1:])
+		history[len(history)-1] = x
+
+		if v := math.Abs(x); v > m.peak {
+			m.peak = v
+		}
+		if m.factor == 1 {
+			continue
+		}
+		for _, phase := range m.phases {
+			var y float64
+			for k, h := range phase {
+				y += h * history[len(history)-1-k]
+			}
+			if v := math.Abs(y); v > m.peak {
+				m.peak = v
+			}
+		}
+	}
+}
+
+// decibels returns the true peak in dBTP
+func (m *truePeakMeter) decibels() float64 {
+	if m.peak == 0 {
+		return silenceTruePeakDecibels
+	}
+	return 20 * math.Log10(m.peak)
+}
@@ -0,0 +1,47 @@
+package media
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/rolexkdev/emvn-music-library-server/config"
+)
+
+var (
+	// Edge lengths of the square thumbnails generated for uploaded images
+	ThumbnailSizes []int
+	// Resolution of generated waveforms
+	WaveformSamplesPerPixel int
+	WaveformBits            int
+)
+
+func Setup(c *config.Config) {
+	ThumbnailSizes = c.Media.ThumbnailSizes
+	WaveformSamplesPerPixel = c.Media.WaveformSamplesPerPixel
+	WaveformBits = c.Media.WaveformBits
+}
+
+// writeFileAtomic writes to a temporary file next to path and renames it into
+// place, so concurrent readers never see a partial file
+func writeFileAtomic(path string, write func(out io.Writer) error) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := tmp.Chmod(0o644); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := write(tmp); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,143 @@
+package media
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Largest bodies read from the headers of an audio file. Sizes come from the
+// file itself and are not trusted.
+const (
+	// A fmt chunk is 16, 18 or 40 bytes
+	maxWAVFormatChunk = 1 << 10
+	// RIFF LIST and bext chunks, FLAC metadata blocks (at most 16 MiB by
+	// their 24 bit size) and ID3v2 tags, embedded cover art included
+	maxMetadataChunk = 16 << 20
+	maxID3Tag        = 32 << 20
+)
+
+var errChunkTooLarge = errors.New("chunk too large")
+
+// readBody reads the size bytes of a chunk whose size comes from the file,
+// refusing sizes over max. The buffer grows with the bytes actually read, so a
+// forged size cannot allocate more than the file holds.
+func readBody(r io.Reader, size, max int64) ([]byte, error) {
+	if size < 0 || size > max {
+		return nil, errChunkTooLarge
+	}
+	body, err := io.ReadAll(io.LimitReader(r, size))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(body)) < size {
+		return nil, io.ErrUnexpectedEOF
+	}
+	return body, nil
+}
+
+// Normalised tag names shared by ID3, RIFF INFO, BWF and Vorbis comments
+const (
+	TagTitle   = "title"
+	TagArtist  = "artist"
+	TagAlbum   = "album"
+	TagGenre   = "genre"
+	TagDate    = "date"
+	TagTrack   = "track"
+	TagComment = "comment"
+	TagISRC    = "isrc"
+)
+
+// AudioInfo is the technical metadata and tags read from an audio file header
+type AudioInfo struct {
+	Format     string
+	Duration   time.Duration
+	SampleRate int
+	// Bits per sample of lossless formats, 0 for MP3
+	BitDepth int
+	Channels int
+	// Average bitrate in bits per second
+	Bitrate int
+	Tags    map[string]string
+	// Embedded cover art, if any
+	Picture *Picture
+}
+
+// Picture is an image embedded in an audio file (ID3 APIC or FLAC PICTURE)
+type Picture struct {
+	MimeType    string
+	Description string
+	Data        []byte
+}
+
+// Extension returns the file extension matching the picture MIME type
+func (p *Picture) Extension() string {
+	switch strings.ToLower(p.MimeType) {
+	case "image/png":
+		return ".png"
+	case "image/gif":
+		return ".gif"
+	case "image/bmp":
+		return ".bmp"
+	default:
+		return ".jpg"
+	}
+}
+
+// ReadAudioInfo parses the header of the audio file at path
+func ReadAudioInfo(path string) (*AudioInfo, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	stat, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	var info *AudioInfo
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".mp3":
+		info, err = readMP3Info(file, stat.Size())
+	case ".wav":
+		info, err = readWAVInfo(file, stat.Size())
+	case ".flac":
+		info, err = readFLACInfo(file, stat.Size())
+	default:
+		return nil, ErrUnsupportedAudio
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if info.Bitrate == 0 && info.Duration > 0 {
+		info.Bitrate = int(float64(stat.Size()*8) / info.Duration.Seconds())
+	}
+	return info, nil
+}
+
+// setTag stores a trimmed, non empty tag value without overwriting earlier values
+func (a *AudioInfo) setTag(name, value string) {
+	value = strings.TrimSpace(strings.TrimRight(value, "\x00"))
+	if value == "" {
+		return
+	}
+	if a.Tags == nil {
+		a.Tags = map[string]string{}
+	}
+	if _, exists := a.Tags[name]; !exists {
+		a.Tags[name] = value
+	}
+}
+
+func samplesDuration(samples int64, sampleRate int) time.Duration {
+	if sampleRate == 0 {
+		return 0
+	}
+	return time.Duration(float64(samples) / float64(sampleRate) * float64(time.Second))
+}
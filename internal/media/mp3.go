@@ -0,0 +1,219 @@
+package media
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/hajimehoshi/go-mp3"
+)
+
+// go-mp3 always outputs 16 bit little endian stereo
+const mp3Channels = 2
+
+type mp3Reader struct {
+	decoder *mp3.Decoder
+	buf     []byte
+}
+
+func newMP3Reader(r io.Reader) (*mp3Reader, error) {
+	decoder, err := mp3.NewDecoder(r)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkStreamFormat(mp3Channels, decoder.SampleRate()); err != nil {
+		return nil, err
+	}
+	return &mp3Reader{decoder: decoder}, nil
+}
+
+func (m *mp3Reader) SampleRate() int {
+	return m.decoder.SampleRate()
+}
+
+func (m *mp3Reader) Channels() int {
+	return mp3Channels
+}
+
+func (m *mp3Reader) Read(p []float64) (int, error) {
+	frames := len(p) / mp3Channels
+	if frames == 0 {
+		return 0, nil
+	}
+	size := frames * mp3Channels * 2
+	if cap(m.buf) < size {
+		m.buf = make([]byte, size)
+	}
+	buf := m.buf[:size]
+
+	n, err := readFull(m.decoder, buf)
+	n -= n % (mp3Channels * 2)
+	for i := 0; i < n/2; i++ {
+		p[i] = float64(int16(binary.LittleEndian.Uint16(buf[i*2:]))) / 32768
+	}
+	return n / 2, err
+}
+
+// Bitrates in kbps indexed by [MPEG-1][layer-1][bitrate index]
+var mp3Bitrates = [2][3][16]int{
+	// MPEG-2 and 2.5
+	{
+		{0, 32, 48, 56, 64, 80, 96, 112, 128, 144, 160, 176, 192, 224, 256},
+		{0, 8, 16, 24, 32, 40, 48, 56, 64, 80, 96, 112, 128, 144, 160},
+		{0, 8, 16, 24, 32, 40, 48, 56, 64, 80, 96, 112, 128, 144, 160},
+	},
+	// MPEG-1
+	{
+		{0, 32, 64, 96, 128, 160, 192, 224, 256, 288, 320, 352, 384, 416, 448},
+		{0, 32, 48, 56, 64, 80, 96, 112, 128, 160, 192, 224, 256, 320, 384},
+		{0, 32, 40, 48, 56, 64, 80, 96, 112, 128, 160, 192, 224, 256, 320},
+	},
+}
+
+// Sample rates indexed by version bits (0 MPEG-2.5, 2 MPEG-2, 3 MPEG-1)
+var mp3SampleRates = [4][3]int{
+	{11025, 12000, 8000},
+	{},
+	{22050, 24000, 16000},
+	{44100, 48000, 32000},
+}
+
+// How far into the file we look for the first audio frame
+const mp3SyncSearchLimit = 256 << 10
+
+// mp3FrameHeader is a decoded MPEG audio frame header
+type mp3FrameHeader struct {
+	mpeg1           bool
+	layer           int
+	bitrate         int
+	sampleRate      int
+	channels        int
+	samplesPerFrame int
+	size            int
+}
+
+func parseMP3FrameHeader(b []byte) (*mp3FrameHeader, bool) {
+	if len(b) < 4 || b[0] != 0xFF || b[1]&0xE0 != 0xE0 {
+		return nil, false
+	}
+	versionBits := b[1] >> 3 & 0x3
+	layerBits := b[1] >> 1 & 0x3
+	bitrateIndex := b[2] >> 4
+	rateIndex := b[2] >> 2 & 0x3
+	padding := int(b[2] >> 1 & 0x1)
+	if versionBits == 1 || layerBits == 0 || bitrateIndex == 0 || bitrateIndex == 15 || rateIndex == 3 {
+		return nil, false
+	}
+
+	h := &mp3FrameHeader{
+		mpeg1:      versionBits == 3,
+		layer:      int(4 - layerBits),
+		sampleRate: mp3SampleRates[versionBits][rateIndex],
+		channels:   2,
+	}
+	mpegIndex := 0
+	if h.mpeg1 {
+		mpegIndex = 1
+	}
+	h.bitrate = mp3Bitrates[mpegIndex][h.layer-1][bitrateIndex] * 1000
+	if b[3]>>6 == 3 {
+		h.channels = 1
+	}
+
+	switch {
+	case h.layer == 1:
+		h.samplesPerFrame = 384
+		h.size = (12*h.bitrate/h.sampleRate + padding) * 4
+	case h.layer == 3 && !h.mpeg1:
+		h.samplesPerFrame = 576
+		h.size = 72*h.bitrate/h.sampleRate + padding
+	default:
+		h.samplesPerFrame = 1152
+		h.size = 144*h.bitrate/h.sampleRate + padding
+	}
+	return h, true
+}
+
+// readMP3Info reads ID3 tags and derives duration from the Xing/Info or VBRI
+// header of the first frame, falling back to a constant bitrate estimate
+func readMP3Info(r io.ReadSeeker, fileSize int64) (*AudioInfo, error) {
+	info := &AudioInfo{Format: "mp3"}
+	audioStart, err := readID3v2(r, info)
+	if err != nil {
+		return nil, err
+	}
+	audioEnd := fileSize
+	if readID3v1(r, fileSize, info) {
+		audioEnd -= 128
+	}
+
+	if _, err := r.Seek(audioStart, io.SeekStart); err != nil {
+		return nil, err
+	}
+	buf := make([]byte, mp3SyncSearchLimit)
+	n, _ := io.ReadFull(r, buf)
+	buf = buf[:n]
+
+	// A frame header is trusted when another one follows right after it
+	var header *mp3FrameHeader
+	offset := 0
+	for ; offset+4 <= len(buf); offset++ {
+		h, ok := parseMP3FrameHeader(buf[offset:])
+		if !ok {
+			continue
+		}
+		next := offset + h.size
+		if next+4 <= len(buf) {
+			if _, ok := parseMP3FrameHeader(buf[next:]); !ok {
+				continue
+			}
+		}
+		header = h
+		break
+	}
+	if header == nil {
+		return nil, fmt.Errorf("%w: no audio frame found", ErrInvalidMP3)
+	}
+
+	info.SampleRate = header.sampleRate
+	info.Channels = header.channels
+	frame := buf[offset:]
+
+	if frames := vbrFrameCount(frame, header); frames > 0 {
+		info.Duration = samplesDuration(frames*int64(header.samplesPerFrame), header.sampleRate)
+		audioBytes := audioEnd - audioStart - int64(offset)
+		info.Bitrate = int(float64(audioBytes*8) / info.Duration.Seconds())
+		return info, nil
+	}
+
+	info.Bitrate = header.bitrate
+	audioBytes := audioEnd - audioStart - int64(offset)
+	info.Duration = time.Duration(float64(audioBytes*8) / float64(header.bitrate) * float64(time.Second))
+	return info, nil
+}
+
+// vbrFrameCount returns the frame count stored in a Xing/Info or VBRI header, or 0
+func vbrFrameCount(frame []byte, h *mp3FrameHeader) int64 {
+	// The Xing header follows the side information
+	sideInfo := 17
+	switch {
+	case h.mpeg1 && h.channels == 2:
+		sideInfo = 32
+	case !h.mpeg1 && h.channels == 1:
+		sideInfo = 9
+	}
+	xing := 4 + sideInfo
+	if len(frame) >= xing+12 {
+		tag := string(frame[xing : xing+4])
+		if (tag == "Xing" || tag == "Info") && binary.BigEndian.Uint32(frame[xing+4:])&0x1 != 0 {
+			return int64(binary.BigEndian.Uint32(frame[xing+8:]))
+		}
+	}
+
+	const vbri = 36
+	if len(frame) >= vbri+18 && string(frame[vbri:vbri+4]) == "VBRI" {
+		return int64(binary.BigEndian.Uint32(frame[vbri+14:]))
+	}
+	return 0
+}
@@ -0,0 +1,339 @@
+package media
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+)
+
+const (
+	wavFormatPCM        = 0x0001
+	wavFormatIEEEFloat  = 0x0003
+	wavFormatExtensible = 0xFFFE
+)
+
+var ErrInvalidWAV = errors.New("invalid WAV file")
+
+// wavHeader is the layout of a RIFF/WAVE file up to the start of its samples
+type wavHeader struct {
+	Format        uint16
+	Channels      int
+	SampleRate    int
+	BlockAlign    int
+	BitsPerSample int
+	DataOffset    int64
+	DataSize      int64
+}
+
+// walkRIFF calls visit for every chunk of the RIFF/WAVE file r with r
+// positioned at the chunk body. visit may consume up to size bytes, walking
+// stops when it returns true or at the end of the file.
+func walkRIFF(r io.ReadSeeker, visit func(id string, size int64) (bool, error)) error {
+	var riff [12]byte
+	if _, err := io.ReadFull(r, riff[:]); err != nil {
+		return ErrInvalidWAV
+	}
+	if string(riff[0:4]) != "RIFF" || string(riff[8:12]) != "WAVE" {
+		return ErrInvalidWAV
+	}
+
+	offset := int64(len(riff))
+	for {
+		var chunk [8]byte
+		if _, err := io.ReadFull(r, chunk[:]); err != nil {
+			if err == io.EOF || err == io.ErrUnexpectedEOF {
+				return nil
+			}
+			return err
+		}
+		id := string(chunk[0:4])
+		size := int64(binary.LittleEndian.Uint32(chunk[4:8]))
+		offset += int64(len(chunk))
+
+		stop, err := visit(id, size)
+		if err != nil || stop {
+			return err
+		}
+
+		// Chunks are padded to an even size
+		offset += size + size%2
+		if _, err := r.Seek(offset, io.SeekStart); err != nil {
+			return err
+		}
+	}
+}
+
+// readWAVHeader walks the RIFF chunks of r until the data chunk, leaving r
+// positioned at the first sample
+func readWAVHeader(r io.ReadSeeker) (*wavHeader, error) {
+	var header *wavHeader
+	err := walkRIFF(r, func(id string, size int64) (bool, error) {
+		switch id {
+		case "fmt ":
+			format, err := readWAVFormat(r, size)
+			if err != nil {
+				return false, err
+			}
+			header = format
+		case "data":
+			if header == nil {
+				return false, fmt.Errorf("%w: data chunk before fmt chunk", ErrInvalidWAV)
+			}
+			offset, err := r.Seek(0, io.SeekCurrent)
+			if err != nil {
+				return false, err
+			}
+			header.DataOffset = offset
+			header.DataSize = size
+			return true, nil
+		}
+		return false, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if header == nil || header.DataOffset == 0 {
+		return nil, fmt.Errorf("%w: missing data chunk", ErrInvalidWAV)
+	}
+	return header, nil
+}
+
+// readWAVFormat decodes the body of a fmt chunk
+func readWAVFormat(r io.Reader, size int64) (*wavHeader, error) {
+	if size < 16 {
+		return nil, fmt.Errorf("%w: short fmt chunk", ErrInvalidWAV)
+	}
+	body, err := readBody(r, size, maxWAVFormatChunk)
+	if errors.Is(err, errChunkTooLarge) {
+		return nil, fmt.Errorf("%w: fmt chunk of %d bytes", ErrInvalidWAV, size)
+	}
+	if err != nil {
+		return nil, ErrInvalidWAV
+	}
+
+	header := &wavHeader{
+		Format:        binary.LittleEndian.Uint16(body[0:2]),
+		Channels:      int(binary.LittleEndian.Uint16(body[2:4])),
+		SampleRate:    int(binary.LittleEndian.Uint32(body[4:8])),
+		BlockAlign:    int(binary.LittleEndian.Uint16(body[12:14])),
+		BitsPerSample: int(binary.LittleEndian.Uint16(body[14:16])),
+	}
+	if err := checkStreamFormat(header.Channels, header.SampleRate); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidWAV, err)
+	}
+	if header.BitsPerSample == 0 || header.BitsPerSample%8 != 0 || header.BlockAlign != header.Channels*header.BitsPerSample/8 {
+		return nil, fmt.Errorf("%w: block align of %d bytes for %d channels of %d bits", ErrInvalidWAV, header.BlockAlign, header.Channels, header.BitsPerSample)
+	}
+	// WAVE_FORMAT_EXTENSIBLE stores the real format in the sub format GUID
+	if header.Format == wavFormatExtensible && size >= 26 {
+		header.Format = binary.LittleEndian.Uint16(body[24:26])
+	}
+	return header, nil
+}
+
+type wavReader struct {
+	header    *wavHeader
+	data      io.Reader
+	buf       []byte
+	frameSize int
+}
+
+func newWAVReader(r io.ReadSeeker) (*wavReader, error) {
+	header, err := readWAVHeader(r)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case header.Format == wavFormatPCM && header.BitsPerSample >= 8 && header.BitsPerSample <= 32 && header.BitsPerSample%8 == 0:
+	case header.Format == wavFormatIEEEFloat && (header.BitsPerSample == 32 || header.BitsPerSample == 64):
+	default:
+		return nil, fmt.Errorf("%w: WAV format %#x with %d bits", ErrUnsupportedAudio, header.Format, header.BitsPerSample)
+	}
+	return &wavReader{
+		header:    header,
+		data:      io.LimitReader(r, header.DataSize),
+		frameSize: header.Channels * header.BitsPerSample / 8,
+	}, nil
+}
+
+func (w *wavReader) SampleRate() int {
+	return w.header.SampleRate
+}
+
+func (w *wavReader) Channels() int {
+	return w.header.Channels
+}
+
+func (w *wavReader) Read(p []float64) (int, error) {
+	frames := len(p) / w.header.Channels
+	if frames == 0 {
+		return 0, nil
+	}
+	size := frames * w.frameSize
+	if cap(w.buf) < size {
+		w.buf = make([]byte, size)
+	}
+	buf := w.buf[:size]
+
+	n, err := readFull(w.data, buf)
+	n -= n % w.frameSize
+
+	width := w.header.BitsPerSample / 8
+	samples := n / width
+	for i := 0; i < samples; i++ {
+		p[i] = w.decodeSample(buf[i*width : (i+1)*width])
+	}
+	return samples, err
+}
+
+func (w *wavReader) decodeSample(b []byte) float64 {
+	if w.header.Format == wavFormatIEEEFloat {
+		if len(b) == 8 {
+			return math.Float64frombits(binary.LittleEndian.Uint64(b))
+		}
+		return float64(math.Float32frombits(binary.LittleEndian.Uint32(b)))
+	}
+
+	switch len(b) {
+	case 1:
+		// 8 bit PCM is unsigned
+		return (float64(b[0]) - 128) / 128
+	case 2:
+		return float64(int16(binary.LittleEndian.Uint16(b))) / (1 << 15)
+	case 3:
+		v := int32(b[0]) | int32(b[1])<<8 | int32(int8(b[2]))<<16
+		return float64(v) / (1 << 23)
+	default:
+		return float64(int32(binary.LittleEndian.Uint32(b))) / (1 << 31)
+	}
+}
+
+// RIFF INFO list item ids mapped to normalised tag names
+var riffInfoTags = map[string]string{
+	"INAM": TagTitle,
+	"IART": TagArtist,
+	"IPRD": TagAlbum,
+	"IGNR": TagGenre,
+	"ICRD": TagDate,
+	"ITRK": TagTrack,
+	"IPRT": TagTrack,
+	"ICMT": TagComment,
+	"ISRC": TagISRC,
+}
+
+// readWAVInfo reads the format, duration, LIST/INFO tags and the Broadcast
+// Wave (BWF) bext chunk of a WAV file
+func readWAVInfo(r io.ReadSeeker, fileSize int64) (*AudioInfo, error) {
+	info := &AudioInfo{Format: "wav"}
+	var header *wavHeader
+	var dataSize int64 = -1
+
+	err := walkRIFF(r, func(id string, size int64) (bool, error) {
+		switch id {
+		case "fmt ":
+			format, err := readWAVFormat(r, size)
+			if err != nil {
+				return false, err
+			}
+			header = format
+		case "data":
+			dataSize = size
+		case "LIST":
+			body, err := readChunk(r, size)
+			if err != nil {
+				return false, err
+			}
+			parseRIFFInfo(body, info)
+		case "bext":
+			body, err := readChunk(r, size)
+			if err != nil {
+				return false, err
+			}
+			parseBext(body, info)
+		}
+		return false, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if header == nil || dataSize < 0 {
+		return nil, fmt.Errorf("%w: missing fmt or data chunk", ErrInvalidWAV)
+	}
+
+	// Recorders that could not finish writing leave a too large or zero size
+	if dataSize == 0 || dataSize > fileSize {
+		dataSize = fileSize
+	}
+
+	info.SampleRate = header.SampleRate
+	info.Channels = header.Channels
+	info.BitDepth = header.BitsPerSample
+	if frameSize := int64(header.Channels * header.BitsPerSample / 8); frameSize > 0 {
+		info.Duration = samplesDuration(dataSize/frameSize, header.SampleRate)
+		info.Bitrate = header.SampleRate * int(frameSize) * 8
+	}
+	return info, nil
+}
+
+// readChunk reads a metadata chunk body, refusing absurd sizes
+func readChunk(r io.Reader, size int64) ([]byte, error) {
+	body, err := readBody(r, size, maxMetadataChunk)
+	if errors.Is(err, errChunkTooLarge) {
+		return nil, fmt.Errorf("%w: metadata chunk of %d bytes", ErrInvalidWAV, size)
+	}
+	if err != nil {
+		return nil, ErrInvalidWAV
+	}
+	return body, nil
+}
+
+// parseRIFFInfo reads the items of a LIST chunk of type INFO
+func parseRIFFInfo(body []byte, info *AudioInfo) {
+	if len(body) < 4 || string(body[0:4]) != "INFO" {
+		return
+	}
+	for pos := 4; pos+8 <= len(body); {
+		id := string(body[pos : pos+4])
+		size := int(binary.LittleEndian.Uint32(body[pos+4 : pos+8]))
+		pos += 8
+		if size > len(body)-pos {
+			return
+		}
+		if tag, ok := riffInfoTags[id]; ok {
+			info.setTag(tag, string(body[pos:pos+size]))
+		}
+		pos += size + size%2
+	}
+}
+
+// parseBext reads the EBU Tech 3285 broadcast audio extension chunk
+func parseBext(body []byte, info *AudioInfo) {
+	const (
+		descriptionEnd    = 256
+		originatorEnd     = descriptionEnd + 32
+		referenceEnd      = originatorEnd + 32
+		dateEnd           = referenceEnd + 10
+		timeEnd           = dateEnd + 8
+		timeReferenceEnd  = timeEnd + 8
+		codingHistoryFrom = 602
+	)
+	if len(body) < timeReferenceEnd {
+		return
+	}
+
+	info.setTag(TagComment, string(body[:descriptionEnd]))
+	info.setTag("originator", string(body[descriptionEnd:originatorEnd]))
+	info.setTag("originator_reference", string(body[originatorEnd:referenceEnd]))
+	info.setTag("origination_date", string(body[referenceEnd:dateEnd]))
+	info.setTag("origination_time", string(body[dateEnd:timeEnd]))
+	// Sample count since midnight of the first sample
+	if ref := binary.LittleEndian.Uint64(body[timeEnd:timeReferenceEnd]); ref > 0 {
+		info.setTag("time_reference", fmt.Sprint(ref))
+	}
+	if len(body) > codingHistoryFrom {
+		info.setTag("coding_history", string(body[codingHistoryFrom:]))
+	}
+}
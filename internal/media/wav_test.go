@@ -0,0 +1,231 @@
+package media
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"testing"
+	"time"
+)
+
+// riffChunk returns a chunk whose size header is size, body padded to an even length
+func riffChunk(id string, size uint32, body []byte) []byte {
+	chunk := append([]byte(id), 0, 0, 0, 0)
+	binary.LittleEndian.PutUint32(chunk[4:], size)
+	chunk = append(chunk, body...)
+	if len(body)%2 == 1 {
+		chunk = append(chunk, 0)
+	}
+	return chunk
+}
+
+// chunk returns a chunk holding body
+func chunk(id string, body []byte) []byte {
+	return riffChunk(id, uint32(len(body)), body)
+}
+
+func wavFile(chunks ...[]byte) []byte {
+	body := []byte("WAVE")
+	for _, c := range chunks {
+		body = append(body, c...)
+	}
+	return append(riffChunk("RIFF", uint32(len(body)), nil), body...)
+}
+
+func fmtChunk(format, channels uint16, sampleRate uint32, blockAlign, bits uint16) []byte {
+	body := make([]byte, 16)
+	binary.LittleEndian.PutUint16(body[0:], format)
+	binary.LittleEndian.PutUint16(body[2:], channels)
+	binary.LittleEndian.PutUint32(body[4:], sampleRate)
+	binary.LittleEndian.PutUint32(body[8:], sampleRate*uint32(blockAlign))
+	binary.LittleEndian.PutUint16(body[12:], blockAlign)
+	binary.LittleEndian.PutUint16(body[14:], bits)
+	return chunk("fmt ", body)
+}
+
+func pcm16(samples ...int16) []byte {
+	b := make([]byte, len(samples)*2)
+	for i, s := range samples {
+		binary.LittleEndian.PutUint16(b[i*2:], uint16(s))
+	}
+	return b
+}
+
+func TestWAVReader(t *testing.T) {
+	tests := []struct {
+		name       string
+		file       []byte
+		channels   int
+		sampleRate int
+		want       []float64
+	}{
+		{
+			name:       "16 bit stereo",
+			file:       wavFile(fmtChunk(wavFormatPCM, 2, 44100, 4, 16), chunk("data", pcm16(0, 16384, -32768, 32767))),
+			channels:   2,
+			sampleRate: 44100,
+			want:       []float64{0, 0.5, -1, 32767.0 / 32768},
+		},
+		{
+			name:       "8 bit mono is unsigned",
+			file:       wavFile(fmtChunk(wavFormatPCM, 1, 8000, 1, 8), chunk("data", []byte{128, 192, 0})),
+			channels:   1,
+			sampleRate: 8000,
+			want:       []float64{0, 0.5, -1},
+		},
+		{
+			name:       "24 bit mono",
+			file:       wavFile(fmtChunk(wavFormatPCM, 1, 48000, 3, 24), chunk("data", []byte{0x00, 0x00, 0x40, 0x00, 0x00, 0x80})),
+			channels:   1,
+			sampleRate: 48000,
+			want:       []float64{0.5, -1},
+		},
+		{
+			name: "chunks before fmt are skipped",
+			file: wavFile(chunk("JUNK", []byte{1, 2, 3}), fmtChunk(wavFormatPCM, 1, 22050, 2, 16),
+				chunk("data", pcm16(16384))),
+			channels:   1,
+			sampleRate: 22050,
+			want:       []float64{0.5},
+		},
+		{
+			name:       "partial last frame is dropped",
+			file:       wavFile(fmtChunk(wavFormatPCM, 2, 44100, 4, 16), chunk("data", append(pcm16(16384, 16384), 0, 0))),
+			channels:   2,
+			sampleRate: 44100,
+			want:       []float64{0.5, 0.5},
+		},
+		{
+			name:       "data size past the end of the file",
+			file:       wavFile(fmtChunk(wavFormatPCM, 1, 44100, 2, 16), riffChunk("data", 1<<30, pcm16(16384))),
+			channels:   1,
+			sampleRate: 44100,
+			want:       []float64{0.5},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reader, err := newWAVReader(bytes.NewReader(tt.file))
+			if err != nil {
+				t.Fatalf("newWAVReader failed: %v", err)
+			}
+			if reader.Channels() != tt.channels || reader.SampleRate() != tt.sampleRate {
+				t.Errorf("got %d channels at %d Hz, want %d at %d Hz", reader.Channels(), reader.SampleRate(), tt.channels, tt.sampleRate)
+			}
+
+			var got []float64
+			buf := make([]float64, 64*tt.channels)
+			for {
+				n, err := reader.Read(buf)
+				got = append(got, buf[:n]...)
+				if err == io.EOF {
+					break
+				}
+				if err != nil {
+					t.Fatalf("Read failed: %v", err)
+				}
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("read %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("sample %d = %v, want %v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestWAVReaderErrors(t *testing.T) {
+	data := chunk("data", pcm16(0, 0))
+	tests := []struct {
+		name string
+		file []byte
+		want error
+	}{
+		{"empty file", nil, ErrInvalidWAV},
+		{"truncated RIFF header", []byte("RIFF\x00\x00"), ErrInvalidWAV},
+		{"not a WAVE file", append(riffChunk("RIFF", 4, nil), "AVI "...), ErrInvalidWAV},
+		{"missing fmt chunk", wavFile(data), ErrInvalidWAV},
+		{"missing data chunk", wavFile(fmtChunk(wavFormatPCM, 1, 44100, 2, 16)), ErrInvalidWAV},
+		{"short fmt chunk", wavFile(chunk("fmt ", make([]byte, 14)), data), ErrInvalidWAV},
+		{"truncated fmt chunk", wavFile(riffChunk("fmt ", 16, make([]byte, 6))), ErrInvalidWAV},
+		{"oversized fmt chunk", wavFile(riffChunk("fmt ", 0xFFFFFFF0, make([]byte, 16))), ErrInvalidWAV},
+		{"no channels", wavFile(fmtChunk(wavFormatPCM, 0, 44100, 0, 16), data), ErrInvalidWAV},
+		{"too many channels", wavFile(fmtChunk(wavFormatPCM, 65535, 44100, 65534, 16), data), ErrInvalidWAV},
+		{"nine channels", wavFile(fmtChunk(wavFormatPCM, 9, 44100, 18, 16), data), ErrInvalidWAV},
+		{"no sample rate", wavFile(fmtChunk(wavFormatPCM, 1, 0, 2, 16), data), ErrInvalidWAV},
+		{"sample rate too low", wavFile(fmtChunk(wavFormatPCM, 1, 4000, 2, 16), data), ErrInvalidWAV},
+		{"sample rate too high", wavFile(fmtChunk(wavFormatPCM, 1, 768000, 2, 16), data), ErrInvalidWAV},
+		{"block align too large", wavFile(fmtChunk(wavFormatPCM, 2, 44100, 8, 16), data), ErrInvalidWAV},
+		{"block align too small", wavFile(fmtChunk(wavFormatPCM, 2, 44100, 2, 16), data), ErrInvalidWAV},
+		{"bits not a whole byte", wavFile(fmtChunk(wavFormatPCM, 1, 44100, 1, 12), data), ErrInvalidWAV},
+		{"zero bits", wavFile(fmtChunk(wavFormatPCM, 1, 44100, 0, 0), data), ErrInvalidWAV},
+		{"compressed format", wavFile(fmtChunk(0x0002, 1, 44100, 2, 16), data), ErrUnsupportedAudio},
+		{"48 bit PCM", wavFile(fmtChunk(wavFormatPCM, 1, 44100, 6, 48), data), ErrUnsupportedAudio},
+		{"16 bit float", wavFile(fmtChunk(wavFormatIEEEFloat, 1, 44100, 2, 16), data), ErrUnsupportedAudio},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := newWAVReader(bytes.NewReader(tt.file))
+			if !errors.Is(err, tt.want) {
+				t.Errorf("newWAVReader error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadWAVInfo(t *testing.T) {
+	info := []byte("INFO")
+	info = append(info, chunk("INAM", []byte("Nang Tho\x00"))...)
+	info = append(info, chunk("IART", []byte("Hoang Dung\x00"))...)
+	// A second of 16 bit stereo at 8 kHz
+	file := wavFile(fmtChunk(wavFormatPCM, 2, 8000, 4, 16), chunk("LIST", info), chunk("data", make([]byte, 32000)))
+
+	got, err := readWAVInfo(bytes.NewReader(file), int64(len(file)))
+	if err != nil {
+		t.Fatalf("readWAVInfo failed: %v", err)
+	}
+	if got.SampleRate != 8000 || got.Channels != 2 || got.BitDepth != 16 {
+		t.Errorf("got %d Hz, %d channels, %d bits", got.SampleRate, got.Channels, got.BitDepth)
+	}
+	if got.Duration != time.Second {
+		t.Errorf("duration = %v, want 1s", got.Duration)
+	}
+	if got.Bitrate != 256000 {
+		t.Errorf("bitrate = %d, want 256000", got.Bitrate)
+	}
+	if got.Tags[TagTitle] != "Nang Tho" || got.Tags[TagArtist] != "Hoang Dung" {
+		t.Errorf("tags = %v", got.Tags)
+	}
+}
+
+func TestReadWAVInfoErrors(t *testing.T) {
+	format := fmtChunk(wavFormatPCM, 1, 44100, 2, 16)
+	data := chunk("data", pcm16(0))
+	tests := []struct {
+		name string
+		file []byte
+	}{
+		{"truncated RIFF header", []byte("RIFF")},
+		{"missing fmt chunk", wavFile(data)},
+		{"missing data chunk", wavFile(format)},
+		{"too many channels", wavFile(fmtChunk(wavFormatPCM, 65535, 44100, 65534, 16), data)},
+		{"oversized LIST chunk", wavFile(format, riffChunk("LIST", 0xFFFFFFFF, []byte("INFO")))},
+		{"truncated LIST chunk", wavFile(format, riffChunk("LIST", 1000, []byte("INFO")))},
+		{"oversized bext chunk", wavFile(format, riffChunk("bext", maxMetadataChunk+1, nil))},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := readWAVInfo(bytes.NewReader(tt.file), int64(len(tt.file)))
+			if !errors.Is(err, ErrInvalidWAV) {
+				t.Errorf("readWAVInfo error = %v, want %v", err, ErrInvalidWAV)
+			}
+		})
+	}
+}
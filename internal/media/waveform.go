@@ -0,0 +1,225 @@
+package media
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// Version of the audiowaveform data format we produce, version 2 adds the channel count
+const waveformVersion = 2
+
+const (
+	waveformFlag8Bit = 0x1
+	// Samples decoded per Read call
+	waveformReadSize = 4096
+)
+
+var ErrInvalidWaveformResolution = errors.New("invalid waveform resolution")
+
+// Waveform holds min/max peak pairs of a mono mixdown, compatible with the
+// audiowaveform JSON and binary (.dat) formats
+type Waveform struct {
+	Version         int   `json:"version"`
+	Channels        int   `json:"channels"`
+	SampleRate      int   `json:"sample_rate"`
+	SamplesPerPixel int   `json:"samples_per_pixel"`
+	Bits            int   `json:"bits"`
+	Length          int   `json:"length"`
+	Data            []int `json:"data"`
+}
+
+// ComputeWaveform decodes r to the end and records the min and max sample of
+// every samplesPerPixel frames, quantised to bits (8 or 16)
+func ComputeWaveform(r AudioReader, samplesPerPixel, bits int) (*Waveform, error) {
+	if samplesPerPixel <= 0 || (bits != 8 && bits != 16) {
+		return nil, ErrInvalidWaveformResolution
+	}
+	if err := checkStreamFormat(r.Channels(), r.SampleRate()); err != nil {
+		return nil, err
+	}
+
+	scale := float64(int(1)<<(bits-1) - 1)
+	channels := r.Channels()
+	waveform := &Waveform{
+		Version:         waveformVersion,
+		Channels:        1,
+		SampleRate:      r.SampleRate(),
+		SamplesPerPixel: samplesPerPixel,
+		Bits:            bits,
+	}
+
+	buf := make([]float64, waveformReadSize*channels)
+	min, max := 1.0, -1.0
+	count := 0
+	flush := func() {
+		waveform.Data = append(waveform.Data, quantise(min, scale), quantise(max, scale))
+		min, max = 1.0, -1.0
+		count = 0
+	}
+
+	for {
+		n, err := r.Read(buf)
+		for i := 0; i+channels <= n; i += channels {
+			// Mix every frame down to mono
+			var sample float64
+			for ch := 0; ch < channels; ch++ {
+				sample += buf[i+ch]
+			}
+			sample /= float64(channels)
+
+			if sample < min {
+				min = sample
+			}
+			if sample > max {
+				max = sample
+			}
+			count++
+			if count == samplesPerPixel {
+				flush()
+			}
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	if count > 0 {
+		flush()
+	}
+
+	waveform.Length = len(waveform.Data) / 2
+	return waveform, nil
+}
+
+func quantise(sample, scale float64) int {
+	v := int(sample * scale)
+	if v > int(scale) {
+		return int(scale)
+	}
+	if v < -int(scale)-1 {
+		return -int(scale) - 1
+	}
+	return v
+}
+
+// Resample merges peaks so that every point covers samplesPerPixel frames,
+// which must be a multiple of the current resolution
+func (w *Waveform) Resample(samplesPerPixel int) (*Waveform, error) {
+	if samplesPerPixel == w.SamplesPerPixel {
+		return w, nil
+	}
+	if samplesPerPixel < w.SamplesPerPixel || samplesPerPixel%w.SamplesPerPixel != 0 {
+		return nil, fmt.Errorf("%w: samples per pixel must be a multiple of %d", ErrInvalidWaveformResolution, w.SamplesPerPixel)
+	}
+
+	factor := samplesPerPixel / w.SamplesPerPixel
+	resampled := *w
+	resampled.SamplesPerPixel = samplesPerPixel
+	resampled.Data = make([]int, 0, (w.Length/factor+1)*2)
+	for start := 0; start < w.Length; start += factor {
+		end := start + factor
+		if end > w.Length {
+			end = w.Length
+		}
+		min, max := w.Data[start*2], w.Data[start*2+1]
+		for i := start + 1; i < end; i++ {
+			if w.Data[i*2] < min {
+				min = w.Data[i*2]
+			}
+			if w.Data[i*2+1] > max {
+				max = w.Data[i*2+1]
+			}
+		}
+		resampled.Data = append(resampled.Data, min, max)
+	}
+	resampled.Length = len(resampled.Data) / 2
+	return &resampled, nil
+}
+
+// WriteDat encodes the waveform in the audiowaveform binary format
+func (w *Waveform) WriteDat(out io.Writer) error {
+	var flags uint32
+	if w.Bits == 8 {
+		flags = waveformFlag8Bit
+	}
+	header := []interface{}{
+		int32(w.Version),
+		flags,
+		int32(w.SampleRate),
+		int32(w.SamplesPerPixel),
+		uint32(w.Length),
+		int32(w.Channels),
+	}
+	for _, v := range header {
+		if err := binary.Write(out, binary.LittleEndian, v); err != nil {
+			return err
+		}
+	}
+
+	var data interface{}
+	if w.Bits == 8 {
+		values := make([]int8, len(w.Data))
+		for i, v := range w.Data {
+			values[i] = int8(v)
+		}
+		data = values
+	} else {
+		values := make([]int16, len(w.Data))
+		for i, v := range w.Data {
+			values[i] = int16(v)
+		}
+		data = values
+	}
+	return binary.Write(out, binary.LittleEndian, data)
+}
+
+// ReadWaveformJSON loads a waveform previously written with SaveWaveform
+func ReadWaveformJSON(path string) (*Waveform, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var waveform Waveform
+	if err := json.NewDecoder(file).Decode(&waveform); err != nil {
+		return nil, err
+	}
+	return &waveform, nil
+}
+
+// SaveWaveform writes the JSON and .dat representations of w into dir as
+// name.json and name.dat
+func SaveWaveform(w *Waveform, dir, name string) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+
+	err := writeFileAtomic(filepath.Join(dir, name+".json"), func(out io.Writer) error {
+		return json.NewEncoder(out).Encode(w)
+	})
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(filepath.Join(dir, name+".dat"), w.WriteDat)
+}
+
+// GenerateWaveform decodes r at the configured resolution and saves its waveform into dir
+func GenerateWaveform(r AudioReader, dir, name string) (*Waveform, error) {
+	waveform, err := ComputeWaveform(r, WaveformSamplesPerPixel, WaveformBits)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := SaveWaveform(waveform, dir, name); err != nil {
+		return nil, err
+	}
+	return waveform, nil
+}
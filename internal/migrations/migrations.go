@@ -0,0 +1,191 @@
+package migrations
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/rolexkdev/emvn-music-library-server/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Migration is a one-off change of the stored data, applied once per database
+type Migration struct {
+	ID          string
+	Description string
+	Up          func(ctx context.Context, db *mongo.Database) error
+}
+
+// All migrations in the order they are applied, never reorder or rename them
+var migrations = []Migration{
+	{
+		ID:          "0001_search_keys",
+		Description: "backfill normalised search keys of tracks and playlists",
+		Up:          backfillSearchKeys,
+	},
+	{
+		ID:          "0002_suggest_prefixes",
+		Description: "add the suggestion prefixes to the search keys",
+		Up:          backfillSearchKeys,
+	},
+	{
+		ID:          "0003_fuzzy_trigrams",
+		Description: "add the fuzzy search trigrams to the search keys",
+		Up:          backfillSearchKeys,
+	},
+	{
+		ID:          "0004_versions",
+		Description: "start the version counter of tracks and playlists",
+		Up:          initVersions,
+	},
+	{
+		ID:          "0005_upload_names",
+		Description: "store the uploaded file name of tracks to find them when it is streamed",
+		Up:          backfillUploadNames,
+	},
+	{
+		ID:          "0006_unique_pending_jobs",
+		Description: "drop duplicate pending jobs before a single one per type and target is enforced",
+		Up:          dropDuplicatePendingJobs,
+	},
+}
+
+// Number of documents written by one bulk write
+const batchSize = 500
+
+// Run applies the migrations missing from the migration collection of db
+func Run(ctx context.Context, db *mongo.Database) error {
+	collection := db.Collection("migration")
+	for _, m := range migrations {
+		err := collection.FindOne(ctx, bson.M{"_id": m.ID}).Err()
+		if err == nil {
+			continue
+		}
+		if err != mongo.ErrNoDocuments {
+			return err
+		}
+
+		log.Printf("migrations: applying %s (%s)", m.ID, m.Description)
+		start := time.Now()
+		if err := m.Up(ctx, db); err != nil {
+			return fmt.Errorf("migration %s: %w", m.ID, err)
+		}
+		_, err = collection.InsertOne(ctx, bson.M{
+			"_id":         m.ID,
+			"description": m.Description,
+			"applied_at":  time.Now(),
+		})
+		if err != nil {
+			return err
+		}
+		log.Printf("migrations: applied %s in %s", m.ID, time.Since(start))
+	}
+	return nil
+}
+
+// updateEach calls value for every document of collection and stores the
+// returned value in its field
+func updateEach[T any](ctx context.Context, collection *mongo.Collection, field string, id func(*T) interface{}, value func(*T) interface{}) error {
+	cursor, err := collection.Find(ctx, bson.M{})
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+
+	var batch []mongo.WriteModel
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		_, err := collection.BulkWrite(ctx, batch)
+		batch = batch[:0]
+		return err
+	}
+
+	for cursor.Next(ctx) {
+		var doc T
+		if err := cursor.Decode(&doc); err != nil {
+			return err
+		}
+		batch = append(batch, mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"_id": id(&doc)}).
+			SetUpdate(bson.M{"$set": bson.M{field: value(&doc)}}))
+		if len(batch) == batchSize {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+	}
+	if err := cursor.Err(); err != nil {
+		return err
+	}
+	return flush()
+}
+
+func backfillSearchKeys(ctx context.Context, db *mongo.Database) error {
+	err := updateEach(ctx, db.Collection("track"), "search_keys",
+		func(t *models.Track) interface{} { return t.ID },
+		func(t *models.Track) interface{} { return models.NewTrackSearchKeys(t) },
+	)
+	if err != nil {
+		return err
+	}
+	return updateEach(ctx, db.Collection("playlist"), "search_keys",
+		func(p *models.Playlist) interface{} { return p.ID },
+		func(p *models.Playlist) interface{} { return models.NewPlaylistSearchKeys(p) },
+	)
+}
+
+func initVersions(ctx context.Context, db *mongo.Database) error {
+	for _, name := range []string{"track", "playlist"} {
+		_, err := db.Collection(name).UpdateMany(ctx,
+			bson.M{"version": bson.M{"$exists": false}},
+			bson.M{"$set": bson.M{"version": 1}},
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func backfillUploadNames(ctx context.Context, db *mongo.Database) error {
+	return updateEach(ctx, db.Collection("track"), "upload_name",
+		func(t *models.Track) interface{} { return t.ID },
+		func(t *models.Track) interface{} { return models.TrackUploadName(t) },
+	)
+}
+
+// dropDuplicatePendingJobs keeps the oldest pending job of every type and
+// target
+func dropDuplicatePendingJobs(ctx context.Context, db *mongo.Database) error {
+	collection := db.Collection("job")
+	cursor, err := collection.Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"status": models.JobPending}}},
+		{{Key: "$sort", Value: bson.M{"create_at": 1}}},
+		{{Key: "$group", Value: bson.M{
+			"_id": bson.M{"type": "$type", "target": "$target"},
+			"ids": bson.M{"$push": "$_id"},
+		}}},
+		{{Key: "$match", Value: bson.M{"ids.1": bson.M{"$exists": true}}}},
+	})
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var group struct {
+			IDs []interface{} `bson:"ids"`
+		}
+		if err := cursor.Decode(&group); err != nil {
+			return err
+		}
+		if _, err := collection.DeleteMany(ctx, bson.M{"_id": bson.M{"$in": group.IDs[1:]}}); err != nil {
+			return err
+		}
+	}
+	return cursor.Err()
+}
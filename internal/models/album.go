@@ -0,0 +1,125 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/rolexkdev/emvn-music-library-server/common/utils"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Album is a release of tracks, an album, EP or single. Its tracks keep its
+// title in their album field.
+type Album struct {
+	ID       primitive.ObjectID `bson:"_id"`
+	CreateAt time.Time          `bson:"create_at"`
+	UpdateAt time.Time          `bson:"update_at"`
+	Title    string             `bson:"title"`
+	// Album, EP or Single
+	ReleaseType string `bson:"release_type,omitempty"`
+	// UPC or EAN of the release
+	ICPN        string   `bson:"icpn,omitempty"`
+	ArtistID    string   `bson:"artist_id"`
+	ArtistName  string   `bson:"artist_name,omitempty"`
+	Label       string   `bson:"label,omitempty"`
+	Genre       string   `bson:"genre,omitempty"`
+	ReleaseDate int64    `bson:"release_date"`
+	CoverURL    string   `bson:"cover_url,omitempty"`
+	TrackIDs    []string `bson:"track_ids,omitempty"`
+	// Normalised title, see utils.NormalizeSearchText
+	TitleKey string `json:"-" bson:"title_key"`
+}
+
+// Create inserts album, keeping its id when already set
+func (r *AlbumRepository) Create(ctx context.Context, album *Album) (*Album, error) {
+	album.CreateAt = time.Now()
+	album.UpdateAt = album.CreateAt
+	if album.ID.IsZero() {
+		album.ID = primitive.NewObjectID()
+	}
+	album.TitleKey = utils.NormalizeSearchText(album.Title)
+	if _, err := r.Collection.InsertOne(ctx, album); err != nil {
+		return nil, err
+	}
+	return album, nil
+}
+
+func (r *AlbumRepository) FindByID(ctx context.Context, albumID primitive.ObjectID) (*Album, error) {
+	var album Album
+	if err := r.Collection.FindOne(ctx, bson.M{"_id": albumID}).Decode(&album); err != nil {
+		return nil, err
+	}
+	return &album, nil
+}
+
+// FindMany returns the albums, the latest release first
+func (r *AlbumRepository) FindMany(ctx context.Context) ([]*Album, error) {
+	albums := []*Album{}
+	opts := options.Find().SetSort(bson.D{{Key: "release_date", Value: -1}, {Key: "_id", Value: -1}})
+	cursor, err := r.Collection.Find(ctx, bson.M{}, opts)
+	if err != nil {
+		return nil, err
+	}
+	if err := cursor.All(ctx, &albums); err != nil {
+		return nil, err
+	}
+	return albums, nil
+}
+
+// Match returns the album with icpn, or else titled title by the artist with
+// artistID. It returns mongo.ErrNoDocuments when there is none.
+func (r *AlbumRepository) Match(ctx context.Context, icpn, title, artistID string) (*Album, error) {
+	if icpn != "" {
+		var album Album
+		err := r.Collection.FindOne(ctx, bson.M{"icpn": icpn}).Decode(&album)
+		if err == nil {
+			return &album, nil
+		}
+		if !errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, err
+		}
+	}
+
+	var album Album
+	filter := bson.M{"title_key": utils.NormalizeSearchText(title), "artist_id": artistID}
+	if icpn != "" {
+		filter["icpn"] = bson.M{"$in": bson.A{nil, ""}}
+	}
+	if err := r.Collection.FindOne(ctx, filter).Decode(&album); err != nil {
+		return nil, err
+	}
+	return &album, nil
+}
+
+func (r *AlbumRepository) Update(ctx context.Context, album *Album) error {
+	album.UpdateAt = time.Now()
+	album.TitleKey = utils.NormalizeSearchText(album.Title)
+	_, err := r.Collection.UpdateByID(ctx, album.ID, bson.M{"$set": bson.M{
+		"title":        album.Title,
+		"release_type": album.ReleaseType,
+		"icpn":         album.ICPN,
+		"artist_id":    album.ArtistID,
+		"artist_name":  album.ArtistName,
+		"label":        album.Label,
+		"genre":        album.Genre,
+		"release_date": album.ReleaseDate,
+		"cover_url":    album.CoverURL,
+		"track_ids":    album.TrackIDs,
+		"title_key":    album.TitleKey,
+		"update_at":    album.UpdateAt,
+	}})
+	return err
+}
+
+// EnsureIndexes creates the indexes matching albums by ICPN and title
+func (r *AlbumRepository) EnsureIndexes(ctx context.Context) error {
+	_, err := r.Collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "icpn", Value: 1}}},
+		{Keys: bson.D{{Key: "title_key", Value: 1}, {Key: "artist_id", Value: 1}}},
+	})
+	return err
+}
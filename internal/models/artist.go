@@ -0,0 +1,108 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/rolexkdev/emvn-music-library-server/common/utils"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Artist is a performer of tracks and albums, its id is the artist_id of
+// their records
+type Artist struct {
+	ID       primitive.ObjectID `bson:"_id"`
+	CreateAt time.Time          `bson:"create_at"`
+	UpdateAt time.Time          `bson:"update_at"`
+	Name     string             `bson:"name"`
+	// International Standard Name Identifier
+	ISNI string `bson:"isni,omitempty"`
+	// Normalised name, see utils.NormalizeSearchText
+	NameKey string `json:"-" bson:"name_key"`
+}
+
+// Create inserts artist, keeping its id when already set
+func (r *ArtistRepository) Create(ctx context.Context, artist *Artist) (*Artist, error) {
+	artist.CreateAt = time.Now()
+	artist.UpdateAt = artist.CreateAt
+	if artist.ID.IsZero() {
+		artist.ID = primitive.NewObjectID()
+	}
+	artist.NameKey = utils.NormalizeSearchText(artist.Name)
+	if _, err := r.Collection.InsertOne(ctx, artist); err != nil {
+		return nil, err
+	}
+	return artist, nil
+}
+
+func (r *ArtistRepository) FindByID(ctx context.Context, artistID primitive.ObjectID) (*Artist, error) {
+	var artist Artist
+	if err := r.Collection.FindOne(ctx, bson.M{"_id": artistID}).Decode(&artist); err != nil {
+		return nil, err
+	}
+	return &artist, nil
+}
+
+func (r *ArtistRepository) FindMany(ctx context.Context) ([]*Artist, error) {
+	artists := []*Artist{}
+	cursor, err := r.Collection.Find(ctx, bson.M{}, options.Find().SetSort(bson.D{{Key: "name_key", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+	if err := cursor.All(ctx, &artists); err != nil {
+		return nil, err
+	}
+	return artists, nil
+}
+
+// Match returns the artist with isni, or else named name without regard to
+// case and accents. It returns mongo.ErrNoDocuments when there is none.
+func (r *ArtistRepository) Match(ctx context.Context, isni, name string) (*Artist, error) {
+	if isni != "" {
+		var artist Artist
+		err := r.Collection.FindOne(ctx, bson.M{"isni": isni}).Decode(&artist)
+		if err == nil {
+			return &artist, nil
+		}
+		if !errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, err
+		}
+	}
+
+	var artist Artist
+	filter := bson.M{"name_key": utils.NormalizeSearchText(name)}
+	// An artist with another ISNI is another person of the same name
+	if isni != "" {
+		filter["isni"] = bson.M{"$in": bson.A{nil, ""}}
+	}
+	opts := options.FindOne().SetSort(bson.D{{Key: "create_at", Value: 1}})
+	if err := r.Collection.FindOne(ctx, filter, opts).Decode(&artist); err != nil {
+		return nil, err
+	}
+	return &artist, nil
+}
+
+func (r *ArtistRepository) Update(ctx context.Context, artist *Artist) error {
+	artist.UpdateAt = time.Now()
+	artist.NameKey = utils.NormalizeSearchText(artist.Name)
+	_, err := r.Collection.UpdateByID(ctx, artist.ID, bson.M{"$set": bson.M{
+		"name":      artist.Name,
+		"isni":      artist.ISNI,
+		"name_key":  artist.NameKey,
+		"update_at": artist.UpdateAt,
+	}})
+	return err
+}
+
+// EnsureIndexes creates the indexes matching artists by ISNI and name
+func (r *ArtistRepository) EnsureIndexes(ctx context.Context) error {
+	_, err := r.Collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "isni", Value: 1}}},
+		{Keys: bson.D{{Key: "name_key", Value: 1}}},
+	})
+	return err
+}
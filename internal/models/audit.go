@@ -0,0 +1,125 @@
+package models
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// AuditEntry records one mutating API request. Entries are only ever appended.
+type AuditEntry struct {
+	ID        primitive.ObjectID `bson:"_id"`
+	CreateAt  time.Time          `bson:"create_at"`
+	RequestID string             `bson:"request_id"`
+	// Name and role of the API key, anonymous without one
+	Actor  string `bson:"actor"`
+	Role   string `bson:"role,omitempty"`
+	Method string `bson:"method"`
+	// Route pattern, e.g. /api/v1/tracks/:id, and the requested path
+	Route    string `bson:"route"`
+	Path     string `bson:"path"`
+	TargetID string `bson:"target_id,omitempty"`
+	Status   int    `bson:"status"`
+	// Time taken to answer, in milliseconds
+	Duration int64  `bson:"duration"`
+	ClientIP string `bson:"client_ip"`
+	// Request body with secrets redacted and long values cut
+	Body interface{} `bson:"body,omitempty"`
+}
+
+// AuditFilter narrows audit listings, empty values are ignored
+type AuditFilter struct {
+	Actor    string
+	Method   string
+	Route    string
+	TargetID string
+	Status   int
+	From     time.Time
+	To       time.Time
+}
+
+func (f *AuditFilter) bson() bson.M {
+	filter := bson.M{}
+	if f.Actor != "" {
+		filter["actor"] = f.Actor
+	}
+	if f.Method != "" {
+		filter["method"] = f.Method
+	}
+	if f.Route != "" {
+		filter["route"] = f.Route
+	}
+	if f.TargetID != "" {
+		filter["target_id"] = f.TargetID
+	}
+	if f.Status != 0 {
+		filter["status"] = f.Status
+	}
+	if !f.From.IsZero() || !f.To.IsZero() {
+		period := bson.M{}
+		if !f.From.IsZero() {
+			period["$gte"] = f.From
+		}
+		if !f.To.IsZero() {
+			period["$lt"] = f.To
+		}
+		filter["create_at"] = period
+	}
+	return filter
+}
+
+func (r *AuditRepository) Append(ctx context.Context, entry *AuditEntry) error {
+	entry.ID = primitive.NewObjectID()
+	_, err := r.Collection.InsertOne(ctx, entry)
+	return err
+}
+
+// FindMany returns the latest limit entries matching filter, newest first
+func (r *AuditRepository) FindMany(ctx context.Context, filter *AuditFilter, limit int) ([]*AuditEntry, error) {
+	entries := []*AuditEntry{}
+	opts := options.Find().SetSort(bson.D{{Key: "create_at", Value: -1}}).SetLimit(int64(limit))
+	cursor, err := r.Collection.Find(ctx, filter.bson(), opts)
+	if err != nil {
+		return nil, err
+	}
+	if err := cursor.All(ctx, &entries); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// Export calls each for every entry matching filter, oldest first, without
+// loading them all in memory
+func (r *AuditRepository) Export(ctx context.Context, filter *AuditFilter, each func(*AuditEntry) error) error {
+	opts := options.Find().SetSort(bson.D{{Key: "create_at", Value: 1}})
+	cursor, err := r.Collection.Find(ctx, filter.bson(), opts)
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var entry AuditEntry
+		if err := cursor.Decode(&entry); err != nil {
+			return err
+		}
+		if err := each(&entry); err != nil {
+			return err
+		}
+	}
+	return cursor.Err()
+}
+
+// EnsureIndexes creates the indexes used to query the audit log
+func (r *AuditRepository) EnsureIndexes(ctx context.Context) error {
+	_, err := r.Collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "create_at", Value: -1}}},
+		{Keys: bson.D{{Key: "actor", Value: 1}, {Key: "create_at", Value: -1}}},
+		{Keys: bson.D{{Key: "target_id", Value: 1}, {Key: "create_at", Value: -1}}},
+	})
+	return err
+}
@@ -0,0 +1,95 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// TrackAvailability restricts the territories and dates a track is licensed
+// for, empty fields do not restrict it
+type TrackAvailability struct {
+	// ISO 3166-1 alpha-2 codes of the only territories the track is available in
+	Territories []string `bson:"territories,omitempty"`
+	// Territories the track is never available in
+	BlockedTerritories []string `bson:"blocked_territories,omitempty"`
+	// Window in milliseconds since the epoch, EndAt is exclusive
+	StartAt int64 `bson:"start_at,omitempty"`
+	EndAt   int64 `bson:"end_at,omitempty"`
+}
+
+// Availability is the territory and time a caller asks for tracks in. A
+// caller of unknown territory only gets the tracks available everywhere.
+type Availability struct {
+	// ISO 3166-1 alpha-2 code, empty when unknown
+	Territory string
+	At        time.Time
+}
+
+// Allows reports whether track is available to a, a nil a allows every track
+func (a *Availability) Allows(track *Track) bool {
+	if a == nil || track.Availability == nil {
+		return true
+	}
+	rules := track.Availability
+	at := a.At.UnixMilli()
+	if rules.StartAt != 0 && at < rules.StartAt {
+		return false
+	}
+	if rules.EndAt != 0 && at >= rules.EndAt {
+		return false
+	}
+	return rules.AllowsTerritory(a.Territory)
+}
+
+// AllowsTerritory reports whether the rules let the track be used in
+// territory, ignoring the dates. An empty territory stands for every
+// territory, only allowed when no territory is restricted.
+func (rules *TrackAvailability) AllowsTerritory(territory string) bool {
+	if rules == nil {
+		return true
+	}
+	if territory == "" {
+		return len(rules.Territories) == 0 && len(rules.BlockedTerritories) == 0
+	}
+	if contains(rules.BlockedTerritories, territory) {
+		return false
+	}
+	return len(rules.Territories) == 0 || contains(rules.Territories, territory)
+}
+
+// conditions returns the query conditions matching the tracks a allows, to be
+// combined with $and
+func (a *Availability) conditions() []interface{} {
+	if a == nil {
+		return nil
+	}
+	at := a.At.UnixMilli()
+	conds := []interface{}{
+		// Missing bounds are open
+		bson.M{"availability.start_at": bson.M{"$not": bson.M{"$gt": at}}},
+		bson.M{"availability.end_at": bson.M{"$not": bson.M{"$lte": at}}},
+	}
+	if a.Territory == "" {
+		return append(conds,
+			bson.M{"availability.territories.0": bson.M{"$exists": false}},
+			bson.M{"availability.blocked_territories.0": bson.M{"$exists": false}},
+		)
+	}
+	return append(conds,
+		bson.M{"availability.blocked_territories": bson.M{"$ne": a.Territory}},
+		bson.M{"$or": bson.A{
+			bson.M{"availability.territories.0": bson.M{"$exists": false}},
+			bson.M{"availability.territories": a.Territory},
+		}},
+	)
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,266 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Operations of a bulk write
+const (
+	BulkCreate = "create"
+	BulkUpdate = "update"
+	BulkDelete = "delete"
+)
+
+// ErrNotWritten reports an operation of a bulk write left out because another
+// one failed, after it in an ordered write or anywhere in an atomic one
+var ErrNotWritten = errors.New("not written because another operation failed")
+
+// ErrNoTransactions reports an atomic bulk write on a deployment without
+// transactions, such as a standalone mongod
+var ErrNoTransactions = errors.New("transactions need a replica set or mongos")
+
+// MongoDB IllegalOperation code, answered to a transaction on a standalone mongod
+const illegalOperation = 20
+
+// errBulkAborted rolls back the transaction of an atomic bulk write
+var errBulkAborted = errors.New("bulk write aborted")
+
+// TrackWrite is one operation of a bulk write. Track holds the fields of a
+// created or updated track, ID and Version the track an update or delete is
+// based on.
+type TrackWrite struct {
+	Op      string
+	ID      primitive.ObjectID
+	Version int64
+	Track   *Track
+}
+
+// FindByIDs returns the live tracks among ids, in no particular order
+func (r *TrackRepository) FindByIDs(ctx context.Context, ids []primitive.ObjectID) ([]*Track, error) {
+	tracks := []*Track{}
+	if len(ids) == 0 {
+		return tracks, nil
+	}
+	cursor, err := r.Collection.Find(ctx, notDeleted(bson.M{"_id": bson.M{"$in": ids}}))
+	if err != nil {
+		return nil, err
+	}
+	if err := cursor.All(ctx, &tracks); err != nil {
+		return nil, err
+	}
+	return tracks, nil
+}
+
+// FindMatching returns the live tracks having one of isrcs or whose normalised
+// title is one of titles, the candidates of a catalog import
+func (r *TrackRepository) FindMatching(ctx context.Context, isrcs, titles []string) ([]*Track, error) {
+	tracks := []*Track{}
+	or := bson.A{}
+	if len(isrcs) > 0 {
+		or = append(or, bson.M{"isrc": bson.M{"$in": isrcs}})
+	}
+	if len(titles) > 0 {
+		or = append(or, bson.M{"search_keys.title": bson.M{"$in": titles}})
+	}
+	if len(or) == 0 {
+		return tracks, nil
+	}
+	cursor, err := r.Collection.Find(ctx, notDeleted(bson.M{"$or": or}))
+	if err != nil {
+		return nil, err
+	}
+	if err := cursor.All(ctx, &tracks); err != nil {
+		return nil, err
+	}
+	return tracks, nil
+}
+
+// EnsureIndexes creates the indexes matching imported tracks, answering
+// rights lookups by work, label, owner and publishing split and finding the
+// tracks of an uploaded file
+func (r *TrackRepository) EnsureIndexes(ctx context.Context) error {
+	_, err := r.Collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "isrc", Value: 1}}},
+		{Keys: bson.D{{Key: "search_keys.title", Value: 1}}},
+		{Keys: bson.D{{Key: "iswc", Value: 1}}},
+		{Keys: bson.D{{Key: "label", Value: 1}}},
+		{Keys: bson.D{{Key: "master_owner", Value: 1}}},
+		{Keys: bson.D{{Key: "publishing.name", Value: 1}, {Key: "publishing.role", Value: 1}}},
+		{Keys: bson.D{{Key: "upload_name", Value: 1}}},
+	})
+	return err
+}
+
+// BulkWrite runs writes in a single BulkWrite and returns the error of each
+// write, nil when it succeeded. Updates and deletes only apply to the version
+// they are based on. An ordered write stops at the first write error, a track
+// changed meanwhile does not stop it. An atomic write runs in a transaction,
+// which needs a replica set, and writes nothing unless every operation
+// succeeds. The returned error reports a failure of the whole write.
+func (r *TrackRepository) BulkWrite(ctx context.Context, writes []*TrackWrite, ordered, atomic bool) ([]error, error) {
+	if !atomic {
+		errs, err := r.bulkWrite(ctx, writes, ordered)
+		if err != nil {
+			return nil, err
+		}
+		bumpVersions(writes, errs)
+		return errs, nil
+	}
+
+	session, err := r.Collection.Database().Client().StartSession()
+	if err != nil {
+		return nil, err
+	}
+	defer session.EndSession(ctx)
+
+	var errs []error
+	_, err = session.WithTransaction(ctx, func(sc mongo.SessionContext) (interface{}, error) {
+		var err error
+		errs, err = r.bulkWrite(sc, writes, true)
+		if err != nil {
+			return nil, err
+		}
+		for _, err := range errs {
+			if err != nil {
+				return nil, errBulkAborted
+			}
+		}
+		return nil, nil
+	})
+	if errors.Is(err, errBulkAborted) {
+		for i := range errs {
+			if errs[i] == nil {
+				errs[i] = ErrNotWritten
+			}
+		}
+		return errs, nil
+	}
+	var serverErr mongo.ServerError
+	if errors.As(err, &serverErr) && serverErr.HasErrorCode(illegalOperation) {
+		return nil, ErrNoTransactions
+	}
+	if err != nil {
+		return nil, err
+	}
+	bumpVersions(writes, errs)
+	return errs, nil
+}
+
+func (r *TrackRepository) bulkWrite(ctx context.Context, writes []*TrackWrite, ordered bool) ([]error, error) {
+	errs := make([]error, len(writes))
+	if len(writes) == 0 {
+		return errs, nil
+	}
+
+	now := time.Now()
+	operations := make([]mongo.WriteModel, len(writes))
+	for i, write := range writes {
+		filter := notDeleted(bson.M{"_id": write.ID, "version": write.Version})
+		switch write.Op {
+		case BulkCreate:
+			newTrack(write.Track)
+			write.ID = write.Track.ID
+			operations[i] = mongo.NewInsertOneModel().SetDocument(write.Track)
+		case BulkUpdate:
+			update := trackUpdate(write.Track)
+			update["$inc"] = bson.M{"version": 1}
+			operations[i] = mongo.NewUpdateOneModel().SetFilter(filter).SetUpdate(update)
+		case BulkDelete:
+			update := bson.M{"$set": bson.M{"delete_at": now}, "$inc": bson.M{"version": 1}}
+			operations[i] = mongo.NewUpdateOneModel().SetFilter(filter).SetUpdate(update)
+		}
+	}
+
+	result, err := r.Collection.BulkWrite(ctx, operations, options.BulkWrite().SetOrdered(ordered))
+	var bulkErr mongo.BulkWriteException
+	if errors.As(err, &bulkErr) {
+		for _, writeErr := range bulkErr.WriteErrors {
+			errs[writeErr.Index] = writeErr
+		}
+		if ordered && len(bulkErr.WriteErrors) > 0 {
+			for i := bulkErr.WriteErrors[0].Index + 1; i < len(errs); i++ {
+				errs[i] = ErrNotWritten
+			}
+		}
+	} else if err != nil {
+		return nil, err
+	}
+
+	// An update or delete matches nothing when its track was changed or
+	// deleted since it was read, find out which ones did
+	expected := int64(0)
+	for i, write := range writes {
+		if write.Op != BulkCreate && errs[i] == nil {
+			expected++
+		}
+	}
+	if result == nil || result.MatchedCount < expected {
+		if err := r.checkWritten(ctx, writes, errs); err != nil {
+			return nil, err
+		}
+	}
+	return errs, nil
+}
+
+// checkWritten sets the error of the updates and deletes that did not apply
+func (r *TrackRepository) checkWritten(ctx context.Context, writes []*TrackWrite, errs []error) error {
+	ids := []primitive.ObjectID{}
+	for i, write := range writes {
+		if write.Op != BulkCreate && errs[i] == nil {
+			ids = append(ids, write.ID)
+		}
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+
+	opts := options.Find().SetProjection(bson.M{"version": 1, "delete_at": 1})
+	cursor, err := r.Collection.Find(ctx, bson.M{"_id": bson.M{"$in": ids}}, opts)
+	if err != nil {
+		return err
+	}
+	var docs []struct {
+		ID       primitive.ObjectID `bson:"_id"`
+		Version  int64              `bson:"version"`
+		DeleteAt *time.Time         `bson:"delete_at"`
+	}
+	if err := cursor.All(ctx, &docs); err != nil {
+		return err
+	}
+	found := map[primitive.ObjectID]int{}
+	for i, doc := range docs {
+		found[doc.ID] = i
+	}
+
+	for i, write := range writes {
+		if write.Op == BulkCreate || errs[i] != nil {
+			continue
+		}
+		index, ok := found[write.ID]
+		if !ok {
+			errs[i] = ErrNotFound
+			continue
+		}
+		doc := docs[index]
+		if doc.Version != write.Version+1 || (doc.DeleteAt != nil) != (write.Op == BulkDelete) {
+			errs[i] = ErrVersionConflict
+		}
+	}
+	return nil
+}
+
+// bumpVersions sets the version of the tracks written by the updates
+func bumpVersions(writes []*TrackWrite, errs []error) {
+	for i, write := range writes {
+		if write.Op == BulkUpdate && errs[i] == nil {
+			write.Track.Version = write.Version + 1
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package models
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Oldest first, so an export lists the catalog in a stable order
+var exportSort = options.Find().SetSort(bson.D{{Key: "create_at", Value: 1}, {Key: "_id", Value: 1}})
+
+// exportEach calls each for every document of collection matching filter,
+// decoding them one at a time
+func exportEach[T any](ctx context.Context, collection *mongo.Collection, filter bson.M, each func(*T) error) error {
+	cursor, err := collection.Find(ctx, filter, exportSort)
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var doc T
+		if err := cursor.Decode(&doc); err != nil {
+			return err
+		}
+		if err := each(&doc); err != nil {
+			return err
+		}
+	}
+	return cursor.Err()
+}
+
+// Export calls each for every live track matching trackFilter, oldest first,
+// without loading them all in memory
+func (r *TrackRepository) Export(ctx context.Context, trackFilter *TrackFilter, each func(*Track) error) error {
+	filter := notDeleted(bson.M{})
+	trackFilter.Apply(filter)
+	return exportEach(ctx, r.Collection, filter, each)
+}
+
+// ArtistNames returns a name of every artist id, taken from the tracks giving
+// one, for the tracks that do not
+func (r *TrackRepository) ArtistNames(ctx context.Context) (map[string]string, error) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: notDeleted(bson.M{"artist_name": bson.M{"$gt": ""}})}},
+		{{Key: "$sort", Value: bson.D{{Key: "update_at", Value: -1}}}},
+		{{Key: "$group", Value: bson.M{"_id": "$artist_id", "name": bson.M{"$first": "$artist_name"}}}},
+	}
+	cursor, err := r.Collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	var artists []struct {
+		ID   string `bson:"_id"`
+		Name string `bson:"name"`
+	}
+	if err := cursor.All(ctx, &artists); err != nil {
+		return nil, err
+	}
+
+	names := make(map[string]string, len(artists))
+	for _, artist := range artists {
+		names[artist.ID] = artist.Name
+	}
+	return names, nil
+}
+
+// Export calls each for every live playlist, oldest first, without loading
+// them all in memory
+func (r *PlaylistRepository) Export(ctx context.Context, each func(*Playlist) error) error {
+	return exportEach(ctx, r.Collection, notDeleted(bson.M{}), each)
+}
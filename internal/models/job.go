@@ -0,0 +1,297 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type JobStatus string
+
+const (
+	JobPending   JobStatus = "pending"
+	JobRunning   JobStatus = "running"
+	JobSucceeded JobStatus = "succeeded"
+	// Jobs that exhausted their attempts stay in the collection as dead letters
+	JobDead JobStatus = "dead"
+)
+
+var (
+	ErrLeaseLost = errors.New("job lease lost")
+	// Another job of the same type and target is already pending
+	ErrJobPending = errors.New("an identical job is already pending")
+)
+
+type Job struct {
+	ID       primitive.ObjectID `bson:"_id"`
+	CreateAt time.Time          `bson:"create_at"`
+	UpdateAt time.Time          `bson:"update_at"`
+	Type     string             `bson:"type"`
+	// Track id or file name the job works on
+	Target string `bson:"target"`
+	// What the target was when the job was queued, e.g. the file_url of a
+	// track, a running job from another source does not stand for a new one
+	Source      string    `bson:"source"`
+	Status      JobStatus `bson:"status"`
+	Progress    int       `bson:"progress"`
+	Attempts    int       `bson:"attempts"`
+	MaxAttempts int       `bson:"max_attempts"`
+	RunAt       time.Time `bson:"run_at"`
+	LeaseOwner  string    `bson:"lease_owner,omitempty"`
+	LeaseUntil  time.Time `bson:"lease_until,omitempty"`
+	LastError   string    `bson:"last_error,omitempty"`
+	FinishedAt  time.Time `bson:"finished_at,omitempty"`
+}
+
+// JobFilter narrows job listings, empty values are ignored
+type JobFilter struct {
+	Type   string
+	Target string
+	Status JobStatus
+}
+
+// Enqueue inserts a pending job unless an identical job (same type and target)
+// is already pending, or running from the same source, in which case that job
+// is returned. A unique index keeps a single pending job per type and target.
+func (r *JobRepository) Enqueue(ctx context.Context, job *Job) (*Job, error) {
+	var running Job
+	err := r.Collection.FindOne(ctx, bson.M{
+		"type":   job.Type,
+		"target": job.Target,
+		"status": JobRunning,
+		"source": job.Source,
+	}).Decode(&running)
+	if err == nil {
+		return &running, nil
+	}
+	if !errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, err
+	}
+
+	now := time.Now()
+	filter := bson.M{
+		"type":   job.Type,
+		"target": job.Target,
+		"status": JobPending,
+	}
+	// The pending job runs on the latest source
+	update := bson.M{
+		"$set": bson.M{"source": job.Source},
+		"$setOnInsert": bson.M{
+			"_id":          primitive.NewObjectID(),
+			"create_at":    now,
+			"update_at":    now,
+			"progress":     0,
+			"attempts":     0,
+			"max_attempts": job.MaxAttempts,
+			"run_at":       now,
+		},
+	}
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+
+	var enqueued Job
+	err = r.Collection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&enqueued)
+	if mongo.IsDuplicateKeyError(err) {
+		// A concurrent enqueue inserted the pending job first
+		err = r.Collection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&enqueued)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &enqueued, nil
+}
+
+// Lease atomically claims the next runnable job for owner until the visibility
+// timeout expires. Jobs whose lease expired are picked up again. Returns
+// mongo.ErrNoDocuments when there is nothing to do.
+func (r *JobRepository) Lease(ctx context.Context, owner string, visibility time.Duration) (*Job, error) {
+	now := time.Now()
+	filter := bson.M{
+		"$or": []interface{}{
+			bson.M{"status": JobPending, "run_at": bson.M{"$lte": now}},
+			bson.M{"status": JobRunning, "lease_until": bson.M{"$lt": now}},
+		},
+	}
+	update := bson.M{
+		"$set": bson.M{
+			"status":      JobRunning,
+			"lease_owner": owner,
+			"lease_until": now.Add(visibility),
+			"update_at":   now,
+		},
+		"$inc": bson.M{"attempts": 1},
+	}
+	opts := options.FindOneAndUpdate().
+		SetSort(bson.D{{Key: "run_at", Value: 1}}).
+		SetReturnDocument(options.After)
+
+	var job Job
+	if err := r.Collection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&job); err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// Heartbeat records progress and extends the lease of a running job
+func (r *JobRepository) Heartbeat(ctx context.Context, job *Job, progress int, visibility time.Duration) error {
+	now := time.Now()
+	update := bson.M{"$set": bson.M{
+		"progress":    progress,
+		"lease_until": now.Add(visibility),
+		"update_at":   now,
+	}}
+	return r.updateLeased(ctx, job, update)
+}
+
+// Complete marks a leased job as succeeded
+func (r *JobRepository) Complete(ctx context.Context, job *Job) error {
+	now := time.Now()
+	update := bson.M{
+		"$set": bson.M{
+			"status":      JobSucceeded,
+			"progress":    100,
+			"update_at":   now,
+			"finished_at": now,
+		},
+		"$unset": bson.M{"lease_owner": "", "lease_until": ""},
+	}
+	return r.updateLeased(ctx, job, update)
+}
+
+// Retry puts a leased job back in the queue, to be run again at runAt. A job
+// superseded by a newer pending one is buried instead.
+func (r *JobRepository) Retry(ctx context.Context, job *Job, jobErr error, runAt time.Time) error {
+	update := bson.M{
+		"$set": bson.M{
+			"status":     JobPending,
+			"run_at":     runAt,
+			"last_error": jobErr.Error(),
+			"update_at":  time.Now(),
+		},
+		"$unset": bson.M{"lease_owner": "", "lease_until": ""},
+	}
+	err := r.updateLeased(ctx, job, update)
+	if mongo.IsDuplicateKeyError(err) {
+		return r.Bury(ctx, job, fmt.Errorf("%w, superseded by a pending job", jobErr))
+	}
+	return err
+}
+
+// Bury moves a leased job to the dead letters
+func (r *JobRepository) Bury(ctx context.Context, job *Job, jobErr error) error {
+	now := time.Now()
+	update := bson.M{
+		"$set": bson.M{
+			"status":      JobDead,
+			"last_error":  jobErr.Error(),
+			"update_at":   now,
+			"finished_at": now,
+		},
+		"$unset": bson.M{"lease_owner": "", "lease_until": ""},
+	}
+	return r.updateLeased(ctx, job, update)
+}
+
+// updateLeased applies update only while owner still holds the lease
+func (r *JobRepository) updateLeased(ctx context.Context, job *Job, update bson.M) error {
+	filter := bson.M{
+		"_id":         job.ID,
+		"status":      JobRunning,
+		"lease_owner": job.LeaseOwner,
+	}
+	result, err := r.Collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrLeaseLost
+	}
+	return nil
+}
+
+// Requeue gives a dead job a fresh set of attempts, ErrJobPending when an
+// identical job is already pending
+func (r *JobRepository) Requeue(ctx context.Context, jobID primitive.ObjectID) error {
+	now := time.Now()
+	filter := bson.M{"_id": jobID, "status": JobDead}
+	update := bson.M{
+		"$set": bson.M{
+			"status":    JobPending,
+			"attempts":  0,
+			"progress":  0,
+			"run_at":    now,
+			"update_at": now,
+		},
+		"$unset": bson.M{"finished_at": ""},
+	}
+	result, err := r.Collection.UpdateOne(ctx, filter, update)
+	if mongo.IsDuplicateKeyError(err) {
+		return ErrJobPending
+	}
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *JobRepository) FindByID(ctx context.Context, jobID primitive.ObjectID) (*Job, error) {
+	var job Job
+	err := r.Collection.FindOne(ctx, bson.M{"_id": jobID}).Decode(&job)
+	if err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+func (r *JobRepository) FindMany(ctx context.Context, jobFilter *JobFilter) ([]*Job, error) {
+	filter := bson.M{}
+	if jobFilter.Type != "" {
+		filter["type"] = jobFilter.Type
+	}
+	if jobFilter.Target != "" {
+		filter["target"] = jobFilter.Target
+	}
+	if jobFilter.Status != "" {
+		filter["status"] = jobFilter.Status
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "create_at", Value: -1}}).SetLimit(100)
+	cursor, err := r.Collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var jobs []*Job
+	if err := cursor.All(ctx, &jobs); err != nil {
+		return nil, err
+	}
+	return jobs, nil
+}
+
+// EnsureIndexes creates the indexes used to lease and look up jobs
+func (r *JobRepository) EnsureIndexes(ctx context.Context) error {
+	_, err := r.Collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "status", Value: 1}, {Key: "run_at", Value: 1}}},
+		{Keys: bson.D{{Key: "status", Value: 1}, {Key: "lease_until", Value: 1}}},
+		{Keys: bson.D{{Key: "type", Value: 1}, {Key: "target", Value: 1}, {Key: "status", Value: 1}}},
+		// A single pending job per type and target, see Enqueue
+		{
+			Keys: bson.D{{Key: "type", Value: 1}, {Key: "target", Value: 1}},
+			Options: options.Index().
+				SetName("type_1_target_1_pending").
+				SetUnique(true).
+				SetPartialFilterExpression(bson.M{"status": JobPending}),
+		},
+	})
+	return err
+}
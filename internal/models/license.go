@@ -0,0 +1,205 @@
+package models
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type LicenseStatus string
+
+const (
+	LicenseRequested LicenseStatus = "requested"
+	LicenseQuoted    LicenseStatus = "quoted"
+	LicenseApproved  LicenseStatus = "approved"
+	LicenseRejected  LicenseStatus = "rejected"
+	LicenseSigned    LicenseStatus = "signed"
+)
+
+// Territory code of a license valid in every territory
+const Worldwide = "WW"
+
+// Statuses a license request can move to from each status, a quoted request
+// can be quoted again. Rejected and signed requests are final.
+var licenseTransitions = map[LicenseStatus][]LicenseStatus{
+	LicenseRequested: {LicenseQuoted, LicenseRejected},
+	LicenseQuoted:    {LicenseQuoted, LicenseApproved, LicenseRejected},
+	LicenseApproved:  {LicenseSigned},
+}
+
+// CanTransition reports whether a license request in status from can move to
+// status to
+func CanTransition(from, to LicenseStatus) bool {
+	for _, status := range licenseTransitions[from] {
+		if status == to {
+			return true
+		}
+	}
+	return false
+}
+
+// LicenseRequest asks for a sync license of tracks, for a production in some
+// media, territories and term
+type LicenseRequest struct {
+	ID       primitive.ObjectID `bson:"_id"`
+	CreateAt time.Time          `bson:"create_at"`
+	UpdateAt time.Time          `bson:"update_at"`
+	Status   LicenseStatus      `bson:"status"`
+	// Name of the API key that made the request
+	Requester string         `bson:"requester"`
+	Contact   LicenseContact `bson:"contact"`
+	// Title and description of the production using the tracks
+	Project     string `bson:"project"`
+	Description string `bson:"description,omitempty"`
+	// Playlist the tracks were taken from, if any
+	PlaylistID string   `bson:"playlist_id,omitempty"`
+	TrackIDs   []string `bson:"track_ids"`
+	MediaType  string   `bson:"media_type"`
+	Usage      string   `bson:"usage"`
+	// ISO 3166-1 alpha-2 codes, or Worldwide alone
+	Territories []string `bson:"territories"`
+	// Length of the license in months, 0 for a perpetual license
+	TermMonths int `bson:"term_months"`
+	// First day of the license in milliseconds since the epoch, 0 when not set
+	StartDate int64 `bson:"start_date,omitempty"`
+	// Price computed from the pricing rules when the request was made
+	Estimate *LicenseQuote `bson:"estimate,omitempty"`
+	// Price offered to the licensee, set once quoted
+	Quote   *LicenseQuote   `bson:"quote,omitempty"`
+	History []*LicenseEvent `bson:"history"`
+}
+
+type LicenseContact struct {
+	Name    string `bson:"name"`
+	Email   string `bson:"email"`
+	Company string `bson:"company,omitempty"`
+}
+
+// LicenseQuote is the price of a license, with the price of every track
+type LicenseQuote struct {
+	Currency string              `bson:"currency"`
+	Lines    []*LicenseQuoteLine `bson:"lines"`
+	Subtotal float64             `bson:"subtotal"`
+	// Volume discount, in percent of the subtotal
+	DiscountPercent float64 `bson:"discount_percent,omitempty"`
+	Discount        float64 `bson:"discount,omitempty"`
+	Total           float64 `bson:"total"`
+	// Total set by hand instead of the computed one, Adjustment is what it
+	// adds to the discounted subtotal
+	Adjusted   bool      `bson:"adjusted,omitempty"`
+	Adjustment float64   `bson:"adjustment,omitempty"`
+	Note       string    `bson:"note,omitempty"`
+	QuotedAt   time.Time `bson:"quoted_at,omitempty"`
+	ValidUntil time.Time `bson:"valid_until,omitempty"`
+}
+
+// LicenseQuoteLine is the price of a track, with a copy of its rights holders
+// at the time of the quote
+type LicenseQuoteLine struct {
+	TrackID     string             `bson:"track_id"`
+	Title       string             `bson:"title"`
+	ArtistName  string             `bson:"artist_name,omitempty"`
+	ISRC        string             `bson:"isrc,omitempty"`
+	ISWC        string             `bson:"iswc,omitempty"`
+	MasterOwner string             `bson:"master_owner,omitempty"`
+	Publishing  []*PublishingSplit `bson:"publishing,omitempty"`
+	Tier        string             `bson:"tier"`
+	// Price of the tier and product of the media, usage, territory and term
+	// multipliers
+	BasePrice  float64 `bson:"base_price"`
+	Multiplier float64 `bson:"multiplier"`
+	Price      float64 `bson:"price"`
+}
+
+// LicenseEvent records a change of status of a license request
+type LicenseEvent struct {
+	Status LicenseStatus `bson:"status"`
+	Actor  string        `bson:"actor"`
+	At     time.Time     `bson:"at"`
+	Note   string        `bson:"note,omitempty"`
+}
+
+// LicenseFilter narrows license request listings, empty values are ignored
+type LicenseFilter struct {
+	Status    LicenseStatus
+	Requester string
+}
+
+func (r *LicenseRepository) Create(ctx context.Context, request *LicenseRequest) (*LicenseRequest, error) {
+	request.ID = primitive.NewObjectID()
+	request.CreateAt = time.Now()
+	request.UpdateAt = request.CreateAt
+	if _, err := r.Collection.InsertOne(ctx, request); err != nil {
+		return nil, err
+	}
+	return request, nil
+}
+
+func (r *LicenseRepository) FindByID(ctx context.Context, requestID primitive.ObjectID) (*LicenseRequest, error) {
+	var request LicenseRequest
+	if err := r.Collection.FindOne(ctx, bson.M{"_id": requestID}).Decode(&request); err != nil {
+		return nil, err
+	}
+	return &request, nil
+}
+
+// FindMany returns the license requests matching filter, newest first
+func (r *LicenseRepository) FindMany(ctx context.Context, filter *LicenseFilter) ([]*LicenseRequest, error) {
+	query := bson.M{}
+	if filter.Status != "" {
+		query["status"] = filter.Status
+	}
+	if filter.Requester != "" {
+		query["requester"] = filter.Requester
+	}
+
+	requests := []*LicenseRequest{}
+	cursor, err := r.Collection.Find(ctx, query, options.Find().SetSort(bson.D{{Key: "create_at", Value: -1}}))
+	if err != nil {
+		return nil, err
+	}
+	if err := cursor.All(ctx, &requests); err != nil {
+		return nil, err
+	}
+	return requests, nil
+}
+
+// Transition moves request to the status of event and appends event to its
+// history, setting its quote when quote is not nil. It returns
+// ErrVersionConflict when the request left its status meanwhile.
+func (r *LicenseRepository) Transition(ctx context.Context, request *LicenseRequest, event *LicenseEvent, quote *LicenseQuote) error {
+	set := bson.M{"status": event.Status, "update_at": event.At}
+	if quote != nil {
+		set["quote"] = quote
+	}
+	filter := bson.M{"_id": request.ID, "status": request.Status}
+	result, err := r.Collection.UpdateOne(ctx, filter, bson.M{"$set": set, "$push": bson.M{"history": event}})
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrVersionConflict
+	}
+
+	request.Status = event.Status
+	request.UpdateAt = event.At
+	if quote != nil {
+		request.Quote = quote
+	}
+	request.History = append(request.History, event)
+	return nil
+}
+
+// EnsureIndexes creates the indexes listing license requests by status and
+// by requester
+func (r *LicenseRepository) EnsureIndexes(ctx context.Context) error {
+	_, err := r.Collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "status", Value: 1}, {Key: "create_at", Value: -1}}},
+		{Keys: bson.D{{Key: "requester", Value: 1}, {Key: "create_at", Value: -1}}},
+	})
+	return err
+}
@@ -1,6 +1,7 @@
 package models
 
 import (
+	"context"
 	"errors"
 	"log"
 
@@ -16,6 +17,8 @@ var (
 
 var (
 	ErrNotFound = errors.New("record not found")
+	// ErrVersionConflict reports a write based on an outdated version of a record
+	ErrVersionConflict = errors.New("record was modified by another request")
 )
 
 func Setup(c *config.Config) {
@@ -29,5 +32,30 @@ func Setup(c *config.Config) {
 	Repository = &AppRepository{
 		Track:    &TrackRepository{DB.Collection("track")},
 		Playlist: &PlaylistRepository{DB.Collection("playlist")},
+		Job:      &JobRepository{DB.Collection("job")},
+		Revision: &RevisionRepository{DB.Collection("revision")},
+		Audit:    &AuditRepository{DB.Collection("audit")},
+		Album:    &AlbumRepository{DB.Collection("album")},
+		Artist:   &ArtistRepository{DB.Collection("artist")},
+		License:  &LicenseRepository{DB.Collection("license")},
+	}
+
+	if err := Repository.Track.EnsureIndexes(context.Background()); err != nil {
+		log.Fatalf("models.Setup err: %v", err)
+	}
+	if err := Repository.Revision.EnsureIndexes(context.Background()); err != nil {
+		log.Fatalf("models.Setup err: %v", err)
+	}
+	if err := Repository.Audit.EnsureIndexes(context.Background()); err != nil {
+		log.Fatalf("models.Setup err: %v", err)
+	}
+	if err := Repository.Album.EnsureIndexes(context.Background()); err != nil {
+		log.Fatalf("models.Setup err: %v", err)
+	}
+	if err := Repository.Artist.EnsureIndexes(context.Background()); err != nil {
+		log.Fatalf("models.Setup err: %v", err)
+	}
+	if err := Repository.License.EnsureIndexes(context.Background()); err != nil {
+		log.Fatalf("models.Setup err: %v", err)
 	}
 }
@@ -2,28 +2,49 @@ package models
 
 import (
 	"context"
-	"errors"
 	"log"
 	"time"
 
+	"github.com/rolexkdev/emvn-music-library-server/common/utils"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 type Playlist struct {
-	ID         primitive.ObjectID `bson:"_id"`
-	CreateAt   time.Time          `bson:"create_at"`
-	UpdateAt   time.Time          `bson:"update_at"`
-	DeleteAt   time.Time          `json:"-" bson:"delete_at,omitempty"`
-	Title      string             `bson:"title"`
-	AlbumCover string             `bson:"album_cover,omitempty"`
-	TrackIDs   []string           `bson:"track_ids,omitempty"`
+	ID         primitive.ObjectID  `bson:"_id"`
+	CreateAt   time.Time           `bson:"create_at"`
+	UpdateAt   time.Time           `bson:"update_at"`
+	DeleteAt   time.Time           `json:"-" bson:"delete_at,omitempty"`
+	Version    int64               `bson:"version"`
+	Title      string              `bson:"title"`
+	AlbumCover string              `bson:"album_cover,omitempty"`
+	TrackIDs   []string            `bson:"track_ids,omitempty"`
+	SearchKeys *PlaylistSearchKeys `json:"-" bson:"search_keys,omitempty"`
+}
+
+// Normalised copies of the searchable playlist fields, see utils.NormalizeSearchText
+type PlaylistSearchKeys struct {
+	Title      string   `bson:"title"`
+	TitleGrams []string `bson:"title_grams,omitempty"`
+	Trigrams   []string `bson:"trigrams,omitempty"`
+}
+
+// NewPlaylistSearchKeys normalises the searchable fields of playlist
+func NewPlaylistSearchKeys(playlist *Playlist) *PlaylistSearchKeys {
+	title := utils.NormalizeSearchText(playlist.Title)
+	return &PlaylistSearchKeys{
+		Title:      title,
+		TitleGrams: utils.EdgeNGrams(title, SuggestMinPrefix, SuggestMaxPrefix),
+		Trigrams:   utils.Trigrams(title),
+	}
 }
 
 func (r *PlaylistRepository) Create(ctx context.Context, playlist *Playlist) (*Playlist, error) {
 	playlist.CreateAt = time.Now()
 	playlist.UpdateAt = playlist.CreateAt
+	playlist.Version = 1
 	playlist.ID = primitive.NewObjectID()
+	playlist.SearchKeys = NewPlaylistSearchKeys(playlist)
 	_, err := r.Collection.InsertOne(ctx, playlist)
 	if err != nil {
 		return nil, err
@@ -34,12 +55,7 @@ func (r *PlaylistRepository) Create(ctx context.Context, playlist *Playlist) (*P
 func (r *PlaylistRepository) FindByID(ctx context.Context, playlistID primitive.ObjectID) (*Playlist, error) {
 	var playlist Playlist
 	log.Printf("playlistID: %s", playlistID)
-	filter := bson.M{
-		"_id": playlistID,
-		"delete_at": bson.M{
-			"$exists": false,
-		},
-	}
+	filter := notDeleted(bson.M{"_id": playlistID})
 
 	err := r.Collection.FindOne(ctx, filter).Decode(&playlist)
 	if err != nil {
@@ -50,58 +66,31 @@ func (r *PlaylistRepository) FindByID(ctx context.Context, playlistID primitive.
 }
 
 func (r *PlaylistRepository) Update(ctx context.Context, playlist *Playlist) error {
-	filter := bson.M{"_id": playlist.ID}
+	playlist.SearchKeys = NewPlaylistSearchKeys(playlist)
 	update := bson.M{"$set": bson.M{
 		"title":       playlist.Title,
 		"album_cover": playlist.AlbumCover,
 		"update_at":   time.Now(),
 		"track_ids":   playlist.TrackIDs,
+		"search_keys": playlist.SearchKeys,
 	}}
-	result, err := r.Collection.UpdateOne(ctx, filter, update)
-	if err != nil {
+	if err := updateVersion(ctx, r.Collection, playlist.ID, playlist.Version, update); err != nil {
 		return err
 	}
-	if result.MatchedCount == 0 {
-		return ErrNotFound
-	}
+	playlist.Version++
 	return nil
 }
 
 // Soft delete playlist record
-func (r *PlaylistRepository) Delete(ctx context.Context, playlistID primitive.ObjectID) error {
-	filter := bson.M{
-		"_id": playlistID,
-		"deleted_at": bson.M{
-			"$exists": false,
-		},
-	}
-	update := bson.D{
-		primitive.E{
-			Key: "$set",
-			Value: bson.D{
-				primitive.E{
-					Key:   "delete_at",
-					Value: time.Now(),
-				},
-			},
-		},
-	}
-
-	result, err := r.Collection.UpdateOne(ctx, filter, update)
-	if err != nil {
-		return err
-	}
-	if result.MatchedCount == 0 {
-		return ErrNotFound
-	}
-
-	return nil
+func (r *PlaylistRepository) Delete(ctx context.Context, playlistID primitive.ObjectID, version int64) error {
+	update := bson.M{"$set": bson.M{"delete_at": time.Now()}}
+	return updateVersion(ctx, r.Collection, playlistID, version, update)
 }
 
 func (r *PlaylistRepository) FindMany(ctx context.Context) ([]*Playlist, error) {
 	var playlists []*Playlist
 
-	filter := bson.M{"delete_at": bson.M{"$eq": nil}}
+	filter := notDeleted(bson.M{})
 	cursor, err := r.Collection.Find(context.Background(), filter)
 	if err != nil {
 		return nil, err
@@ -121,32 +110,3 @@ func (r *PlaylistRepository) FindMany(ctx context.Context) ([]*Playlist, error)
 
 	return playlists, nil
 }
-
-func (r *PlaylistRepository) Search(ctx context.Context, searchKey string) ([]*Playlist, error) {
-	if searchKey == "" {
-		return nil, errors.New("search key cannot be empty")
-	}
-
-	filter := bson.M{}
-	filter["title"] = bson.M{"$regex": searchKey, "$options": "i"}
-
-	cursor, err := r.Collection.Find(ctx, filter)
-	if err != nil {
-		return nil, err
-	}
-	defer cursor.Close(ctx)
-
-	var playlists []*Playlist
-	for cursor.Next(ctx) {
-		var playlist Playlist
-		if err := cursor.Decode(&playlist); err != nil {
-			return nil, err
-		}
-		playlists = append(playlists, &playlist)
-	}
-	if err := cursor.Err(); err != nil {
-		return nil, err
-	}
-
-	return playlists, nil
-}
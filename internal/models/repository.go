@@ -2,6 +2,7 @@ package models
 
 import (
 	"context"
+	"time"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
@@ -10,6 +11,12 @@ import (
 type AppRepository struct {
 	Track    TrackRepositoryInterface
 	Playlist PlaylistRepositoryInterface
+	Job      JobRepositoryInterface
+	Revision RevisionRepositoryInterface
+	Audit    AuditRepositoryInterface
+	Album    AlbumRepositoryInterface
+	Artist   ArtistRepositoryInterface
+	License  LicenseRepositoryInterface
 }
 
 type TrackRepository struct {
@@ -18,21 +25,114 @@ type TrackRepository struct {
 type PlaylistRepository struct {
 	Collection *mongo.Collection
 }
+type JobRepository struct {
+	Collection *mongo.Collection
+}
+type RevisionRepository struct {
+	Collection *mongo.Collection
+}
+type AuditRepository struct {
+	Collection *mongo.Collection
+}
+type AlbumRepository struct {
+	Collection *mongo.Collection
+}
+type ArtistRepository struct {
+	Collection *mongo.Collection
+}
+type LicenseRepository struct {
+	Collection *mongo.Collection
+}
 
 type TrackRepositoryInterface interface {
 	Create(ctx context.Context, track *Track) (*Track, error)
 	FindByID(ctx context.Context, trackID primitive.ObjectID) (*Track, error)
 	Update(ctx context.Context, track *Track) error
-	Delete(ctx context.Context, trackID primitive.ObjectID) error
-	FindMany(ctx context.Context) ([]*Track, error)
-	Search(ctx context.Context, searchKey string) ([]*Track, error)
+	SetWaveform(ctx context.Context, trackID primitive.ObjectID, waveform *TrackWaveform) error
+	SetAnalysis(ctx context.Context, trackID primitive.ObjectID, analysis *TrackAnalysis) error
+	SetAudioInfo(ctx context.Context, trackID primitive.ObjectID, info *TrackAudioInfo) (*Track, error)
+	Delete(ctx context.Context, trackID primitive.ObjectID, version int64) error
+	FindMany(ctx context.Context, filter *TrackFilter) ([]*Track, error)
+	FindByUploadName(ctx context.Context, name string) ([]*Track, error)
+	FindDeleted(ctx context.Context) ([]*Track, error)
+	Restore(ctx context.Context, trackID primitive.ObjectID) error
+	FindExpired(ctx context.Context, deletedBefore time.Time) ([]primitive.ObjectID, error)
+	Purge(ctx context.Context, ids []primitive.ObjectID, deletedBefore time.Time) error
+	FindByIDs(ctx context.Context, ids []primitive.ObjectID) ([]*Track, error)
+	BulkWrite(ctx context.Context, writes []*TrackWrite, ordered, atomic bool) ([]error, error)
+	FindMatching(ctx context.Context, isrcs, titles []string) ([]*Track, error)
+	Export(ctx context.Context, filter *TrackFilter, each func(*Track) error) error
+	ArtistNames(ctx context.Context) (map[string]string, error)
+	EnsureIndexes(ctx context.Context) error
 }
 
 type PlaylistRepositoryInterface interface {
 	Create(ctx context.Context, playlist *Playlist) (*Playlist, error)
 	FindByID(ctx context.Context, playlistID primitive.ObjectID) (*Playlist, error)
 	Update(ctx context.Context, playlist *Playlist) error
-	Delete(ctx context.Context, playlistID primitive.ObjectID) error
+	Delete(ctx context.Context, playlistID primitive.ObjectID, version int64) error
 	FindMany(ctx context.Context) ([]*Playlist, error)
-	Search(ctx context.Context, query string) ([]*Playlist, error)
+	FindDeleted(ctx context.Context) ([]*Playlist, error)
+	Restore(ctx context.Context, playlistID primitive.ObjectID) error
+	FindExpired(ctx context.Context, deletedBefore time.Time) ([]primitive.ObjectID, error)
+	Purge(ctx context.Context, ids []primitive.ObjectID, deletedBefore time.Time) error
+	RemoveTracks(ctx context.Context, trackIDs []string) error
+	Export(ctx context.Context, each func(*Playlist) error) error
+}
+
+type JobRepositoryInterface interface {
+	Enqueue(ctx context.Context, job *Job) (*Job, error)
+	Lease(ctx context.Context, owner string, visibility time.Duration) (*Job, error)
+	Heartbeat(ctx context.Context, job *Job, progress int, visibility time.Duration) error
+	Complete(ctx context.Context, job *Job) error
+	Retry(ctx context.Context, job *Job, jobErr error, runAt time.Time) error
+	Bury(ctx context.Context, job *Job, jobErr error) error
+	Requeue(ctx context.Context, jobID primitive.ObjectID) error
+	FindByID(ctx context.Context, jobID primitive.ObjectID) (*Job, error)
+	FindMany(ctx context.Context, filter *JobFilter) ([]*Job, error)
+	EnsureIndexes(ctx context.Context) error
+}
+
+type RevisionRepositoryInterface interface {
+	Create(ctx context.Context, revision *Revision) (*Revision, error)
+	FindByTarget(ctx context.Context, kind string, targetID primitive.ObjectID) ([]*Revision, error)
+	FindByVersion(ctx context.Context, kind string, targetID primitive.ObjectID, version int64) (*Revision, error)
+	DeleteByTargets(ctx context.Context, kind string, targetIDs []primitive.ObjectID) error
+	RecordSystemChange(ctx context.Context, kind string, targetID primitive.ObjectID, version int64, action string, change *FieldChange) error
+	EnsureIndexes(ctx context.Context) error
+}
+
+// AuditRepositoryInterface has no way to change or remove entries, the audit
+// log is append-only
+type AuditRepositoryInterface interface {
+	Append(ctx context.Context, entry *AuditEntry) error
+	FindMany(ctx context.Context, filter *AuditFilter, limit int) ([]*AuditEntry, error)
+	Export(ctx context.Context, filter *AuditFilter, each func(*AuditEntry) error) error
+	EnsureIndexes(ctx context.Context) error
+}
+
+type AlbumRepositoryInterface interface {
+	Create(ctx context.Context, album *Album) (*Album, error)
+	FindByID(ctx context.Context, albumID primitive.ObjectID) (*Album, error)
+	FindMany(ctx context.Context) ([]*Album, error)
+	Match(ctx context.Context, icpn, title, artistID string) (*Album, error)
+	Update(ctx context.Context, album *Album) error
+	EnsureIndexes(ctx context.Context) error
+}
+
+type ArtistRepositoryInterface interface {
+	Create(ctx context.Context, artist *Artist) (*Artist, error)
+	FindByID(ctx context.Context, artistID primitive.ObjectID) (*Artist, error)
+	FindMany(ctx context.Context) ([]*Artist, error)
+	Match(ctx context.Context, isni, name string) (*Artist, error)
+	Update(ctx context.Context, artist *Artist) error
+	EnsureIndexes(ctx context.Context) error
+}
+
+type LicenseRepositoryInterface interface {
+	Create(ctx context.Context, request *LicenseRequest) (*LicenseRequest, error)
+	FindByID(ctx context.Context, requestID primitive.ObjectID) (*LicenseRequest, error)
+	FindMany(ctx context.Context, filter *LicenseFilter) ([]*LicenseRequest, error)
+	Transition(ctx context.Context, request *LicenseRequest, event *LicenseEvent, quote *LicenseQuote) error
+	EnsureIndexes(ctx context.Context) error
 }
@@ -0,0 +1,138 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Kinds of records with a revision history
+const (
+	RevisionTrack    = "track"
+	RevisionPlaylist = "playlist"
+)
+
+// Changes recorded by a revision
+const (
+	RevisionCreate  = "create"
+	RevisionUpdate  = "update"
+	RevisionDelete  = "delete"
+	RevisionRestore = "restore"
+	// The editable fields were set back to those of an older revision
+	RevisionRollback = "rollback"
+	// The duration was measured from the audio file by a background job
+	RevisionMeasure = "measure"
+)
+
+// Actor of the revisions made by background jobs
+const SystemActor = "system"
+
+// Revision is one change of a track or playlist
+type Revision struct {
+	ID       primitive.ObjectID `bson:"_id"`
+	CreateAt time.Time          `bson:"create_at"`
+	Kind     string             `bson:"kind"`
+	TargetID primitive.ObjectID `bson:"target_id"`
+	// Version of the record produced by the change
+	Version int64  `bson:"version"`
+	Action  string `bson:"action"`
+	// Name of the API key behind the change
+	Actor   string         `bson:"actor"`
+	Changes []*FieldChange `bson:"changes"`
+	// Editable fields of the record after the change, by JSON name
+	State map[string]interface{} `json:"-" bson:"state"`
+	// Version the fields were rolled back to
+	RollbackTo int64 `bson:"rollback_to,omitempty"`
+}
+
+// FieldChange is the old and new value of a field, by JSON name
+type FieldChange struct {
+	Field string      `bson:"field"`
+	Old   interface{} `bson:"old"`
+	New   interface{} `bson:"new"`
+}
+
+func (r *RevisionRepository) Create(ctx context.Context, revision *Revision) (*Revision, error) {
+	revision.ID = primitive.NewObjectID()
+	revision.CreateAt = time.Now()
+	_, err := r.Collection.InsertOne(ctx, revision)
+	if err != nil {
+		return nil, err
+	}
+	return revision, nil
+}
+
+// FindByTarget lists the revisions of a record, newest first
+func (r *RevisionRepository) FindByTarget(ctx context.Context, kind string, targetID primitive.ObjectID) ([]*Revision, error) {
+	revisions := []*Revision{}
+	filter := bson.M{"kind": kind, "target_id": targetID}
+	opts := options.Find().SetSort(bson.D{{Key: "version", Value: -1}})
+	cursor, err := r.Collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	if err := cursor.All(ctx, &revisions); err != nil {
+		return nil, err
+	}
+	return revisions, nil
+}
+
+// FindByVersion returns the revision of a record that produced version
+func (r *RevisionRepository) FindByVersion(ctx context.Context, kind string, targetID primitive.ObjectID, version int64) (*Revision, error) {
+	var revision Revision
+	filter := bson.M{"kind": kind, "target_id": targetID, "version": version}
+	if err := r.Collection.FindOne(ctx, filter).Decode(&revision); err != nil {
+		return nil, err
+	}
+	return &revision, nil
+}
+
+// RecordSystemChange stores a change of a single field made by a background
+// job. Its state is that of the latest revision with the field changed.
+func (r *RevisionRepository) RecordSystemChange(ctx context.Context, kind string, targetID primitive.ObjectID, version int64, action string, change *FieldChange) error {
+	state := map[string]interface{}{}
+	var latest Revision
+	filter := bson.M{"kind": kind, "target_id": targetID}
+	opts := options.FindOne().SetSort(bson.D{{Key: "version", Value: -1}})
+	err := r.Collection.FindOne(ctx, filter, opts).Decode(&latest)
+	if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+		return err
+	}
+	for field, value := range latest.State {
+		state[field] = value
+	}
+	state[change.Field] = change.New
+
+	_, err = r.Create(ctx, &Revision{
+		Kind:     kind,
+		TargetID: targetID,
+		Version:  version,
+		Action:   action,
+		Actor:    SystemActor,
+		Changes:  []*FieldChange{change},
+		State:    state,
+	})
+	return err
+}
+
+// DeleteByTargets removes the history of purged records
+func (r *RevisionRepository) DeleteByTargets(ctx context.Context, kind string, targetIDs []primitive.ObjectID) error {
+	if len(targetIDs) == 0 {
+		return nil
+	}
+	_, err := r.Collection.DeleteMany(ctx, bson.M{"kind": kind, "target_id": bson.M{"$in": targetIDs}})
+	return err
+}
+
+// EnsureIndexes creates the index listing the history of a record
+func (r *RevisionRepository) EnsureIndexes(ctx context.Context) error {
+	_, err := r.Collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "kind", Value: 1}, {Key: "target_id", Value: 1}, {Key: "version", Value: -1}},
+	})
+	return err
+}
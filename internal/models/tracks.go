@@ -4,10 +4,14 @@ import (
 	"context"
 	"errors"
 	"log"
+	"strings"
 	"time"
 
+	"github.com/rolexkdev/emvn-music-library-server/common/utils"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 type Track struct {
@@ -15,20 +19,186 @@ type Track struct {
 	CreateAt    time.Time          `bson:"create_at"`
 	UpdateAt    time.Time          `bson:"update_at"`
 	DeleteAt    time.Time          `json:"-" bson:"delete_at,omitempty"`
+	Version     int64              `bson:"version"`
 	Name        string             `bson:"name"`
 	Title       string             `bson:"title"`
 	ArtistID    string             `bson:"artist_id"`
+	ArtistName  string             `bson:"artist_name,omitempty"`
 	Album       string             `bson:"album"`
 	Genre       string             `bson:"genre,omitempty"`
 	ReleaseDate int64              `bson:"release_date"`
 	Duration    int64              `bson:"duration"`
 	FileURL     string             `bson:"file_url"`
+	ISRC        string             `bson:"isrc,omitempty"`
+	// ISWC of the musical work recorded by the track
+	ISWC string `bson:"iswc,omitempty"`
+	// Phonographic copyright of the recording and copyright of the artwork,
+	// e.g. "2024 EMVN"
+	PLine string `bson:"p_line,omitempty"`
+	CLine string `bson:"c_line,omitempty"`
+	Label string `bson:"label,omitempty"`
+	// Owner of the master recording
+	MasterOwner string `bson:"master_owner,omitempty"`
+	// Publishing splits of the work, their shares sum to 100
+	Publishing   []*PublishingSplit `bson:"publishing,omitempty"`
+	Availability *TrackAvailability `bson:"availability,omitempty"`
+	// Licensing price tier, see the pricing rules of package licensing
+	Tier string `bson:"tier,omitempty"`
+	// Name of the uploaded file of FileURL, empty for other URLs
+	UploadName string           `json:"-" bson:"upload_name,omitempty"`
+	Waveform   *TrackWaveform   `bson:"waveform,omitempty"`
+	Analysis   *TrackAnalysis   `bson:"analysis,omitempty"`
+	AudioInfo  *TrackAudioInfo  `bson:"audio_info,omitempty"`
+	SearchKeys *TrackSearchKeys `json:"-" bson:"search_keys,omitempty"`
 }
 
-func (r *TrackRepository) Create(ctx context.Context, track *Track) (*Track, error) {
+// Publishing roles of a split
+const (
+	PublishingWriter    = "writer"
+	PublishingPublisher = "publisher"
+)
+
+// PublishingSplit is the share of the work owned by a writer or publisher
+type PublishingSplit struct {
+	Name string `bson:"name"`
+	// PublishingWriter or PublishingPublisher
+	Role string `bson:"role"`
+	// Interested Party Information number of the writer or publisher
+	IPI string `bson:"ipi,omitempty"`
+	// Percentage of the work
+	Share float64 `bson:"share"`
+}
+
+// PublishingNames returns the names of the splits with role
+func PublishingNames(splits []*PublishingSplit, role string) []string {
+	var names []string
+	for _, split := range splits {
+		if split.Role == role {
+			names = append(names, split.Name)
+		}
+	}
+	return names
+}
+
+// Shortest and longest prefixes stored for search-as-you-type suggestions
+const (
+	SuggestMinPrefix = 2
+	SuggestMaxPrefix = 15
+)
+
+// Normalised copies of the searchable track fields, see utils.NormalizeSearchText.
+// The *Grams fields hold the prefixes matched by suggestions and Trigrams the
+// trigrams of title, artist and album used to find fuzzy matches.
+type TrackSearchKeys struct {
+	Title       string `bson:"title"`
+	Name        string `bson:"name"`
+	ArtistName  string `bson:"artist_name,omitempty"`
+	Album       string `bson:"album"`
+	Genre       string `bson:"genre,omitempty"`
+	Label       string `bson:"label,omitempty"`
+	MasterOwner string `bson:"master_owner,omitempty"`
+	// Names of the writers and publishers of the publishing splits
+	Writers         string   `bson:"writers,omitempty"`
+	Publishers      string   `bson:"publishers,omitempty"`
+	TitleGrams      []string `bson:"title_grams,omitempty"`
+	ArtistNameGrams []string `bson:"artist_name_grams,omitempty"`
+	AlbumGrams      []string `bson:"album_grams,omitempty"`
+	Trigrams        []string `bson:"trigrams,omitempty"`
+}
+
+// NewTrackSearchKeys normalises the searchable fields of track
+func NewTrackSearchKeys(track *Track) *TrackSearchKeys {
+	keys := &TrackSearchKeys{
+		Title:       utils.NormalizeSearchText(track.Title),
+		Name:        utils.NormalizeSearchText(track.Name),
+		ArtistName:  utils.NormalizeSearchText(track.ArtistName),
+		Album:       utils.NormalizeSearchText(track.Album),
+		Genre:       utils.NormalizeSearchText(track.Genre),
+		Label:       utils.NormalizeSearchText(track.Label),
+		MasterOwner: utils.NormalizeSearchText(track.MasterOwner),
+		Writers:     utils.NormalizeSearchText(strings.Join(PublishingNames(track.Publishing, PublishingWriter), " ")),
+		Publishers:  utils.NormalizeSearchText(strings.Join(PublishingNames(track.Publishing, PublishingPublisher), " ")),
+	}
+	keys.TitleGrams = utils.EdgeNGrams(keys.Title, SuggestMinPrefix, SuggestMaxPrefix)
+	keys.ArtistNameGrams = utils.EdgeNGrams(keys.ArtistName, SuggestMinPrefix, SuggestMaxPrefix)
+	keys.AlbumGrams = utils.EdgeNGrams(keys.Album, SuggestMinPrefix, SuggestMaxPrefix)
+	keys.Trigrams = utils.Trigrams(keys.Title + " " + keys.ArtistName + " " + keys.Album)
+	return keys
+}
+
+// Technical metadata and tags read from the track audio file header
+type TrackAudioInfo struct {
+	SourceURL   string            `bson:"source_url"`
+	Format      string            `bson:"format"`
+	MimeType    string            `bson:"mime_type"`
+	Duration    int64             `bson:"duration"`
+	SampleRate  int               `bson:"sample_rate"`
+	BitDepth    int               `bson:"bit_depth,omitempty"`
+	Channels    int               `bson:"channels"`
+	Bitrate     int               `bson:"bitrate"`
+	Tags        map[string]string `bson:"tags,omitempty"`
+	ArtworkURL  string            `bson:"artwork_url,omitempty"`
+	ExtractedAt time.Time         `bson:"extracted_at"`
+}
+
+// Waveform peaks derived from the track audio, the data itself lives in WaveformDir
+type TrackWaveform struct {
+	SourceURL       string    `bson:"source_url"`
+	SampleRate      int       `bson:"sample_rate"`
+	SamplesPerPixel int       `bson:"samples_per_pixel"`
+	Bits            int       `bson:"bits"`
+	Length          int       `bson:"length"`
+	GeneratedAt     time.Time `bson:"generated_at"`
+}
+
+// newTrack fills the fields set on creation of track
+func newTrack(track *Track) {
 	track.CreateAt = time.Now()
 	track.UpdateAt = track.CreateAt
+	track.Version = 1
 	track.ID = primitive.NewObjectID()
+	track.UploadName = TrackUploadName(track)
+	track.SearchKeys = NewTrackSearchKeys(track)
+}
+
+// TrackUploadName returns the name of the uploaded file played by track,
+// empty when its file_url is not an upload
+func TrackUploadName(track *Track) string {
+	name, _ := utils.UploadNameFromURL(track.FileURL)
+	return name
+}
+
+// trackUpdate returns the update replacing the editable fields of track
+func trackUpdate(track *Track) bson.M {
+	track.UploadName = TrackUploadName(track)
+	track.SearchKeys = NewTrackSearchKeys(track)
+	return bson.M{"$set": bson.M{
+		"title":        track.Title,
+		"name":         track.Name,
+		"album":        track.Album,
+		"update_at":    time.Now(),
+		"artist_id":    track.ArtistID,
+		"artist_name":  track.ArtistName,
+		"genre":        track.Genre,
+		"release_date": track.ReleaseDate,
+		"duration":     track.Duration,
+		"file_url":     track.FileURL,
+		"isrc":         track.ISRC,
+		"iswc":         track.ISWC,
+		"p_line":       track.PLine,
+		"c_line":       track.CLine,
+		"label":        track.Label,
+		"master_owner": track.MasterOwner,
+		"publishing":   track.Publishing,
+		"availability": track.Availability,
+		"tier":         track.Tier,
+		"upload_name":  track.UploadName,
+		"search_keys":  track.SearchKeys,
+	}}
+}
+
+func (r *TrackRepository) Create(ctx context.Context, track *Track) (*Track, error) {
+	newTrack(track)
 	_, err := r.Collection.InsertOne(ctx, track)
 	if err != nil {
 		return nil, err
@@ -39,12 +209,7 @@ func (r *TrackRepository) Create(ctx context.Context, track *Track) (*Track, err
 func (r *TrackRepository) FindByID(ctx context.Context, trackID primitive.ObjectID) (*Track, error) {
 	var track Track
 	log.Printf("trackID: %s", trackID)
-	filter := bson.M{
-		"_id": trackID,
-		"delete_at": bson.M{
-			"$exists": false,
-		},
-	}
+	filter := notDeleted(bson.M{"_id": trackID})
 
 	err := r.Collection.FindOne(ctx, filter).Decode(&track)
 	if err != nil {
@@ -55,16 +220,169 @@ func (r *TrackRepository) FindByID(ctx context.Context, trackID primitive.Object
 }
 
 func (r *TrackRepository) Update(ctx context.Context, track *Track) error {
-	filter := bson.M{"_id": track.ID}
-	update := bson.M{"$set": bson.M{
-		"title":     track.Title,
-		"name":      track.Name,
-		"album":     track.Album,
-		"update_at": time.Now(),
-		"artist_id": track.ArtistID,
-		"genre":     track.Genre,
-		"file_url":  track.FileURL,
-	}}
+	if err := updateVersion(ctx, r.Collection, track.ID, track.Version, trackUpdate(track)); err != nil {
+		return err
+	}
+	track.Version++
+	return nil
+}
+
+// Loudness, tempo and key computed from the track audio
+type TrackAnalysis struct {
+	SourceURL  string    `bson:"source_url"`
+	Loudness   float64   `bson:"loudness"`
+	TruePeak   float64   `bson:"true_peak"`
+	BPM        float64   `bson:"bpm"`
+	Key        string    `bson:"key,omitempty"`
+	AnalyzedAt time.Time `bson:"analyzed_at"`
+}
+
+// Named ranges of track duration used to filter and facet tracks
+type DurationBucket struct {
+	Name string
+	// Bounds in milliseconds, Max is exclusive and 0 means unbounded
+	Min int64
+	Max int64
+}
+
+var DurationBuckets = []DurationBucket{
+	{Name: "under_2m", Min: 0, Max: 2 * 60 * 1000},
+	{Name: "2m_4m", Min: 2 * 60 * 1000, Max: 4 * 60 * 1000},
+	{Name: "4m_6m", Min: 4 * 60 * 1000, Max: 6 * 60 * 1000},
+	{Name: "over_6m", Min: 6 * 60 * 1000},
+}
+
+// FindDurationBucket returns the bucket called name
+func FindDurationBucket(name string) (DurationBucket, bool) {
+	for _, bucket := range DurationBuckets {
+		if bucket.Name == name {
+			return bucket, true
+		}
+	}
+	return DurationBucket{}, false
+}
+
+// TrackFilter narrows track listings and searches, nil and empty values are
+// ignored. Values of the same list field are alternatives.
+type TrackFilter struct {
+	MinBPM      *float64
+	MaxBPM      *float64
+	Key         string
+	MinLoudness *float64
+	MaxLoudness *float64
+	Genres      []string
+	// Release years, in UTC
+	Years []int
+	// Names of DurationBuckets
+	DurationBuckets []string
+	ArtistIDs       []string
+	Labels          []string
+	MasterOwners    []string
+	// Names of writers and publishers in the publishing splits
+	Writers    []string
+	Publishers []string
+	ISRC       string
+	ISWC       string
+	// Territory and time the tracks must be available in, nil for any
+	Availability *Availability
+}
+
+// Apply adds the conditions of f to a track query
+func (f *TrackFilter) Apply(filter bson.M) {
+	if f == nil {
+		return
+	}
+
+	addRange := func(field string, min, max *float64) {
+		cond := bson.M{}
+		if min != nil {
+			cond["$gte"] = *min
+		}
+		if max != nil {
+			cond["$lte"] = *max
+		}
+		if len(cond) > 0 {
+			filter[field] = cond
+		}
+	}
+	addRange("analysis.bpm", f.MinBPM, f.MaxBPM)
+	addRange("analysis.loudness", f.MinLoudness, f.MaxLoudness)
+	if f.Key != "" {
+		filter["analysis.key"] = f.Key
+	}
+	if len(f.Genres) > 0 {
+		filter["genre"] = bson.M{"$in": f.Genres}
+	}
+	if len(f.ArtistIDs) > 0 {
+		filter["artist_id"] = bson.M{"$in": f.ArtistIDs}
+	}
+	if len(f.Labels) > 0 {
+		filter["label"] = bson.M{"$in": f.Labels}
+	}
+	if len(f.MasterOwners) > 0 {
+		filter["master_owner"] = bson.M{"$in": f.MasterOwners}
+	}
+	if f.ISRC != "" {
+		filter["isrc"] = f.ISRC
+	}
+	if f.ISWC != "" {
+		filter["iswc"] = f.ISWC
+	}
+
+	// Alternatives of a range field need an $or, which are combined with $and
+	// so they do not overwrite each other or one set by the caller
+	var ors []interface{}
+	if len(f.Years) > 0 {
+		var years []interface{}
+		for _, year := range f.Years {
+			start := time.Date(year, time.January, 1, 0, 0, 0, 0, time.UTC)
+			years = append(years, bson.M{"release_date": bson.M{
+				"$gte": start.UnixMilli(),
+				"$lt":  start.AddDate(1, 0, 0).UnixMilli(),
+			}})
+		}
+		ors = append(ors, bson.M{"$or": years})
+	}
+	if len(f.DurationBuckets) > 0 {
+		var durations []interface{}
+		for _, name := range f.DurationBuckets {
+			bucket, ok := FindDurationBucket(name)
+			if !ok {
+				continue
+			}
+			cond := bson.M{"$gte": bucket.Min}
+			if bucket.Max > 0 {
+				cond["$lt"] = bucket.Max
+			}
+			durations = append(durations, bson.M{"duration": cond})
+		}
+		if len(durations) > 0 {
+			ors = append(ors, bson.M{"$or": durations})
+		}
+	}
+	// Writers and publishers both match the publishing splits
+	addSplit := func(role string, names []string) {
+		if len(names) > 0 {
+			ors = append(ors, bson.M{"publishing": bson.M{"$elemMatch": bson.M{"role": role, "name": bson.M{"$in": names}}}})
+		}
+	}
+	addSplit(PublishingWriter, f.Writers)
+	addSplit(PublishingPublisher, f.Publishers)
+	ors = append(ors, f.Availability.conditions()...)
+	if len(ors) > 0 {
+		if and, ok := filter["$and"].([]interface{}); ok {
+			ors = append(and, ors...)
+		}
+		filter["$and"] = ors
+	}
+}
+
+// SetWaveform stores the derived waveform metadata without touching
+// update_at or the version, ErrNotFound when the track no longer plays its
+// source
+func (r *TrackRepository) SetWaveform(ctx context.Context, trackID primitive.ObjectID, waveform *TrackWaveform) error {
+	filter := notDeleted(bson.M{"_id": trackID, "file_url": waveform.SourceURL})
+	update := bson.M{"$set": bson.M{"waveform": waveform}}
 	result, err := r.Collection.UpdateOne(ctx, filter, update)
 	if err != nil {
 		return err
@@ -75,26 +393,11 @@ func (r *TrackRepository) Update(ctx context.Context, track *Track) error {
 	return nil
 }
 
-// Soft delete track record
-func (r *TrackRepository) Delete(ctx context.Context, trackID primitive.ObjectID) error {
-	filter := bson.M{
-		"_id": trackID,
-		"deleted_at": bson.M{
-			"$exists": false,
-		},
-	}
-	update := bson.D{
-		primitive.E{
-			Key: "$set",
-			Value: bson.D{
-				primitive.E{
-					Key:   "delete_at",
-					Value: time.Now(),
-				},
-			},
-		},
-	}
-
+// SetAnalysis stores the audio analysis result without touching update_at or
+// the version, ErrNotFound when the track no longer plays its source
+func (r *TrackRepository) SetAnalysis(ctx context.Context, trackID primitive.ObjectID, analysis *TrackAnalysis) error {
+	filter := notDeleted(bson.M{"_id": trackID, "file_url": analysis.SourceURL})
+	update := bson.M{"$set": bson.M{"analysis": analysis}}
 	result, err := r.Collection.UpdateOne(ctx, filter, update)
 	if err != nil {
 		return err
@@ -102,61 +405,73 @@ func (r *TrackRepository) Delete(ctx context.Context, trackID primitive.ObjectID
 	if result.MatchedCount == 0 {
 		return ErrNotFound
 	}
-
 	return nil
 }
 
-func (r *TrackRepository) FindMany(ctx context.Context) ([]*Track, error) {
-	var tracks []*Track
+// SetAudioInfo stores the metadata read from the audio file and replaces the
+// track duration with the measured one, bumping the version only when the
+// duration changes. It returns the track as it was before, ErrNotFound when
+// the track no longer plays its source.
+func (r *TrackRepository) SetAudioInfo(ctx context.Context, trackID primitive.ObjectID, info *TrackAudioInfo) (*Track, error) {
+	filter := notDeleted(bson.M{"_id": trackID, "file_url": info.SourceURL})
+	update := mongo.Pipeline{{{Key: "$set", Value: bson.M{
+		"audio_info": bson.M{"$literal": info},
+		"duration":   bson.M{"$literal": info.Duration},
+		"version": bson.M{"$cond": bson.A{
+			bson.M{"$eq": bson.A{"$duration", info.Duration}},
+			"$version",
+			bson.M{"$add": bson.A{"$version", 1}},
+		}},
+	}}}}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.Before)
 
-	filter := bson.M{"delete_at": bson.M{"$eq": nil}}
-	cursor, err := r.Collection.Find(context.Background(), filter)
+	var before Track
+	err := r.Collection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&before)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrNotFound
+	}
 	if err != nil {
 		return nil, err
 	}
-	defer cursor.Close(context.Background())
+	return &before, nil
+}
 
-	for cursor.Next(context.Background()) {
-		var track *Track
-		if err := cursor.Decode(&track); err != nil {
-			return nil, err
-		}
-		tracks = append(tracks, track)
+// Soft delete track record
+func (r *TrackRepository) Delete(ctx context.Context, trackID primitive.ObjectID, version int64) error {
+	update := bson.M{"$set": bson.M{"delete_at": time.Now()}}
+	return updateVersion(ctx, r.Collection, trackID, version, update)
+}
+
+// FindByUploadName returns the live tracks playing the uploaded file name
+func (r *TrackRepository) FindByUploadName(ctx context.Context, name string) ([]*Track, error) {
+	tracks := []*Track{}
+	cursor, err := r.Collection.Find(ctx, notDeleted(bson.M{"upload_name": name}))
+	if err != nil {
+		return nil, err
 	}
-	if err := cursor.Err(); err != nil {
+	if err := cursor.All(ctx, &tracks); err != nil {
 		return nil, err
 	}
-
 	return tracks, nil
 }
 
-func (r *TrackRepository) Search(ctx context.Context, searchKey string) ([]*Track, error) {
-	if searchKey == "" {
-		return nil, errors.New("search key cannot be empty")
-	}
-
-	filter := bson.M{
-		"$or": []interface{}{
-			bson.M{"title": bson.M{"$regex": searchKey, "$options": "i"}},
-			bson.M{"name": bson.M{"$regex": searchKey, "$options": "i"}},
-			bson.M{"album": bson.M{"$regex": searchKey, "$options": "i"}},
-			bson.M{"genre": bson.M{"$regex": searchKey, "$options": "i"}},
-		},
-	}
+func (r *TrackRepository) FindMany(ctx context.Context, trackFilter *TrackFilter) ([]*Track, error) {
+	var tracks []*Track
 
-	cursor, err := r.Collection.Find(ctx, filter)
+	filter := notDeleted(bson.M{})
+	trackFilter.Apply(filter)
+	cursor, err := r.Collection.Find(context.Background(), filter)
 	if err != nil {
 		return nil, err
 	}
-	defer cursor.Close(ctx)
+	defer cursor.Close(context.Background())
 
-	var tracks []*Track
-	for cursor.Next(ctx) {
-		var track Track
+	for cursor.Next(context.Background()) {
+		var track *Track
 		if err := cursor.Decode(&track); err != nil {
 			return nil, err
 		}
-		tracks = append(tracks, &track)
+		tracks = append(tracks, track)
 	}
 	if err := cursor.Err(); err != nil {
 		return nil, err
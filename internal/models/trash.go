@@ -0,0 +1,144 @@
+package models
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// notDeleted restricts filter to the records that are not in the trash
+func notDeleted(filter bson.M) bson.M {
+	filter["delete_at"] = bson.M{"$exists": false}
+	return filter
+}
+
+// deleted restricts filter to the records in the trash
+func deleted(filter bson.M) bson.M {
+	filter["delete_at"] = bson.M{"$exists": true}
+	return filter
+}
+
+// Most recently deleted first
+var trashSort = options.Find().SetSort(bson.D{{Key: "delete_at", Value: -1}})
+
+// restore takes the record id out of the trash
+func restore(ctx context.Context, collection *mongo.Collection, id primitive.ObjectID) error {
+	update := bson.M{
+		"$unset": bson.M{"delete_at": ""},
+		"$set":   bson.M{"update_at": time.Now()},
+		"$inc":   bson.M{"version": 1},
+	}
+	result, err := collection.UpdateOne(ctx, deleted(bson.M{"_id": id}), update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// expired returns the ids of the records deleted before deletedBefore
+func expired(ctx context.Context, collection *mongo.Collection, deletedBefore time.Time) ([]primitive.ObjectID, error) {
+	filter := bson.M{"delete_at": bson.M{"$lt": deletedBefore}}
+	cursor, err := collection.Find(ctx, filter, options.Find().SetProjection(bson.M{"_id": 1}))
+	if err != nil {
+		return nil, err
+	}
+	var docs []struct {
+		ID primitive.ObjectID `bson:"_id"`
+	}
+	if err := cursor.All(ctx, &docs); err != nil {
+		return nil, err
+	}
+
+	ids := make([]primitive.ObjectID, len(docs))
+	for i, doc := range docs {
+		ids[i] = doc.ID
+	}
+	return ids, nil
+}
+
+// purge permanently removes the records of ids that are still deleted before
+// deletedBefore
+func purge(ctx context.Context, collection *mongo.Collection, ids []primitive.ObjectID, deletedBefore time.Time) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	// A record restored meanwhile no longer matches the filter
+	filter := bson.M{"_id": bson.M{"$in": ids}, "delete_at": bson.M{"$lt": deletedBefore}}
+	_, err := collection.DeleteMany(ctx, filter)
+	return err
+}
+
+// FindDeleted lists the tracks in the trash
+func (r *TrackRepository) FindDeleted(ctx context.Context) ([]*Track, error) {
+	tracks := []*Track{}
+	cursor, err := r.Collection.Find(ctx, deleted(bson.M{}), trashSort)
+	if err != nil {
+		return nil, err
+	}
+	if err := cursor.All(ctx, &tracks); err != nil {
+		return nil, err
+	}
+	return tracks, nil
+}
+
+// Restore takes a track out of the trash
+func (r *TrackRepository) Restore(ctx context.Context, trackID primitive.ObjectID) error {
+	return restore(ctx, r.Collection, trackID)
+}
+
+// FindExpired returns the ids of the tracks deleted before deletedBefore
+func (r *TrackRepository) FindExpired(ctx context.Context, deletedBefore time.Time) ([]primitive.ObjectID, error) {
+	return expired(ctx, r.Collection, deletedBefore)
+}
+
+// Purge permanently removes the tracks of trackIDs deleted before deletedBefore
+func (r *TrackRepository) Purge(ctx context.Context, trackIDs []primitive.ObjectID, deletedBefore time.Time) error {
+	return purge(ctx, r.Collection, trackIDs, deletedBefore)
+}
+
+// FindDeleted lists the playlists in the trash
+func (r *PlaylistRepository) FindDeleted(ctx context.Context) ([]*Playlist, error) {
+	playlists := []*Playlist{}
+	cursor, err := r.Collection.Find(ctx, deleted(bson.M{}), trashSort)
+	if err != nil {
+		return nil, err
+	}
+	if err := cursor.All(ctx, &playlists); err != nil {
+		return nil, err
+	}
+	return playlists, nil
+}
+
+// Restore takes a playlist out of the trash
+func (r *PlaylistRepository) Restore(ctx context.Context, playlistID primitive.ObjectID) error {
+	return restore(ctx, r.Collection, playlistID)
+}
+
+// FindExpired returns the ids of the playlists deleted before deletedBefore
+func (r *PlaylistRepository) FindExpired(ctx context.Context, deletedBefore time.Time) ([]primitive.ObjectID, error) {
+	return expired(ctx, r.Collection, deletedBefore)
+}
+
+// Purge permanently removes the playlists of playlistIDs deleted before
+// deletedBefore
+func (r *PlaylistRepository) Purge(ctx context.Context, playlistIDs []primitive.ObjectID, deletedBefore time.Time) error {
+	return purge(ctx, r.Collection, playlistIDs, deletedBefore)
+}
+
+// RemoveTracks takes the tracks out of every playlist, trashed ones included
+func (r *PlaylistRepository) RemoveTracks(ctx context.Context, trackIDs []string) error {
+	if len(trackIDs) == 0 {
+		return nil
+	}
+	filter := bson.M{"track_ids": bson.M{"$in": trackIDs}}
+	update := bson.M{"$pull": bson.M{"track_ids": bson.M{"$in": trackIDs}}}
+	_, err := r.Collection.UpdateMany(ctx, filter, update)
+	return err
+}
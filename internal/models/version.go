@@ -0,0 +1,32 @@
+package models
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// updateVersion applies update to the live record id if it is still at version,
+// bumping the version. It fails with ErrVersionConflict when the record changed
+// meanwhile and ErrNotFound when it does not exist.
+func updateVersion(ctx context.Context, collection *mongo.Collection, id primitive.ObjectID, version int64, update bson.M) error {
+	update["$inc"] = bson.M{"version": 1}
+	result, err := collection.UpdateOne(ctx, notDeleted(bson.M{"_id": id, "version": version}), update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount > 0 {
+		return nil
+	}
+
+	err = collection.FindOne(ctx, notDeleted(bson.M{"_id": id})).Err()
+	if err == mongo.ErrNoDocuments {
+		return ErrNotFound
+	}
+	if err != nil {
+		return err
+	}
+	return ErrVersionConflict
+}
@@ -0,0 +1,230 @@
+package search
+
+import (
+	"regexp"
+	"time"
+
+	"github.com/rolexkdev/emvn-music-library-server/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// compileTarget maps the fields of the query language to a collection
+type compileTarget struct {
+	// Search keys matched by free terms
+	terms []string
+	// Document paths of the query fields, a missing field matches nothing
+	fields map[string]string
+}
+
+var trackTarget = compileTarget{
+	terms: []string{
+		"search_keys.title", "search_keys.name", "search_keys.artist_name",
+		"search_keys.album", "search_keys.label", "search_keys.master_owner",
+		"search_keys.writers", "search_keys.publishers", "search_keys.genre",
+	},
+	fields: map[string]string{
+		"title":     "search_keys.title",
+		"artist":    "search_keys.artist_name",
+		"album":     "search_keys.album",
+		"genre":     "search_keys.genre",
+		"label":     "search_keys.label",
+		"owner":     "search_keys.master_owner",
+		"writer":    "search_keys.writers",
+		"publisher": "search_keys.publishers",
+		"isrc":      "isrc",
+		"iswc":      "iswc",
+		"year":      "release_date",
+		"duration":  "duration",
+		"bpm":       "analysis.bpm",
+		"key":       "analysis.key",
+	},
+}
+
+var playlistTarget = compileTarget{
+	terms: []string{"search_keys.title"},
+	fields: map[string]string{
+		"title": "search_keys.title",
+	},
+}
+
+// Filter no document can satisfy
+var matchNothing = bson.M{"_id": bson.M{"$exists": false}}
+
+// wordsPattern matches text as a sequence of whole words of a normalised key
+func wordsPattern(text string) string {
+	return `(^|\s)` + regexp.QuoteMeta(text) + `(\s|$)`
+}
+
+// compile turns a parsed query into a Mongo filter
+func (t compileTarget) compile(n node) bson.M {
+	switch n := n.(type) {
+	case *andNode:
+		return bson.M{"$and": t.compileAll(n.children)}
+	case *orNode:
+		return bson.M{"$or": t.compileAll(n.children)}
+	case *notNode:
+		return bson.M{"$nor": bson.A{t.compile(n.child)}}
+	case *termNode:
+		var or bson.A
+		for _, path := range t.terms {
+			or = append(or, bson.M{path: bson.M{"$regex": wordsPattern(n.text)}})
+		}
+		return bson.M{"$or": or}
+	case *fieldNode:
+		path, ok := t.fields[n.field]
+		if !ok {
+			return matchNothing
+		}
+		switch {
+		case n.text != "":
+			return bson.M{path: bson.M{"$regex": wordsPattern(n.text)}}
+		case n.key != "":
+			return bson.M{path: n.key}
+		case n.field == "year":
+			return bson.M{path: yearCondition(n.rng)}
+		case n.field == "duration":
+			// Seconds in the query, milliseconds in the database
+			return bson.M{path: rangeCondition(n.rng, 1000)}
+		default:
+			return bson.M{path: rangeCondition(n.rng, 1)}
+		}
+	}
+	return matchNothing
+}
+
+func (t compileTarget) compileAll(nodes []node) bson.A {
+	filters := make(bson.A, len(nodes))
+	for i, n := range nodes {
+		filters[i] = t.compile(n)
+	}
+	return filters
+}
+
+// rangeCondition returns the comparison of rng with its bounds multiplied by scale
+func rangeCondition(rng numberRange, scale float64) bson.M {
+	cond := bson.M{}
+	if rng.Min != nil {
+		op := "$gte"
+		if rng.ExclusiveMin {
+			op = "$gt"
+		}
+		cond[op] = *rng.Min * scale
+	}
+	if rng.Max != nil {
+		op := "$lte"
+		if rng.ExclusiveMax {
+			op = "$lt"
+		}
+		cond[op] = *rng.Max * scale
+	}
+	return cond
+}
+
+// yearCondition compares the release date, in milliseconds, with the UTC
+// years of rng
+func yearCondition(rng numberRange) bson.M {
+	startOf := func(year int) int64 {
+		return time.Date(year, time.January, 1, 0, 0, 0, 0, time.UTC).UnixMilli()
+	}
+
+	cond := bson.M{}
+	if rng.Min != nil {
+		year := int(*rng.Min)
+		if rng.ExclusiveMin {
+			year++
+		}
+		cond["$gte"] = startOf(year)
+	}
+	if rng.Max != nil {
+		year := int(*rng.Max)
+		if !rng.ExclusiveMax {
+			year++
+		}
+		cond["$lt"] = startOf(year)
+	}
+	return cond
+}
+
+// positiveText collects the free terms and text field values of n that are
+// not negated, they rank the matching documents
+func positiveText(n node, terms []string, fields []*fieldNode) ([]string, []*fieldNode) {
+	switch n := n.(type) {
+	case *andNode:
+		for _, child := range n.children {
+			terms, fields = positiveText(child, terms, fields)
+		}
+	case *orNode:
+		for _, child := range n.children {
+			terms, fields = positiveText(child, terms, fields)
+		}
+	case *termNode:
+		terms = append(terms, n.text)
+	case *fieldNode:
+		if n.text != "" {
+			fields = append(fields, n)
+		}
+	}
+	return terms, fields
+}
+
+// Search key of a track per query field name
+var trackQueryKeys = map[string]func(*models.TrackSearchKeys) string{
+	"title":        func(k *models.TrackSearchKeys) string { return k.Title },
+	"name":         func(k *models.TrackSearchKeys) string { return k.Name },
+	"artist_name":  func(k *models.TrackSearchKeys) string { return k.ArtistName },
+	"album":        func(k *models.TrackSearchKeys) string { return k.Album },
+	"label":        func(k *models.TrackSearchKeys) string { return k.Label },
+	"master_owner": func(k *models.TrackSearchKeys) string { return k.MasterOwner },
+	"writers":      func(k *models.TrackSearchKeys) string { return k.Writers },
+	"publishers":   func(k *models.TrackSearchKeys) string { return k.Publishers },
+	"genre":        func(k *models.TrackSearchKeys) string { return k.Genre },
+}
+
+// Track field of each text query field
+var queryFieldTrackField = map[string]string{
+	"title":     "title",
+	"artist":    "artist_name",
+	"album":     "album",
+	"genre":     "genre",
+	"label":     "label",
+	"owner":     "master_owner",
+	"writer":    "writers",
+	"publisher": "publishers",
+}
+
+// advancedTrackScore returns the scorer of tracks found by an advanced query:
+// every positive term adds the weights of the fields holding it and every
+// positive text field the weight of that field
+func advancedTrackScore(n node) func(*models.Track) float64 {
+	terms, fields := positiveText(n, nil, nil)
+	patterns := make([]*regexp.Regexp, len(terms))
+	for i, term := range terms {
+		patterns[i] = regexp.MustCompile(wordsPattern(term))
+	}
+	fieldPatterns := make([]*regexp.Regexp, len(fields))
+	for i, f := range fields {
+		fieldPatterns[i] = regexp.MustCompile(wordsPattern(f.text))
+	}
+
+	return func(track *models.Track) float64 {
+		keys := track.SearchKeys
+		if keys == nil {
+			keys = models.NewTrackSearchKeys(track)
+		}
+		score := 0
+		for _, re := range patterns {
+			for _, field := range trackFields {
+				if re.MatchString(trackQueryKeys[field.Name](keys)) {
+					score += TrackWeights[field.Name]
+				}
+			}
+		}
+		for i, f := range fields {
+			name := queryFieldTrackField[f.field]
+			if fieldPatterns[i].MatchString(trackQueryKeys[name](keys)) {
+				score += TrackWeights[name]
+			}
+		}
+		return float64(score)
+	}
+}
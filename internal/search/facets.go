@@ -0,0 +1,180 @@
+package search
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/rolexkdev/emvn-music-library-server/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Most values returned for the genre and artist facets
+const maxFacetValues = 20
+
+// FacetCount is the number of matching tracks sharing a value
+type FacetCount struct {
+	Value string `json:"value"`
+	// Display name of the value when it is an id
+	Label string `json:"label,omitempty"`
+	Count int    `json:"count"`
+}
+
+// Facets break the matching tracks down by field. The counts of a facet
+// ignore the filter on that same facet so the alternatives stay visible.
+type Facets struct {
+	Genre    []FacetCount `json:"genre"`
+	Year     []FacetCount `json:"year"`
+	Duration []FacetCount `json:"duration"`
+	Artist   []FacetCount `json:"artist"`
+}
+
+// facetMatch returns the $match stage of a facet, made of the facet filters
+// other than its own
+func facetMatch(filter *models.TrackFilter, without func(*models.TrackFilter)) bson.D {
+	own := &models.TrackFilter{}
+	if filter != nil {
+		own.Genres = filter.Genres
+		own.Years = filter.Years
+		own.DurationBuckets = filter.DurationBuckets
+		own.ArtistIDs = filter.ArtistIDs
+	}
+	without(own)
+
+	match := bson.M{}
+	own.Apply(match)
+	return bson.D{{Key: "$match", Value: match}}
+}
+
+// Facets counts the tracks matching query and filter by genre, release year,
+// duration bucket and artist
+func (s *MongoSearcher) Facets(ctx context.Context, query Query, filter *models.TrackFilter) (*Facets, error) {
+	match, _, err := trackMatch(query)
+	if err != nil {
+		return nil, err
+	}
+
+	// The facet filters are applied inside each facet
+	base := &models.TrackFilter{}
+	if filter != nil {
+		*base = *filter
+		base.Genres, base.Years, base.DurationBuckets, base.ArtistIDs = nil, nil, nil, nil
+	}
+	base.Apply(match)
+
+	// $bucket names a bucket by its lower bound, the last bucket is
+	// unbounded so it collects the values from its lower bound on
+	boundaries := bson.A{}
+	for _, bucket := range models.DurationBuckets {
+		boundaries = append(boundaries, bucket.Min)
+	}
+	last := models.DurationBuckets[len(models.DurationBuckets)-1]
+
+	byCount := bson.D{{Key: "$sort", Value: bson.D{{Key: "count", Value: -1}, {Key: "_id", Value: 1}}}}
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: match}},
+		{{Key: "$facet", Value: bson.M{
+			"genre": bson.A{
+				facetMatch(filter, func(f *models.TrackFilter) { f.Genres = nil }),
+				bson.D{{Key: "$match", Value: bson.M{"genre": bson.M{"$nin": bson.A{nil, ""}}}}},
+				bson.D{{Key: "$group", Value: bson.M{"_id": "$genre", "count": bson.M{"$sum": 1}}}},
+				byCount,
+				bson.D{{Key: "$limit", Value: maxFacetValues}},
+			},
+			"year": bson.A{
+				facetMatch(filter, func(f *models.TrackFilter) { f.Years = nil }),
+				bson.D{{Key: "$match", Value: bson.M{"release_date": bson.M{"$gt": 0}}}},
+				bson.D{{Key: "$group", Value: bson.M{
+					"_id":   bson.M{"$year": bson.M{"$toDate": "$release_date"}},
+					"count": bson.M{"$sum": 1},
+				}}},
+				bson.D{{Key: "$sort", Value: bson.D{{Key: "_id", Value: -1}}}},
+			},
+			"duration": bson.A{
+				facetMatch(filter, func(f *models.TrackFilter) { f.DurationBuckets = nil }),
+				bson.D{{Key: "$match", Value: bson.M{"duration": bson.M{"$gte": 0}}}},
+				bson.D{{Key: "$bucket", Value: bson.M{
+					"groupBy":    "$duration",
+					"boundaries": boundaries,
+					"default":    last.Name,
+					"output":     bson.M{"count": bson.M{"$sum": 1}},
+				}}},
+			},
+			"artist": bson.A{
+				facetMatch(filter, func(f *models.TrackFilter) { f.ArtistIDs = nil }),
+				bson.D{{Key: "$match", Value: bson.M{"artist_id": bson.M{"$nin": bson.A{nil, ""}}}}},
+				bson.D{{Key: "$group", Value: bson.M{
+					"_id":   "$artist_id",
+					"label": bson.M{"$max": "$artist_name"},
+					"count": bson.M{"$sum": 1},
+				}}},
+				byCount,
+				bson.D{{Key: "$limit", Value: maxFacetValues}},
+			},
+		}}},
+	}
+
+	cursor, err := s.Tracks.Aggregate(ctx, pipeline, options.Aggregate().SetMaxTime(scanMaxTime))
+	if err != nil {
+		return nil, queryError(err)
+	}
+	defer cursor.Close(ctx)
+
+	type count struct {
+		ID    interface{} `bson:"_id"`
+		Label string      `bson:"label"`
+		Count int         `bson:"count"`
+	}
+	var doc struct {
+		Genre    []count `bson:"genre"`
+		Year     []count `bson:"year"`
+		Duration []count `bson:"duration"`
+		Artist   []count `bson:"artist"`
+	}
+	if cursor.Next(ctx) {
+		if err := cursor.Decode(&doc); err != nil {
+			return nil, err
+		}
+	}
+	if err := cursor.Err(); err != nil {
+		return nil, queryError(err)
+	}
+
+	toCounts := func(counts []count, value func(interface{}) string) []FacetCount {
+		result := []FacetCount{}
+		for _, c := range counts {
+			result = append(result, FacetCount{Value: value(c.ID), Label: c.Label, Count: c.Count})
+		}
+		return result
+	}
+	asString := func(id interface{}) string {
+		value, _ := id.(string)
+		return value
+	}
+
+	facets := &Facets{
+		Genre:  toCounts(doc.Genre, asString),
+		Artist: toCounts(doc.Artist, asString),
+		Year: toCounts(doc.Year, func(id interface{}) string {
+			year, _ := id.(int32)
+			return strconv.Itoa(int(year))
+		}),
+		Duration: toCounts(doc.Duration, func(id interface{}) string {
+			if name, ok := id.(string); ok {
+				return name
+			}
+			min, _ := id.(int64)
+			if n, ok := id.(int32); ok {
+				min = int64(n)
+			}
+			for _, bucket := range models.DurationBuckets {
+				if bucket.Min == min {
+					return bucket.Name
+				}
+			}
+			return ""
+		}),
+	}
+	return facets, nil
+}
@@ -0,0 +1,231 @@
+package search
+
+import (
+	"context"
+	"strings"
+
+	"github.com/rolexkdev/emvn-music-library-server/common/utils"
+	"github.com/rolexkdev/emvn-music-library-server/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	// Documents sharing the most trigrams with the query that are scored
+	fuzzyCandidates = 200
+	// Share of the query trigrams a candidate must hold
+	fuzzyMinTrigramShare = 1.0 / 3
+)
+
+// Results of a fuzzy search are ranked after the exact ones whatever their score
+const (
+	MatchExact = "exact"
+	MatchFuzzy = "fuzzy"
+)
+
+// maxEdits is the number of typos tolerated in a word of n characters
+func maxEdits(n int) int {
+	switch {
+	case n <= 2:
+		return 0
+	case n <= 5:
+		return 1
+	default:
+		return 2
+	}
+}
+
+// editDistance returns the optimal string alignment distance between a and b
+// (insertions, deletions, substitutions and transpositions of two adjacent
+// characters) or bound+1 once the distance is known to exceed bound
+func editDistance(a, b []rune, bound int) int {
+	if d := len(a) - len(b); d > bound || -d > bound {
+		return bound + 1
+	}
+
+	// Three rows are enough for transpositions
+	prev2 := make([]int, len(b)+1)
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		rowMin := cur[0]
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+			if i > 1 && j > 1 && a[i-1] == b[j-2] && a[i-2] == b[j-1] {
+				cur[j] = min(cur[j], prev2[j-2]+1)
+			}
+			rowMin = min(rowMin, cur[j])
+		}
+		if rowMin > bound {
+			return bound + 1
+		}
+		prev2, prev, cur = prev, cur, prev2
+	}
+	return prev[len(b)]
+}
+
+// wordSimilarity returns how close the query word is to the closest word of
+// a field, from 1 for an exact match down to 0 when it is too far
+func wordSimilarity(word string, fieldWords []string) float64 {
+	w := []rune(word)
+	best := 0.0
+	for _, fieldWord := range fieldWords {
+		if fieldWord == word {
+			return 1
+		}
+		bound := maxEdits(len(w))
+		if d := editDistance(w, []rune(fieldWord), bound); d <= bound {
+			best = max(best, 1-float64(d)/float64(len(w)+1))
+		}
+	}
+	return best
+}
+
+// fuzzyField is a normalised field scored by fuzzyScore
+type fuzzyField struct {
+	value  string
+	weight float64
+}
+
+// fuzzyScore rates how well the query words match fields, each word counting
+// in its best field. It is 0 when less than half of the words match.
+func fuzzyScore(words []string, fields []fuzzyField) float64 {
+	if len(words) == 0 {
+		return 0
+	}
+	fieldWords := make([][]string, len(fields))
+	for i, field := range fields {
+		fieldWords[i] = strings.Fields(field.value)
+	}
+
+	score := 0.0
+	matched := 0
+	for _, word := range words {
+		best := 0.0
+		for i, field := range fields {
+			best = max(best, wordSimilarity(word, fieldWords[i])*field.weight)
+		}
+		if best > 0 {
+			matched++
+		}
+		score += best
+	}
+	if matched*2 < len(words) {
+		return 0
+	}
+	return score / float64(len(words))
+}
+
+// fuzzyPipeline returns the pipeline selecting the live documents of a
+// collection sharing the most trigrams with the normalised query. exclude
+// lists the documents already found by the exact search.
+func fuzzyPipeline(query string, filter bson.M, exclude []interface{}) mongo.Pipeline {
+	trigrams := utils.Trigrams(query)
+	minShared := int(float64(len(trigrams))*fuzzyMinTrigramShare + 0.5)
+	if minShared < 1 {
+		minShared = 1
+	}
+
+	filter["search_keys.trigrams"] = bson.M{"$in": trigrams}
+	filter["delete_at"] = bson.M{"$exists": false}
+	if len(exclude) > 0 {
+		filter["_id"] = bson.M{"$nin": exclude}
+	}
+
+	return mongo.Pipeline{
+		{{Key: "$match", Value: filter}},
+		{{Key: "$addFields", Value: bson.M{"shared_trigrams": bson.M{
+			"$size": bson.M{"$setIntersection": bson.A{"$search_keys.trigrams", trigrams}},
+		}}}},
+		{{Key: "$match", Value: bson.M{"shared_trigrams": bson.M{"$gte": minShared}}}},
+		{{Key: "$sort", Value: bson.D{{Key: "shared_trigrams", Value: -1}}}},
+		{{Key: "$limit", Value: fuzzyCandidates}},
+	}
+}
+
+// fuzzyWords splits a query into normalised words
+func fuzzyWords(query string) []string {
+	return strings.Fields(utils.NormalizeSearchText(query))
+}
+
+// fuzzyTracks returns the tracks close to query but not in exclude, best first
+func (s *MongoSearcher) fuzzyTracks(ctx context.Context, query Query, trackFilter *models.TrackFilter, exclude []interface{}) ([]*TrackResult, error) {
+	filter := bson.M{}
+	trackFilter.Apply(filter)
+
+	pipeline := fuzzyPipeline(utils.NormalizeSearchText(query.Text), filter, exclude)
+	cursor, err := s.Tracks.Aggregate(ctx, pipeline, options.Aggregate().SetMaxTime(scanMaxTime))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	words := fuzzyWords(query.Text)
+	var results []*TrackResult
+	for cursor.Next(ctx) {
+		var track models.Track
+		if err := cursor.Decode(&track); err != nil {
+			return nil, err
+		}
+		keys := track.SearchKeys
+		if keys == nil {
+			continue
+		}
+		score := fuzzyScore(words, []fuzzyField{
+			{keys.Title, float64(TrackWeights["title"])},
+			{keys.ArtistName, float64(TrackWeights["artist_name"])},
+			{keys.Album, float64(TrackWeights["album"])},
+		})
+		if score > 0 {
+			results = append(results, &TrackResult{Track: &track, Score: score, Match: MatchFuzzy})
+		}
+	}
+	if err := cursor.Err(); err != nil {
+		return nil, err
+	}
+
+	sortByScore(results, func(r *TrackResult) float64 { return r.Score })
+	return results, nil
+}
+
+// fuzzyPlaylists returns the playlists with a title close to query but not in
+// exclude, best first
+func (s *MongoSearcher) fuzzyPlaylists(ctx context.Context, query Query, exclude []interface{}) ([]*PlaylistResult, error) {
+	pipeline := fuzzyPipeline(utils.NormalizeSearchText(query.Text), bson.M{}, exclude)
+	cursor, err := s.Playlists.Aggregate(ctx, pipeline, options.Aggregate().SetMaxTime(scanMaxTime))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	words := fuzzyWords(query.Text)
+	var results []*PlaylistResult
+	for cursor.Next(ctx) {
+		var playlist models.Playlist
+		if err := cursor.Decode(&playlist); err != nil {
+			return nil, err
+		}
+		if playlist.SearchKeys == nil {
+			continue
+		}
+		score := fuzzyScore(words, []fuzzyField{{playlist.SearchKeys.Title, 1}})
+		if score > 0 {
+			results = append(results, &PlaylistResult{Playlist: &playlist, Score: score, Match: MatchFuzzy})
+		}
+	}
+	if err := cursor.Err(); err != nil {
+		return nil, err
+	}
+
+	sortByScore(results, func(r *PlaylistResult) float64 { return r.Score })
+	return results, nil
+}
@@ -0,0 +1,140 @@
+package search
+
+import (
+	"context"
+
+	"github.com/rolexkdev/emvn-music-library-server/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// groupPipeline groups the tracks matching query and filter by key and returns
+// page of the groups with their total. A group is as relevant as its best
+// track in text mode, otherwise groups with more matching tracks come first.
+func groupPipeline(query Query, trackFilter *models.TrackFilter, key string, group bson.M, page Page) ([]bson.M, error) {
+	match, _, err := trackMatch(query)
+	if err != nil {
+		return nil, err
+	}
+	trackFilter.Apply(match)
+	// The filter may already hold a condition on key
+	and, _ := match["$and"].([]interface{})
+	match["$and"] = append(and, bson.M{key: bson.M{"$nin": bson.A{nil, ""}}})
+
+	score := interface{}(bson.M{"$sum": 1})
+	if query.Mode == ModeText {
+		score = bson.M{"$max": bson.M{"$meta": "textScore"}}
+	}
+	group["_id"] = "$" + key
+	group["tracks"] = bson.M{"$sum": 1}
+	group["score"] = score
+
+	results := bson.A{
+		bson.M{"$sort": bson.D{{Key: "score", Value: -1}, {Key: "tracks", Value: -1}, {Key: "_id", Value: 1}}},
+		bson.M{"$skip": page.Offset},
+	}
+	if page.Limit > 0 {
+		results = append(results, bson.M{"$limit": page.Limit})
+	}
+
+	return []bson.M{
+		{"$match": match},
+		{"$group": group},
+		{"$facet": bson.M{
+			"results": results,
+			"total":   bson.A{bson.M{"$count": "count"}},
+		}},
+	}, nil
+}
+
+// searchGroups runs a groupPipeline and decodes the page of groups into results
+func (s *MongoSearcher) searchGroups(ctx context.Context, pipeline []bson.M, results interface{}) (int64, error) {
+	cursor, err := s.Tracks.Aggregate(ctx, pipeline, options.Aggregate().SetMaxTime(scanMaxTime))
+	if err != nil {
+		return 0, queryError(err)
+	}
+	defer cursor.Close(ctx)
+
+	var doc struct {
+		Results bson.Raw `bson:"results"`
+		Total   []struct {
+			Count int64 `bson:"count"`
+		} `bson:"total"`
+	}
+	if cursor.Next(ctx) {
+		if err := cursor.Decode(&doc); err != nil {
+			return 0, err
+		}
+	}
+	if err := cursor.Err(); err != nil {
+		return 0, queryError(err)
+	}
+	if len(doc.Total) == 0 {
+		return 0, nil
+	}
+	return doc.Total[0].Count, bson.RawValue{Type: bson.TypeArray, Value: doc.Results}.Unmarshal(results)
+}
+
+// SearchAlbums returns the albums of the tracks matching query and filter,
+// albums are told apart by their normalised name
+func (s *MongoSearcher) SearchAlbums(ctx context.Context, query Query, trackFilter *models.TrackFilter, page Page) (*AlbumPage, error) {
+	pipeline, err := groupPipeline(query, trackFilter, "search_keys.album", bson.M{
+		"name":        bson.M{"$first": "$album"},
+		"artist_name": bson.M{"$first": "$artist_name"},
+	}, page)
+	if err != nil {
+		return nil, err
+	}
+
+	var docs []struct {
+		Name       string  `bson:"name"`
+		ArtistName string  `bson:"artist_name"`
+		Tracks     int     `bson:"tracks"`
+		Score      float64 `bson:"score"`
+	}
+	total, err := s.searchGroups(ctx, pipeline, &docs)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]*AlbumResult, len(docs))
+	for i, doc := range docs {
+		results[i] = &AlbumResult{Name: doc.Name, ArtistName: doc.ArtistName, Tracks: doc.Tracks, Score: doc.Score}
+	}
+	return &AlbumPage{
+		Results:    results,
+		Total:      total,
+		NextCursor: nextCursor(TypeAlbums, page, len(results), total),
+	}, nil
+}
+
+// SearchArtists returns the artists of the tracks matching query and filter
+func (s *MongoSearcher) SearchArtists(ctx context.Context, query Query, trackFilter *models.TrackFilter, page Page) (*ArtistPage, error) {
+	pipeline, err := groupPipeline(query, trackFilter, "artist_id", bson.M{
+		"name": bson.M{"$max": "$artist_name"},
+	}, page)
+	if err != nil {
+		return nil, err
+	}
+
+	var docs []struct {
+		ID     string  `bson:"_id"`
+		Name   string  `bson:"name"`
+		Tracks int     `bson:"tracks"`
+		Score  float64 `bson:"score"`
+	}
+	total, err := s.searchGroups(ctx, pipeline, &docs)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]*ArtistResult, len(docs))
+	for i, doc := range docs {
+		results[i] = &ArtistResult{ID: doc.ID, Name: doc.Name, Tracks: doc.Tracks, Score: doc.Score}
+	}
+	return &ArtistPage{
+		Results:    results,
+		Total:      total,
+		NextCursor: nextCursor(TypeArtists, page, len(results), total),
+	}, nil
+}
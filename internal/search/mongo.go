@@ -0,0 +1,386 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/rolexkdev/emvn-music-library-server/common/utils"
+	"github.com/rolexkdev/emvn-music-library-server/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	trackTextIndex    = "track_search_text_v2"
+	playlistTextIndex = "playlist_search_text"
+
+	// Text indexes on the raw fields, replaced by the ones on search keys. A
+	// collection holds a single text index so they have to go first.
+	legacyTrackTextIndex    = "track_text"
+	legacyPlaylistTextIndex = "playlist_text"
+	// Text index on the search keys before the rights fields were added
+	previousTrackTextIndex = "track_search_text"
+
+	// Longest a search not using the text index may run in the database
+	scanMaxTime = 2 * time.Second
+)
+
+// MongoSearcher ranks documents with the MongoDB text index of each collection
+type MongoSearcher struct {
+	Tracks    *mongo.Collection
+	Playlists *mongo.Collection
+}
+
+func NewMongoSearcher(db *mongo.Database) *MongoSearcher {
+	return &MongoSearcher{
+		Tracks:    db.Collection("track"),
+		Playlists: db.Collection("playlist"),
+	}
+}
+
+// EnsureIndexes creates the weighted text indexes over the normalised search
+// keys. Stemming is disabled with the "none" language since titles are mostly
+// Vietnamese and English is not a safe guess.
+func (s *MongoSearcher) EnsureIndexes(ctx context.Context) error {
+	for _, name := range []string{legacyTrackTextIndex, previousTrackTextIndex} {
+		if err := dropIndex(ctx, s.Tracks, name); err != nil {
+			return err
+		}
+	}
+	if err := dropIndex(ctx, s.Playlists, legacyPlaylistTextIndex); err != nil {
+		return err
+	}
+
+	keys := bson.D{}
+	weights := bson.D{}
+	for _, field := range trackFields {
+		keys = append(keys, bson.E{Key: "search_keys." + field.Name, Value: "text"})
+		weights = append(weights, bson.E{Key: "search_keys." + field.Name, Value: TrackWeights[field.Name]})
+	}
+	_, err := s.Tracks.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: keys,
+		Options: options.Index().
+			SetName(trackTextIndex).
+			SetWeights(weights).
+			SetDefaultLanguage("none"),
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = s.Playlists.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "search_keys.title", Value: "text"}},
+		Options: options.Index().
+			SetName(playlistTextIndex).
+			SetDefaultLanguage("none"),
+	})
+	if err != nil {
+		return err
+	}
+
+	// Multikey indexes of the suggestion prefixes and fuzzy search trigrams
+	_, err = s.Tracks.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "search_keys.title_grams", Value: 1}}},
+		{Keys: bson.D{{Key: "search_keys.artist_name_grams", Value: 1}}},
+		{Keys: bson.D{{Key: "search_keys.album_grams", Value: 1}}},
+		{Keys: bson.D{{Key: "search_keys.trigrams", Value: 1}}},
+	})
+	if err != nil {
+		return err
+	}
+	_, err = s.Playlists.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "search_keys.title_grams", Value: 1}}},
+		{Keys: bson.D{{Key: "search_keys.trigrams", Value: 1}}},
+	})
+	return err
+}
+
+// dropIndex removes the index called name if the collection has it
+func dropIndex(ctx context.Context, collection *mongo.Collection, name string) error {
+	_, err := collection.Indexes().DropOne(ctx, name)
+	var cmdErr mongo.CommandError
+	if errors.As(err, &cmdErr) && (cmdErr.Name == "IndexNotFound" || cmdErr.Name == "NamespaceNotFound") {
+		return nil
+	}
+	return err
+}
+
+// textOperators turns the characters $text reads as negation and phrase
+// markers into word separators, so the query is matched literally
+var textOperators = strings.NewReplacer("-", " ", `"`, " ")
+
+// textFilter matches the live documents containing the words of query, which
+// is normalised like the indexed search keys
+func textFilter(query string) bson.M {
+	return bson.M{
+		"$text":     bson.M{"$search": utils.NormalizeSearchText(textOperators.Replace(query))},
+		"delete_at": bson.M{"$exists": false},
+	}
+}
+
+// textQuery returns the find options sorting the text matches of page by score
+func textQuery(page Page) *options.FindOptions {
+	score := bson.M{"score": bson.M{"$meta": "textScore"}}
+	opts := options.Find().SetProjection(score).SetSort(score).SetSkip(int64(page.Offset))
+	if page.Limit > 0 {
+		opts.SetLimit(int64(page.Limit))
+	}
+	return opts
+}
+
+// regexFilter matches the live documents with one of fields matching pattern.
+// The raw fields are used so the pattern is not altered by normalisation.
+func regexFilter(pattern string, fields ...string) bson.M {
+	var or []interface{}
+	for _, field := range fields {
+		or = append(or, bson.M{field: bson.M{"$regex": pattern, "$options": "i"}})
+	}
+	return bson.M{
+		"$or":       or,
+		"delete_at": bson.M{"$exists": false},
+	}
+}
+
+// scanQuery returns the find options of a search the database cannot serve
+// from the text index, bounded in time since it may scan the collection
+func scanQuery(limit int) *options.FindOptions {
+	opts := options.Find().SetMaxTime(scanMaxTime)
+	if limit > 0 {
+		opts.SetLimit(int64(limit))
+	}
+	return opts
+}
+
+// scanPage returns the find options of page in a search not using the text index
+func scanPage(page Page) *options.FindOptions {
+	return scanQuery(page.Limit).SetSkip(int64(page.Offset))
+}
+
+// advancedFilter matches the live documents selected by an advanced query
+func advancedFilter(target compileTarget, n node) bson.M {
+	return bson.M{
+		"$and":      []interface{}{target.compile(n)},
+		"delete_at": bson.M{"$exists": false},
+	}
+}
+
+// trackMatch returns the filter of the tracks matching query and, for the
+// modes the database does not score, the function scoring them
+func trackMatch(query Query) (bson.M, func(*models.Track) float64, error) {
+	if err := query.Validate(); err != nil {
+		return nil, nil, err
+	}
+
+	switch query.Mode {
+	case ModeRegex:
+		re, _ := compileRegex(query.Text)
+		score := func(track *models.Track) float64 { return regexScore(re, track) }
+		return regexFilter(query.Text, trackFieldPaths()...), score, nil
+	case ModeAdvanced:
+		n, _ := parseQuery(query.Text)
+		return advancedFilter(trackTarget, n), advancedTrackScore(n), nil
+	}
+	return textFilter(query.Text), nil, nil
+}
+
+// queryError turns a search cut by the time limit into an invalid query
+func queryError(err error) error {
+	if mongo.IsTimeout(err) {
+		return fmt.Errorf("%w: query is too expensive", ErrInvalidQuery)
+	}
+	return err
+}
+
+// findTracks decodes the tracks found with their text score, if projected
+func findTracks(ctx context.Context, collection *mongo.Collection, filter bson.M, opts *options.FindOptions) ([]*TrackResult, error) {
+	cursor, err := collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, queryError(err)
+	}
+	defer cursor.Close(ctx)
+
+	results := []*TrackResult{}
+	for cursor.Next(ctx) {
+		var doc struct {
+			models.Track `bson:",inline"`
+			Score        float64 `bson:"score"`
+		}
+		if err := cursor.Decode(&doc); err != nil {
+			return nil, err
+		}
+		results = append(results, &TrackResult{Track: &doc.Track, Score: doc.Score, Match: MatchExact})
+	}
+	if err := cursor.Err(); err != nil {
+		return nil, queryError(err)
+	}
+	return results, nil
+}
+
+// matchedIDs returns the ids of the documents matching filter, they are left
+// out of the fuzzy matches
+func matchedIDs(ctx context.Context, collection *mongo.Collection, filter bson.M) ([]interface{}, error) {
+	opts := options.Find().SetProjection(bson.M{"_id": 1}).SetLimit(maxRankedResults).SetMaxTime(scanMaxTime)
+	cursor, err := collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, queryError(err)
+	}
+	defer cursor.Close(ctx)
+
+	var ids []interface{}
+	for cursor.Next(ctx) {
+		ids = append(ids, cursor.Current.Lookup("_id"))
+	}
+	return ids, queryError(cursor.Err())
+}
+
+// pageWithFuzzy returns the part of page after the exact matches, taken from
+// the fuzzy matches
+func pageWithFuzzy[T any](page Page, exactTotal int64, exact, fuzzy []T) []T {
+	offset := page.Offset - int(exactTotal)
+	limit := page.Limit - len(exact)
+	if offset < 0 {
+		offset = 0
+	}
+	if limit <= 0 {
+		return exact
+	}
+	return append(exact, slicePage(fuzzy, Page{Offset: offset, Limit: limit})...)
+}
+
+func (s *MongoSearcher) SearchTracks(ctx context.Context, query Query, trackFilter *models.TrackFilter, page Page) (*TrackPage, error) {
+	filter, score, err := trackMatch(query)
+	if err != nil {
+		return nil, err
+	}
+	trackFilter.Apply(filter)
+
+	total, err := s.Tracks.CountDocuments(ctx, filter, options.Count().SetMaxTime(scanMaxTime))
+	if err != nil {
+		return nil, queryError(err)
+	}
+
+	var results []*TrackResult
+	if score == nil {
+		// The text index ranks and pages the matches
+		results, err = findTracks(ctx, s.Tracks, filter, textQuery(page))
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		results, err = findTracks(ctx, s.Tracks, filter, scanQuery(maxRankedResults))
+		if err != nil {
+			return nil, err
+		}
+		for _, result := range results {
+			result.Score = score(result.Track)
+		}
+		sortByScore(results, func(r *TrackResult) float64 { return r.Score })
+		results = slicePage(results, page)
+		// Only the ranked matches can be paged
+		if total > maxRankedResults {
+			total = maxRankedResults
+		}
+	}
+
+	if query.Fuzzy {
+		exclude, err := matchedIDs(ctx, s.Tracks, filter)
+		if err != nil {
+			return nil, err
+		}
+		fuzzy, err := s.fuzzyTracks(ctx, query, trackFilter, exclude)
+		if err != nil {
+			return nil, err
+		}
+		results = pageWithFuzzy(page, total, results, fuzzy)
+		total += int64(len(fuzzy))
+	}
+
+	return &TrackPage{
+		Results:    results,
+		Total:      total,
+		NextCursor: nextCursor(TypeTracks, page, len(results), total),
+	}, nil
+}
+
+// regexScore adds up the weights of the track fields matching re, the
+// database does not score regex matches
+func regexScore(re *regexp.Regexp, track *models.Track) float64 {
+	score := 0
+	for _, field := range trackFields {
+		if re.MatchString(field.Value(track)) {
+			score += TrackWeights[field.Name]
+		}
+	}
+	return float64(score)
+}
+
+func (s *MongoSearcher) SearchPlaylists(ctx context.Context, query Query, page Page) (*PlaylistPage, error) {
+	if err := query.Validate(); err != nil {
+		return nil, err
+	}
+
+	// Only the title is searched, every match of the modes the database does
+	// not score is as good
+	filter, opts := textFilter(query.Text), textQuery(page)
+	switch query.Mode {
+	case ModeRegex:
+		filter, opts = regexFilter(query.Text, "title"), scanPage(page)
+	case ModeAdvanced:
+		n, _ := parseQuery(query.Text)
+		filter, opts = advancedFilter(playlistTarget, n), scanPage(page)
+	}
+
+	total, err := s.Playlists.CountDocuments(ctx, filter, options.Count().SetMaxTime(scanMaxTime))
+	if err != nil {
+		return nil, queryError(err)
+	}
+
+	cursor, err := s.Playlists.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, queryError(err)
+	}
+	defer cursor.Close(ctx)
+
+	results := []*PlaylistResult{}
+	for cursor.Next(ctx) {
+		var doc struct {
+			models.Playlist `bson:",inline"`
+			Score           float64 `bson:"score"`
+		}
+		if err := cursor.Decode(&doc); err != nil {
+			return nil, err
+		}
+		result := &PlaylistResult{Playlist: &doc.Playlist, Score: doc.Score, Match: MatchExact}
+		if query.Mode != ModeText {
+			result.Score = 1
+		}
+		results = append(results, result)
+	}
+	if err := cursor.Err(); err != nil {
+		return nil, queryError(err)
+	}
+
+	if query.Fuzzy {
+		exclude, err := matchedIDs(ctx, s.Playlists, filter)
+		if err != nil {
+			return nil, err
+		}
+		fuzzy, err := s.fuzzyPlaylists(ctx, query, exclude)
+		if err != nil {
+			return nil, err
+		}
+		results = pageWithFuzzy(page, total, results, fuzzy)
+		total += int64(len(fuzzy))
+	}
+
+	return &PlaylistPage{
+		Results:    results,
+		Total:      total,
+		NextCursor: nextCursor(TypePlaylists, page, len(results), total),
+	}, nil
+}
@@ -0,0 +1,144 @@
+package search
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Result types, each paged on its own
+const (
+	TypeTracks    = "tracks"
+	TypePlaylists = "playlists"
+	TypeAlbums    = "albums"
+	TypeArtists   = "artists"
+)
+
+// Types lists every result type in response order
+var Types = []string{TypeTracks, TypePlaylists, TypeAlbums, TypeArtists}
+
+// Most matches ranked when the database cannot sort them by score, the total
+// of such searches is capped to it so no cursor points past the ranking
+const maxRankedResults = 1000
+
+// Page selects a slice of the ranked results
+type Page struct {
+	Offset int
+	Limit  int
+}
+
+// TrackPage is a page of matching tracks
+type TrackPage struct {
+	Results    []*TrackResult `json:"results"`
+	Total      int64          `json:"total"`
+	NextCursor string         `json:"next_cursor,omitempty"`
+}
+
+// PlaylistPage is a page of matching playlists
+type PlaylistPage struct {
+	Results    []*PlaylistResult `json:"results"`
+	Total      int64             `json:"total"`
+	NextCursor string            `json:"next_cursor,omitempty"`
+}
+
+// AlbumResult is an album of the matching tracks
+type AlbumResult struct {
+	Name       string `json:"name"`
+	ArtistName string `json:"artist_name,omitempty"`
+	// Number of matching tracks of the album
+	Tracks int     `json:"tracks"`
+	Score  float64 `json:"score"`
+}
+
+// AlbumPage is a page of albums of the matching tracks
+type AlbumPage struct {
+	Results    []*AlbumResult `json:"results"`
+	Total      int64          `json:"total"`
+	NextCursor string         `json:"next_cursor,omitempty"`
+}
+
+// ArtistResult is an artist of the matching tracks
+type ArtistResult struct {
+	ID   string `json:"id"`
+	Name string `json:"name,omitempty"`
+	// Number of matching tracks of the artist
+	Tracks int     `json:"tracks"`
+	Score  float64 `json:"score"`
+}
+
+// ArtistPage is a page of artists of the matching tracks
+type ArtistPage struct {
+	Results    []*ArtistResult `json:"results"`
+	Total      int64           `json:"total"`
+	NextCursor string          `json:"next_cursor,omitempty"`
+}
+
+// ParseTypes reads a comma separated list of result types, empty meaning all
+func ParseTypes(s string) ([]string, error) {
+	if strings.TrimSpace(s) == "" {
+		return Types, nil
+	}
+
+	var types []string
+	for _, name := range strings.Split(s, ",") {
+		name = strings.TrimSpace(name)
+		known := false
+		for _, t := range Types {
+			known = known || t == name
+		}
+		if !known {
+			return nil, fmt.Errorf("%w: unknown type %q, expected %s", ErrInvalidQuery, name, strings.Join(Types, ", "))
+		}
+		types = append(types, name)
+	}
+	return types, nil
+}
+
+// EncodeCursor returns the opaque cursor of the results of resultType from offset on
+func EncodeCursor(resultType string, offset int) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(resultType + ":" + strconv.Itoa(offset)))
+}
+
+// DecodeCursor returns the offset stored in a cursor of resultType
+func DecodeCursor(cursor, resultType string) (int, error) {
+	invalid := fmt.Errorf("%w: invalid cursor", ErrInvalidQuery)
+
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, invalid
+	}
+	kind, value, ok := strings.Cut(string(raw), ":")
+	if !ok {
+		return 0, invalid
+	}
+	if kind != resultType {
+		return 0, fmt.Errorf("%w: cursor belongs to type %s", ErrInvalidQuery, kind)
+	}
+	offset, err := strconv.Atoi(value)
+	if err != nil || offset < 0 {
+		return 0, invalid
+	}
+	return offset, nil
+}
+
+// nextCursor returns the cursor of the page after the n results returned for
+// page, empty on the last page
+func nextCursor(resultType string, page Page, n int, total int64) string {
+	if n == 0 || int64(page.Offset+n) >= total {
+		return ""
+	}
+	return EncodeCursor(resultType, page.Offset+n)
+}
+
+// slicePage returns the part of results covered by page
+func slicePage[T any](results []T, page Page) []T {
+	if page.Offset >= len(results) {
+		return []T{}
+	}
+	end := len(results)
+	if page.Limit > 0 && page.Offset+page.Limit < end {
+		end = page.Offset + page.Limit
+	}
+	return results[page.Offset:end]
+}
@@ -0,0 +1,420 @@
+package search
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/rolexkdev/emvn-music-library-server/common/utils"
+	"github.com/rolexkdev/emvn-music-library-server/internal/media"
+)
+
+// The advanced query language:
+//
+//	query   = or
+//	or      = and { "OR" and }
+//	and     = unary { unary }
+//	unary   = "-" unary | primary
+//	primary = "(" or ")" | field ":" value | word | "\"" phrase "\""
+//
+// e.g. genre:pop artist:"Hoang Dung" year:2019..2021 duration:<240 -remix
+// Numeric fields take a number, a range "a..b" (either end may be left out)
+// or a comparison such as "<240" or ">=2019". Durations are in seconds.
+
+// ParseError is a query rejected by the parser, Pos counts characters from 1
+type ParseError struct {
+	Pos int
+	Msg string
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("%v: position %d: %s", ErrInvalidQuery, e.Pos, e.Msg)
+}
+
+func (e *ParseError) Unwrap() error {
+	return ErrInvalidQuery
+}
+
+// Types of field values
+type fieldKind int
+
+const (
+	textField fieldKind = iota
+	numberField
+	keyField
+	// Identifiers matched exactly once normalised, such as an ISRC
+	codeField
+)
+
+// queryField is a field that can be named in a query
+type queryField struct {
+	kind fieldKind
+	// Integer values only
+	integer bool
+	// Validation and normalisation of the values of a codeField
+	valid     func(string) bool
+	normalize func(string) string
+}
+
+var queryFields = map[string]queryField{
+	"title":     {kind: textField},
+	"artist":    {kind: textField},
+	"album":     {kind: textField},
+	"genre":     {kind: textField},
+	"label":     {kind: textField},
+	"owner":     {kind: textField},
+	"writer":    {kind: textField},
+	"publisher": {kind: textField},
+	"isrc":      {kind: codeField, valid: utils.IsISRC, normalize: utils.NormalizeISRC},
+	"iswc":      {kind: codeField, valid: utils.IsISWC, normalize: utils.NormalizeISWC},
+	"year":      {kind: numberField, integer: true},
+	"duration":  {kind: numberField},
+	"bpm":       {kind: numberField},
+	"key":       {kind: keyField},
+}
+
+// numberRange bounds a numeric field, nil ends are open
+type numberRange struct {
+	Min, Max                   *float64
+	ExclusiveMin, ExclusiveMax bool
+}
+
+// Nodes of a parsed query
+type (
+	node interface{}
+
+	andNode struct{ children []node }
+	orNode  struct{ children []node }
+	notNode struct{ child node }
+
+	// termNode matches the words of text in any searchable field
+	termNode struct{ text string }
+
+	// fieldNode matches a single field, with text for text fields, rng for
+	// numeric fields and key for the musical key and identifiers
+	fieldNode struct {
+		field string
+		text  string
+		rng   numberRange
+		key   string
+	}
+)
+
+type tokenKind int
+
+const (
+	tokenWord tokenKind = iota
+	tokenPhrase
+	tokenField
+	tokenOpen
+	tokenClose
+	tokenNot
+	tokenOr
+	tokenEnd
+)
+
+type token struct {
+	kind tokenKind
+	// Word or phrase text, or field name
+	text string
+	// Field value
+	value       string
+	valuePhrase bool
+	pos         int
+	valuePos    int
+}
+
+// lex splits a query into tokens
+func lex(query string) ([]token, error) {
+	runes := []rune(query)
+	var tokens []token
+	i := 0
+
+	readPhrase := func() (string, error) {
+		start := i
+		i++
+		end := i
+		for end < len(runes) && runes[end] != '"' {
+			end++
+		}
+		if end == len(runes) {
+			return "", &ParseError{Pos: start + 1, Msg: "unterminated quote"}
+		}
+		text := string(runes[i:end])
+		i = end + 1
+		return text, nil
+	}
+
+	for i < len(runes) {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '(':
+			tokens = append(tokens, token{kind: tokenOpen, pos: i + 1})
+			i++
+		case r == ')':
+			tokens = append(tokens, token{kind: tokenClose, pos: i + 1})
+			i++
+		case r == '-':
+			// A dash inside a word such as "M-TP" is read with the word
+			if i+1 == len(runes) || unicode.IsSpace(runes[i+1]) {
+				return nil, &ParseError{Pos: i + 1, Msg: "nothing to exclude after -"}
+			}
+			tokens = append(tokens, token{kind: tokenNot, pos: i + 1})
+			i++
+		case r == '"':
+			pos := i + 1
+			text, err := readPhrase()
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, token{kind: tokenPhrase, text: text, pos: pos})
+		default:
+			start := i
+			for i < len(runes) && !unicode.IsSpace(runes[i]) && !strings.ContainsRune(`()"`, runes[i]) {
+				i++
+			}
+			word := string(runes[start:i])
+
+			colon := strings.IndexRune(word, ':')
+			switch {
+			case word == "OR":
+				tokens = append(tokens, token{kind: tokenOr, pos: start + 1})
+			case colon < 0:
+				tokens = append(tokens, token{kind: tokenWord, text: word, pos: start + 1})
+			default:
+				field := word[:colon]
+				value := word[colon+1:]
+				valuePos := start + len([]rune(field)) + 2
+				phrase := false
+				if value == "" && i < len(runes) && runes[i] == '"' {
+					text, err := readPhrase()
+					if err != nil {
+						return nil, err
+					}
+					value, phrase = text, true
+				}
+				if value == "" {
+					return nil, &ParseError{Pos: valuePos, Msg: fmt.Sprintf("missing value for field %q", field)}
+				}
+				tokens = append(tokens, token{
+					kind: tokenField, text: field, value: value, valuePhrase: phrase,
+					pos: start + 1, valuePos: valuePos,
+				})
+			}
+		}
+	}
+	return append(tokens, token{kind: tokenEnd, pos: len(runes) + 1}), nil
+}
+
+type parser struct {
+	tokens []token
+	next   int
+}
+
+// parseQuery parses an advanced query into a tree of nodes
+func parseQuery(query string) (node, error) {
+	tokens, err := lex(query)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens}
+	if p.peek().kind == tokenEnd {
+		return nil, &ParseError{Pos: 1, Msg: "empty query"}
+	}
+
+	n, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != tokenEnd {
+		return nil, &ParseError{Pos: t.pos, Msg: "unexpected )"}
+	}
+	return n, nil
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.next]
+}
+
+func (p *parser) parseOr() (node, error) {
+	first, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	children := []node{first}
+	for p.peek().kind == tokenOr {
+		p.next++
+		n, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		children = append(children, n)
+	}
+	if len(children) == 1 {
+		return first, nil
+	}
+	return &orNode{children: children}, nil
+}
+
+func (p *parser) parseAnd() (node, error) {
+	var children []node
+	for {
+		switch t := p.peek(); t.kind {
+		case tokenEnd, tokenClose, tokenOr:
+			if len(children) == 0 {
+				return nil, &ParseError{Pos: t.pos, Msg: "expected a term"}
+			}
+			if len(children) == 1 {
+				return children[0], nil
+			}
+			return &andNode{children: children}, nil
+		}
+
+		n, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		children = append(children, n)
+	}
+}
+
+func (p *parser) parseUnary() (node, error) {
+	t := p.peek()
+	switch t.kind {
+	case tokenNot:
+		p.next++
+		child, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &notNode{child: child}, nil
+	case tokenOpen:
+		p.next++
+		n, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if p.peek().kind != tokenClose {
+			return nil, &ParseError{Pos: t.pos, Msg: "unclosed ("}
+		}
+		p.next++
+		return n, nil
+	case tokenWord, tokenPhrase:
+		p.next++
+		text := utils.NormalizeSearchText(t.text)
+		if text == "" {
+			return nil, &ParseError{Pos: t.pos, Msg: "empty term"}
+		}
+		return &termNode{text: text}, nil
+	case tokenField:
+		p.next++
+		return parseField(t)
+	}
+	return nil, &ParseError{Pos: t.pos, Msg: "expected a term"}
+}
+
+// parseField checks the name and value of a field token
+func parseField(t token) (node, error) {
+	name := strings.ToLower(t.text)
+	field, ok := queryFields[name]
+	if !ok {
+		return nil, &ParseError{Pos: t.pos, Msg: fmt.Sprintf("unknown field %q, expected one of %s", t.text, fieldNames())}
+	}
+
+	n := &fieldNode{field: name}
+	switch field.kind {
+	case textField:
+		n.text = utils.NormalizeSearchText(t.value)
+		if n.text == "" {
+			return nil, &ParseError{Pos: t.valuePos, Msg: fmt.Sprintf("missing value for field %q", name)}
+		}
+	case keyField:
+		key, ok := media.ParseKey(t.value)
+		if !ok {
+			return nil, &ParseError{Pos: t.valuePos, Msg: fmt.Sprintf("invalid key %q, expected e.g. Am or \"A minor\"", t.value)}
+		}
+		n.key = key
+	case codeField:
+		if !field.valid(t.value) {
+			return nil, &ParseError{Pos: t.valuePos, Msg: fmt.Sprintf("invalid %s %q", name, t.value)}
+		}
+		n.key = field.normalize(t.value)
+	case numberField:
+		if t.valuePhrase {
+			return nil, &ParseError{Pos: t.valuePos, Msg: fmt.Sprintf("field %q takes a number or a range", name)}
+		}
+		rng, err := parseRange(t.value, t.valuePos, field.integer)
+		if err != nil {
+			return nil, err
+		}
+		n.rng = rng
+	}
+	return n, nil
+}
+
+// parseRange reads "n", "a..b", "a..", "..b", ">n", ">=n", "<n" or "<=n"
+func parseRange(value string, pos int, integer bool) (numberRange, error) {
+	number := func(s string, at int) (*float64, error) {
+		n, err := strconv.ParseFloat(s, 64)
+		if err != nil || (integer && n != float64(int64(n))) {
+			kind := "a number"
+			if integer {
+				kind = "an integer"
+			}
+			return nil, &ParseError{Pos: at, Msg: fmt.Sprintf("expected %s, got %q", kind, s)}
+		}
+		return &n, nil
+	}
+
+	var rng numberRange
+	var err error
+	switch {
+	case strings.HasPrefix(value, ">="):
+		rng.Min, err = number(value[2:], pos+2)
+	case strings.HasPrefix(value, "<="):
+		rng.Max, err = number(value[2:], pos+2)
+	case strings.HasPrefix(value, ">"):
+		rng.Min, err = number(value[1:], pos+1)
+		rng.ExclusiveMin = true
+	case strings.HasPrefix(value, "<"):
+		rng.Max, err = number(value[1:], pos+1)
+		rng.ExclusiveMax = true
+	case strings.Contains(value, ".."):
+		dots := strings.Index(value, "..")
+		low, high := value[:dots], value[dots+2:]
+		if low == "" && high == "" {
+			return rng, &ParseError{Pos: pos, Msg: "range needs at least one bound"}
+		}
+		if low != "" {
+			if rng.Min, err = number(low, pos); err != nil {
+				return rng, err
+			}
+		}
+		if high != "" {
+			if rng.Max, err = number(high, pos+dots+2); err != nil {
+				return rng, err
+			}
+		}
+		if rng.Min != nil && rng.Max != nil && *rng.Min > *rng.Max {
+			return rng, &ParseError{Pos: pos, Msg: "range start is greater than its end"}
+		}
+	default:
+		rng.Min, err = number(value, pos)
+		rng.Max = rng.Min
+	}
+	return rng, err
+}
+
+// fieldNames lists the query fields for error messages
+func fieldNames() string {
+	names := make([]string, 0, len(queryFields))
+	for name := range queryFields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
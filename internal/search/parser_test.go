@@ -0,0 +1,118 @@
+package search
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func number(v float64) *float64 {
+	return &v
+}
+
+func TestParseQuery(t *testing.T) {
+	tests := []struct {
+		query string
+		want  node
+	}{
+		{"pop", &termNode{text: "pop"}},
+		{"M-TP", &termNode{text: "m-tp"}},
+		{`"Nàng Thơ"`, &termNode{text: "nang tho"}},
+		{"genre:pop -remix", &andNode{children: []node{
+			&fieldNode{field: "genre", text: "pop"},
+			&notNode{child: &termNode{text: "remix"}},
+		}}},
+		{"-genre:pop", &notNode{child: &fieldNode{field: "genre", text: "pop"}}},
+		{"--pop", &notNode{child: &notNode{child: &termNode{text: "pop"}}}},
+		{`artist:"Hoang Dung"`, &fieldNode{field: "artist", text: "hoang dung"}},
+		{"Genre:Pop", &fieldNode{field: "genre", text: "pop"}},
+		{"year:2019", &fieldNode{field: "year", rng: numberRange{Min: number(2019), Max: number(2019)}}},
+		{"year:2019..2021", &fieldNode{field: "year", rng: numberRange{Min: number(2019), Max: number(2021)}}},
+		{"year:2019..", &fieldNode{field: "year", rng: numberRange{Min: number(2019)}}},
+		{"year:..2021", &fieldNode{field: "year", rng: numberRange{Max: number(2021)}}},
+		{"-year:..2000", &notNode{child: &fieldNode{field: "year", rng: numberRange{Max: number(2000)}}}},
+		{"duration:<240", &fieldNode{field: "duration", rng: numberRange{Max: number(240), ExclusiveMax: true}}},
+		{"duration:<=240.5", &fieldNode{field: "duration", rng: numberRange{Max: number(240.5)}}},
+		{"bpm:>120", &fieldNode{field: "bpm", rng: numberRange{Min: number(120), ExclusiveMin: true}}},
+		{"bpm:>=120", &fieldNode{field: "bpm", rng: numberRange{Min: number(120)}}},
+		{"bpm:90.5..100", &fieldNode{field: "bpm", rng: numberRange{Min: number(90.5), Max: number(100)}}},
+		{"key:Am", &fieldNode{field: "key", key: "A minor"}},
+		{"isrc:vn-a01-19-00001", &fieldNode{field: "isrc", key: "VNA011900001"}},
+		{"pop OR rock", &orNode{children: []node{&termNode{text: "pop"}, &termNode{text: "rock"}}}},
+		{"(pop OR rock) live", &andNode{children: []node{
+			&orNode{children: []node{&termNode{text: "pop"}, &termNode{text: "rock"}}},
+			&termNode{text: "live"},
+		}}},
+		{"a b OR c", &orNode{children: []node{
+			&andNode{children: []node{&termNode{text: "a"}, &termNode{text: "b"}}},
+			&termNode{text: "c"},
+		}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			got, err := parseQuery(tt.query)
+			if err != nil {
+				t.Fatalf("parseQuery(%q) failed: %v", tt.query, err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseQuery(%q) = %#v, want %#v", tt.query, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseQueryErrors(t *testing.T) {
+	tests := []struct {
+		query string
+		pos   int
+		msg   string
+	}{
+		{"", 1, "empty query"},
+		{"   ", 1, "empty query"},
+		{"-", 1, "nothing to exclude after -"},
+		{"- pop", 1, "nothing to exclude after -"},
+		{"pop -", 5, "nothing to exclude after -"},
+		{"genre:", 7, `missing value for field "genre"`},
+		{"-genre:", 8, `missing value for field "genre"`},
+		{"pop -genre: rock", 12, `missing value for field "genre"`},
+		{`genre:""`, 7, `missing value for field "genre"`},
+		{`"pop`, 1, "unterminated quote"},
+		{`title:"pop`, 7, "unterminated quote"},
+		{"foo:bar", 1, `unknown field "foo"`},
+		{"pop -foo:bar", 6, `unknown field "foo"`},
+		{"year:..", 6, "range needs at least one bound"},
+		{"year:2021..2019", 6, "range start is greater than its end"},
+		{"year:x..2019", 6, `expected an integer, got "x"`},
+		{"year:2019..20x1", 12, `expected an integer, got "20x1"`},
+		{"year:2019.5", 6, `expected an integer, got "2019.5"`},
+		{"duration:<abc", 11, `expected a number, got "abc"`},
+		{"duration:>=abc", 12, `expected a number, got "abc"`},
+		{`year:"2019"`, 6, `field "year" takes a number or a range`},
+		{"key:H", 5, `invalid key "H"`},
+		{"isrc:XX", 6, `invalid isrc "XX"`},
+		{"(pop", 1, "unclosed ("},
+		{"pop)", 4, "unexpected )"},
+		{"()", 2, "expected a term"},
+		{"pop OR", 7, "expected a term"},
+		{"OR pop", 1, "expected a term"},
+		{"nàng -", 6, "nothing to exclude after -"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			_, err := parseQuery(tt.query)
+			var parseErr *ParseError
+			if !errors.As(err, &parseErr) {
+				t.Fatalf("parseQuery(%q) error = %v, want a ParseError", tt.query, err)
+			}
+			if !errors.Is(err, ErrInvalidQuery) {
+				t.Errorf("parseQuery(%q) error does not wrap ErrInvalidQuery", tt.query)
+			}
+			if parseErr.Pos != tt.pos || !strings.Contains(parseErr.Msg, tt.msg) {
+				t.Errorf("parseQuery(%q) error at %d %q, want at %d %q", tt.query, parseErr.Pos, parseErr.Msg, tt.pos, tt.msg)
+			}
+		})
+	}
+}
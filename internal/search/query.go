@@ -0,0 +1,145 @@
+package search
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"regexp/syntax"
+	"strings"
+)
+
+// Mode selects how the text of a query is interpreted
+type Mode string
+
+const (
+	// ModeText matches the words of the query, the default
+	ModeText Mode = "text"
+	// ModeRegex matches a regular expression, reserved to admins
+	ModeRegex Mode = "regex"
+	// ModeAdvanced parses the query language described in parser.go
+	ModeAdvanced Mode = "advanced"
+)
+
+// Longest pattern accepted in regex mode
+const MaxRegexLength = 100
+
+// ErrInvalidQuery is returned for queries rejected before reaching the database
+var ErrInvalidQuery = errors.New("invalid search query")
+
+// Query is what the caller searches for
+type Query struct {
+	Text string
+	Mode Mode
+	// Also return the documents matching with typos, after the exact matches
+	Fuzzy bool
+}
+
+// ParseMode reads the mode query parameter, empty meaning ModeText
+func ParseMode(s string) (Mode, error) {
+	switch Mode(s) {
+	case "", ModeText:
+		return ModeText, nil
+	case ModeRegex, ModeAdvanced:
+		return Mode(s), nil
+	}
+	return "", fmt.Errorf("%w: mode must be text, regex or advanced", ErrInvalidQuery)
+}
+
+// Validate checks q can be run safely
+func (q Query) Validate() error {
+	if strings.TrimSpace(q.Text) == "" {
+		return fmt.Errorf("%w: query cannot be empty", ErrInvalidQuery)
+	}
+	if q.Fuzzy && q.Mode != ModeText {
+		return fmt.Errorf("%w: fuzzy matching only applies to text mode", ErrInvalidQuery)
+	}
+	switch q.Mode {
+	case ModeRegex:
+		_, err := compileRegex(q.Text)
+		return err
+	case ModeAdvanced:
+		_, err := parseQuery(q.Text)
+		return err
+	}
+	return nil
+}
+
+// compileRegex validates a user supplied pattern and compiles it case
+// insensitively. Only the RE2 syntax is accepted (no backreferences or
+// lookarounds) and nested repetitions such as (a+)+, (a|ab)* or (a?){25} are
+// refused since they backtrack exponentially in the database regex engine.
+func compileRegex(pattern string) (*regexp.Regexp, error) {
+	if len(pattern) > MaxRegexLength {
+		return nil, fmt.Errorf("%w: pattern longer than %d characters", ErrInvalidQuery, MaxRegexLength)
+	}
+
+	re, err := syntax.Parse(pattern, syntax.Perl)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidQuery, err)
+	}
+	if hasNestedRepeat(re, false) {
+		return nil, fmt.Errorf("%w: nested repetition in pattern", ErrInvalidQuery)
+	}
+
+	return regexp.Compile("(?i)" + pattern)
+}
+
+// hasNestedRepeat reports whether re holds a repetition, an optional part or
+// an alternation inside another repetition, or repeats something that can
+// match the empty string
+func hasNestedRepeat(re *syntax.Regexp, inRepeat bool) bool {
+	repeat := false
+	switch re.Op {
+	case syntax.OpStar, syntax.OpPlus, syntax.OpQuest:
+		repeat = true
+	case syntax.OpRepeat:
+		repeat = re.Max == -1 || re.Max > 1 || re.Min == 0
+	case syntax.OpAlternate:
+		// Overlapping branches backtrack like a nested repetition
+		if inRepeat {
+			return true
+		}
+	}
+	if repeat && inRepeat {
+		return true
+	}
+	// Repeating an empty match, e.g. (a?){25}, backtracks over every way to
+	// split the input between the iterations
+	if repeat && re.Op != syntax.OpQuest && matchesEmpty(re.Sub[0]) {
+		return true
+	}
+	for _, sub := range re.Sub {
+		if hasNestedRepeat(sub, inRepeat || repeat) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesEmpty reports whether re can match the empty string
+func matchesEmpty(re *syntax.Regexp) bool {
+	switch re.Op {
+	case syntax.OpEmptyMatch, syntax.OpStar, syntax.OpQuest,
+		syntax.OpBeginLine, syntax.OpEndLine, syntax.OpBeginText, syntax.OpEndText,
+		syntax.OpWordBoundary, syntax.OpNoWordBoundary:
+		return true
+	case syntax.OpRepeat:
+		return re.Min == 0 || matchesEmpty(re.Sub[0])
+	case syntax.OpPlus, syntax.OpCapture:
+		return matchesEmpty(re.Sub[0])
+	case syntax.OpConcat:
+		for _, sub := range re.Sub {
+			if !matchesEmpty(sub) {
+				return false
+			}
+		}
+		return true
+	case syntax.OpAlternate:
+		for _, sub := range re.Sub {
+			if matchesEmpty(sub) {
+				return true
+			}
+		}
+	}
+	return false
+}
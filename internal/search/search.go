@@ -0,0 +1,113 @@
+package search
+
+import (
+	"context"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/rolexkdev/emvn-music-library-server/config"
+	"github.com/rolexkdev/emvn-music-library-server/internal/models"
+)
+
+// Relative weight of every searchable track field, a match in a heavier field
+// ranks the track higher
+var TrackWeights = map[string]int{
+	"title":        10,
+	"name":         8,
+	"artist_name":  5,
+	"album":        3,
+	"label":        2,
+	"master_owner": 2,
+	"writers":      2,
+	"publishers":   2,
+	"genre":        1,
+}
+
+// Searchable track fields from the heaviest to the lightest, Path is the raw
+// document field matched by regular expressions
+var trackFields = []struct {
+	Name  string
+	Path  string
+	Value func(*models.Track) string
+}{
+	{"title", "title", func(t *models.Track) string { return t.Title }},
+	{"name", "name", func(t *models.Track) string { return t.Name }},
+	{"artist_name", "artist_name", func(t *models.Track) string { return t.ArtistName }},
+	{"album", "album", func(t *models.Track) string { return t.Album }},
+	{"label", "label", func(t *models.Track) string { return t.Label }},
+	{"master_owner", "master_owner", func(t *models.Track) string { return t.MasterOwner }},
+	{"writers", "publishing.name", func(t *models.Track) string {
+		return strings.Join(models.PublishingNames(t.Publishing, models.PublishingWriter), " ")
+	}},
+	{"publishers", "publishing.name", func(t *models.Track) string {
+		return strings.Join(models.PublishingNames(t.Publishing, models.PublishingPublisher), " ")
+	}},
+	{"genre", "genre", func(t *models.Track) string { return t.Genre }},
+}
+
+// trackFieldPaths lists the distinct paths of trackFields
+func trackFieldPaths() []string {
+	var paths []string
+	seen := map[string]bool{}
+	for _, field := range trackFields {
+		if !seen[field.Path] {
+			seen[field.Path] = true
+			paths = append(paths, field.Path)
+		}
+	}
+	return paths
+}
+
+// TrackResult is a matching track with its relevance score
+type TrackResult struct {
+	*models.Track
+	Score float64 `json:"score"`
+	// MatchExact or MatchFuzzy
+	Match string `json:"match"`
+}
+
+// PlaylistResult is a matching playlist with its relevance score
+type PlaylistResult struct {
+	*models.Playlist
+	Score float64 `json:"score"`
+	// MatchExact or MatchFuzzy
+	Match string `json:"match"`
+}
+
+// sortByScore orders results by descending score, keeping the order of ties
+func sortByScore[T any](results []T, score func(T) float64) {
+	sort.SliceStable(results, func(i, j int) bool {
+		return score(results[i]) > score(results[j])
+	})
+}
+
+// Searcher finds tracks, playlists, albums and artists matching a query, best
+// match first, one page at a time. A query failing Validate is refused with
+// ErrInvalidQuery.
+type Searcher interface {
+	SearchTracks(ctx context.Context, query Query, filter *models.TrackFilter, page Page) (*TrackPage, error)
+	SearchPlaylists(ctx context.Context, query Query, page Page) (*PlaylistPage, error)
+	SearchAlbums(ctx context.Context, query Query, filter *models.TrackFilter, page Page) (*AlbumPage, error)
+	SearchArtists(ctx context.Context, query Query, filter *models.TrackFilter, page Page) (*ArtistPage, error)
+	Suggest(ctx context.Context, prefix string, filter *models.TrackFilter, limit int) ([]*Suggestion, error)
+	Facets(ctx context.Context, query Query, filter *models.TrackFilter) (*Facets, error)
+	EnsureIndexes(ctx context.Context) error
+}
+
+var (
+	Default        Searcher
+	MaxResults     int
+	SuggestTimeout time.Duration
+)
+
+func Setup(c *config.Config) {
+	MaxResults = c.Search.MaxResults
+	SuggestTimeout = c.Search.SuggestTimeout
+
+	Default = NewMongoSearcher(models.DB)
+	if err := Default.EnsureIndexes(context.Background()); err != nil {
+		log.Fatalf("search.Setup err: %v", err)
+	}
+}
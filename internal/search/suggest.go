@@ -0,0 +1,160 @@
+package search
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/rolexkdev/emvn-music-library-server/common/utils"
+	"github.com/rolexkdev/emvn-music-library-server/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"golang.org/x/sync/errgroup"
+)
+
+// Kinds of suggestion
+const (
+	SuggestTrack    = "track"
+	SuggestArtist   = "artist"
+	SuggestAlbum    = "album"
+	SuggestPlaylist = "playlist"
+)
+
+// Weight of every kind of suggestion, in the spirit of TrackWeights
+var suggestWeights = map[string]float64{
+	SuggestTrack:    10,
+	SuggestArtist:   5,
+	SuggestPlaylist: 4,
+	SuggestAlbum:    3,
+}
+
+// Suggestion completes what the user is typing
+type Suggestion struct {
+	Text string `json:"text"`
+	Type string `json:"type"`
+	// Track or playlist to open, empty for artists and albums
+	ID    string  `json:"id,omitempty"`
+	Score float64 `json:"score"`
+}
+
+// suggestSource is a field looked up for suggestions
+type suggestSource struct {
+	kind       string
+	collection *mongo.Collection
+	// Raw and normalised field names
+	field string
+	key   string
+	// Filter of the documents of a track source
+	filter *models.TrackFilter
+}
+
+// Suggest returns up to limit values of track titles, artist names, albums and
+// playlist titles with a word starting with prefix, the track values from the
+// tracks matching filter. Values starting with the prefix rank first, then the
+// heavier kinds and the values shared by many tracks.
+func (s *MongoSearcher) Suggest(ctx context.Context, prefix string, filter *models.TrackFilter, limit int) ([]*Suggestion, error) {
+	prefix = utils.NormalizeSearchText(prefix)
+	if utf8.RuneCountInString(prefix) < models.SuggestMinPrefix {
+		return nil, fmt.Errorf("%w: type at least %d characters", ErrInvalidQuery, models.SuggestMinPrefix)
+	}
+
+	sources := []suggestSource{
+		{SuggestTrack, s.Tracks, "title", "title", filter},
+		{SuggestArtist, s.Tracks, "artist_name", "artist_name", filter},
+		{SuggestAlbum, s.Tracks, "album", "album", filter},
+		{SuggestPlaylist, s.Playlists, "title", "title", nil},
+	}
+
+	found := make([][]*Suggestion, len(sources))
+	group, groupCtx := errgroup.WithContext(ctx)
+	for i, source := range sources {
+		i, source := i, source
+		group.Go(func() error {
+			suggestions, err := suggestFrom(groupCtx, source, prefix, limit)
+			found[i] = suggestions
+			return err
+		})
+	}
+	if err := group.Wait(); err != nil {
+		return nil, err
+	}
+
+	suggestions := []*Suggestion{}
+	for _, list := range found {
+		suggestions = append(suggestions, list...)
+	}
+	sortByScore(suggestions, func(s *Suggestion) float64 { return s.Score })
+	if len(suggestions) > limit {
+		suggestions = suggestions[:limit]
+	}
+	return suggestions, nil
+}
+
+// suggestFrom groups the documents of source holding a value with a word
+// starting with prefix by that value
+func suggestFrom(ctx context.Context, source suggestSource, prefix string, limit int) ([]*Suggestion, error) {
+	key := "search_keys." + source.key
+
+	// The gram index only goes up to SuggestMaxPrefix characters, longer
+	// prefixes are checked on the matching keys
+	gram := prefix
+	if runes := []rune(prefix); len(runes) > models.SuggestMaxPrefix {
+		gram = string(runes[:models.SuggestMaxPrefix])
+	}
+	match := bson.M{
+		key + "_grams": gram,
+		"delete_at":    bson.M{"$exists": false},
+	}
+	if gram != prefix {
+		match[key] = bson.M{"$regex": `(^|\s)` + regexp.QuoteMeta(prefix)}
+	}
+	source.filter.Apply(match)
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: match}},
+		{{Key: "$group", Value: bson.M{
+			"_id":   "$" + key,
+			"text":  bson.M{"$first": "$" + source.field},
+			"id":    bson.M{"$first": "$_id"},
+			"count": bson.M{"$sum": 1},
+		}}},
+		{{Key: "$sort", Value: bson.D{{Key: "count", Value: -1}}}},
+		{{Key: "$limit", Value: limit}},
+	}
+	cursor, err := source.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var suggestions []*Suggestion
+	for cursor.Next(ctx) {
+		var doc struct {
+			Key   string             `bson:"_id"`
+			Text  string             `bson:"text"`
+			ID    primitive.ObjectID `bson:"id"`
+			Count int                `bson:"count"`
+		}
+		if err := cursor.Decode(&doc); err != nil {
+			return nil, err
+		}
+
+		suggestion := &Suggestion{
+			Text:  doc.Text,
+			Type:  source.kind,
+			Score: suggestWeights[source.kind] + math.Log1p(float64(doc.Count)),
+		}
+		if strings.HasPrefix(doc.Key, prefix) {
+			suggestion.Score += suggestWeights[SuggestTrack]
+		}
+		if source.kind == SuggestTrack || source.kind == SuggestPlaylist {
+			suggestion.ID = doc.ID.Hex()
+		}
+		suggestions = append(suggestions, suggestion)
+	}
+	return suggestions, cursor.Err()
+}
@@ -0,0 +1,169 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rolexkdev/emvn-music-library-server/internal/models"
+)
+
+const (
+	auditTargetKey = "audit_target"
+
+	// Longest body read for the audit summary, the rest is only counted
+	maxAuditBody = 64 << 10
+	// Longest string kept in a body summary
+	maxAuditString = 200
+	// Most array items kept in a body summary
+	maxAuditItems = 50
+	// Deepest nesting kept in a body summary
+	maxAuditDepth = 5
+
+	redacted = "[REDACTED]"
+)
+
+// Parts of the JSON keys whose value is never written to the audit log
+var secretKeys = []string{"password", "secret", "token", "api_key", "apikey", "authorization"}
+
+// Audit records every mutating request in the audit log once it is answered,
+// including the ones rejected
+func Audit(c *gin.Context) {
+	switch c.Request.Method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+	default:
+		c.Next()
+		return
+	}
+
+	start := time.Now()
+	var body []byte
+	if c.Request.Body != nil {
+		body, _ = io.ReadAll(io.LimitReader(c.Request.Body, maxAuditBody))
+		c.Request.Body = struct {
+			io.Reader
+			io.Closer
+		}{io.MultiReader(bytes.NewReader(body), c.Request.Body), c.Request.Body}
+	}
+
+	c.Next()
+
+	caller := GetCaller(c)
+	route := c.FullPath()
+	if route == "" {
+		route = c.Request.URL.Path
+	}
+	entry := &models.AuditEntry{
+		CreateAt:  start,
+		RequestID: GetRequestID(c),
+		Actor:     caller.Name,
+		Role:      caller.Role,
+		Method:    c.Request.Method,
+		Route:     route,
+		Path:      c.Request.URL.Path,
+		TargetID:  auditTarget(c),
+		Status:    c.Writer.Status(),
+		Duration:  time.Since(start).Milliseconds(),
+		ClientIP:  c.ClientIP(),
+		Body:      summarizeBody(c.ContentType(), body, c.Request.ContentLength),
+	}
+
+	// A lost entry must not fail a request already answered
+	if err := models.Repository.Audit.Append(context.Background(), entry); err != nil {
+		log.Printf("Append audit entry of request %s failed with error: %v", entry.RequestID, err)
+	}
+}
+
+// SetAuditTarget records the id of the record a request created, which is not
+// in its path
+func SetAuditTarget(c *gin.Context, id string) {
+	c.Set(auditTargetKey, id)
+}
+
+func auditTarget(c *gin.Context) string {
+	if id := c.GetString(auditTargetKey); id != "" {
+		return id
+	}
+	if id := c.Param("id"); id != "" {
+		return id
+	}
+	return c.Param("filename")
+}
+
+// summarizeBody returns what the audit log keeps of a request body: JSON with
+// secrets redacted and long values cut, only the type and size of anything else
+func summarizeBody(contentType string, body []byte, length int64) interface{} {
+	if len(body) == 0 {
+		return nil
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	if strings.HasSuffix(mediaType, "json") && len(body) < maxAuditBody {
+		var value interface{}
+		if err := json.Unmarshal(body, &value); err == nil {
+			return redact(value, 0)
+		}
+	}
+
+	if length < 0 {
+		length = int64(len(body))
+	}
+	return map[string]interface{}{"content_type": mediaType, "bytes": length}
+}
+
+func redact(value interface{}, depth int) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		if depth >= maxAuditDepth {
+			return "[...]"
+		}
+		for key, item := range v {
+			if isSecret(key) {
+				v[key] = redacted
+			} else {
+				v[key] = redact(item, depth+1)
+			}
+		}
+		return v
+	case []interface{}:
+		if depth >= maxAuditDepth {
+			return "[...]"
+		}
+		if len(v) > maxAuditItems {
+			v = v[:maxAuditItems]
+		}
+		for i, item := range v {
+			v[i] = redact(item, depth+1)
+		}
+		return v
+	case string:
+		if len(v) > maxAuditString {
+			cut := maxAuditString
+			for cut > 0 && !utf8.RuneStart(v[cut]) {
+				cut--
+			}
+			return v[:cut] + "..."
+		}
+		return v
+	default:
+		return v
+	}
+}
+
+func isSecret(key string) bool {
+	key = strings.ToLower(key)
+	for _, secret := range secretKeys {
+		if strings.Contains(key, secret) {
+			return true
+		}
+	}
+	return false
+}
@@ -1 +1,91 @@
-package middleware
\ No newline at end of file
+package middleware
+
+import (
+	"crypto/subtle"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rolexkdev/emvn-music-library-server/common/app"
+	"github.com/rolexkdev/emvn-music-library-server/common/e"
+	"github.com/rolexkdev/emvn-music-library-server/config"
+)
+
+const (
+	RoleAdmin = "admin"
+	RoleUser  = "user"
+
+	// Header carrying the API key of the caller
+	APIKeyHeader = "X-API-Key"
+
+	callerKey = "caller"
+)
+
+// Caller is the client behind a request, anonymous when no API key was sent
+type Caller struct {
+	Name string
+	Role string
+	// Territory set on the API key, empty when the client sends its own
+	Territory string
+}
+
+var anonymous = &Caller{Name: "anonymous"}
+
+// Auth identifies the caller from its API key. Requests without a key go on
+// anonymously, requests with an unknown key are rejected.
+func Auth(keys []config.APIKey) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader(APIKeyHeader)
+		if key == "" {
+			c.Next()
+			return
+		}
+
+		for _, apiKey := range keys {
+			if subtle.ConstantTimeCompare([]byte(key), []byte(apiKey.Key)) == 1 {
+				c.Set(callerKey, &Caller{Name: apiKey.Name, Role: apiKey.Role, Territory: apiKey.Territory})
+				c.Next()
+				return
+			}
+		}
+
+		appG := app.Gin{C: c}
+		appG.Response401(e.UNAUTHORIZED, "Invalid API key")
+		c.Abort()
+	}
+}
+
+// GetCaller returns the caller identified by Auth
+func GetCaller(c *gin.Context) *Caller {
+	if caller, ok := c.Get(callerKey); ok {
+		return caller.(*Caller)
+	}
+	return anonymous
+}
+
+// IsAdmin reports whether the request was made with an admin API key
+func IsAdmin(c *gin.Context) bool {
+	return GetCaller(c).Role == RoleAdmin
+}
+
+// RequireRole rejects the requests not made with an API key of role
+func RequireRole(role string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if GetCaller(c).Role != role {
+			appG := app.Gin{C: c}
+			appG.Response403(e.FORBIDDEN, "This endpoint requires an API key with the "+role+" role")
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// RequireAPIKey rejects the requests made without an API key
+func RequireAPIKey(c *gin.Context) {
+	if GetCaller(c) == anonymous {
+		appG := app.Gin{C: c}
+		appG.Response403(e.FORBIDDEN, "This endpoint requires an API key")
+		c.Abort()
+		return
+	}
+	c.Next()
+}
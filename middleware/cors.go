@@ -14,6 +14,7 @@ func CORS(c *gin.Context) {
 	c.Header("Access-Control-Allow-Origin", "*")
 	c.Header("Access-Control-Allow-Methods", "*")
 	c.Header("Access-Control-Allow-Headers", "*")
+	c.Header("Access-Control-Expose-Headers", "ETag, X-Request-ID")
 	c.Header("Content-Type", "application/json")
 
 	// Second, we handle the OPTIONS problem
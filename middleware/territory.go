@@ -0,0 +1,22 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/rolexkdev/emvn-music-library-server/common/utils"
+)
+
+// Header carrying the ISO 3166-1 alpha-2 territory of the caller, e.g. VN
+const TerritoryHeader = "X-Territory"
+
+// GetTerritory returns the territory of the caller: the one set on its API key,
+// or else the one it sends in TerritoryHeader. It is empty when unknown, a
+// malformed header is ignored.
+func GetTerritory(c *gin.Context) string {
+	if territory := GetCaller(c).Territory; territory != "" {
+		return territory
+	}
+	if territory := c.GetHeader(TerritoryHeader); utils.IsTerritory(territory) {
+		return utils.NormalizeTerritory(territory)
+	}
+	return ""
+}
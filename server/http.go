@@ -5,6 +5,7 @@ import (
 	"github.com/rolexkdev/emvn-music-library-server/config"
 	"github.com/rolexkdev/emvn-music-library-server/docs"
 	v1 "github.com/rolexkdev/emvn-music-library-server/internal/handlers"
+	"github.com/rolexkdev/emvn-music-library-server/middleware"
 )
 
 func InitHttpRoutes(r *gin.RouterGroup, conf *config.Config) {
@@ -20,23 +21,72 @@ func InitHttpRoutes(r *gin.RouterGroup, conf *config.Config) {
 	//tracks
 	tracks := router.Group("/tracks")
 	tracks.POST("", v1.CreateTrack)
+	tracks.POST("/bulk", v1.BulkTracks)
+	tracks.POST("/import", v1.ImportTracks)
 	tracks.GET("", v1.GetTracks)
+	tracks.GET("/export", v1.ExportTracks)
 	tracks.GET("/:id", v1.GetTrack)
 	tracks.DELETE("/:id", v1.DeleteTrack)
 	tracks.PUT("/:id", v1.UpdateTrack)
+	tracks.PATCH("/:id", v1.PatchTrack)
+	tracks.GET("/:id/waveform", v1.GetTrackWaveform)
+	tracks.POST("/:id/analysis", v1.AnalyzeTrack)
+	tracks.POST("/:id/restore", v1.RestoreTrack)
+	tracks.GET("/:id/history", v1.GetTrackHistory)
+	tracks.POST("/:id/history/:rev/restore", v1.RestoreTrackRevision)
 
 	//playlist
 	playlists := router.Group("/playlists")
 	playlists.POST("", v1.CreatePlaylist)
 	playlists.GET("", v1.GetPlaylists)
+	playlists.GET("/export", v1.ExportPlaylists)
 	playlists.GET("/:id", v1.GetPlaylist)
 	playlists.DELETE("/:id", v1.DeletePlaylist)
 	playlists.PUT("/:id", v1.UpdatePlaylist)
+	playlists.PATCH("/:id", v1.PatchPlaylist)
 	playlists.POST("/:id/tracks", v1.UpdatePlaylistTrack)
 	playlists.GET("/:id/m3u", v1.GenerateM3UPlaylist)
+	playlists.POST("/:id/restore", v1.RestorePlaylist)
+	playlists.GET("/:id/history", v1.GetPlaylistHistory)
+	playlists.POST("/:id/history/:rev/restore", v1.RestorePlaylistRevision)
+
+	//albums and artists
+	albums := router.Group("/albums")
+	albums.GET("", v1.GetAlbums)
+	albums.GET("/:id", v1.GetAlbum)
+	albums.GET("/:id/ddex", v1.ExportAlbumDDEX)
+
+	artists := router.Group("/artists")
+	artists.GET("", v1.GetArtists)
+	artists.GET("/:id", v1.GetArtist)
+
+	//trash
+	router.GET("/trash", v1.GetTrash)
 
 	//search
 	search := router.Group("/search")
 	search.GET("", v1.Search)
+	search.GET("/suggest", v1.Suggest)
+
+	//jobs
+	jobs := router.Group("/jobs")
+	jobs.GET("", v1.GetJobs)
+	jobs.GET("/:id", v1.GetJob)
+	jobs.POST("/:id/retry", v1.RetryJob)
+
+	//licenses
+	licenses := router.Group("/licenses", middleware.RequireAPIKey)
+	licenses.POST("/requests", v1.CreateLicenseRequest)
+	licenses.GET("/requests", v1.GetLicenseRequests)
+	licenses.GET("/requests/:id", v1.GetLicenseRequest)
+	licenses.POST("/requests/:id/quote", middleware.RequireRole(middleware.RoleAdmin), v1.QuoteLicenseRequest)
+	licenses.POST("/requests/:id/status", v1.UpdateLicenseStatus)
+	licenses.GET("/requests/:id/summary", v1.GetLicenseSummary)
+
+	//admin
+	admin := router.Group("/admin", middleware.RequireRole(middleware.RoleAdmin))
+	admin.GET("/audit", v1.GetAuditLog)
+	admin.GET("/audit/export", v1.ExportAuditLog)
+	admin.POST("/ddex/import", v1.ImportDDEX)
 
 }
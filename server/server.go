@@ -23,6 +23,9 @@ func InitServer(conf *config.Config) {
 
 	// Middlewares
 	appEngine.Use(middleware.CORS)
+	appEngine.Use(middleware.RequestID)
+	appEngine.Use(middleware.Audit)
+	appEngine.Use(middleware.Auth(conf.Auth.APIKeys))
 
 	router := appEngine.Group("/api/" + serverConfig.AppVersion)
 